@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/database"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+	"github.com/Bilal-Cplusoft/sunready/internal/service"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const catalogUsage = `usage: sunready catalog import [-kind panels|inverters|storages] [-dry-run] [-json] FILE
+
+Upserts a California Energy Commission panel, inverter or battery list,
+exported to CSV, into the hardware catalog. FILE may be - for stdin.`
+
+// runCommand runs the subcommand named by args instead of the server.
+func runCommand(args []string) error {
+	switch args[0] {
+	case "catalog":
+		if len(args) < 2 || args[1] != "import" {
+			return errors.New(catalogUsage)
+		}
+		return runCatalogImport(args[2:])
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", args[0], catalogUsage)
+	}
+}
+
+func runCatalogImport(args []string) error {
+	flags := flag.NewFlagSet("catalog import", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), catalogUsage) }
+	kind := flags.String("kind", "", "panels, inverters or storages; detected from the columns when omitted")
+	dryRun := flags.Bool("dry-run", false, "report the changes without saving them")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New(catalogUsage)
+	}
+
+	var in io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		return errors.New("DATABASE_URL environment variable is required")
+	}
+	db, err := database.New(databaseURL)
+	if err != nil {
+		return err
+	}
+	// Statement logging would print every row of the import.
+	db = db.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Warn)})
+
+	importService := service.NewCatalogImportService(repo.NewHardwareRepo(db))
+	report, err := importService.Import(context.Background(), in, *kind, *dryRun)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printCatalogReport(os.Stdout, report)
+	return nil
+}
+
+func printCatalogReport(w io.Writer, report *service.CatalogImportReport) {
+	for _, item := range report.Created {
+		fmt.Fprintf(w, "+ %s %s (line %d)\n", item.Manufacturer, item.Model, item.Line)
+		for _, change := range item.Changes {
+			fmt.Fprintf(w, "    %s: %v\n", change.Field, change.To)
+		}
+	}
+	for _, item := range report.Updated {
+		fmt.Fprintf(w, "~ #%d %s %s (line %d)\n", item.ID, item.Manufacturer, item.Model, item.Line)
+		for _, change := range item.Changes {
+			from := "-"
+			if change.From != nil {
+				from = fmt.Sprint(change.From)
+			}
+			fmt.Fprintf(w, "    %s: %s -> %v\n", change.Field, from, change.To)
+		}
+	}
+	for _, skip := range report.Skipped {
+		fmt.Fprintf(w, "! line %d %s %s: %s\n", skip.Line, skip.Manufacturer, skip.Model, skip.Reason)
+	}
+
+	verb := "Imported"
+	if report.DryRun {
+		verb = "Dry run:"
+	}
+	fmt.Fprintf(w, "%s %d %s rows: %d created, %d updated, %d unchanged, %d skipped\n",
+		verb, report.Rows, report.Kind, len(report.Created), len(report.Updated), report.Unchanged, len(report.Skipped))
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
     "io/ioutil"
 	_ "github.com/Bilal-Cplusoft/sunready/docs"
 	"github.com/Bilal-Cplusoft/sunready/internal/client"
@@ -40,6 +43,12 @@ func main() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using system environment variables")
 	}
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	databaseURL, jwtSecret, port := os.Getenv("DATABASE_URL"), os.Getenv("JWT_SECRET"), os.Getenv("PORT")
 	if databaseURL == "" {
@@ -67,26 +76,87 @@ func main() {
 
 	userRepo := repo.NewUserRepo(db)
 	quoteRepo := repo.NewQuoteRepo(db)
+	pricingProfileRepo := repo.NewPricingProfileRepo(db)
+	incentiveRuleRepo := repo.NewIncentiveRuleRepo(db)
+	proposalRepo := repo.NewProposalRepo(db)
+	proposalShareRepo := repo.NewProposalShareRepo(db)
 	leadRepo := repo.NewLeadRepo(db)
 	houseRepo := repo.NewHouseRepo(db)
 	hardwareRepo := repo.NewHardwareRepo(db)
+	jobRepo := repo.NewJobRepo(db)
 
 	twilioClient, sendGridClient := client.InitializeTwilio(), client.InitializeSendGrid()
-	lightFusionURL, lightFusionEmail, lightFusionPassword := os.Getenv("LIGHTFUSION_API"), os.Getenv("LIGHTFUSION_EMAIL"), os.Getenv("LIGHTFUSION_PASSWORD")
-	lightFusionClient := client.NewLightFusionClient(lightFusionURL,lightFusionEmail, lightFusionPassword)
+	var lightFusionClient client.LightFusion
+	if os.Getenv("LIGHTFUSION_FAKE") == "true" {
+		log.Println("Using in-process fake LightFusion API")
+		fakeLightFusion := client.NewFakeLightFusion()
+		defer fakeLightFusion.Close()
+		lightFusionClient = fakeLightFusion.Client("./media")
+	} else {
+		lightFusionClient = client.NewLightFusionClient(client.LightFusionConfig{
+			BaseURL:            os.Getenv("LIGHTFUSION_API"),
+			Email:              os.Getenv("LIGHTFUSION_EMAIL"),
+			Password:           os.Getenv("LIGHTFUSION_PASSWORD"),
+			MeshBaseURL:        os.Getenv("LIGHTFUSION_MESH_URL"),
+			InsecureSkipVerify: os.Getenv("LIGHTFUSION_INSECURE_TLS") == "true",
+		})
+	}
 
 	authService := service.NewAuthService(userRepo, jwtSecret)
 	userService := service.NewUserService(userRepo)
-	quoteService := service.NewQuoteService(quoteRepo)
-	leadService := service.NewLeadService(leadRepo, houseRepo,lightFusionClient,userRepo)
+	jobWorkers, _ := strconv.Atoi(os.Getenv("JOB_WORKERS"))
+	if jobWorkers <= 0 {
+		jobWorkers = 4
+	}
+	jobQueue := service.NewJobQueue(jobRepo, jobWorkers)
+
+	eventBus := service.NewEventBus()
+	quoteService := service.NewQuoteService(quoteRepo, pricingProfileRepo, incentiveRuleRepo, userRepo, hardwareRepo, eventBus)
+	leadStateMachine := service.NewLeadStateMachine(leadRepo, eventBus)
+	milestoneService := service.NewMilestoneService(leadRepo)
+	if path := os.Getenv("CLIMATE_GRID_FILE"); path != "" {
+		if err := service.UseClimateGridFile(path); err != nil {
+			log.Fatalf("Failed to load climate grid: %v", err)
+		}
+	}
+	productionService := service.NewProductionService(hardwareRepo)
+	catalogImportService := service.NewCatalogImportService(hardwareRepo)
+	storageService := service.NewStorageService(hardwareRepo, productionService)
+	designService := service.NewDesignService(hardwareRepo)
+	proposalService := service.NewProposalService(proposalRepo, quoteRepo, userRepo, hardwareRepo, lightFusionClient)
+	transactor := repo.NewTransactor(db)
+	proposalShareService := service.NewProposalShareService(transactor, proposalShareRepo, leadRepo, quoteRepo, userRepo, hardwareRepo, proposalService, lightFusionClient, sendGridClient, jobQueue, eventBus, jwtSecret)
+	leadService := service.NewLeadService(transactor, leadRepo, houseRepo,lightFusionClient,userRepo, jobQueue, leadStateMachine, eventBus, designService, hardwareRepo, service.LightFusionHardwareDefaults{
+		PanelID:    envInt("LIGHTFUSION_DEFAULT_PANEL_ID", 156),
+		InverterID: envInt("LIGHTFUSION_DEFAULT_INVERTER_ID", 324),
+		StorageID:  envInt("LIGHTFUSION_DEFAULT_STORAGE_ID", 0),
+	})
+
+	leadSyncService := service.NewLeadSyncService(leadRepo, lightFusionClient, leadStateMachine, eventBus)
+	leadSyncInterval, err := time.ParseDuration(os.Getenv("LEAD_SYNC_INTERVAL"))
+	if err != nil || leadSyncInterval <= 0 {
+		leadSyncInterval = time.Minute
+	}
+
+	go jobQueue.Start(context.Background())
+	go leadSyncService.Start(context.Background(), leadSyncInterval)
 
 
 	authHandler := handler.NewAuthHandler(authService, sendGridClient)
 	userHandler := handler.NewUserHandler(userService)
-	quoteHandler := handler.NewQuoteHandler(quoteService)
+	quoteHandler := handler.NewQuoteHandler(quoteService, leadRepo)
 	leadHandler := handler.NewLeadHandler(leadRepo, leadService, userRepo)
 	otpHandler := handler.NewOtpHandler(twilioClient, sendGridClient)
 	hardwareHandler := handler.NewHardwareHandler(hardwareRepo)
+	catalogHandler := handler.NewCatalogHandler(catalogImportService)
+	milestoneHandler := handler.NewMilestoneHandler(milestoneService, leadRepo)
+	pricingProfileHandler := handler.NewPricingProfileHandler(pricingProfileRepo)
+	incentiveHandler := handler.NewIncentiveHandler(incentiveRuleRepo)
+	productionHandler := handler.NewProductionHandler(productionService, leadRepo)
+	storageHandler := handler.NewStorageHandler(storageService, leadRepo)
+	designHandler := handler.NewDesignHandler(designService)
+	proposalHandler := handler.NewProposalHandler(proposalService, leadRepo)
+	proposalShareHandler := handler.NewProposalShareHandler(proposalShareService, leadRepo)
 
 	r := chi.NewRouter()
 
@@ -104,9 +174,25 @@ func main() {
 		user.Use(middleware.AuthMiddleware(authService))
 		user.Post("/api/leads", leadHandler.CreateLead)
 		user.Get("/api/leads/{id}/mesh-files", leadHandler.GetMeshFiles)
+		user.Get("/api/leads/{id}/history", leadHandler.GetLeadHistory)
+		user.Get("/api/leads/{id}/events", leadHandler.StreamLeadEvents)
+		user.Post("/api/leads/{id}/quote", quoteHandler.QuoteLead)
+		user.Get("/api/leads/{id}/quotes", quoteHandler.ListLeadQuotes)
+		user.Get("/api/leads/{id}/quotes/diff", quoteHandler.DiffLeadQuotes)
+		user.Get("/api/leads/{id}/quotes/{version}", quoteHandler.GetLeadQuote)
+		user.Get("/api/leads/{id}/production", productionHandler.GetLeadProduction)
+		user.Post("/api/leads/{id}/storage-analysis", storageHandler.AnalyzeLeadStorage)
+		user.Get("/api/leads/{id}/proposal.pdf", proposalHandler.GetProposalPDF)
+		user.Get("/api/leads/{id}/proposals", proposalHandler.ListLeadProposals)
+		user.Post("/api/leads/{id}/shares", proposalShareHandler.CreateProposalShare)
+		user.Get("/api/leads/{id}/shares", proposalShareHandler.ListProposalShares)
+		user.Get("/api/leads/{id}/milestones", milestoneHandler.GetMilestones)
+		user.Put("/api/leads/{id}/milestones/{milestone}", milestoneHandler.UpdateMilestone)
 		user.Get("/api/leads/{id}", leadHandler.GetLead)
 		user.Put("/api/leads/{id}", leadHandler.UpdateLead)
 		user.Post("/api/quote", quoteHandler.GetQuote)
+		user.Post("/api/quote/sensitivity", quoteHandler.QuoteSensitivity)
+		user.Post("/api/design/validate", designHandler.ValidateDesign)
 	})
 	r.Group(func(admin chi.Router) {
 		admin.Use(middleware.AdminMiddleware(authService))
@@ -114,11 +200,34 @@ func main() {
 		admin.Put("/admin/users/{id}", userHandler.Update)
 		admin.Delete("/admin/users/{id}", userHandler.Delete)
 		admin.Get("/admin/users", userHandler.List)
-		admin.Post("/admin/hardware/panel",hardwareHandler.AddPanel)
-		admin.Post("/admin/hardware/storage",hardwareHandler.AddStorage)
-		admin.Post("/admin/hardware/inverter",hardwareHandler.AddInverter)
+		// The singular routes predate the plural ones and are only kept
+		// for old clients.
+		admin.With(middleware.Deprecated("/admin/hardware/panels")).Post("/admin/hardware/panel", hardwareHandler.AddPanel)
+		admin.With(middleware.Deprecated("/admin/hardware/storages")).Post("/admin/hardware/storage", hardwareHandler.AddStorage)
+		admin.With(middleware.Deprecated("/admin/hardware/inverters")).Post("/admin/hardware/inverter", hardwareHandler.AddInverter)
+		admin.Post("/admin/hardware/panels", hardwareHandler.AddPanel)
+		admin.Put("/admin/hardware/panels/{id}", hardwareHandler.UpdatePanel)
+		admin.Delete("/admin/hardware/panels/{id}", hardwareHandler.DeletePanel)
+		admin.Post("/admin/hardware/inverters", hardwareHandler.AddInverter)
+		admin.Put("/admin/hardware/inverters/{id}", hardwareHandler.UpdateInverter)
+		admin.Delete("/admin/hardware/inverters/{id}", hardwareHandler.DeleteInverter)
+		admin.Post("/admin/hardware/storages", hardwareHandler.AddStorage)
+		admin.Put("/admin/hardware/storages/{id}", hardwareHandler.UpdateStorage)
+		admin.Delete("/admin/hardware/storages/{id}", hardwareHandler.DeleteStorage)
+		admin.Post("/admin/hardware/import", catalogHandler.ImportCatalog)
 		admin.Get("/admin/leads", leadHandler.ListLeads)
+		admin.Get("/admin/leads/pipeline", milestoneHandler.Pipeline)
 		admin.Delete("/admin/leads/{id}", leadHandler.DeleteLead)
+		admin.Get("/admin/pricing-profiles", pricingProfileHandler.ListPricingProfiles)
+		admin.Post("/admin/pricing-profiles", pricingProfileHandler.CreatePricingProfile)
+		admin.Get("/admin/pricing-profiles/{id}", pricingProfileHandler.GetPricingProfile)
+		admin.Put("/admin/pricing-profiles/{id}", pricingProfileHandler.UpdatePricingProfile)
+		admin.Delete("/admin/pricing-profiles/{id}", pricingProfileHandler.DeletePricingProfile)
+		admin.Get("/admin/incentives", incentiveHandler.ListIncentives)
+		admin.Post("/admin/incentives", incentiveHandler.CreateIncentive)
+		admin.Get("/admin/incentives/{id}", incentiveHandler.GetIncentive)
+		admin.Put("/admin/incentives/{id}", incentiveHandler.UpdateIncentive)
+		admin.Delete("/admin/incentives/{id}", incentiveHandler.DeleteIncentive)
 	})
 
 	r.Post("/api/auth/register", authHandler.Register)
@@ -130,6 +239,14 @@ func main() {
 	r.Get("/api/hardware/panels", hardwareHandler.ListPanels)
 	r.Get("/api/hardware/storages", hardwareHandler.ListStorages)
 	r.Get("/api/hardware/inverters", hardwareHandler.ListInverters)
+	r.Get("/api/hardware/panels/{id}", hardwareHandler.GetPanel)
+	r.Get("/api/hardware/storages/{id}", hardwareHandler.GetStorage)
+	r.Get("/api/hardware/inverters/{id}", hardwareHandler.GetInverter)
+
+	r.Get("/p/{code}", proposalShareHandler.OpenProposalShare)
+	r.Get("/p/{code}/proposal.pdf", proposalShareHandler.GetSharedProposalPDF)
+	r.Post("/p/{code}/accept", proposalShareHandler.AcceptProposalShare)
+	r.Post("/p/{code}/request-changes", proposalShareHandler.RequestProposalChanges)
 
 	fileServer := http.StripPrefix("/media/", http.FileServer(http.Dir("./media")))
 	r.Handle("/media/*", fileServer)
@@ -153,3 +270,13 @@ func main() {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
+
+// envInt reads an integer environment variable, falling back when it is
+// unset or not a number.
+func envInt(name string, fallback int) int {
+	v, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
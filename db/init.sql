@@ -1,33 +1,37 @@
 -- Insert into storages
 INSERT INTO storages (id, manufacturer, model, capacity, created_at, updated_at) VALUES
-(101, 'BigBattery, Inc.', 'FETHS-48051-G1-08-18K-01 [240V]', 4096, NOW(), NOW()),
-(102, 'BigBattery, Inc.', 'FETHS-48051-G1-08-18K-02 [208V]', 4096, NOW(), NOW()),
-(103, 'BigBattery, Inc.', 'FETHS-48051-G1-08-18K-02 [240V]', 4096, NOW(), NOW()),
-(104, 'BigBattery, Inc.', 'FETHS-48051-G1-08-18K-03 [208V]', 4096, NOW(), NOW()),
-(105, 'BigBattery, Inc.', 'FETHS-48051-G1-08-18K-03 [240V]', 4096, NOW(), NOW()),
-(106, 'BigBattery, Inc.', 'FETHS-48051-G1-08-INV020-01 [208V]', 4096, NOW(), NOW()),
-(107, 'BigBattery, Inc.', 'FETHS-48051-G1-08-INV020-01 [240V]', 4096, NOW(), NOW()),
-(108, 'BigBattery, Inc.', 'FETHS-48051-G1-08-INV020-02 [208V]', 4096, NOW(), NOW()),
-(109, 'BigBattery, Inc.', 'FETHS-48051-G1-08-INV020-02 [240V]', 4096, NOW(), NOW()),
-(110, 'BigBattery, Inc.', 'FETHS-48051-G1-08-INV020-03 [208V]', 4096, NOW(), NOW())
+(101, 'BigBattery, Inc.', 'FETHS-48051-G1-08-18K-01 [240V]', 4.096, NOW(), NOW()),
+(102, 'BigBattery, Inc.', 'FETHS-48051-G1-08-18K-02 [208V]', 4.096, NOW(), NOW()),
+(103, 'BigBattery, Inc.', 'FETHS-48051-G1-08-18K-02 [240V]', 4.096, NOW(), NOW()),
+(104, 'BigBattery, Inc.', 'FETHS-48051-G1-08-18K-03 [208V]', 4.096, NOW(), NOW()),
+(105, 'BigBattery, Inc.', 'FETHS-48051-G1-08-18K-03 [240V]', 4.096, NOW(), NOW()),
+(106, 'BigBattery, Inc.', 'FETHS-48051-G1-08-INV020-01 [208V]', 4.096, NOW(), NOW()),
+(107, 'BigBattery, Inc.', 'FETHS-48051-G1-08-INV020-01 [240V]', 4.096, NOW(), NOW()),
+(108, 'BigBattery, Inc.', 'FETHS-48051-G1-08-INV020-02 [208V]', 4.096, NOW(), NOW()),
+(109, 'BigBattery, Inc.', 'FETHS-48051-G1-08-INV020-02 [240V]', 4.096, NOW(), NOW()),
+(110, 'BigBattery, Inc.', 'FETHS-48051-G1-08-INV020-03 [208V]', 4.096, NOW(), NOW())
 ON CONFLICT (id) DO NOTHING;
 
+-- Storage capacity is in kWh. The seeds above were once inserted in Wh;
+-- no home battery unit reaches 1000 kWh, so larger values are converted.
+UPDATE storages SET capacity = capacity / 1000 WHERE capacity >= 1000;
+
 -- Insert into inverters
-INSERT INTO inverters (id, manufacturer, model, capacity, created_at, updated_at) VALUES
-(5557, 'ABB', 'PVI-3.0-OUTD-S-US-A [208V]', 3, NOW(), NOW()),
-(5558, 'ABB', 'PVI-3.0-OUTD-S-US-A [240V]', 3, NOW(), NOW()),
-(5559, 'ABB', 'PVI-3.0-OUTD-S-US-A [277V]', 3, NOW(), NOW()),
-(5560, 'ABB', 'PVI-3.0-OUTD-S-US-Z-A [208V]', 3, NOW(), NOW()),
-(6035, 'Enphase Energy Inc.', 'IQ7A-72-E-ACM-US-NM [240V]', 0.349, NOW(), NOW()),
-(6036, 'Enphase Energy Inc.', 'IQ7A-72-E-US [240V]', 0.349, NOW(), NOW()),
-(6037, 'Enphase Energy Inc.', 'IQ7A-72-M-US [240V]', 0.349, NOW(), NOW()),
-(6091, 'Fortress Power LLC', 'FP-ENVY-8K [208V]', 7.85995, NOW(), NOW()),
-(6092, 'Fortress Power LLC', 'FP-ENVY-8K [240V]', 7.86266, NOW(), NOW()),
-(6105, 'Fronius International GmbH', 'Fronius Primo 3.8-1 208-240 [240V]', 3.8, NOW(), NOW()),
-(6106, 'Fronius International GmbH', 'Primo GEN24 3.8 208-240 [240V]', 3.802, NOW(), NOW()),
-(6107, 'Fronius International GmbH', 'Primo GEN24 3.8 208-240 Plus [240V]', 3.802, NOW(), NOW()),
-(6128, 'Fronius International GmbH', 'Fronius Primo 8.2-1 208-240 [208V]', 7.9, NOW(), NOW()),
-(6129, 'Fronius International GmbH', 'Fronius Primo 8.2-1 208-240 [240V]', 8.2, NOW(), NOW())
+INSERT INTO inverters (id, manufacturer, model, capacity, type, created_at, updated_at) VALUES
+(5557, 'ABB', 'PVI-3.0-OUTD-S-US-A [208V]', 3, 'string', NOW(), NOW()),
+(5558, 'ABB', 'PVI-3.0-OUTD-S-US-A [240V]', 3, 'string', NOW(), NOW()),
+(5559, 'ABB', 'PVI-3.0-OUTD-S-US-A [277V]', 3, 'string', NOW(), NOW()),
+(5560, 'ABB', 'PVI-3.0-OUTD-S-US-Z-A [208V]', 3, 'string', NOW(), NOW()),
+(6035, 'Enphase Energy Inc.', 'IQ7A-72-E-ACM-US-NM [240V]', 0.349, 'micro', NOW(), NOW()),
+(6036, 'Enphase Energy Inc.', 'IQ7A-72-E-US [240V]', 0.349, 'micro', NOW(), NOW()),
+(6037, 'Enphase Energy Inc.', 'IQ7A-72-M-US [240V]', 0.349, 'micro', NOW(), NOW()),
+(6091, 'Fortress Power LLC', 'FP-ENVY-8K [208V]', 7.85995, 'string', NOW(), NOW()),
+(6092, 'Fortress Power LLC', 'FP-ENVY-8K [240V]', 7.86266, 'string', NOW(), NOW()),
+(6105, 'Fronius International GmbH', 'Fronius Primo 3.8-1 208-240 [240V]', 3.8, 'string', NOW(), NOW()),
+(6106, 'Fronius International GmbH', 'Primo GEN24 3.8 208-240 [240V]', 3.802, 'string', NOW(), NOW()),
+(6107, 'Fronius International GmbH', 'Primo GEN24 3.8 208-240 Plus [240V]', 3.802, 'string', NOW(), NOW()),
+(6128, 'Fronius International GmbH', 'Fronius Primo 8.2-1 208-240 [208V]', 7.9, 'string', NOW(), NOW()),
+(6129, 'Fronius International GmbH', 'Fronius Primo 8.2-1 208-240 [240V]', 8.2, 'string', NOW(), NOW())
 ON CONFLICT (id) DO NOTHING;
 
 -- Insert into panels
@@ -49,3 +53,9 @@ INSERT INTO panels (id, manufacturer, model, wattage, longside, shortside, creat
 (321, 'APOS Energy', 'AS180', 180, 1.341, 1, NOW(), NOW()),
 (322, 'APOS Energy', 'AP185', 185, 1.341, 1, NOW(), NOW())
 ON CONFLICT (id) DO NOTHING;
+
+-- The seeds above use fixed IDs; move the ID sequences past them so items
+-- added by admins or catalog imports do not collide.
+SELECT setval(pg_get_serial_sequence('storages', 'id'), GREATEST((SELECT MAX(id) FROM storages), 1));
+SELECT setval(pg_get_serial_sequence('inverters', 'id'), GREATEST((SELECT MAX(id) FROM inverters), 1));
+SELECT setval(pg_get_serial_sequence('panels', 'id'), GREATEST((SELECT MAX(id) FROM panels), 1));
@@ -23,11 +23,12 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/admin/hardware/panels": {
+        "/admin/hardware/import": {
             "post": {
-                "description": "Add a new panel record",
+                "description": "Upserts the panels, inverters or batteries of a California Energy Commission equipment list exported to CSV. Items are matched on manufacturer and model, so existing items keep their IDs; items missing from the file are left alone. Send the file as the \"file\" field of a multipart form or as the raw request body. Use dry_run to see the changes without saving them.",
                 "consumes": [
-                    "application/json"
+                    "multipart/form-data",
+                    "text/csv"
                 ],
                 "produces": [
                     "application/json"
@@ -35,49 +36,58 @@ const docTemplate = `{
                 "tags": [
                     "Hardware"
                 ],
-                "summary": "Add a new panel",
+                "summary": "Import a CEC equipment list",
                 "parameters": [
                     {
-                        "description": "Panel payload",
-                        "name": "panel",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Panel"
-                        }
+                        "type": "string",
+                        "description": "panels, inverters or storages; detected from the columns when omitted",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Report the changes without saving them",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "file",
+                        "description": "CEC list as CSV",
+                        "name": "file",
+                        "in": "formData"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Panel"
+                            "$ref": "#/definitions/service.CatalogImportReport"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/admin/hardware/storages": {
+        "/admin/hardware/inverters": {
             "post": {
-                "description": "Add a new storage record",
+                "description": "Add a new inverter record",
                 "consumes": [
                     "application/json"
                 ],
@@ -87,15 +97,15 @@ const docTemplate = `{
                 "tags": [
                     "Hardware"
                 ],
-                "summary": "Add a new storage unit",
+                "summary": "Add a new inverter",
                 "parameters": [
                     {
-                        "description": "Storage payload",
-                        "name": "storage",
+                        "description": "Inverter payload",
+                        "name": "inverter",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Storage"
+                            "$ref": "#/definitions/models.Inverter"
                         }
                     }
                 ],
@@ -103,7 +113,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Storage"
+                            "$ref": "#/definitions/models.Inverter"
                         }
                     },
                     "400": {
@@ -127,60 +137,74 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/leads": {
-            "get": {
-                "description": "Retrieves a paginated list of leads",
+        "/admin/hardware/inverters/{id}": {
+            "put": {
+                "description": "Updates the fields present in the body; omitted fields keep their values. Set active to true to restore a removed inverter.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "leads"
+                    "Hardware"
                 ],
-                "summary": "List leads",
+                "summary": "Update an inverter",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Filter by creator ID",
-                        "name": "user_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 20,
-                        "description": "Number of items per page",
-                        "name": "limit",
-                        "in": "query"
+                        "description": "Inverter ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "default": 0,
-                        "description": "Number of items to skip",
-                        "name": "offset",
-                        "in": "query"
+                        "description": "Inverter fields",
+                        "name": "inverter",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Inverter"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/models.Inverter"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/admin/leads/{id}": {
+            },
             "delete": {
-                "description": "Deletes a lead by ID",
+                "description": "Marks the inverter inactive so it no longer lists. Leads and quotes that use it keep resolving it.",
                 "tags": [
-                    "leads"
+                    "Hardware"
                 ],
-                "summary": "Delete a lead",
+                "summary": "Remove an inverter from the catalog",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Lead ID",
+                        "description": "Inverter ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -190,6 +214,12 @@ const docTemplate = `{
                     "204": {
                         "description": "No Content"
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -205,9 +235,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/users/{id}": {
-            "get": {
-                "description": "Retrieves a user by their unique ID",
+        "/admin/hardware/panels": {
+            "post": {
+                "description": "Add a new panel record",
                 "consumes": [
                     "application/json"
                 ],
@@ -215,27 +245,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "Hardware"
                 ],
-                "summary": "Get user by ID",
+                "summary": "Add a new panel",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Panel payload",
+                        "name": "panel",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Panel"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.Panel"
                         }
                     },
                     "400": {
-                        "description": "Invalid user ID",
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -243,8 +275,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "User not found",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -253,9 +285,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/hardware/panels/{id}": {
             "put": {
-                "description": "Updates an existing user's information",
+                "description": "Updates the fields present in the body; omitted fields keep their values. Set active to true to restore a removed panel.",
                 "consumes": [
                     "application/json"
                 ],
@@ -263,24 +297,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "Hardware"
                 ],
-                "summary": "Update user",
+                "summary": "Update a panel",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "User ID",
+                        "description": "Panel ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "User update payload",
-                        "name": "user",
+                        "description": "Panel fields",
+                        "name": "panel",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.Panel"
                         }
                     }
                 ],
@@ -288,45 +322,39 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.Panel"
                         }
                     },
                     "400": {
-                        "description": "Invalid user ID or request body",
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to update user",
+                        "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Deletes a user by their unique ID",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Marks the panel inactive so it no longer lists. Leads and quotes that use it keep resolving it.",
                 "tags": [
-                    "users"
+                    "Hardware"
                 ],
-                "summary": "Delete user",
+                "summary": "Remove a panel from the catalog",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "User ID",
+                        "description": "Panel ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -337,29 +365,29 @@ const docTemplate = `{
                         "description": "No Content"
                     },
                     "400": {
-                        "description": "Invalid user ID",
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to delete user",
+                        "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/auth/login": {
+        "/admin/hardware/storages": {
             "post": {
-                "description": "Authenticate user and return JWT token",
+                "description": "Add a new storage record",
                 "consumes": [
                     "application/json"
                 ],
@@ -367,45 +395,51 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "Hardware"
                 ],
-                "summary": "Login user",
+                "summary": "Add a new storage unit",
                 "parameters": [
                     {
-                        "description": "Login credentials",
-                        "name": "request",
+                        "description": "Storage payload",
+                        "name": "storage",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.LoginRequest"
+                            "$ref": "#/definitions/models.Storage"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/handler.AuthResponse"
+                            "$ref": "#/definitions/models.Storage"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/api/auth/register": {
-            "post": {
-                "description": "Register a new user with email and password",
+        "/admin/hardware/storages/{id}": {
+            "put": {
+                "description": "Updates the fields present in the body; omitted fields keep their values. Set active to true to restore a removed storage unit.",
                 "consumes": [
                     "application/json"
                 ],
@@ -413,25 +447,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "Hardware"
                 ],
-                "summary": "Register a new user",
+                "summary": "Update a storage unit",
                 "parameters": [
                     {
-                        "description": "Registration details",
-                        "name": "request",
+                        "type": "integer",
+                        "description": "Storage ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Storage fields",
+                        "name": "storage",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.RegisterRequest"
+                            "$ref": "#/definitions/models.Storage"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.AuthResponse"
+                            "$ref": "#/definitions/models.Storage"
                         }
                     },
                     "400": {
@@ -440,164 +481,90 @@ const docTemplate = `{
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/api/hardware/inverters": {
-            "get": {
-                "description": "Get a list of all inverters",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Hardware"
-                ],
-                "summary": "List all inverters",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Inverter"
-                            }
-                        }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
             },
-            "post": {
-                "description": "Add a new inverter record",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+            "delete": {
+                "description": "Marks the storage unit inactive so it no longer lists or is recommended. Leads and quotes that use it keep resolving it.",
                 "tags": [
                     "Hardware"
                 ],
-                "summary": "Add a new inverter",
+                "summary": "Remove a storage unit from the catalog",
                 "parameters": [
                     {
-                        "description": "Inverter payload",
-                        "name": "inverter",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Inverter"
-                        }
+                        "type": "integer",
+                        "description": "Storage ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/models.Inverter"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    }
-                }
-            }
-        },
-        "/api/hardware/panels": {
-            "get": {
-                "description": "Get a list of all panels",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Hardware"
-                ],
-                "summary": "List all panels",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Panel"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/hardware/storages": {
+        "/admin/incentives": {
             "get": {
-                "description": "Get a list of all storage units",
+                "description": "Lists every incentive, including expired and future ones",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Hardware"
+                    "incentives"
                 ],
-                "summary": "List all storages",
+                "summary": "List incentives",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Storage"
+                                "$ref": "#/definitions/models.IncentiveRule"
                             }
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/leads": {
+            },
             "post": {
-                "description": "Creates a new lead and optionally initiates 3D model generation",
+                "description": "Creates a rebate or credit program that quotes in its state and utility pick up while it is in effect. Leave state empty to apply to every state and utility_id empty to apply to every utility.",
                 "consumes": [
                     "application/json"
                 ],
@@ -605,25 +572,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "leads"
+                    "incentives"
                 ],
-                "summary": "Create a new lead",
+                "summary": "Create a incentive",
                 "parameters": [
                     {
-                        "description": "Lead details",
-                        "name": "request",
+                        "description": "Incentive",
+                        "name": "incentive",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/service.CreateLead"
+                            "$ref": "#/definitions/models.IncentiveRule"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Returns lead and house IDs",
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/handler.LeadResponse"
+                            "$ref": "#/definitions/models.IncentiveRule"
                         }
                     },
                     "400": {
@@ -641,20 +608,19 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/leads/{id}": {
+        "/admin/incentives/{id}": {
             "get": {
-                "description": "Retrieves a lead by its ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "leads"
+                    "incentives"
                 ],
-                "summary": "Get a lead by ID",
+                "summary": "Get a incentive",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Lead ID",
+                        "description": "Incentive ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -664,7 +630,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.LeadResponse"
+                            "$ref": "#/definitions/models.IncentiveRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
                     "404": {
@@ -672,11 +644,17 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
                     }
                 }
             },
             "put": {
-                "description": "Updates an existing lead",
+                "description": "Replaces every field of an incentive",
                 "consumes": [
                     "application/json"
                 ],
@@ -684,25 +662,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "leads"
+                    "incentives"
                 ],
-                "summary": "Update a lead",
+                "summary": "Update a incentive",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Lead ID",
+                        "description": "Incentive ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Lead updates",
-                        "name": "request",
+                        "description": "Incentive",
+                        "name": "incentive",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/models.IncentiveRule"
                         }
                     }
                 ],
@@ -710,7 +687,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.LeadResponse"
+                            "$ref": "#/definitions/models.IncentiveRule"
                         }
                     },
                     "400": {
@@ -732,121 +709,224 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/otp/send": {
-            "get": {
-                "description": "Sends a one-time password (OTP) via SMS to the specified phone number using Twilio.",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+            },
+            "delete": {
                 "tags": [
-                    "OTP"
+                    "incentives"
                 ],
-                "summary": "Send OTP to a phone number",
+                "summary": "Delete a incentive",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Phone number with country code (e.g. +923001234567)",
-                        "name": "phone",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Email Address (e.g. test@example.com)",
-                        "name": "email",
-                        "in": "query",
+                        "type": "integer",
+                        "description": "Incentive ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OTP sent successfully",
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
-                    "400": {
-                        "description": "Missing or invalid phone parameter",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to send OTP",
+                        "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/otp/verify": {
+        "/admin/leads": {
             "get": {
-                "description": "Verifies one-time passwords (OTPs) sent to both phone and email.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Retrieves a paginated list of leads",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "OTP"
+                    "leads"
                 ],
-                "summary": "Verify phone and email OTP codes",
+                "summary": "List leads",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Phone number with country code (e.g. +923001234567)",
-                        "name": "phone",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "OTP code received via SMS",
-                        "name": "sms_otp",
-                        "in": "query",
-                        "required": true
+                        "type": "integer",
+                        "description": "Filter by creator ID",
+                        "name": "user_id",
+                        "in": "query"
                     },
                     {
-                        "type": "string",
-                        "description": "Email address used for OTP verification",
-                        "name": "email",
-                        "in": "query",
-                        "required": true
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Number of items per page",
+                        "name": "limit",
+                        "in": "query"
                     },
                     {
-                        "type": "string",
-                        "description": "OTP code received via Email",
-                        "name": "email_otp",
-                        "in": "query",
-                        "required": true
-                    }
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OTP verified successfully",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handler.MessageResponse"
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/leads/pipeline": {
+            "get": {
+                "description": "Groups the leads by their current milestone, in milestone order. Each stage carries its full count and a page of its most recently updated leads; pass milestone to page through a single stage.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "milestones"
+                ],
+                "summary": "Installation pipeline",
+                "parameters": [
+                    {
+                        "enum": [
+                            "welcome_call",
+                            "financing",
+                            "utility_bill",
+                            "site_photos",
+                            "design_approved",
+                            "permitting_approved",
+                            "install_crew",
+                            "installation",
+                            "final_inspection",
+                            "pto",
+                            "complete"
+                        ],
+                        "type": "string",
+                        "description": "Only list this stage",
+                        "name": "milestone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Leads listed per stage",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Leads to skip in each stage",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/service.PipelineStage"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Missing or invalid parameters / OTP verification failed",
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handler.MessageResponse"
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/quote": {
+        "/admin/leads/{id}": {
+            "delete": {
+                "description": "Deletes a lead by ID",
+                "tags": [
+                    "leads"
+                ],
+                "summary": "Delete a lead",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/pricing-profiles": {
+            "get": {
+                "description": "Lists every pricing profile, including expired and future ones",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pricing"
+                ],
+                "summary": "List pricing profiles",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PricingProfile"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Takes input parameters for a solar system and returns a detailed quote with costs, savings, and payback period.",
+                "description": "Creates a named set of quote assumptions. Leave company_id empty to apply to every company, state empty to apply to every state, and any value null to fall through to a less specific profile or the default.",
                 "consumes": [
                     "application/json"
                 ],
@@ -854,109 +934,89 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "quote"
+                    "pricing"
                 ],
-                "summary": "Calculate solar quote",
+                "summary": "Create a pricing profile",
                 "parameters": [
                     {
-                        "description": "Quote input payload",
-                        "name": "quote",
+                        "description": "Pricing profile",
+                        "name": "profile",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/service.QuoteInput"
+                            "$ref": "#/definitions/models.PricingProfile"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/service.QuoteResult"
+                            "$ref": "#/definitions/models.PricingProfile"
                         }
                     },
                     "400": {
-                        "description": "Invalid request payload",
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to calculate quote",
+                        "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/users": {
+        "/admin/pricing-profiles/{id}": {
             "get": {
-                "description": "Retrieves a paginated list of users for a specific company",
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "pricing"
                 ],
-                "summary": "List users",
+                "summary": "Get a pricing profile",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Limit (default: 20)",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Offset (default: 0)",
-                        "name": "offset",
-                        "in": "query"
+                        "description": "Pricing profile ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.User"
-                            }
+                            "$ref": "#/definitions/models.PricingProfile"
                         }
                     },
                     "400": {
-                        "description": "Invalid company ID",
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to fetch users",
+                        "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/leads/{id}/mesh-files": {
-            "get": {
-                "description": "Retrieves the 3D mesh files associated with a specific lead ID",
+            },
+            "put": {
+                "description": "Replaces every field of a pricing profile",
                 "consumes": [
                     "application/json"
                 ],
@@ -964,505 +1024,5292 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Leads"
+                    "pricing"
                 ],
-                "summary": "Get 3D mesh files for a lead",
+                "summary": "Update a pricing profile",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Lead ID",
+                        "description": "Pricing profile ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Pricing profile",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.PricingProfile"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/client.ProfilesFiles3DResponse"
+                            "$ref": "#/definitions/models.PricingProfile"
                         }
                     },
                     "400": {
-                        "description": "Invalid lead ID",
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Lead not found",
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "client.ProfilesFiles3DResponse": {
-            "type": "object",
-            "properties": {
-                "downloaded": {
-                    "type": "boolean"
-                },
-                "errors": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
+            },
+            "delete": {
+                "tags": [
+                    "pricing"
+                ],
+                "summary": "Delete a pricing profile",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Pricing profile ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
-                },
-                "jpg_path": {
-                    "type": "string"
-                },
-                "jpg_url": {
-                    "type": "string"
-                },
-                "mtl_path": {
-                    "type": "string"
-                },
-                "mtl_url": {
-                    "type": "string"
-                },
-                "obj_path": {
-                    "type": "string"
-                },
-                "obj_url": {
-                    "type": "string"
-                },
-                "ply_path": {
-                    "type": "string"
-                },
-                "ply_url": {
-                    "type": "string"
-                },
-                "project_id": {
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}": {
+            "get": {
+                "description": "Retrieves a user by their unique ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Updates an existing user's information",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "User update payload",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID or request body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a user by their unique ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/login": {
+            "post": {
+                "description": "Authenticate user and return JWT token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Login user",
+                "parameters": [
+                    {
+                        "description": "Login credentials",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/auth/register": {
+            "post": {
+                "description": "Register a new user with email and password",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "Registration details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handler.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/design/validate": {
+            "post": {
+                "description": "Sizes strings of the catalog panel for the catalog inverter so they stay below its maximum DC voltage at the record low and inside its MPPT window at the average high, and works out the DC/AC ratio and clipping risk. Temperatures default to estimates from the climate grid at latitude and longitude; where it has no data for them, pass record_low_c and average_high_c. An invalid design is still a 200 with valid set to false and the problems under errors.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "design"
+                ],
+                "summary": "Check that a panel and inverter work together",
+                "parameters": [
+                    {
+                        "description": "Design to check",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.DesignInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.DesignValidation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/hardware/inverters": {
+            "get": {
+                "description": "Lists active inverters a page at a time. Follow the cursor in the X-Next-Cursor header, or the Link header with rel=\"next\", for the next page; neither is set on the last page. Responses carry an ETag and answer a matching If-None-Match with 304.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hardware"
+                ],
+                "summary": "List inverters",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Matches manufacturer or model, case-insensitive",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Matches manufacturer, case-insensitive",
+                        "name": "manufacturer",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Matches model, case-insensitive",
+                        "name": "model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum AC capacity in kW",
+                        "name": "min_capacity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum AC capacity in kW",
+                        "name": "max_capacity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "id",
+                        "description": "id, manufacturer, model or capacity; prefix with - to sort descending",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size, at most 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Inverter"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/hardware/inverters/{id}": {
+            "get": {
+                "description": "Returns an inverter, including one removed from the catalog",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hardware"
+                ],
+                "summary": "Get an inverter",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Inverter ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Inverter"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/hardware/panels": {
+            "get": {
+                "description": "Lists active panels a page at a time. Follow the cursor in the X-Next-Cursor header, or the Link header with rel=\"next\", for the next page; neither is set on the last page. Responses carry an ETag and answer a matching If-None-Match with 304.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hardware"
+                ],
+                "summary": "List panels",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Matches manufacturer or model, case-insensitive",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Matches manufacturer, case-insensitive",
+                        "name": "manufacturer",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Matches model, case-insensitive",
+                        "name": "model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum wattage",
+                        "name": "min_wattage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum wattage",
+                        "name": "max_wattage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "id",
+                        "description": "id, manufacturer, model or wattage; prefix with - to sort descending",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size, at most 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Panel"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/hardware/panels/{id}": {
+            "get": {
+                "description": "Returns a panel, including one removed from the catalog",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hardware"
+                ],
+                "summary": "Get a panel",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Panel ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Panel"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/hardware/storages": {
+            "get": {
+                "description": "Lists active storage units a page at a time. Follow the cursor in the X-Next-Cursor header, or the Link header with rel=\"next\", for the next page; neither is set on the last page. Responses carry an ETag and answer a matching If-None-Match with 304.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hardware"
+                ],
+                "summary": "List storage units",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Matches manufacturer or model, case-insensitive",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Matches manufacturer, case-insensitive",
+                        "name": "manufacturer",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Matches model, case-insensitive",
+                        "name": "model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Minimum nameplate capacity per unit in kWh",
+                        "name": "min_capacity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Maximum nameplate capacity per unit in kWh",
+                        "name": "max_capacity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "id",
+                        "description": "id, manufacturer, model or capacity; prefix with - to sort descending",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Page size, at most 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Storage"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/hardware/storages/{id}": {
+            "get": {
+                "description": "Returns a storage unit, including one removed from the catalog",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hardware"
+                ],
+                "summary": "Get a storage unit",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Storage ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Storage"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads": {
+            "post": {
+                "description": "Creates a new lead in the initialized state and queues 3D model generation and tariff lookup. Its panel, inverter and optional storage are sent to LightFusion by their mapped LightFusion IDs; the lead is rejected when any of them is unknown, no longer offered or unmapped, or when the panel and inverter do not work together.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "leads"
+                ],
+                "summary": "Create a new lead",
+                "parameters": [
+                    {
+                        "description": "Lead details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.CreateLead"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Returns lead and house IDs",
+                        "schema": {
+                            "$ref": "#/definitions/handler.LeadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads/{id}": {
+            "get": {
+                "description": "Retrieves a lead by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "leads"
+                ],
+                "summary": "Get a lead by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.LeadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Updates an existing lead. Changes to panel_id, inverter_id, storage_id or storage_quantity are checked as on creation: the hardware must be offered and known to LightFusion. Those and changes to inverter_quantity or panel_count are rejected when the panel and inverter would not work together. A null storage_id removes the storage.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "leads"
+                ],
+                "summary": "Update a lead",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Lead updates; a state change may carry a reason",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.LeadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads/{id}/events": {
+            "get": {
+                "description": "Server-Sent Events stream of state changes, build progress, mesh file availability and quote updates for a lead. The first event is a \"state\" snapshot of the lead. Only the lead's owner or an admin may subscribe.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "leads"
+                ],
+                "summary": "Stream lead events",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.LeadEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads/{id}/history": {
+            "get": {
+                "description": "Lists every state transition of a lead, oldest first, with who made it and why",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "leads"
+                ],
+                "summary": "Get a lead's state history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LeadStateHistory"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads/{id}/milestones": {
+            "get": {
+                "description": "Returns the state of every milestone from welcome call through PTO, with prerequisites and the lead's current milestone",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "milestones"
+                ],
+                "summary": "Get installation milestones for a lead",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.LeadMilestones"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads/{id}/milestones/{milestone}": {
+            "put": {
+                "description": "Sets the state of one milestone. Milestones cannot start before their prerequisites are completed (e.g. no installation before permitting approval).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "milestones"
+                ],
+                "summary": "Update a lead milestone",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "welcome_call",
+                            "financing",
+                            "utility_bill",
+                            "site_photos",
+                            "design_approved",
+                            "permitting_approved",
+                            "install_crew",
+                            "installation",
+                            "final_inspection",
+                            "pto"
+                        ],
+                        "type": "string",
+                        "description": "Milestone",
+                        "name": "milestone",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Milestone update",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.UpdateMilestoneRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.LeadMilestones"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads/{id}/production": {
+            "get": {
+                "description": "Simulates a typical year of AC output for the lead's system at its coordinates from the climate grid, derated for the panel's temperature coefficient and clipped at the inverter capacity. Runs entirely offline. Needs the climate grid loaded from CLIMATE_GRID_FILE; without it, and for locations outside it, the answer is a 400.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "production"
+                ],
+                "summary": "Estimate a lead's solar production",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "description": "Array tilt in degrees (default 20)",
+                        "name": "tilt",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Array azimuth in degrees clockwise from north (default 180)",
+                        "name": "azimuth",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the 8760 hourly values (default true)",
+                        "name": "hourly",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.ProductionEstimate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads/{id}/proposal.pdf": {
+            "get": {
+                "description": "Returns the customer-facing proposal PDF for a quote version of the lead, or for its latest quote. It shows the customer, the system and its catalog hardware, the roof render, costs, incentives, cash-flow charts and financing, styled with the company's LightFusion logo and colors. The PDF is rendered once per quote version and stored.",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "proposal"
+                ],
+                "summary": "Download a lead's proposal",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Quote version (default latest)",
+                        "name": "version",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads/{id}/proposals": {
+            "get": {
+                "description": "Lists the proposals rendered for the lead's quote versions, without their content.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "proposal"
+                ],
+                "summary": "List a lead's proposals",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Proposal"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads/{id}/quote": {
+            "post": {
+                "description": "Builds the quote input from the lead (system size, panel count, consumption, design production or a simulation at its coordinates, utility), its catalog panel and inverter, and its owner's state and average monthly bill, then stores the quote as the lead's next version. Any field in the body overrides the value taken from the lead; the body may be empty. The lead's tariff ID carries no rates, so pass a tariff to use the billing engine.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quote"
+                ],
+                "summary": "Quote a lead",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Values overriding the lead",
+                        "name": "overrides",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/service.QuoteInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/service.QuoteResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads/{id}/quotes": {
+            "get": {
+                "description": "Lists every stored quote version of a lead, oldest first, with the inputs, resolved assumptions and result of each.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quote"
+                ],
+                "summary": "List a lead's quote versions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/service.QuoteVersion"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads/{id}/quotes/diff": {
+            "get": {
+                "description": "Lists the input, assumption and result fields that changed between two quote versions of a lead. Numeric changes include the delta.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quote"
+                ],
+                "summary": "Diff two quote versions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Version to compare from",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Version to compare to",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.QuoteDiff"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads/{id}/quotes/{version}": {
+            "get": {
+                "description": "Returns one stored quote version of a lead.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quote"
+                ],
+                "summary": "Get a quote version",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Quote version",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.QuoteVersion"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads/{id}/shares": {
+            "get": {
+                "description": "Lists the lead's share links, newest first, with how often they were opened and the homeowner's response.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "proposal"
+                ],
+                "summary": "List a lead's proposal links",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/service.ProposalShareLink"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a signed, expiring link a homeowner can open without an account at /p/{code} to view the lead's latest quote, hardware and 3D model and to accept it or request changes. Responses are emailed to the user who created the link.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "proposal"
+                ],
+                "summary": "Share a lead's proposal",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Link options",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/service.CreateProposalShareRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/service.ProposalShareLink"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/leads/{id}/storage-analysis": {
+            "post": {
+                "description": "Simulates hourly battery state of charge on top of the lead's simulated solar production and reports the self-consumption gain, TOU arbitrage savings and hours of backup for the critical loads during an outage. Recommends a storage quantity of the selected unit, which defaults to the lead's storage, or from the whole catalog when there is none. quantity defaults to the lead's storage quantity and is at most 20.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "storage"
+                ],
+                "summary": "Analyse battery storage for a lead",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Storage analysis options",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.StorageAnalysisRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.StorageAnalysis"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/otp/send": {
+            "get": {
+                "description": "Sends a one-time password (OTP) via SMS to the specified phone number using Twilio.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "OTP"
+                ],
+                "summary": "Send OTP to a phone number",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone number with country code (e.g. +923001234567)",
+                        "name": "phone",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email Address (e.g. test@example.com)",
+                        "name": "email",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OTP sent successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing or invalid phone parameter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to send OTP",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/otp/verify": {
+            "get": {
+                "description": "Verifies one-time passwords (OTPs) sent to both phone and email.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "OTP"
+                ],
+                "summary": "Verify phone and email OTP codes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Phone number with country code (e.g. +923001234567)",
+                        "name": "phone",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "OTP code received via SMS",
+                        "name": "sms_otp",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email address used for OTP verification",
+                        "name": "email",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "OTP code received via Email",
+                        "name": "email_otp",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OTP verified successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handler.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing or invalid parameters / OTP verification failed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.MessageResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/quote": {
+            "post": {
+                "description": "Takes input parameters for a solar system and returns a detailed quote with costs, savings, and payback period. Every quote is stored; when lead_id is given it becomes the lead's next quote version.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quote"
+                ],
+                "summary": "Calculate solar quote",
+                "parameters": [
+                    {
+                        "description": "Quote input payload",
+                        "name": "quote",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.QuoteInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.QuoteResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not allowed to quote this lead",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Lead not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to calculate quote",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/quote/sensitivity": {
+            "post": {
+                "description": "Recalculates a quote across ranges of utility escalation, loan interest rate, cost per watt and production (a multiplier of the base production) and returns a sweep per variable plus tornado-chart bars of 25-year savings, widest swing first. With no ranges each variable gets a default range around its base value. Nothing is stored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quote"
+                ],
+                "summary": "Quote sensitivity analysis",
+                "parameters": [
+                    {
+                        "description": "Base quote input and ranges",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.SensitivityRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.SensitivityResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users": {
+            "get": {
+                "description": "Retrieves a paginated list of users for a specific company",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List users",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Limit (default: 20)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset (default: 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.User"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid company ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to fetch users",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/leads/{id}/mesh-files": {
+            "get": {
+                "description": "Retrieves the 3D mesh files associated with a specific lead ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Leads"
+                ],
+                "summary": "Get 3D mesh files for a lead",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lead ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/client.ProfilesFiles3DResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid lead ID",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not allowed to view this lead",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Lead not found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "3D project not created yet",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Mesh files could not be downloaded",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/p/{code}": {
+            "get": {
+                "description": "Public. Returns the read-only proposal behind a share link and counts the open. Browsers (Accept: text/html) or format=html get an HTML page with accept and request-changes forms.",
+                "produces": [
+                    "application/json",
+                    "text/html"
+                ],
+                "tags": [
+                    "proposal"
+                ],
+                "summary": "Open a shared proposal",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Share code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json or html",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/service.ProposalShareView"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/p/{code}/accept": {
+            "post": {
+                "description": "Public. Accepts the lead's latest quote and notifies the rep who shared it. Takes JSON or a form post; form posts are redirected back to the HTML view.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "proposal"
+                ],
+                "summary": "Accept a shared proposal",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Share code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Homeowner name and message",
+                        "name": "response",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/service.ProposalShareResponse"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProposalShare"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/p/{code}/proposal.pdf": {
+            "get": {
+                "description": "Public. Returns the proposal PDF of the lead's latest quote behind a share link.",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "proposal"
+                ],
+                "summary": "Download a shared proposal",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Share code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/p/{code}/request-changes": {
+            "post": {
+                "description": "Public. Sends the homeowner's requested changes to the rep who shared the proposal. A message is required. Takes JSON or a form post; form posts are redirected back to the HTML view.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "proposal"
+                ],
+                "summary": "Request changes to a shared proposal",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Share code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Homeowner name and message",
+                        "name": "response",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.ProposalShareResponse"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ProposalShare"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "client.FinancingOption": {
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "interest_rate": {
+                    "type": "number"
+                },
+                "loan_fee": {
+                    "type": "number"
+                },
+                "loan_fee_fixed": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "integer"
+                }
+            }
+        },
+        "client.ProfilesFiles3DResponse": {
+            "type": "object",
+            "properties": {
+                "downloaded": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "jpg_path": {
+                    "type": "string"
+                },
+                "jpg_url": {
+                    "type": "string"
+                },
+                "mtl_path": {
+                    "type": "string"
+                },
+                "mtl_url": {
+                    "type": "string"
+                },
+                "obj_path": {
+                    "type": "string"
+                },
+                "obj_url": {
+                    "type": "string"
+                },
+                "ply_path": {
+                    "type": "string"
+                },
+                "ply_url": {
+                    "type": "string"
+                },
+                "project_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.AuthResponse": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                },
+                "user": {}
+            }
+        },
+        "handler.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "error message"
+                }
+            }
+        },
+        "handler.LeadResponse": {
+            "type": "object",
+            "properties": {
+                "house_id": {
+                    "type": "integer",
+                    "example": 123
+                },
+                "lead_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "handler.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "user@example.com"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "password123"
+                }
+            }
+        },
+        "handler.MessageResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.RegisterRequest": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string",
+                    "example": "Anytown"
+                },
+                "country": {
+                    "type": "string",
+                    "example": "USA"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "user@example.com"
+                },
+                "first_name": {
+                    "type": "string",
+                    "example": "John"
+                },
+                "last_name": {
+                    "type": "string",
+                    "example": "Doe"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "password123"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "555-123-4567"
+                },
+                "postal_code": {
+                    "type": "string",
+                    "example": "12345"
+                },
+                "state": {
+                    "type": "string",
+                    "example": "CA"
+                },
+                "street": {
+                    "type": "string",
+                    "example": "123 Main St"
+                },
+                "user_type": {
+                    "type": "string",
+                    "example": "0"
+                }
+            }
+        },
+        "models.IncentiveRule": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 0.2
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "effective_from": {
+                    "type": "string"
+                },
+                "effective_to": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "max_amount": {
+                    "type": "number",
+                    "example": 1500
+                },
+                "max_system_size_kw": {
+                    "type": "number",
+                    "example": 10
+                },
+                "name": {
+                    "type": "string",
+                    "example": "SGIP Residential Rebate"
+                },
+                "payment_years": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "reduces_itc_basis": {
+                    "type": "boolean"
+                },
+                "state": {
+                    "type": "string",
+                    "example": "CA"
+                },
+                "type": {
+                    "enum": [
+                        "per_watt",
+                        "per_kwh",
+                        "flat",
+                        "percentage"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.IncentiveType"
+                        }
+                    ],
+                    "example": "per_watt"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "utility_id": {
+                    "type": "integer",
+                    "example": 734
+                }
+            }
+        },
+        "models.IncentiveType": {
+            "type": "string",
+            "enum": [
+                "per_watt",
+                "per_kwh",
+                "flat",
+                "percentage"
+            ],
+            "x-enum-varnames": [
+                "IncentivePerWatt",
+                "IncentivePerKWh",
+                "IncentiveFlat",
+                "IncentivePercentage"
+            ]
+        },
+        "models.Inverter": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "Active is false once the item is removed from the catalog. Removed\nitems are kept for the leads and quotes that reference them.",
+                    "type": "boolean"
+                },
+                "capacity": {
+                    "type": "number"
+                },
+                "cec_id": {
+                    "description": "CECID identifies the item's listing on the CEC equipment list it was\nimported from, so it is found again after the catalog renames it.\nItems added by hand have none.",
+                    "type": "string",
+                    "example": "Enphase Energy Inc. | IQ8PLUS-72-2-US [240V]"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "efficiency": {
+                    "description": "Efficiency is the CEC weighted efficiency as a fraction. Phase is 1\nor 3 and NominalACVoltage the grid voltage it connects at.",
+                    "type": "number",
+                    "example": 0.97
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lightfusion_id": {
+                    "description": "LightFusionID is the item's ID in LightFusion's hardware catalog, which\n3D projects are designed with. Items without one cannot be put on a\nlead.",
+                    "type": "integer",
+                    "example": 5557
+                },
+                "manufacturer": {
+                    "type": "string"
+                },
+                "max_dc_power_kw": {
+                    "type": "number",
+                    "example": 9.6
+                },
+                "max_dc_voltage": {
+                    "type": "number",
+                    "example": 600
+                },
+                "max_input_current": {
+                    "type": "number",
+                    "example": 13
+                },
+                "model": {
+                    "type": "string"
+                },
+                "mppt_count": {
+                    "description": "DC input: the number of MPPT inputs, the operating voltage window of\neach, the highest voltage and, per input, the highest current the\ninverter accepts. MaxDCPowerKW is the largest array it takes.",
+                    "type": "integer",
+                    "example": 2
+                },
+                "mppt_voltage_max": {
+                    "type": "number",
+                    "example": 480
+                },
+                "mppt_voltage_min": {
+                    "type": "number",
+                    "example": 200
+                },
+                "nominal_ac_voltage": {
+                    "type": "number",
+                    "example": 240
+                },
+                "phase": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "type": {
+                    "description": "Type is micro for microinverters, which take one panel each, and\nstring for inverters that take strings of panels. It defaults to\nstring.",
+                    "type": "string",
+                    "enum": [
+                        "string",
+                        "micro"
+                    ],
+                    "example": "string"
+                },
+                "unit_cost": {
+                    "description": "UnitCost is the price of one inverter, used to cost its replacement\nonce the warranty runs out.",
+                    "type": "number",
+                    "example": 1800
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "warranty_years": {
+                    "type": "integer",
+                    "example": 12
+                }
+            }
+        },
+        "models.LeadState": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2,
+                3
+            ],
+            "x-enum-varnames": [
+                "LeadStateProgress",
+                "LeadStateDone",
+                "LeadStateErrored",
+                "LeadStateInitialized"
+            ]
+        },
+        "models.LeadStateHistory": {
+            "type": "object",
+            "properties": {
+                "actor_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "from_state": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.LeadState"
+                        }
+                    ],
+                    "example": 3
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lead_id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "3D project creation started"
+                },
+                "to_state": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.LeadState"
+                        }
+                    ],
+                    "example": 0
+                }
+            }
+        },
+        "models.Milestone": {
+            "type": "string",
+            "enum": [
+                "welcome_call",
+                "financing",
+                "utility_bill",
+                "site_photos",
+                "design_approved",
+                "permitting_approved",
+                "install_crew",
+                "installation",
+                "final_inspection",
+                "pto",
+                "complete"
+            ],
+            "x-enum-varnames": [
+                "MilestoneWelcomeCall",
+                "MilestoneFinancing",
+                "MilestoneUtilityBill",
+                "MilestoneSitePhotos",
+                "MilestoneDesignApproved",
+                "MilestonePermittingApproved",
+                "MilestoneInstallCrew",
+                "MilestoneInstallation",
+                "MilestoneFinalInspection",
+                "MilestonePTO",
+                "MilestoneComplete"
+            ]
+        },
+        "models.MilestoneState": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2
+            ],
+            "x-enum-varnames": [
+                "MilestoneStatePending",
+                "MilestoneStateInProgress",
+                "MilestoneStateCompleted"
+            ]
+        },
+        "models.Panel": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "Active is false once the item is removed from the catalog. Removed\nitems are kept for the leads and quotes that reference them.",
+                    "type": "boolean"
+                },
+                "annual_degradation": {
+                    "type": "number",
+                    "example": 0.0055
+                },
+                "cec_id": {
+                    "description": "CECID identifies the item's listing on the CEC equipment list it was\nimported from, so it is found again after the catalog renames it.\nItems added by hand have none.",
+                    "type": "string",
+                    "example": "REC Solar | REC400AA"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "efficiency": {
+                    "description": "Efficiency is the module efficiency as a fraction. WarrantyYears is\nthe product warranty and PerformanceWarrantyYears the power output\nwarranty.",
+                    "type": "number",
+                    "example": 0.216
+                },
+                "first_year_degradation": {
+                    "description": "Degradation rates are fractions of nameplate output: FirstYearDegradation\nis lost in year 1, AnnualDegradation every year after.",
+                    "type": "number",
+                    "example": 0.02
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "imp": {
+                    "type": "number",
+                    "example": 10.1
+                },
+                "isc": {
+                    "type": "number",
+                    "example": 10.8
+                },
+                "lightfusion_id": {
+                    "description": "LightFusionID is the item's ID in LightFusion's hardware catalog, which\n3D projects are designed with. Items without one cannot be put on a\nlead.",
+                    "type": "integer",
+                    "example": 289
+                },
+                "longside": {
+                    "type": "number"
+                },
+                "manufacturer": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "noct": {
+                    "type": "number",
+                    "example": 44
+                },
+                "performance_warranty_years": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "shortside": {
+                    "type": "number"
+                },
+                "temp_coefficient_isc": {
+                    "type": "number",
+                    "example": 0.04
+                },
+                "temp_coefficient_pmax": {
+                    "description": "TempCoefficientPmax is the change in maximum power per °C above 25 °C,\nin percent; NOCT is the nominal operating cell temperature in °C.",
+                    "type": "number",
+                    "example": -0.34
+                },
+                "temp_coefficient_voc": {
+                    "description": "TempCoefficientVoc and TempCoefficientIsc are the change in Voc and\nIsc per °C above 25 °C, in percent.",
+                    "type": "number",
+                    "example": -0.24
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "vmp": {
+                    "type": "number",
+                    "example": 40.6
+                },
+                "voc": {
+                    "description": "Electrical ratings at standard test conditions: open-circuit voltage,\nshort-circuit current, and voltage and current at maximum power (what\nLightFusion calls max_power_voltage and max_power_current).",
+                    "type": "number",
+                    "example": 48.7
+                },
+                "warranty_years": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "wattage": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.PricingProfile": {
+            "type": "object",
+            "properties": {
+                "annual_utility_increase": {
+                    "type": "number",
+                    "example": 0.04
+                },
+                "company_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "cost_per_watt": {
+                    "type": "number",
+                    "example": 2.85
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "effective_from": {
+                    "type": "string"
+                },
+                "effective_to": {
+                    "type": "string"
+                },
+                "electrical_offset_pct": {
+                    "type": "number",
+                    "example": 100
+                },
+                "federal_tax_credit": {
+                    "type": "number",
+                    "example": 0.3
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "loan_interest_rate": {
+                    "type": "number",
+                    "example": 0.0599
+                },
+                "loan_term_years": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "name": {
+                    "type": "string",
+                    "example": "California 2025"
+                },
+                "state": {
+                    "type": "string",
+                    "example": "CA"
+                },
+                "sun_hours_per_day": {
+                    "type": "number",
+                    "example": 5.5
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "utility_rate_per_kwh": {
+                    "type": "number",
+                    "example": 0.32
+                }
+            }
+        },
+        "models.Proposal": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string",
+                    "example": "proposal-42-v3.pdf"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lead_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "quote_id": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "quote_version": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "sha256": {
+                    "type": "string",
+                    "example": "9f2c..."
+                },
+                "size_bytes": {
+                    "type": "integer",
+                    "example": 48211
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.ProposalShare": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "first_opened_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_opened_at": {
+                    "type": "string"
+                },
+                "lead_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "open_count": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "quote_version": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "responded_at": {
+                    "type": "string"
+                },
+                "responder_name": {
+                    "type": "string",
+                    "example": "Jane Doe"
+                },
+                "response_message": {
+                    "type": "string",
+                    "example": "Could we add a battery?"
+                },
+                "status": {
+                    "enum": [
+                        "pending",
+                        "accepted",
+                        "changes_requested"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ProposalShareStatus"
+                        }
+                    ],
+                    "example": "pending"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ProposalShareStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "accepted",
+                "changes_requested"
+            ],
+            "x-enum-varnames": [
+                "ProposalSharePending",
+                "ProposalShareAccepted",
+                "ProposalShareChangesRequested"
+            ]
+        },
+        "models.Storage": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "description": "Active is false once the item is removed from the catalog. Removed\nitems are kept for the leads and quotes that reference them.",
+                    "type": "boolean"
+                },
+                "capacity": {
+                    "description": "Capacity is the nameplate energy of one unit in kWh, never Wh.",
+                    "type": "number",
+                    "example": 13.5
+                },
+                "cec_id": {
+                    "description": "CECID identifies the item's listing on the CEC equipment list it was\nimported from, so it is found again after the catalog renames it.\nItems added by hand have none.",
+                    "type": "string",
+                    "example": "Tesla | 1707000-XX-Y [240V]"
+                },
+                "chemistry": {
+                    "type": "string",
+                    "example": "LFP"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lightfusion_id": {
+                    "description": "LightFusionID is the item's ID in LightFusion's hardware catalog, which\n3D projects are designed with. Items without one cannot be put on a\nlead.",
+                    "type": "integer",
+                    "example": 101
+                },
+                "manufacturer": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "power_kw": {
+                    "description": "PowerKW is the continuous charge and discharge rating of one unit and\nRoundTripEfficiency the share of charged energy that comes back out.",
+                    "type": "number",
+                    "example": 5
+                },
+                "round_trip_efficiency": {
+                    "type": "number",
+                    "example": 0.9
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "usable_kwh": {
+                    "description": "UsableKWh is the energy one unit can deliver, when less than its\nnameplate Capacity. Chemistry is the cell chemistry, e.g. LFP or NMC.",
+                    "type": "number",
+                    "example": 13.5
+                }
+            }
+        },
+        "models.User": {
+            "type": "object",
+            "properties": {
+                "average_monthly_bill": {
+                    "type": "number",
+                    "example": 150
+                },
+                "city": {
+                    "type": "string"
+                },
+                "company_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "country": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "home_ownership_type": {
+                    "type": "string",
+                    "example": "owner"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "phone_number": {
+                    "type": "string"
+                },
+                "postal_code": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "street": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_type": {
+                    "$ref": "#/definitions/models.UserType"
+                },
+                "utility_provider": {
+                    "type": "string",
+                    "example": "PG\u0026E"
+                }
+            }
+        },
+        "models.UserType": {
+            "type": "integer",
+            "format": "int32",
+            "enum": [
+                0,
+                1,
+                2
+            ],
+            "x-enum-varnames": [
+                "UserTypeAdmin",
+                "UserTypeCustomer",
+                "UserTypeGeneral"
+            ]
+        },
+        "service.AmortizationRow": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "number",
+                    "example": 19975.27
+                },
+                "interest": {
+                    "type": "number",
+                    "example": 116.5
+                },
+                "month": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "payment": {
+                    "type": "number",
+                    "example": 141.23
+                },
+                "principal": {
+                    "type": "number",
+                    "example": 24.73
+                }
+            }
+        },
+        "service.AssumptionSource": {
+            "type": "object",
+            "properties": {
+                "profile_id": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "profile_name": {
+                    "type": "string",
+                    "example": "California 2025"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "profile"
+                }
+            }
+        },
+        "service.BillingResult": {
+            "type": "object",
+            "properties": {
+                "annual_post_solar": {
+                    "type": "number",
+                    "example": 1380
+                },
+                "annual_pre_solar": {
+                    "type": "number",
+                    "example": 3420
+                },
+                "annual_savings": {
+                    "type": "number",
+                    "example": 2040
+                },
+                "credits_forfeited": {
+                    "type": "number",
+                    "example": 0
+                },
+                "months": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.MonthlyBill"
+                    }
+                }
+            }
+        },
+        "service.CashFlowYear": {
+            "type": "object",
+            "properties": {
+                "bill_with_solar": {
+                    "type": "number",
+                    "example": 120
+                },
+                "bill_without_solar": {
+                    "type": "number",
+                    "example": 2400
+                },
+                "cumulative_savings": {
+                    "type": "number",
+                    "example": 585.24
+                },
+                "incentives": {
+                    "type": "number",
+                    "example": 0
+                },
+                "loan_payments": {
+                    "type": "number",
+                    "example": 1694.76
+                },
+                "operating_costs": {
+                    "type": "number",
+                    "example": 250
+                },
+                "production_kwh": {
+                    "type": "number",
+                    "example": 10950
+                },
+                "savings": {
+                    "type": "number",
+                    "example": 585.24
+                },
+                "year": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "service.CatalogFieldChange": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "wattage"
+                },
+                "from": {},
+                "to": {}
+            }
+        },
+        "service.CatalogImportReport": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.CatalogItemChange"
+                    }
+                },
+                "dry_run": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "kind": {
+                    "type": "string",
+                    "example": "panels"
+                },
+                "rows": {
+                    "type": "integer",
+                    "example": 1520
+                },
+                "skipped": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.CatalogSkip"
+                    }
+                },
+                "unchanged": {
+                    "type": "integer",
+                    "example": 1490
+                },
+                "updated": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.CatalogItemChange"
+                    }
+                }
+            }
+        },
+        "service.CatalogItemChange": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.CatalogFieldChange"
+                    }
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 289
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 18
+                },
+                "manufacturer": {
+                    "type": "string",
+                    "example": "Anji Technology"
+                },
+                "model": {
+                    "type": "string",
+                    "example": "AJP-M660-230"
+                }
+            }
+        },
+        "service.CatalogSkip": {
+            "type": "object",
+            "properties": {
+                "line": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "manufacturer": {
+                    "type": "string",
+                    "example": "Anji Technology"
+                },
+                "model": {
+                    "type": "string",
+                    "example": "AJP-M660-230"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "missing nameplate Pmax"
+                }
+            }
+        },
+        "service.CreateLead": {
+            "type": "object",
+            "properties": {
+                "consumption": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "hardware_type": {
+                    "type": "string"
+                },
+                "inverter_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "inverter_quantity": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "kwh_usage": {
+                    "type": "number",
+                    "example": 12000
+                },
+                "latitude": {
+                    "type": "number",
+                    "example": 37.7749
+                },
+                "longitude": {
+                    "type": "number",
+                    "example": -122.4194
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "panel_count": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "panel_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "period": {
+                    "type": "string",
+                    "example": "year"
+                },
+                "project_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "storage_id": {
+                    "type": "integer",
+                    "example": 101
+                },
+                "storage_quantity": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "system_size": {
+                    "type": "number",
+                    "example": 10.5
+                },
+                "target_solar_offset": {
+                    "type": "integer"
+                },
+                "unit": {
+                    "type": "string",
+                    "example": "kwh"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "service.CreateProposalShareRequest": {
+            "type": "object",
+            "properties": {
+                "expires_in_hours": {
+                    "description": "ExpiresInHours defaults to 168 (one week); at most 720.",
+                    "type": "integer",
+                    "example": 168
+                }
+            }
+        },
+        "service.DesignInput": {
+            "type": "object",
+            "properties": {
+                "average_high_c": {
+                    "type": "number",
+                    "example": 33
+                },
+                "inverter_count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "inverter_id": {
+                    "type": "integer",
+                    "example": 5557
+                },
+                "latitude": {
+                    "type": "number",
+                    "example": 37.7749
+                },
+                "longitude": {
+                    "type": "number",
+                    "example": -122.4194
+                },
+                "panel_count": {
+                    "type": "integer",
+                    "example": 24
+                },
+                "panel_id": {
+                    "type": "integer",
+                    "example": 289
+                },
+                "record_low_c": {
+                    "type": "number",
+                    "example": -12
+                }
+            }
+        },
+        "service.DesignIssue": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "no_valid_string_length"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "No string length fits: at least 12 modules are needed to stay in the MPPT window at 33 °C, but at most 11 stay below 600 V at -12 °C"
+                }
+            }
+        },
+        "service.DesignValidation": {
+            "type": "object",
+            "properties": {
+                "ac_size_kw": {
+                    "type": "number",
+                    "example": 7.6
+                },
+                "average_high_c": {
+                    "type": "number",
+                    "example": 33
+                },
+                "clipping_risk": {
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "moderate",
+                        "high"
+                    ],
+                    "example": "moderate"
+                },
+                "dc_ac_ratio": {
+                    "type": "number",
+                    "example": 1.26
+                },
+                "dc_size_kw": {
+                    "type": "number",
+                    "example": 9.6
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.DesignIssue"
+                    }
+                },
+                "inverter_count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "max_string_length": {
+                    "type": "integer",
+                    "example": 11
+                },
+                "microinverters": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "min_string_length": {
+                    "type": "integer",
+                    "example": 6
+                },
+                "module_vmp_cold_v": {
+                    "type": "number",
+                    "example": 44.9
+                },
+                "module_vmp_hot_v": {
+                    "type": "number",
+                    "example": 33.9
+                },
+                "module_voc_cold_v": {
+                    "description": "Module voltages at the design temperatures: Voc on the coldest\nmorning and Vmp with the cells at their hottest and coldest.",
+                    "type": "number",
+                    "example": 54.1
+                },
+                "panel_count": {
+                    "type": "integer",
+                    "example": 24
+                },
+                "record_low_c": {
+                    "type": "number",
+                    "example": -12
+                },
+                "strings": {
+                    "description": "Strings lists the length of each string in the layout, and\nStringsPerMPPT how many share an MPPT input at most.",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        8,
+                        8,
+                        8
+                    ]
+                },
+                "strings_per_mppt": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "temperature_source": {
+                    "type": "string",
+                    "enum": [
+                        "input",
+                        "climate",
+                        "default"
+                    ],
+                    "example": "input"
+                },
+                "valid": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.DesignIssue"
+                    }
+                }
+            }
+        },
+        "service.FinancingResult": {
+            "type": "object",
+            "properties": {
+                "amortization": {
+                    "description": "Set in detailed mode only.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.AmortizationRow"
+                    }
+                },
+                "amount_financed": {
+                    "type": "number"
+                },
+                "break_even_year": {
+                    "type": "integer"
+                },
+                "cash_flow": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.CashFlowYear"
+                    }
+                },
+                "federal_tax_credit": {
+                    "type": "number"
+                },
+                "first_year_savings": {
+                    "type": "number"
+                },
+                "incentives": {
+                    "type": "number"
+                },
+                "interest_rate": {
+                    "type": "number"
+                },
+                "lifetime_cost": {
+                    "type": "number"
+                },
+                "lifetime_savings": {
+                    "type": "number"
+                },
+                "monthly_payment": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "25 year loan"
+                },
+                "term_years": {
+                    "type": "integer"
+                },
+                "total_payments": {
+                    "type": "number"
+                },
+                "type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.FinancingType"
+                        }
+                    ],
+                    "example": "loan"
+                },
+                "upfront_cost": {
+                    "type": "number"
+                }
+            }
+        },
+        "service.FinancingScenario": {
+            "type": "object",
+            "properties": {
+                "dealer_fee_fixed": {
+                    "type": "number",
+                    "example": 0
+                },
+                "dealer_fee_pct": {
+                    "type": "number",
+                    "example": 0.2
+                },
+                "down_payment": {
+                    "description": "Cash and loan",
+                    "type": "number",
+                    "example": 0
+                },
+                "escalator_pct": {
+                    "description": "Lease and PPA",
+                    "type": "number",
+                    "example": 0.029
+                },
+                "interest_rate": {
+                    "description": "Loan",
+                    "type": "number",
+                    "example": 0.0699
+                },
+                "lightfusion_option": {
+                    "$ref": "#/definitions/client.FinancingOption"
+                },
+                "monthly_payment": {
+                    "description": "Lease",
+                    "type": "number",
+                    "example": 139
+                },
+                "name": {
+                    "type": "string",
+                    "example": "25 year loan"
+                },
+                "rate_per_kwh": {
+                    "description": "PPA",
+                    "type": "number",
+                    "example": 0.17
+                },
+                "term_years": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "type": {
+                    "enum": [
+                        "cash",
+                        "loan",
+                        "lease",
+                        "ppa"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.FinancingType"
+                        }
+                    ],
+                    "example": "loan"
+                }
+            }
+        },
+        "service.FinancingType": {
+            "type": "string",
+            "enum": [
+                "cash",
+                "loan",
+                "lease",
+                "ppa"
+            ],
+            "x-enum-varnames": [
+                "FinancingCash",
+                "FinancingLoan",
+                "FinancingLease",
+                "FinancingPPA"
+            ]
+        },
+        "service.LeadEvent": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "lead_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "time": {
+                    "type": "string"
+                },
+                "type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.LeadEventType"
+                        }
+                    ],
+                    "example": "state"
+                }
+            }
+        },
+        "service.LeadEventType": {
+            "type": "string",
+            "enum": [
+                "state",
+                "build_progress",
+                "mesh_files",
+                "quote",
+                "proposal_response"
+            ],
+            "x-enum-varnames": [
+                "LeadEventState",
+                "LeadEventBuildProgress",
+                "LeadEventMeshFiles",
+                "LeadEventQuote",
+                "LeadEventProposalResponse"
+            ]
+        },
+        "service.LeadMilestones": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Milestone"
+                        }
+                    ],
+                    "example": "permitting_approved"
+                },
+                "date_installed": {
+                    "type": "string"
+                },
+                "date_ntp": {
+                    "type": "string"
+                },
+                "installation_date": {
+                    "type": "string"
+                },
+                "lead_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "milestones": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.MilestoneStatus"
+                    }
+                }
+            }
+        },
+        "service.MilestoneStatus": {
+            "type": "object",
+            "properties": {
+                "blocked": {
+                    "type": "boolean"
+                },
+                "milestone": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Milestone"
+                        }
+                    ],
+                    "example": "installation"
+                },
+                "prerequisites": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Milestone"
+                    }
+                },
+                "state": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.MilestoneState"
+                        }
+                    ],
+                    "example": 0
+                }
+            }
+        },
+        "service.MonthlyBill": {
+            "type": "object",
+            "properties": {
+                "consumption_kwh": {
+                    "type": "number",
+                    "example": 900
+                },
+                "credit_carryover": {
+                    "type": "number",
+                    "example": 0
+                },
+                "export_credit": {
+                    "type": "number",
+                    "example": 12
+                },
+                "export_kwh": {
+                    "type": "number",
+                    "example": 230
+                },
+                "import_cost": {
+                    "type": "number",
+                    "example": 190
+                },
+                "import_kwh": {
+                    "type": "number",
+                    "example": 610
+                },
+                "month": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "post_solar_bill": {
+                    "type": "number",
+                    "example": 193
+                },
+                "pre_solar_bill": {
+                    "type": "number",
+                    "example": 285
+                },
+                "production_kwh": {
+                    "type": "number",
+                    "example": 520
+                }
+            }
+        },
+        "service.PipelineLead": {
+            "type": "object",
+            "properties": {
+                "installation_date": {
+                    "type": "string"
+                },
+                "lead_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "milestone_state": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.MilestoneState"
+                        }
+                    ],
+                    "example": 1
+                },
+                "state": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.LeadState"
+                        }
+                    ],
+                    "example": 1
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "service.PipelineStage": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "leads": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.PipelineLead"
+                    }
+                },
+                "milestone": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Milestone"
+                        }
+                    ],
+                    "example": "installation"
+                }
+            }
+        },
+        "service.ProductionEstimate": {
+            "type": "object",
+            "properties": {
+                "annual_kwh": {
+                    "type": "number",
+                    "example": 12150
+                },
+                "azimuth_deg": {
+                    "type": "number",
+                    "example": 180
+                },
+                "climate_cell": {
+                    "type": "string",
+                    "example": "35,-125"
+                },
+                "clipped_kwh": {
+                    "type": "number",
+                    "example": 42.5
+                },
+                "hourly_kwh": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "inverter_capacity_kw": {
+                    "type": "number",
+                    "example": 7.25
+                },
+                "latitude": {
+                    "type": "number",
+                    "example": 37.7749
+                },
+                "longitude": {
+                    "type": "number",
+                    "example": -122.4194
+                },
+                "monthly_kwh": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "specific_yield_kwh_per_kw": {
+                    "type": "number",
+                    "example": 1518.75
+                },
+                "system_size_kw": {
+                    "type": "number",
+                    "example": 8
+                },
+                "tilt_deg": {
+                    "type": "number",
+                    "example": 20
+                }
+            }
+        },
+        "service.ProposalCustomer": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string",
+                    "example": "San Francisco"
+                },
+                "first_name": {
+                    "type": "string",
+                    "example": "Jane"
+                },
+                "last_name": {
+                    "type": "string",
+                    "example": "Doe"
+                },
+                "postal_code": {
+                    "type": "string",
+                    "example": "94105"
+                },
+                "state": {
+                    "type": "string",
+                    "example": "CA"
+                },
+                "street": {
+                    "type": "string",
+                    "example": "1 Market St"
+                }
+            }
+        },
+        "service.ProposalMeshFiles": {
+            "type": "object",
+            "properties": {
+                "jpg_url": {
+                    "type": "string",
+                    "example": "/media/1001/scene.jpg"
+                },
+                "mtl_url": {
+                    "type": "string",
+                    "example": "/media/1001/scene.mtl"
+                },
+                "obj_url": {
+                    "type": "string",
+                    "example": "/media/1001/scene.obj"
+                },
+                "ply_url": {
+                    "type": "string",
+                    "example": "/media/1001/scene.ply"
+                }
+            }
+        },
+        "service.ProposalShareLink": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "AAAAAAAAAAcAAAAAaQ3a8A.Yk1n2b7x0Qf4sUeR9mCzLw"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "first_opened_at": {
+                    "type": "string"
+                },
+                "id": {
                     "type": "integer"
+                },
+                "last_opened_at": {
+                    "type": "string"
+                },
+                "lead_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "open_count": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "quote_version": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "responded_at": {
+                    "type": "string"
+                },
+                "responder_name": {
+                    "type": "string",
+                    "example": "Jane Doe"
+                },
+                "response_message": {
+                    "type": "string",
+                    "example": "Could we add a battery?"
+                },
+                "status": {
+                    "enum": [
+                        "pending",
+                        "accepted",
+                        "changes_requested"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ProposalShareStatus"
+                        }
+                    ],
+                    "example": "pending"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "/p/AAAAAAAAAAcAAAAAaQ3a8A.Yk1n2b7x0Qf4sUeR9mCzLw"
                 }
             }
         },
-        "handler.AuthResponse": {
+        "service.ProposalShareResponse": {
             "type": "object",
             "properties": {
-                "token": {
+                "message": {
                     "type": "string",
-                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                    "example": "Could we add a battery?"
                 },
-                "user": {}
+                "name": {
+                    "type": "string",
+                    "example": "Jane Doe"
+                }
             }
         },
-        "handler.ErrorResponse": {
+        "service.ProposalShareView": {
             "type": "object",
             "properties": {
-                "error": {
+                "access_code": {
+                    "description": "AccessCode opens the lead in LightFusion's own viewer.",
                     "type": "string",
-                    "example": "error message"
+                    "example": "X7K2PQ"
+                },
+                "company_name": {
+                    "type": "string",
+                    "example": "SunReady Solar"
+                },
+                "customer": {
+                    "$ref": "#/definitions/service.ProposalCustomer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "inverter": {
+                    "$ref": "#/definitions/models.Inverter"
+                },
+                "lead_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "mesh_files": {
+                    "$ref": "#/definitions/service.ProposalMeshFiles"
+                },
+                "panel": {
+                    "$ref": "#/definitions/models.Panel"
+                },
+                "proposal_url": {
+                    "type": "string",
+                    "example": "/p/AAAAAAAAAAcAAAAAaQ3a8A.Yk1n2b7x0Qf4sUeR9mCzLw/proposal.pdf"
+                },
+                "quote": {
+                    "$ref": "#/definitions/service.QuoteResult"
+                },
+                "quote_version": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "responded_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ProposalShareStatus"
+                        }
+                    ],
+                    "example": "pending"
                 }
             }
         },
-        "handler.LeadResponse": {
+        "service.QuoteAssumptions": {
             "type": "object",
             "properties": {
-                "house_id": {
+                "annual_degradation": {
+                    "type": "number",
+                    "example": 0.005
+                },
+                "annual_insurance_cost": {
+                    "type": "number",
+                    "example": 100
+                },
+                "annual_om_cost": {
+                    "type": "number",
+                    "example": 150
+                },
+                "annual_utility_increase": {
+                    "type": "number",
+                    "example": 0.03
+                },
+                "cost_per_watt": {
+                    "type": "number",
+                    "example": 2.5
+                },
+                "electrical_offset_pct": {
+                    "type": "number",
+                    "example": 95
+                },
+                "federal_tax_credit": {
+                    "type": "number",
+                    "example": 0.3
+                },
+                "first_year_degradation": {
+                    "type": "number",
+                    "example": 0.02
+                },
+                "inverter_replacement_cost": {
+                    "type": "number",
+                    "example": 2500
+                },
+                "inverter_replacement_year": {
+                    "type": "integer",
+                    "example": 13
+                },
+                "loan_interest_rate": {
+                    "type": "number",
+                    "example": 0.0699
+                },
+                "loan_term_years": {
+                    "type": "integer",
+                    "example": 25
+                },
+                "sources": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/service.AssumptionSource"
+                    }
+                },
+                "sun_hours_per_day": {
+                    "type": "number",
+                    "example": 5
+                },
+                "utility_rate_per_kwh": {
+                    "type": "number",
+                    "example": 0.25
+                }
+            }
+        },
+        "service.QuoteDiff": {
+            "type": "object",
+            "properties": {
+                "assumptions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.QuoteFieldChange"
+                    }
+                },
+                "from_version": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "input": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.QuoteFieldChange"
+                    }
+                },
+                "lead_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "result": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.QuoteFieldChange"
+                    }
+                },
+                "to_version": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "service.QuoteFieldChange": {
+            "type": "object",
+            "properties": {
+                "delta": {
+                    "type": "number",
+                    "example": 1.2
+                },
+                "field": {
+                    "type": "string",
+                    "example": "system_size_kw"
+                },
+                "from": {},
+                "to": {}
+            }
+        },
+        "service.QuoteIncentive": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 1500
+                },
+                "capped": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "SGIP Residential Rebate"
+                },
+                "payment_years": {
+                    "description": "PaymentYears is set for per-kWh programs, which pay Amount out over\nthat many years of production rather than at installation.",
+                    "type": "integer",
+                    "example": 5
+                },
+                "reduces_itc_basis": {
+                    "type": "boolean"
+                },
+                "rule_id": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "type": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.IncentiveType"
+                        }
+                    ],
+                    "example": "per_watt"
+                }
+            }
+        },
+        "service.QuoteInput": {
+            "type": "object",
+            "properties": {
+                "annual_degradation": {
+                    "type": "number",
+                    "example": 0.0055
+                },
+                "annual_insurance_cost": {
+                    "type": "number",
+                    "example": 100
+                },
+                "annual_om_cost": {
+                    "type": "number",
+                    "example": 150
+                },
+                "annual_production_kwh": {
+                    "type": "number",
+                    "example": 11000
+                },
+                "annual_utility_increase": {
+                    "type": "number",
+                    "example": 0.03
+                },
+                "azimuth_deg": {
+                    "type": "number",
+                    "example": 180
+                },
+                "cost_per_watt": {
+                    "type": "number",
+                    "example": 2.5
+                },
+                "detailed": {
+                    "description": "Detailed adds the loan amortization schedule and the year-by-year\ncash-flow table to the result.",
+                    "type": "boolean"
+                },
+                "electrical_offset_pct": {
+                    "type": "number",
+                    "example": 95
+                },
+                "federal_tax_credit": {
+                    "type": "number",
+                    "example": 0.3
+                },
+                "financing": {
+                    "description": "Financing lists scenarios to compare side by side in the result.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.FinancingScenario"
+                    }
+                },
+                "first_year_degradation": {
+                    "type": "number",
+                    "example": 0.02
+                },
+                "inverter_id": {
                     "type": "integer",
-                    "example": 123
+                    "example": 1
                 },
-                "lead_id": {
+                "inverter_replacement_cost": {
+                    "type": "number",
+                    "example": 2500
+                },
+                "inverter_replacement_year": {
                     "type": "integer",
-                    "example": 42
+                    "example": 13
                 },
-                "success": {
-                    "type": "boolean",
-                    "example": true
-                }
-            }
-        },
-        "handler.LoginRequest": {
-            "type": "object",
-            "properties": {
-                "email": {
-                    "type": "string",
-                    "example": "user@example.com"
+                "latitude": {
+                    "description": "Latitude and Longitude, when no production is given, switch the\nproduction estimate from sun hours to the offline simulator. TiltDeg\nand AzimuthDeg default to 20° facing south.",
+                    "type": "number",
+                    "example": 37.7749
                 },
-                "password": {
-                    "type": "string",
-                    "example": "password123"
-                }
-            }
-        },
-        "handler.MessageResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
-                }
-            }
-        },
-        "handler.RegisterRequest": {
-            "type": "object",
-            "properties": {
-                "city": {
-                    "type": "string",
-                    "example": "Anytown"
+                "lead_id": {
+                    "type": "integer"
                 },
-                "country": {
-                    "type": "string",
-                    "example": "USA"
+                "loan_interest_rate": {
+                    "type": "number",
+                    "example": 0.0699
                 },
-                "email": {
-                    "type": "string",
-                    "example": "user@example.com"
+                "loan_term_years": {
+                    "type": "integer",
+                    "example": 25
                 },
-                "first_name": {
-                    "type": "string",
-                    "example": "John"
+                "longitude": {
+                    "type": "number",
+                    "example": -122.4194
                 },
-                "last_name": {
-                    "type": "string",
-                    "example": "Doe"
+                "monthly_consumption_kwh": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
                 },
-                "password": {
-                    "type": "string",
-                    "example": "password123"
+                "monthly_electric_bill": {
+                    "type": "number",
+                    "example": 180
                 },
-                "phone": {
-                    "type": "string",
-                    "example": "555-123-4567"
+                "monthly_production_kwh": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
                 },
-                "postal_code": {
-                    "type": "string",
-                    "example": "12345"
+                "panel_count": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "panel_id": {
+                    "description": "PanelID and InverterID select catalog hardware whose degradation and\nwarranty data default the lifetime assumptions below.",
+                    "type": "integer",
+                    "example": 1
                 },
                 "state": {
                     "type": "string",
                     "example": "CA"
                 },
-                "street": {
-                    "type": "string",
-                    "example": "123 Main St"
+                "system_size_kw": {
+                    "type": "number",
+                    "example": 8
                 },
-                "user_type": {
-                    "type": "string",
-                    "example": "0"
+                "tariff": {
+                    "description": "Tariff switches bill savings from the flat-rate offset estimate to\nthe billing engine. It needs 12 monthly consumption values; monthly\nproduction defaults to a seasonal split of annual production.\nWithout a tariff, consumption priced at the utility rate stands in\nfor a missing monthly bill.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.Tariff"
+                        }
+                    ]
+                },
+                "tilt_deg": {
+                    "type": "number",
+                    "example": 20
+                },
+                "utility_id": {
+                    "description": "UtilityID is the LightFusion LSE ID of the utility; with State it\nselects the incentive programs that apply.",
+                    "type": "integer",
+                    "example": 734
+                },
+                "utility_rate_per_kwh": {
+                    "type": "number",
+                    "example": 0.25
                 }
             }
         },
-        "models.Inverter": {
+        "service.QuoteResult": {
             "type": "object",
             "properties": {
-                "capacity": {
+                "amortization": {
+                    "description": "Set in detailed mode only; they describe the default loan.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.AmortizationRow"
+                    }
+                },
+                "annual_production_kwh": {
                     "type": "number"
                 },
-                "created_at": {
-                    "type": "string"
+                "assumptions": {
+                    "$ref": "#/definitions/service.QuoteAssumptions"
                 },
-                "id": {
+                "billing": {
+                    "description": "Billing holds the first-year monthly bills when a tariff was given.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.BillingResult"
+                        }
+                    ]
+                },
+                "break_even_year": {
                     "type": "integer"
                 },
-                "manufacturer": {
-                    "type": "string"
+                "cash_flow": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.CashFlowYear"
+                    }
                 },
-                "model": {
-                    "type": "string"
+                "cost_per_watt": {
+                    "type": "number"
                 },
-                "updated_at": {
-                    "type": "string"
-                }
-            }
-        },
-        "models.Panel": {
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
+                "current_monthly_bill": {
+                    "type": "number"
                 },
-                "id": {
+                "electrical_offset_pct": {
+                    "type": "number"
+                },
+                "estimated_monthly_payment": {
+                    "type": "number"
+                },
+                "estimated_new_monthly_bill": {
+                    "type": "number"
+                },
+                "federal_tax_credit": {
+                    "type": "number"
+                },
+                "financing": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.FinancingResult"
+                    }
+                },
+                "first_year_savings": {
+                    "type": "number"
+                },
+                "incentives": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.QuoteIncentive"
+                    }
+                },
+                "lead_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "monthly_savings": {
+                    "type": "number"
+                },
+                "panel_count": {
                     "type": "integer"
                 },
-                "longside": {
+                "production": {
+                    "description": "Production is the simulated year, without hourly values, when the\nproduction source is the simulator.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.ProductionEstimate"
+                        }
+                    ]
+                },
+                "production_source": {
+                    "type": "string",
+                    "example": "simulation"
+                },
+                "quote_id": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "simple_payback_years": {
                     "type": "number"
                 },
-                "manufacturer": {
+                "summary": {
                     "type": "string"
                 },
-                "model": {
-                    "type": "string"
+                "system_cost_after_incentives": {
+                    "type": "number"
                 },
-                "shortside": {
+                "system_cost_before_incentives": {
                     "type": "number"
                 },
-                "updated_at": {
-                    "type": "string"
+                "system_size_kw": {
+                    "type": "number"
                 },
-                "wattage": {
+                "tax_credit_basis": {
+                    "type": "number"
+                },
+                "total_incentives": {
+                    "description": "TotalIncentives includes per-kWh incentives paid over the years after\ninstallation; SystemCostAfterIncentives only takes off those paid at\ninstallation.",
+                    "type": "number"
+                },
+                "twenty_five_year_savings": {
                     "type": "number"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
                 }
             }
         },
-        "models.Storage": {
+        "service.QuoteVersion": {
             "type": "object",
             "properties": {
-                "capacity": {
-                    "type": "number"
+                "assumptions": {
+                    "$ref": "#/definitions/service.QuoteAssumptions"
                 },
                 "created_at": {
                     "type": "string"
                 },
                 "id": {
-                    "type": "integer"
+                    "type": "integer",
+                    "example": 7
                 },
-                "manufacturer": {
-                    "type": "string"
+                "input": {
+                    "$ref": "#/definitions/service.QuoteInput"
                 },
-                "model": {
-                    "type": "string"
+                "lead_id": {
+                    "type": "integer",
+                    "example": 42
                 },
-                "updated_at": {
-                    "type": "string"
+                "result": {
+                    "$ref": "#/definitions/service.QuoteResult"
+                },
+                "user_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
                 }
             }
         },
-        "models.User": {
+        "service.SensitivityOutcome": {
             "type": "object",
             "properties": {
-                "average_monthly_bill": {
-                    "type": "number",
-                    "example": 150
-                },
-                "city": {
-                    "type": "string"
+                "break_even_year": {
+                    "type": "integer",
+                    "example": 11
                 },
-                "country": {
-                    "type": "string"
+                "simple_payback_years": {
+                    "type": "number",
+                    "example": 8.4
                 },
-                "created_at": {
-                    "type": "string"
+                "twenty_five_year_savings": {
+                    "type": "number",
+                    "example": 38250.4
+                }
+            }
+        },
+        "service.SensitivityPoint": {
+            "type": "object",
+            "properties": {
+                "break_even_year": {
+                    "type": "integer",
+                    "example": 11
                 },
-                "email": {
-                    "type": "string"
+                "simple_payback_years": {
+                    "type": "number",
+                    "example": 8.4
                 },
-                "first_name": {
-                    "type": "string"
+                "twenty_five_year_savings": {
+                    "type": "number",
+                    "example": 38250.4
                 },
-                "home_ownership_type": {
-                    "type": "string",
-                    "example": "owner"
+                "value": {
+                    "type": "number",
+                    "example": 0.02
+                }
+            }
+        },
+        "service.SensitivityRange": {
+            "type": "object",
+            "properties": {
+                "high": {
+                    "type": "number",
+                    "example": 0.05
                 },
-                "id": {
-                    "type": "integer"
+                "low": {
+                    "type": "number",
+                    "example": 0.02
                 },
-                "last_name": {
-                    "type": "string"
+                "steps": {
+                    "type": "integer",
+                    "example": 4
+                }
+            }
+        },
+        "service.SensitivityRequest": {
+            "type": "object",
+            "properties": {
+                "annual_utility_increase": {
+                    "$ref": "#/definitions/service.SensitivityRange"
                 },
-                "phone_number": {
-                    "type": "string"
+                "cost_per_watt": {
+                    "$ref": "#/definitions/service.SensitivityRange"
                 },
-                "postal_code": {
-                    "type": "string"
+                "input": {
+                    "$ref": "#/definitions/service.QuoteInput"
                 },
-                "state": {
-                    "type": "string"
+                "loan_interest_rate": {
+                    "$ref": "#/definitions/service.SensitivityRange"
                 },
-                "street": {
-                    "type": "string"
+                "production": {
+                    "$ref": "#/definitions/service.SensitivityRange"
+                }
+            }
+        },
+        "service.SensitivityResult": {
+            "type": "object",
+            "properties": {
+                "assumptions": {
+                    "$ref": "#/definitions/service.QuoteAssumptions"
                 },
-                "updated_at": {
-                    "type": "string"
+                "base": {
+                    "$ref": "#/definitions/service.SensitivityOutcome"
                 },
-                "user_type": {
-                    "$ref": "#/definitions/models.UserType"
+                "series": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.SensitivitySeries"
+                    }
                 },
-                "utility_provider": {
-                    "type": "string",
-                    "example": "PG\u0026E"
+                "tornado": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.TornadoBar"
+                    }
                 }
             }
         },
-        "models.UserType": {
-            "type": "integer",
-            "format": "int32",
-            "enum": [
-                0,
-                1,
-                2
-            ],
-            "x-enum-varnames": [
-                "UserTypeAdmin",
-                "UserTypeCustomer",
-                "UserTypeGeneral"
-            ]
-        },
-        "service.CreateLead": {
+        "service.SensitivitySeries": {
             "type": "object",
             "properties": {
-                "consumption": {
+                "points": {
                     "type": "array",
                     "items": {
-                        "type": "integer"
+                        "$ref": "#/definitions/service.SensitivityPoint"
                     }
                 },
-                "hardware_type": {
-                    "type": "string"
+                "variable": {
+                    "type": "string",
+                    "example": "annual_utility_increase"
+                }
+            }
+        },
+        "service.StorageAnalysis": {
+            "type": "object",
+            "properties": {
+                "annual_consumption_kwh": {
+                    "type": "number",
+                    "example": 12000
                 },
-                "inverter_id": {
+                "annual_production_kwh": {
+                    "type": "number",
+                    "example": 11800
+                },
+                "critical_load_pct": {
+                    "type": "number",
+                    "example": 50
+                },
+                "lead_id": {
                     "type": "integer",
-                    "example": 1
+                    "example": 42
                 },
-                "kwh_usage": {
+                "recommendation": {
+                    "description": "Recommendation is the smallest bank whose average backup meets the\ntarget, or the longest-lasting one if none does.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.StorageOption"
+                        }
+                    ]
+                },
+                "recommendation_reason": {
+                    "type": "string",
+                    "example": "Smallest bank averaging at least 12 hours of backup"
+                },
+                "selected": {
+                    "description": "Selected is the requested storage, when one was given.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.StorageOption"
+                        }
+                    ]
+                },
+                "target_backup_hours": {
                     "type": "number",
-                    "example": 12000
+                    "example": 12
+                }
+            }
+        },
+        "service.StorageAnalysisRequest": {
+            "type": "object",
+            "properties": {
+                "allow_grid_charging": {
+                    "type": "boolean"
                 },
-                "latitude": {
+                "azimuth_deg": {
                     "type": "number",
-                    "example": 37.7749
+                    "example": 180
                 },
-                "longitude": {
+                "backup_reserve_pct": {
                     "type": "number",
-                    "example": -122.4194
+                    "example": 20
                 },
-                "mode": {
-                    "type": "string"
+                "critical_load_pct": {
+                    "type": "number",
+                    "example": 50
                 },
-                "panel_count": {
+                "monthly_consumption_kwh": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "quantity": {
                     "type": "integer",
-                    "example": 30
+                    "example": 2
                 },
-                "panel_id": {
+                "storage_id": {
                     "type": "integer",
-                    "example": 1
+                    "example": 2
                 },
-                "period": {
-                    "type": "string"
+                "target_backup_hours": {
+                    "type": "number",
+                    "example": 12
                 },
-                "project_id": {
-                    "type": "integer",
-                    "example": 1
+                "tariff": {
+                    "$ref": "#/definitions/service.Tariff"
                 },
-                "system_size": {
+                "tilt_deg": {
                     "type": "number",
-                    "example": 10.5
+                    "example": 20
+                }
+            }
+        },
+        "service.StorageConfig": {
+            "type": "object",
+            "properties": {
+                "capacity_kwh": {
+                    "type": "number",
+                    "example": 27
                 },
-                "target_solar_offset": {
-                    "type": "integer"
+                "power_kw": {
+                    "type": "number",
+                    "example": 10
                 },
-                "unit": {
-                    "type": "string"
+                "reserve_fraction": {
+                    "type": "number",
+                    "example": 0.2
                 },
-                "user_id": {
-                    "type": "integer",
-                    "example": 1
+                "round_trip_efficiency": {
+                    "type": "number",
+                    "example": 0.9
                 }
             }
         },
-        "service.QuoteInput": {
+        "service.StorageOption": {
             "type": "object",
             "properties": {
-                "annualProductionKWh": {
+                "annual_bill_solar_only": {
                     "type": "number",
-                    "format": "float64"
+                    "example": 1380
                 },
-                "annualUtilityIncrease": {
+                "annual_bill_with_storage": {
                     "type": "number",
-                    "format": "float64"
+                    "example": 910
                 },
-                "costPerWatt": {
+                "annual_savings": {
                     "type": "number",
-                    "format": "float64"
+                    "example": 470
                 },
-                "electricalOffsetPct": {
+                "backup_hours_avg": {
                     "type": "number",
-                    "format": "float64"
+                    "example": 21.5
                 },
-                "federalTaxCredit": {
+                "backup_hours_min": {
                     "type": "number",
-                    "format": "float64"
+                    "example": 11
                 },
-                "loanInterestRate": {
+                "battery_discharged_kwh": {
                     "type": "number",
-                    "format": "float64"
+                    "example": 3900
                 },
-                "loanTermYears": {
-                    "type": "integer"
+                "dispatch": {
+                    "type": "string",
+                    "enum": [
+                        "self_consumption",
+                        "tou"
+                    ],
+                    "example": "tou"
                 },
-                "monthlyElectricBill": {
+                "equivalent_cycles": {
                     "type": "number",
-                    "format": "float64"
+                    "example": 144
                 },
-                "panelCount": {
-                    "type": "integer"
+                "grid_charged_kwh": {
+                    "type": "number",
+                    "example": 0
                 },
-                "state": {
-                    "type": "string"
+                "manufacturer": {
+                    "type": "string",
+                    "example": "Tesla"
+                },
+                "model": {
+                    "type": "string",
+                    "example": "Powerwall 3"
+                },
+                "monthly_backup_hours": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "quantity": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "self_consumption_gain_pct": {
+                    "type": "number",
+                    "example": 32.2
+                },
+                "self_consumption_pct": {
+                    "type": "number",
+                    "example": 78.4
+                },
+                "self_consumption_savings": {
+                    "type": "number",
+                    "example": 310
                 },
-                "systemSizeKW": {
+                "self_consumption_without_storage_pct": {
                     "type": "number",
-                    "format": "float64"
+                    "example": 46.2
+                },
+                "storage": {
+                    "$ref": "#/definitions/service.StorageConfig"
                 },
-                "utilityRatePerKWh": {
+                "storage_id": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "tou_arbitrage_savings": {
                     "type": "number",
-                    "format": "float64"
+                    "example": 160
                 }
             }
         },
-        "service.QuoteResult": {
+        "service.Tariff": {
             "type": "object",
             "properties": {
-                "annual_production_kwh": {
-                    "type": "number"
+                "export_credit_rate": {
+                    "type": "number",
+                    "example": 0.05
                 },
-                "break_even_year": {
-                    "type": "integer"
+                "export_periods": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.TariffPeriod"
+                    }
                 },
-                "cost_per_watt": {
-                    "type": "number"
+                "fixed_monthly_charge": {
+                    "type": "number",
+                    "example": 15
                 },
-                "current_monthly_bill": {
-                    "type": "number"
+                "flat_rate": {
+                    "type": "number",
+                    "example": 0.32
                 },
-                "electrical_offset_pct": {
-                    "type": "number"
+                "periods": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.TariffPeriod"
+                    }
                 },
-                "estimated_monthly_payment": {
-                    "type": "number"
+                "tiers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.TariffTier"
+                    }
                 },
-                "estimated_new_monthly_bill": {
-                    "type": "number"
+                "true_up": {
+                    "enum": [
+                        "monthly",
+                        "annual"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.TrueUp"
+                        }
+                    ],
+                    "example": "annual"
                 },
-                "federal_tax_credit": {
-                    "type": "number"
+                "type": {
+                    "enum": [
+                        "flat",
+                        "tiered",
+                        "tou"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.TariffType"
+                        }
+                    ],
+                    "example": "tou"
+                }
+            }
+        },
+        "service.TariffPeriod": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "string",
+                    "enum": [
+                        "all",
+                        "weekdays",
+                        "weekends"
+                    ],
+                    "example": "all"
                 },
-                "first_year_savings": {
-                    "type": "number"
+                "end_hour": {
+                    "type": "integer",
+                    "example": 21
                 },
-                "monthly_savings": {
-                    "type": "number"
+                "months": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 },
-                "panel_count": {
-                    "type": "integer"
+                "name": {
+                    "type": "string",
+                    "example": "peak"
                 },
-                "simple_payback_years": {
-                    "type": "number"
+                "rate": {
+                    "type": "number",
+                    "example": 0.48
                 },
-                "summary": {
-                    "type": "string"
+                "start_hour": {
+                    "type": "integer",
+                    "example": 16
+                }
+            }
+        },
+        "service.TariffTier": {
+            "type": "object",
+            "properties": {
+                "rate": {
+                    "type": "number",
+                    "example": 0.28
                 },
-                "system_cost_after_incentives": {
-                    "type": "number"
+                "up_to_kwh": {
+                    "type": "number",
+                    "example": 300
+                }
+            }
+        },
+        "service.TariffType": {
+            "type": "string",
+            "enum": [
+                "flat",
+                "tiered",
+                "tou"
+            ],
+            "x-enum-varnames": [
+                "TariffFlat",
+                "TariffTiered",
+                "TariffTOU"
+            ]
+        },
+        "service.TornadoBar": {
+            "type": "object",
+            "properties": {
+                "base_value": {
+                    "type": "number",
+                    "example": 0.03
                 },
-                "system_cost_before_incentives": {
-                    "type": "number"
+                "high": {
+                    "$ref": "#/definitions/service.SensitivityPoint"
                 },
-                "system_size_kw": {
-                    "type": "number"
+                "low": {
+                    "$ref": "#/definitions/service.SensitivityPoint"
                 },
-                "twenty_five_year_savings": {
-                    "type": "number"
+                "savings_swing": {
+                    "type": "number",
+                    "example": 14200.5
+                },
+                "variable": {
+                    "type": "string",
+                    "example": "annual_utility_increase"
+                }
+            }
+        },
+        "service.TrueUp": {
+            "type": "string",
+            "enum": [
+                "monthly",
+                "annual"
+            ],
+            "x-enum-varnames": [
+                "TrueUpMonthly",
+                "TrueUpAnnual"
+            ]
+        },
+        "service.UpdateMilestoneRequest": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "description": "Date is stored as the notice-to-proceed date for financing, the\nscheduled installation date for install_crew and the installed date\nfor installation. It is ignored for other milestones.",
+                    "type": "string",
+                    "example": "2025-11-03"
+                },
+                "state": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.MilestoneState"
+                        }
+                    ],
+                    "example": 2
                 }
             }
         }
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// CircuitBreaker stops calls to an upstream after a run of consecutive
+// failures. Once the cooldown has passed a single trial call is let through;
+// its outcome closes the breaker again or restarts the cooldown.
+type CircuitBreaker struct {
+	mu               sync.Mutex
+	failureThreshold int
+	cooldown         time.Duration
+	state            circuitState
+	failures         int
+	openedAt         time.Time
+	trialInFlight    bool
+}
+
+func NewCircuitBreaker(failureThreshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+	}
+}
+
+// Allow reports whether a call may proceed. Every allowed call must be
+// followed by Success, Failure or Abandon.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = circuitHalfOpen
+		b.trialInFlight = true
+		return nil
+	case circuitHalfOpen:
+		if b.trialInFlight {
+			return ErrCircuitOpen
+		}
+		b.trialInFlight = true
+		return nil
+	}
+	return nil
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = circuitClosed
+	b.failures = 0
+	b.trialInFlight = false
+}
+
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trialInFlight = false
+	if b.state == circuitHalfOpen {
+		b.trip()
+		return
+	}
+	b.failures++
+	if b.failures >= b.failureThreshold {
+		b.trip()
+	}
+}
+
+// Abandon ends a call its caller gave up on, which says nothing about the
+// upstream: it counts as neither success nor failure, and a trial call's
+// slot goes to the next caller.
+func (b *CircuitBreaker) Abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trialInFlight = false
+}
+
+func (b *CircuitBreaker) trip() {
+	b.state = circuitOpen
+	b.openedAt = time.Now()
+	b.failures = 0
+}
@@ -2,10 +2,12 @@ package client
 
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
@@ -95,13 +97,24 @@ func NewAgent() *Agent {
 func (a *Agent) doRequest(ctx context.Context, method, path string, body any, out any) error {
 	url := a.base + path
 
-	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("genability: failed to encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		return err
 	}
 
 	req.SetBasicAuth(a.creds.AppID, a.creds.AppKey)
 	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	resp, err := a.client.Do(req)
 	if err != nil {
@@ -127,12 +140,15 @@ func NewAccountProp(key, val string) AccountProperty {
 	}
 }
 
+// Account is a Genability account. ProviderAccountID is our own unique key
+// for it, which lets Upsert find the account again.
 type Account struct {
-	ID         string                     `json:"accountId"`
-	Name       string                     `json:"accountName"`
-	Status     string                     `json:"status"`
-	Address    AccountAddress             `json:"address"`
-	Properties map[string]AccountProperty `json:"properties"`
+	ID                string                     `json:"accountId,omitempty"`
+	ProviderAccountID string                     `json:"providerAccountId,omitempty"`
+	Name              string                     `json:"accountName,omitempty"`
+	Status            string                     `json:"status,omitempty"`
+	Address           AccountAddress             `json:"address"`
+	Properties        map[string]AccountProperty `json:"properties,omitempty"`
 }
 
 type Accounts struct {
@@ -156,6 +172,21 @@ func (a *Accounts) Create(ctx context.Context, input Account) (*Account, error)
 	return &resp.Results[0], nil
 }
 
+// Upsert creates the account, or updates the one with the same
+// ProviderAccountID, so a retried call does not create a second account.
+func (a *Accounts) Upsert(ctx context.Context, input Account) (*Account, error) {
+	var resp struct {
+		Results []Account `json:"results"`
+	}
+	if err := a.Agent.doRequest(ctx, "PUT", "v1/accounts", input, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Results) == 0 {
+		return nil, errors.New("no account returned from Genability")
+	}
+	return &resp.Results[0], nil
+}
+
 func (a *Accounts) Show(ctx context.Context, id string) (*Account, error) {
 	var resp struct {
 		Results []Account `json:"results"`
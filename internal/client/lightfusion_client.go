@@ -1,7 +1,6 @@
 package client
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -10,39 +9,94 @@ import (
 	"time"
 	"log"
 	"net/http"
-	"net/http/httputil"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 )
 
-type LightFusionClient struct {
-	baseURL    string
-	httpClient *http.Client
-	apiKey     string
+// LightFusion is the subset of the LightFusion API used by SunReady.
+type LightFusion interface {
+	Create3DProject(ctx context.Context, req Create3DProjectRequest) (*Create3DProjectResponse, error)
+	GetProjectStatus(ctx context.Context, projectID int, houseID int) (*Status3DProjectResponse, error)
+	GetProjectFiles(ctx context.Context, projectID int) (*ProfilesFiles3DResponse, error)
+	GetLeadCompletion(ctx context.Context, leadID int) (*LeadData, error)
+	GetAsset(ctx context.Context, path string) ([]byte, error)
+}
+
+const (
+	defaultLightFusionMeshURL  = "https://storage.googleapis.com/lightfusiondev"
+	defaultLightFusionMediaDir = "./media"
+	maxLightFusionAssetBytes   = 5 << 20
+)
+
+type LightFusionConfig struct {
+	BaseURL  string
+	Email    string
+	Password string
+	// MeshBaseURL is where mesh files are downloaded from, as
+	// {MeshBaseURL}/leads/{id}/mesh/{file}.
+	MeshBaseURL string
+	// MediaDir is where downloaded mesh files are stored and served from.
+	MediaDir string
+	// InsecureSkipVerify disables TLS certificate checks. Only meant for
+	// development servers with self-signed certificates.
+	InsecureSkipVerify bool
 }
 
-func NewLightFusionClient(baseURL,lightFusionEmail, lightFusionPassword string) *LightFusionClient {
-	var token string
-	var err error
-	if lightFusionEmail != "" && lightFusionPassword != "" {
+type LightFusionClient struct {
+	baseURL     string
+	meshBaseURL string
+	mediaDir    string
+	httpClient  *http.Client
+	email       string
+	password    string
+	breaker     *CircuitBreaker
+
+	// apiKey is the current session token. loginMu serialises renewals so
+	// concurrent callers share a single login.
+	sessionMu   sync.RWMutex
+	apiKey      string
+	apiKeyExp   time.Time
+	loginMu     sync.Mutex
+}
+
+var _ LightFusion = (*LightFusionClient)(nil)
+
+func NewLightFusionClient(cfg LightFusionConfig) *LightFusionClient {
+	c := &LightFusionClient{
+		baseURL:     strings.TrimRight(cfg.BaseURL, "/"),
+		meshBaseURL: strings.TrimRight(cfg.MeshBaseURL, "/"),
+		mediaDir:    cfg.MediaDir,
+		httpClient:  &http.Client{Timeout: 60 * time.Second},
+		email:       cfg.Email,
+		password:    cfg.Password,
+		breaker:     NewCircuitBreaker(lightFusionBreakerThreshold, lightFusionBreakerCooldown),
+	}
+	if c.meshBaseURL == "" {
+		c.meshBaseURL = defaultLightFusionMeshURL
+	}
+	if c.mediaDir == "" {
+		c.mediaDir = defaultLightFusionMediaDir
+	}
+	if cfg.InsecureSkipVerify {
+		c.httpClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+
+	if c.email != "" && c.password != "" {
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
-			token, err = Login(ctx, baseURL, lightFusionEmail, lightFusionPassword)
-			if err != nil {
-				log.Printf("Warning: Failed to authenticate with LightFusion API: %v", err)
+			if _, err := c.token(ctx); err != nil {
+				log.Printf("Warning: Failed to authenticate with LightFusion API, will retry on first use: %v", err)
 			} else {
-				log.Printf("\n Session token obtained: %s \n", token)
+				log.Println("LightFusion session token obtained")
 			}
 	} else {
 			log.Println("LightFusion credentials not provided. Set LIGHTFUSION_EMAIL and LIGHTFUSION_PASSWORD in .env")
 	}
-	return &LightFusionClient{
-		baseURL:    baseURL,
-		httpClient: &http.Client{},
-		apiKey: token,
-	}
+	return c
 }
 
 type LoginRequest struct {
@@ -69,6 +123,7 @@ type Create3DProjectRequest struct {
 	Period            string           `json:"period"`
 	TargetSolarOffset int              `json:"targetSolarOffset"`
 	Mode              *string          `json:"mode,omitempty"`
+	HardwareType      *string          `json:"hardwareType,omitempty"`
 	Unit              string           `json:"unit"`
 }
 
@@ -166,6 +221,14 @@ type BuildInfo struct {
     Progress int `json:"progress"`
 }
 
+// LightFusion build states.
+const (
+    BuildStateInProgress  = 0
+    BuildStateDone        = 1
+    BuildStateFailed      = 2
+    BuildStateInitialized = 3
+)
+
 type AddressInfo struct {
     ID         int    `json:"id"`
     Street     string `json:"street"`
@@ -319,82 +382,62 @@ type ProfilesFiles3DResponse struct {
 	Errors     []string `json:"errors,omitempty"`
 }
 
-func Login(ctx context.Context, baseURL, email, password string) (string, error) {
-	formData := fmt.Sprintf(`{"contact":"%s","password":"%s"}`, email, password)
-	body := bytes.NewBufferString(formData)
-
-	endpoint := baseURL + "/v1/users/sessions"
-	log.Printf("Sending login request to %s with body: %s", endpoint, formData)
-
-	req, err := http.NewRequest("POST", endpoint, body)
+// Login exchanges credentials for a session token.
+func (c *LightFusionClient) Login(ctx context.Context, email, password string) (string, error) {
+	payload, err := json.Marshal(LoginRequest{Contact: email, Password: password})
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{Transport: tr}
+	log.Printf("Sending login request to %s/v1/users/sessions for %s", c.baseURL, email)
 
-	resp, err := client.Do(req)
+	status, respBody, err := c.do(ctx, lightFusionRequest{
+		method:     "POST",
+		path:       "/v1/users/sessions",
+		body:       payload,
+		idempotent: true,
+	})
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return "", fmt.Errorf("login request failed: %w", err)
 	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return "", fmt.Errorf("login failed with status %d: %s", resp.StatusCode, string(respBody))
-	}
-
-	var result struct {
-		Token   string      `json:"token"`
-		User    interface{} `json:"user"`
-		Contact interface{} `json:"contact"`
+	if status != http.StatusOK && status != http.StatusCreated {
+		return "", fmt.Errorf("login failed with status %d: %s", status, string(respBody))
 	}
 
+	var result LoginResponse
 	if err := json.Unmarshal(respBody, &result); err != nil {
 		return "", fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if result.Token == "" {
-		return "", fmt.Errorf("no token in response: %s", string(respBody))
+		return "", fmt.Errorf("no token in response")
 	}
 
 	return result.Token, nil
 }
 
+// Create3DProject is not idempotent, so it is only retried when the
+// request was rejected because the session had expired.
 func (c *LightFusionClient) Create3DProject(ctx context.Context, req Create3DProjectRequest) (*Create3DProjectResponse, error) {
-	if c.apiKey == "" {
-		return nil, fmt.Errorf("not authenticated with LightFusion API")
-	}
-
 	reqJSON, err := json.MarshalIndent(req, "", "  ")
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
     log.Printf("Creating 3D project with request: %s", string(reqJSON))
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/lead/create", bytes.NewBuffer(reqJSON))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	resp, err := c.httpClient.Do(httpReq)
+	status, bodyBytes, err := c.do(ctx, lightFusionRequest{
+		method:        "POST",
+		path:          "/v1/lead/create",
+		body:          reqJSON,
+		authenticated: true,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
-	defer resp.Body.Close()
-
-	bodyBytes, _ := io.ReadAll(resp.Body)
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	if status != http.StatusOK && status != http.StatusCreated {
+		return nil, fmt.Errorf("API returned status %d: %s", status, string(bodyBytes))
 	}
 
 	var projectResp Create3DProjectResponse
@@ -406,140 +449,110 @@ func (c *LightFusionClient) Create3DProject(ctx context.Context, req Create3DPro
 }
 
 func (c *LightFusionClient) GetProjectStatus(ctx context.Context, projectID int, houseID int) (*Status3DProjectResponse, error) {
-	if c.apiKey == "" {
-		return nil, fmt.Errorf("not authenticated with LightFusion API")
-	}
-
-	requestBody := struct {
+	jsonBody, err := json.Marshal(struct {
 		ProjectID int `json:"project_id"`
 	}{
 		ProjectID: projectID,
-	}
-
-	jsonBody, err := json.Marshal(requestBody)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	endpoint := fmt.Sprintf("%s/v3/adders.ListProjectAdders", c.baseURL)
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Accept", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	log.Printf("Fetching project adders from %s for project ID: %d", endpoint, projectID)
-
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{Transport: tr}
+	log.Printf("Fetching project adders for project ID: %d", projectID)
 
-	resp, err := client.Do(httpReq)
+	status, bodyBytes, err := c.do(ctx, lightFusionRequest{
+		method:        "POST",
+		path:          "/v3/adders.ListProjectAdders",
+		body:          jsonBody,
+		idempotent:    true,
+		authenticated: true,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
-	defer resp.Body.Close()
-
-	bodyBytes, _ := io.ReadAll(resp.Body)
-
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d: %s", status, string(bodyBytes))
 	}
 
 	var projectResp Status3DProjectResponse
 	if err := json.Unmarshal(bodyBytes, &projectResp); err != nil {
-		return nil, fmt.Errorf(" hookah bar failed to decode response: %w, body: %s", err, string(bodyBytes))
+		return nil, fmt.Errorf("failed to decode response: %w, body: %s", err, string(bodyBytes))
 	}
 
-	priceRequestBody := struct {
+	priceJsonBody, err := json.Marshal(struct {
 		ProjectID int `json:"project_id"`
 		HouseID   int `json:"house_id"`
 	}{
 		ProjectID: projectID,
 		HouseID:   houseID,
-	}
-
-	priceJsonBody, err := json.Marshal(priceRequestBody)
+	})
 	if err != nil {
 		log.Printf("Warning: failed to marshal price breakdown request: %v", err)
 	} else {
-		priceEndpoint := fmt.Sprintf("%s/v3/adders.GetPriceBreakdown", c.baseURL)
-		priceReq, err := http.NewRequestWithContext(ctx, "POST", priceEndpoint, bytes.NewBuffer(priceJsonBody))
+		log.Printf("Fetching price breakdown for project ID: %d, house ID: %d", projectID, houseID)
+		priceStatus, priceBodyBytes, err := c.do(ctx, lightFusionRequest{
+			method:        "POST",
+			path:          "/v3/adders.GetPriceBreakdown",
+			body:          priceJsonBody,
+			idempotent:    true,
+			authenticated: true,
+		})
 		if err != nil {
-			log.Printf("Warning: failed to create price breakdown request: %v", err)
-		} else {
-			priceReq.Header.Set("Content-Type", "application/json")
-			priceReq.Header.Set("Accept", "application/json")
-			priceReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-			log.Printf("Fetching price breakdown from %s for project ID: %d, house ID: %d", priceEndpoint, projectID, houseID)
-
-			priceResp, err := client.Do(priceReq)
-			if err != nil {
-				log.Printf("Warning: failed to fetch price breakdown: %v", err)
+			log.Printf("Warning: failed to fetch price breakdown: %v", err)
+		} else if priceStatus == http.StatusOK {
+			var priceBreakdown PriceBreakdown
+			if err := json.Unmarshal(priceBodyBytes, &priceBreakdown); err != nil {
+				log.Printf("Warning: failed to decode price breakdown: %v", err)
 			} else {
-				defer priceResp.Body.Close()
-				priceBodyBytes, _ := io.ReadAll(priceResp.Body)
-				if priceResp.StatusCode == http.StatusOK {
-					var priceBreakdown PriceBreakdown
-					if err := json.Unmarshal(priceBodyBytes, &priceBreakdown); err != nil {
-						log.Printf("Warning: failed to decode price breakdown: %v", err)
-					} else {
-						projectResp.PriceBreakdown = &priceBreakdown
-					}
-				} else {
-					log.Printf("Warning: price breakdown API returned status %d: %s", priceResp.StatusCode, string(priceBodyBytes))
-				}
+				projectResp.PriceBreakdown = &priceBreakdown
 			}
-		}
-	}
-	leadEndpoint := fmt.Sprintf("%s/v1/leads/%d/complete", c.baseURL, houseID)
-		leadReq, err := http.NewRequestWithContext(ctx, "POST", leadEndpoint, nil)
-		if err != nil {
-			log.Printf("Warning: failed to create lead completion request: %v", err)
 		} else {
-			leadReq.Header.Set("Accept", "application/json")
-			leadReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-			log.Printf("Fetching lead completion data from %s for lead ID: %d", leadEndpoint, projectID)
-			leadResp, err := client.Do(leadReq)
-			if err != nil {
-				log.Printf("Warning: failed to fetch lead completion: %v", err)
-			} else {
-				defer leadResp.Body.Close()
-				leadBodyBytes, _ := io.ReadAll(leadResp.Body)
-				if leadResp.StatusCode == http.StatusOK || leadResp.StatusCode == http.StatusCreated {
-					var leadCompletion LeadCompletionResponse
-					if err := json.Unmarshal(leadBodyBytes, &leadCompletion); err != nil {
-						log.Printf("Warning: failed to decode lead completion: %v", err)
-					} else {
-						projectResp.LeadCompletion = &leadCompletion
-					}
-				} else {
-					log.Printf("Warning: lead completion API returned status %d: %s", leadResp.StatusCode, string(leadBodyBytes))
-				}
-			}
+			log.Printf("Warning: price breakdown API returned status %d: %s", priceStatus, string(priceBodyBytes))
 		}
+	}
+
+	lead, err := c.GetLeadCompletion(ctx, houseID)
+	if err != nil {
+		log.Printf("Warning: failed to fetch lead completion: %v", err)
+	} else {
+		projectResp.LeadCompletion = &LeadCompletionResponse{Lead: *lead}
+	}
 
 	return &projectResp, nil
 }
 
-func (c *LightFusionClient) GetProjectFiles(ctx context.Context, projectID int) (*ProfilesFiles3DResponse, error) {
-	if c.apiKey == "" {
-		return nil, fmt.Errorf("not authenticated with LightFusion API")
+// GetLeadCompletion fetches the lead as LightFusion currently sees it,
+// including build progress, production and the selected utility tariff.
+func (c *LightFusionClient) GetLeadCompletion(ctx context.Context, leadID int) (*LeadData, error) {
+	log.Printf("Fetching lead completion data for lead ID: %d", leadID)
+	status, bodyBytes, err := c.do(ctx, lightFusionRequest{
+		method:        "POST",
+		path:          fmt.Sprintf("/v1/leads/%d/complete", leadID),
+		idempotent:    true,
+		authenticated: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if status != http.StatusOK && status != http.StatusCreated {
+		return nil, fmt.Errorf("API returned status %d: %s", status, string(bodyBytes))
+	}
+
+	var leadCompletion LeadCompletionResponse
+	if err := json.Unmarshal(bodyBytes, &leadCompletion); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w, body: %s", err, string(bodyBytes))
 	}
+	return &leadCompletion.Lead, nil
+}
 
+func (c *LightFusionClient) GetProjectFiles(ctx context.Context, projectID int) (*ProfilesFiles3DResponse, error) {
 	response := &ProfilesFiles3DResponse{
 		ProjectID: projectID,
 		Errors:    []string{},
 	}
 
-	mediaDir := "./media"
-	projectDir := filepath.Join(mediaDir, fmt.Sprintf("%d", projectID))
+	projectDir := filepath.Join(c.mediaDir, fmt.Sprintf("%d", projectID))
 	if err := os.MkdirAll(projectDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
@@ -581,7 +594,7 @@ func (c *LightFusionClient) GetProjectFiles(ctx context.Context, projectID int)
 				return
 			}
 
-			endpoint := fmt.Sprintf("https://storage.googleapis.com/lightfusiondev/leads/%d/mesh/%s", projectID, f.filename)
+			endpoint := fmt.Sprintf("%s/leads/%d/mesh/%s", c.meshBaseURL, projectID, f.filename)
 			if err := c.downloadMeshFile(ctx, endpoint, filePath); err != nil {
 				errMsg := fmt.Sprintf("failed to download %s: %v", f.filename, err)
 				log.Printf("Warning: %s", errMsg)
@@ -612,46 +625,99 @@ func (c *LightFusionClient) GetProjectFiles(ctx context.Context, projectID int)
 	return response, nil
 }
 
+// downloadMeshFile retries transient failures. Mesh files are served from
+// storage rather than the API, so they do not count towards the breaker.
 func (c *LightFusionClient) downloadMeshFile(ctx context.Context, endpoint, destPath string) error {
+	return retryWithBackoff(ctx, lightFusionMaxAttempts, func() (bool, error) {
+		return c.downloadMeshFileOnce(ctx, endpoint, destPath)
+	})
+}
+
+func (c *LightFusionClient) downloadMeshFileOnce(ctx context.Context, endpoint, destPath string) (bool, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return false, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Accept", "application/octet-stream")
 
-	if strings.Contains(endpoint, "api.lightfusion.io") {
-		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if strings.HasPrefix(endpoint, c.baseURL+"/") {
+		token, err := c.token(ctx)
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
 
 	log.Printf("Downloading file from %s", endpoint)
-	dump, _ := httputil.DumpRequestOut(req, true)
-	log.Printf("Outgoing request:\n%s", string(dump))
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return true, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		return isRetryableStatus(resp.StatusCode), fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	out, err := os.Create(destPath)
+	tmpPath := destPath + ".part"
+	out, err := os.Create(tmpPath)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return false, fmt.Errorf("failed to create file: %w", err)
 	}
-	defer out.Close()
 
 	written, err := io.Copy(out, resp.Body)
+	out.Close()
 	if err != nil {
-		os.Remove(destPath)
-		return fmt.Errorf("failed to write file: %w", err)
+		os.Remove(tmpPath)
+		return true, fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		os.Remove(tmpPath)
+		return false, fmt.Errorf("failed to move file into place: %w", err)
 	}
 
 	log.Printf("Downloaded %d bytes to %s", written, destPath)
 
-	return nil
+	return false, nil
+}
+
+// GetAsset downloads a file LightFusion refers to by path, such as a
+// company's LogoPath. Relative paths are resolved against the storage the
+// mesh files are served from.
+func (c *LightFusionClient) GetAsset(ctx context.Context, path string) ([]byte, error) {
+	endpoint := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		endpoint = c.meshBaseURL + "/" + strings.TrimLeft(path, "/")
+	}
+	var data []byte
+	err := retryWithBackoff(ctx, lightFusionMaxAttempts, func() (bool, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return false, fmt.Errorf("failed to create request: %w", err)
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return true, fmt.Errorf("failed to send request: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			return isRetryableStatus(resp.StatusCode), fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		}
+		data, err = io.ReadAll(io.LimitReader(resp.Body, maxLightFusionAssetBytes+1))
+		if err != nil {
+			return true, fmt.Errorf("failed to read asset: %w", err)
+		}
+		if len(data) > maxLightFusionAssetBytes {
+			return false, fmt.Errorf("asset %s is larger than %d bytes", path, maxLightFusionAssetBytes)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
 }
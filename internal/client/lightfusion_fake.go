@@ -0,0 +1,277 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FakeLightFusion is an in-process LightFusion API backed by httptest.Server.
+// It serves canned lead, adders, price breakdown and mesh payloads so lead
+// creation and mesh download can run without network access.
+type FakeLightFusion struct {
+	Server *httptest.Server
+
+	mu         sync.Mutex
+	nextLeadID int
+	created    []Create3DProjectRequest
+	sessions   map[string]bool
+	logins     int
+
+	// Canned responses. They can be replaced before the fake is used.
+	Status         Status3DProjectResponse
+	PriceBreakdown PriceBreakdown
+	Lead           LeadData
+	MeshFiles      map[string][]byte
+	// Assets are served by path under the mesh storage, e.g. the company
+	// logo.
+	Assets map[string][]byte
+}
+
+func NewFakeLightFusion() *FakeLightFusion {
+	f := &FakeLightFusion{
+		nextLeadID: 1000,
+		sessions:   make(map[string]bool),
+		Status: Status3DProjectResponse{
+			Panel: &Panel{ID: 156, Manufacturer: "REC", Model: "REC400AA", DisplayName: "REC Alpha 400", Active: true, Power: 400, PricePerWatt: 0.45},
+			Inverter: []Inverter{
+				{ID: 324, Name: "IQ8PLUS", Manufacturer: "Enphase", Category: "micro", IsActive: true, Cost: 180, Capacity: 0.29, Quantity: 25, CostType: "per_unit"},
+			},
+			Adders: []Adder{
+				{ID: 1, Name: "Main panel upgrade", Cost: 2500, CostType: "fixed", States: []string{"CA"}, Active: true, Quantity: 1},
+			},
+		},
+		PriceBreakdown: PriceBreakdown{
+			Items: []PriceItem{
+				{Name: "Base system", Price: 25000},
+				{Name: "Main panel upgrade", Price: 2500},
+			},
+			BasePricePerWatt:            2.5,
+			TotalPricePerWatt:           2.75,
+			TotalPricePerWattFinanced:   3.1,
+			DefaultBasePrice:            2.5,
+			MinimumBasePrice:            2.1,
+			TotalAmount:                 31000,
+			TotalAmountWithoutDealerFee: 27500,
+			TotalFee:                    3500,
+		},
+		Lead: LeadData{
+			State:      1,
+			Latitude:   37.7749,
+			Longitude:  -122.4194,
+			AccessCode: "FAKE-ACCESS",
+			Build:      BuildInfo{ID: 1, State: BuildStateDone, Progress: 100},
+			Address:    AddressInfo{Street: "1 Market St", City: "San Francisco", State: "CA", PostalCode: "94105", Country: "US"},
+			House: HouseInfo{
+				KwhUsage:      12000,
+				InverterCount: 25,
+				Panel:         PanelInfo{ID: 156, Name: "REC400AA", Manufacturer: "REC", MaxPowerCurrent: 10.5, MaxPowerVoltage: 38.1, Power: 400},
+				Inverter:      InverterInfo{ID: 324, Name: "IQ8PLUS", Manufacturer: "Enphase"},
+				PanelCount:    25,
+				SystemSize:    10000,
+			},
+			Production:      ProductionInfo{Annual: 14200, KwhPerKw: 1420},
+			CurrentProvider: ProviderInfo{UtilityID: 734, Utility: UtilityInfo{ID: 734, Name: "Pacific Gas & Electric"}, TariffID: 3153311, Tariff: TariffInfo{ID: 3153311, Name: "E-TOU-C"}},
+			FinancingProvider: FinancingProvider{
+				Name:   "Fake Lender",
+				Option: FinancingOption{ID: 1, Name: "25 year 6.99%", InterestRate: 6.99, Duration: 25, LoanFee: 0.2},
+			},
+			Company: CompanyInfo{ID: 1, Name: "SunReady Solar", Slug: "sunready", LogoPath: "companies/1/logo.png", Colors: &fakeCompanyColors},
+		},
+		MeshFiles: map[string][]byte{
+			"scene.jpg": fakeImage(jpeg.Encode),
+			"scene.obj": []byte("o roof\nv 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\n"),
+			"scene.ply": []byte("ply\nformat ascii 1.0\nend_header\n"),
+			"scene.mtl": []byte("newmtl roof\n"),
+		},
+		Assets: map[string][]byte{
+			"companies/1/logo.png": fakeImage(func(w io.Writer, img image.Image, _ *jpeg.Options) error { return png.Encode(w, img) }),
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/users/sessions", f.handleLogin)
+	mux.HandleFunc("/v1/lead/create", f.authenticated(f.handleCreate))
+	mux.HandleFunc("/v3/adders.ListProjectAdders", f.authenticated(f.handleAdders))
+	mux.HandleFunc("/v3/adders.GetPriceBreakdown", f.authenticated(f.handlePriceBreakdown))
+	mux.HandleFunc("/v1/leads/", f.authenticated(f.handleLeadComplete))
+	mux.HandleFunc("/mesh/leads/", f.handleMesh)
+	mux.HandleFunc("/mesh/companies/", f.handleAsset)
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+// Config returns a client configuration pointing at the fake server.
+func (f *FakeLightFusion) Config(mediaDir string) LightFusionConfig {
+	return LightFusionConfig{
+		BaseURL:     f.Server.URL,
+		Email:       "fake@sunready.test",
+		Password:    "fake",
+		MeshBaseURL: f.Server.URL + "/mesh",
+		MediaDir:    mediaDir,
+	}
+}
+
+// Client returns a LightFusionClient logged in to the fake server.
+func (f *FakeLightFusion) Client(mediaDir string) *LightFusionClient {
+	return NewLightFusionClient(f.Config(mediaDir))
+}
+
+// CreatedProjects returns every Create3DProject request received so far.
+func (f *FakeLightFusion) CreatedProjects() []Create3DProjectRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Create3DProjectRequest(nil), f.created...)
+}
+
+// Logins returns how many sessions the fake has issued.
+func (f *FakeLightFusion) Logins() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.logins
+}
+
+// ExpireSessions invalidates every issued token, as if they had expired.
+func (f *FakeLightFusion) ExpireSessions() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sessions = make(map[string]bool)
+}
+
+func (f *FakeLightFusion) Close() {
+	f.Server.Close()
+}
+
+func (f *FakeLightFusion) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		ok := f.sessions[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		f.mu.Unlock()
+		if !ok {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (f *FakeLightFusion) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Contact == "" || req.Password == "" {
+		http.Error(w, `{"error":"invalid credentials"}`, http.StatusUnauthorized)
+		return
+	}
+	f.mu.Lock()
+	f.logins++
+	token := fmt.Sprintf("fake-lightfusion-token-%d", f.logins)
+	f.sessions[token] = true
+	f.mu.Unlock()
+	fakeJSON(w, http.StatusCreated, LoginResponse{Token: token})
+}
+
+func (f *FakeLightFusion) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req Create3DProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid request"}`, http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.nextLeadID++
+	leadID := f.nextLeadID
+	f.created = append(f.created, req)
+	f.mu.Unlock()
+
+	fakeJSON(w, http.StatusCreated, Create3DProjectResponse{
+		ID:     leadID,
+		LeadID: leadID,
+		Status: "created",
+	})
+}
+
+func (f *FakeLightFusion) handleAdders(w http.ResponseWriter, r *http.Request) {
+	status := f.Status
+	status.PriceBreakdown = nil
+	status.LeadCompletion = nil
+	fakeJSON(w, http.StatusOK, status)
+}
+
+func (f *FakeLightFusion) handlePriceBreakdown(w http.ResponseWriter, r *http.Request) {
+	fakeJSON(w, http.StatusOK, f.PriceBreakdown)
+}
+
+// handleLeadComplete serves /v1/leads/{id}/complete.
+func (f *FakeLightFusion) handleLeadComplete(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/leads/"), "/"), "/")
+	if len(parts) != 2 || parts[1] != "complete" {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	lead := f.Lead
+	lead.ID = id
+	lead.LeadID = id
+	fakeJSON(w, http.StatusOK, LeadCompletionResponse{Lead: lead})
+}
+
+// handleMesh serves /mesh/leads/{id}/mesh/{file}.
+func (f *FakeLightFusion) handleMesh(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/mesh/leads/"), "/")
+	if len(parts) != 3 || parts[1] != "mesh" {
+		http.NotFound(w, r)
+		return
+	}
+	data, ok := f.MeshFiles[parts[2]]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+	w.Write(data)
+}
+
+// handleAsset serves /mesh/{path} from Assets.
+func (f *FakeLightFusion) handleAsset(w http.ResponseWriter, r *http.Request) {
+	data, ok := f.Assets[strings.TrimPrefix(r.URL.Path, "/mesh/")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(data)
+}
+
+var fakeCompanyColors = `{"primary":"#F59E0B","secondary":"#1F2937"}`
+
+// fakeImage encodes a small gradient so image consumers get a real file.
+func fakeImage(encode func(io.Writer, image.Image, *jpeg.Options) error) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(140 + x), G: uint8(90 + 2*y), B: 60, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	encode(&buf, img, nil)
+	return buf.Bytes()
+}
+
+func fakeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
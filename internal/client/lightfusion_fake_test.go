@@ -0,0 +1,80 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestFakeLightFusionCreateAndDownloadMesh(t *testing.T) {
+	fake := NewFakeLightFusion()
+	defer fake.Close()
+	mediaDir := t.TempDir()
+	lf := fake.Client(mediaDir)
+	ctx := context.Background()
+
+	storageID, quantity := 7, 2
+	created, err := lf.Create3DProject(ctx, Create3DProjectRequest{
+		Latitude:  37.7749,
+		Longitude: -122.4194,
+		Unit:      "kwh",
+		Period:    "year",
+		Hardware:  HardwareDetails{PanelID: 156, InverterID: 324, StorageID: &storageID, StorageQuantity: &quantity},
+	})
+	if err != nil {
+		t.Fatalf("Create3DProject: %v", err)
+	}
+	if created.LeadID == 0 {
+		t.Fatal("Create3DProject returned no lead ID")
+	}
+	projects := fake.CreatedProjects()
+	if len(projects) != 1 {
+		t.Fatalf("fake received %d projects, want 1", len(projects))
+	}
+	if hw := projects[0].Hardware; hw.PanelID != 156 || hw.InverterID != 324 || hw.StorageID == nil || *hw.StorageID != 7 || *hw.StorageQuantity != 2 {
+		t.Errorf("fake received hardware %+v", hw)
+	}
+
+	files, err := lf.GetProjectFiles(ctx, created.LeadID)
+	if err != nil {
+		t.Fatalf("GetProjectFiles: %v", err)
+	}
+	if !files.Downloaded || len(files.Errors) > 0 {
+		t.Fatalf("GetProjectFiles downloaded %v, errors %v", files.Downloaded, files.Errors)
+	}
+	for name, want := range fake.MeshFiles {
+		got, err := os.ReadFile(filepath.Join(mediaDir, strconv.Itoa(created.LeadID), name))
+		if err != nil {
+			t.Errorf("mesh file %s: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("mesh file %s has %d bytes, want %d", name, len(got), len(want))
+		}
+	}
+}
+
+func TestFakeLightFusionReloginAfterExpiry(t *testing.T) {
+	fake := NewFakeLightFusion()
+	defer fake.Close()
+	lf := fake.Client(t.TempDir())
+	ctx := context.Background()
+
+	if _, err := lf.GetLeadCompletion(ctx, 1001); err != nil {
+		t.Fatalf("GetLeadCompletion: %v", err)
+	}
+	fake.ExpireSessions()
+	lead, err := lf.GetLeadCompletion(ctx, 1001)
+	if err != nil {
+		t.Fatalf("GetLeadCompletion after expiry: %v", err)
+	}
+	if lead.LeadID != 1001 {
+		t.Errorf("lead ID %d, want 1001", lead.LeadID)
+	}
+	if fake.Logins() != 2 {
+		t.Errorf("%d logins, want 2", fake.Logins())
+	}
+}
@@ -0,0 +1,227 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	lightFusionMaxAttempts      = 3
+	lightFusionBaseBackoff      = 250 * time.Millisecond
+	lightFusionMaxBackoff       = 5 * time.Second
+	lightFusionBreakerThreshold = 5
+	lightFusionBreakerCooldown  = 30 * time.Second
+	// Tokens without an exp claim are renewed after this long.
+	lightFusionDefaultTokenTTL = 12 * time.Hour
+	// Tokens are renewed this long before they expire.
+	lightFusionTokenRefreshMargin = 5 * time.Minute
+)
+
+var ErrLightFusionCredentials = errors.New("LightFusion credentials not configured")
+
+type lightFusionRequest struct {
+	method string
+	path   string
+	body   []byte
+	// idempotent requests are retried on network errors and 5xx/429
+	// responses. Every request is retried once after re-authenticating
+	// when the API answers 401.
+	idempotent    bool
+	authenticated bool
+}
+
+// do sends a request to the LightFusion API and returns the status code and
+// body of the final response.
+func (c *LightFusionClient) do(ctx context.Context, r lightFusionRequest) (int, []byte, error) {
+	attempts := 1
+	if r.idempotent {
+		attempts = lightFusionMaxAttempts
+	}
+
+	var status int
+	var body []byte
+	reauthenticated := false
+	err := retryWithBackoff(ctx, attempts, func() (bool, error) {
+		for {
+			var token string
+			if r.authenticated {
+				var err error
+				if token, err = c.token(ctx); err != nil {
+					return false, err
+				}
+			}
+
+			var retry bool
+			var err error
+			status, body, retry, err = c.send(ctx, r, token)
+			if err != nil {
+				return retry, err
+			}
+			if status == http.StatusUnauthorized && r.authenticated && !reauthenticated {
+				log.Printf("LightFusion session rejected, re-authenticating")
+				c.invalidateToken(token)
+				reauthenticated = true
+				continue
+			}
+			if isRetryableStatus(status) {
+				return true, fmt.Errorf("API returned status %d: %s", status, string(body))
+			}
+			return false, nil
+		}
+	})
+	if err != nil && status != 0 && isRetryableStatus(status) {
+		// Hand the last upstream response back to the caller.
+		return status, body, nil
+	}
+	return status, body, err
+}
+
+func (c *LightFusionClient) send(ctx context.Context, r lightFusionRequest, token string) (int, []byte, bool, error) {
+	if err := c.breaker.Allow(); err != nil {
+		return 0, nil, false, fmt.Errorf("LightFusion API unavailable: %w", err)
+	}
+
+	var reqBody io.Reader
+	if r.body != nil {
+		reqBody = bytes.NewReader(r.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, c.baseURL+r.path, reqBody)
+	if err != nil {
+		c.breaker.Abandon()
+		return 0, nil, false, fmt.Errorf("failed to create request: %w", err)
+	}
+	if r.body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.failed(ctx)
+		return 0, nil, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.failed(ctx)
+		return 0, nil, ctx.Err() == nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 500 {
+		c.breaker.Failure()
+	} else {
+		c.breaker.Success()
+	}
+	return resp.StatusCode, body, false, nil
+}
+
+// failed records a failed call with the breaker, unless the caller's own
+// context was cancelled or ran out, so clients hanging up mid-request
+// cannot open it for everyone.
+func (c *LightFusionClient) failed(ctx context.Context) {
+	if ctx.Err() != nil {
+		c.breaker.Abandon()
+		return
+	}
+	c.breaker.Failure()
+}
+
+// token returns a valid session token, logging in when there is none or the
+// current one is about to expire. Concurrent callers share one login.
+func (c *LightFusionClient) token(ctx context.Context) (string, error) {
+	if token, ok := c.currentToken(); ok {
+		return token, nil
+	}
+
+	c.loginMu.Lock()
+	defer c.loginMu.Unlock()
+
+	if token, ok := c.currentToken(); ok {
+		return token, nil
+	}
+	if c.email == "" || c.password == "" {
+		return "", ErrLightFusionCredentials
+	}
+
+	token, err := c.Login(ctx, c.email, c.password)
+	if err != nil {
+		return "", err
+	}
+
+	c.sessionMu.Lock()
+	c.apiKey = token
+	c.apiKeyExp = tokenExpiry(token)
+	c.sessionMu.Unlock()
+	return token, nil
+}
+
+func (c *LightFusionClient) currentToken() (string, bool) {
+	c.sessionMu.RLock()
+	defer c.sessionMu.RUnlock()
+	if c.apiKey == "" || time.Now().Add(lightFusionTokenRefreshMargin).After(c.apiKeyExp) {
+		return "", false
+	}
+	return c.apiKey, true
+}
+
+// invalidateToken drops the given token unless another caller has already
+// replaced it.
+func (c *LightFusionClient) invalidateToken(token string) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	if c.apiKey == token {
+		c.apiKey = ""
+		c.apiKeyExp = time.Time{}
+	}
+}
+
+// tokenExpiry reads the exp claim of a JWT session token without verifying
+// it. Opaque tokens are assumed to last lightFusionDefaultTokenTTL.
+func tokenExpiry(token string) time.Time {
+	claims := jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err == nil && claims.ExpiresAt != nil {
+		return claims.ExpiresAt.Time
+	}
+	return time.Now().Add(lightFusionDefaultTokenTTL)
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryWithBackoff calls fn up to attempts times, sleeping with jittered
+// exponential backoff between attempts while fn reports the failure as
+// retryable.
+func retryWithBackoff(ctx context.Context, attempts int, fn func() (bool, error)) error {
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var retry bool
+		retry, err = fn()
+		if err == nil || !retry || attempt == attempts {
+			return err
+		}
+		backoff := lightFusionBaseBackoff << uint(attempt-1)
+		if backoff > lightFusionMaxBackoff {
+			backoff = lightFusionMaxBackoff
+		}
+		delay := time.Duration(rand.Int63n(int64(backoff)) + 1)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"fmt"
+	"html"
 	"log"
 	"os"
     "github.com/Bilal-Cplusoft/sunready/utils"
@@ -90,3 +91,40 @@ func (sg *SendGridClient) SendOTP(toEmail string) (string,error) {
 	fmt.Printf("OTP email sent to %s: %v \n", toEmail,otp)
 	return otp, nil
 }
+
+// ProposalResponseNotice tells a rep how a homeowner answered a shared
+// proposal.
+type ProposalResponseNotice struct {
+	LeadID       int
+	Customer     string
+	Accepted     bool
+	Message      string
+	QuoteVersion int
+}
+
+func (sg *SendGridClient) SendProposalResponse(toEmail, name string, notice ProposalResponseNotice) error {
+	to := mail.NewEmail(name, toEmail)
+	action := "requested changes to"
+	if notice.Accepted {
+		action = "accepted"
+	}
+	subject := fmt.Sprintf("%s %s their proposal (lead %d)", notice.Customer, action, notice.LeadID)
+	plainTextContent := fmt.Sprintf("Hello %s,\n\n%s %s the proposal for lead %d (quote version %d).",
+		name, notice.Customer, action, notice.LeadID, notice.QuoteVersion)
+	htmlContent := fmt.Sprintf("<strong>Hello %s,</strong><br><br>%s %s the proposal for lead %d (quote version %d).",
+		html.EscapeString(name), html.EscapeString(notice.Customer), action, notice.LeadID, notice.QuoteVersion)
+	if notice.Message != "" {
+		plainTextContent += fmt.Sprintf("\n\nTheir message:\n%s", notice.Message)
+		htmlContent += fmt.Sprintf("<br><br>Their message:<blockquote>%s</blockquote>", html.EscapeString(notice.Message))
+	}
+
+	message := mail.NewSingleEmail(sg.from, subject, to, plainTextContent, htmlContent)
+	response, err := sg.client.Send(message)
+	if err != nil {
+		return fmt.Errorf("failed to send proposal response email: %w", err)
+	}
+	if response.StatusCode >= 400 {
+		return fmt.Errorf("failed to send proposal response email, status code: %d, body: %s", response.StatusCode, response.Body)
+	}
+	return nil
+}
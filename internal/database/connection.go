@@ -3,6 +3,7 @@ package database
 import (
 	"fmt"
 	"log"
+	"slices"
 
 	"github.com/Bilal-Cplusoft/sunready/internal/models"
 	"gorm.io/driver/postgres"
@@ -41,7 +42,16 @@ func runMigrations(db *gorm.DB) error {
 		{&models.Storage{},"storages"},
 		{&models.Panel{},"panels"},
 		{&models.Inverter{},"inverters"},
+		{&models.LeadStateHistory{}, "lead_state_history"},
+		{&models.Job{}, "jobs"},
+		{&models.DeadJob{}, "dead_jobs"},
+		{&models.Quote{}, "quotes"},
+		{&models.PricingProfile{}, "pricing_profiles"},
+		{&models.IncentiveRule{}, "incentive_rules"},
+		{&models.Proposal{}, "proposals"},
+		{&models.ProposalShare{}, "proposal_shares"},
 	}
+	added := map[string][]string{}
 	for _, table := range tables {
 		if !db.Migrator().HasTable(table.name) {
 			log.Printf("Creating table: %s", table.name)
@@ -49,9 +59,69 @@ func runMigrations(db *gorm.DB) error {
 				log.Printf("Error creating table %s: %v", table.name, err)
 			}
 		} else {
-			log.Printf("Table already exists: %s (skipping)", table.name)
+			log.Printf("Table already exists: %s (adding missing columns only)", table.name)
+			columns, err := addMissingColumns(db, table.model)
+			if err != nil {
+				log.Printf("Error updating table %s: %v", table.name, err)
+			}
+			added[table.name] = columns
+		}
+	}
+	if slices.Contains(added["leads"], "state") {
+		if err := backfillLeadStates(db); err != nil {
+			return fmt.Errorf("failed to backfill lead states: %w", err)
+		}
+	}
+	if slices.Contains(added["inverters"], "type") {
+		// Inverters were told apart by capacity before they had a type;
+		// below 1 kW they were treated as microinverters.
+		log.Println("Backfilling inverters.type")
+		err := db.Model(&models.Inverter{}).Where("capacity < 1").Update("type", models.InverterTypeMicro).Error
+		if err != nil {
+			return fmt.Errorf("failed to backfill inverter types: %w", err)
 		}
 	}
 	log.Println("Database migrations completed")
 	return nil
 }
+
+// backfillLeadStates sets the state of leads created before it was tracked,
+// which the column default would otherwise report as in progress. Those
+// leads created their LightFusion project inline, so one with a project is
+// in progress until the sync sees its build finish, and one without never
+// got a project.
+func backfillLeadStates(db *gorm.DB) error {
+	log.Println("Backfilling leads.state")
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("UPDATE leads SET state = CASE WHEN external_id IS NOT NULL THEN ? ELSE ? END",
+			models.LeadStateProgress, models.LeadStateErrored).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec("INSERT INTO lead_state_history (created_at, lead_id, to_state, reason) SELECT NOW(), id, state, ? FROM leads",
+			"state backfilled for a lead created before state tracking").Error
+	})
+}
+
+// addMissingColumns adds columns declared on the model that the existing
+// table lacks and returns their names. Existing columns are never altered
+// or dropped.
+func addMissingColumns(db *gorm.DB, model any) ([]string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	var added []string
+	migrator := db.Migrator()
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" || migrator.HasColumn(model, field.DBName) {
+			continue
+		}
+		log.Printf("Adding column %s.%s", stmt.Schema.Table, field.DBName)
+		if err := migrator.AddColumn(model, field.Name); err != nil {
+			return added, err
+		}
+		added = append(added, field.DBName)
+	}
+	return added, nil
+}
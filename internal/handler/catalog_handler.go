@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/service"
+)
+
+// maxCatalogUpload bounds a catalog upload; the full CEC module list is
+// around 10 MB.
+const maxCatalogUpload = 64 << 20
+
+type CatalogHandler struct {
+	catalogImportService *service.CatalogImportService
+}
+
+func NewCatalogHandler(catalogImportService *service.CatalogImportService) *CatalogHandler {
+	return &CatalogHandler{catalogImportService: catalogImportService}
+}
+
+// ImportCatalog godoc
+// @Summary Import a CEC equipment list
+// @Description Upserts the panels, inverters or batteries of a California Energy Commission equipment list exported to CSV. Items are matched on manufacturer and model, so existing items keep their IDs; items missing from the file are left alone. Send the file as the "file" field of a multipart form or as the raw request body. Use dry_run to see the changes without saving them.
+// @Tags Hardware
+// @Accept multipart/form-data
+// @Accept text/csv
+// @Produce json
+// @Param kind query string false "panels, inverters or storages; detected from the columns when omitted"
+// @Param dry_run query bool false "Report the changes without saving them"
+// @Param file formData file false "CEC list as CSV"
+// @Success 200 {object} service.CatalogImportReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/hardware/import [post]
+func (h *CatalogHandler) ImportCatalog(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid dry_run flag")
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCatalogUpload)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				respondError(w, http.StatusRequestEntityTooLarge, "File too large")
+				return
+			}
+			respondError(w, http.StatusBadRequest, "Missing file")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	report, err := h.catalogImportService.Import(r.Context(), body, r.URL.Query().Get("kind"), dryRun)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			respondError(w, http.StatusRequestEntityTooLarge, "File too large")
+		case errors.Is(err, models.ErrInvalidCatalogFile), errors.Is(err, models.ErrInvalidCatalogKind):
+			respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Printf("Failed to import catalog: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to import catalog")
+		}
+		return
+	}
+	respondJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/service"
+)
+
+type DesignHandler struct {
+	designService *service.DesignService
+}
+
+func NewDesignHandler(designService *service.DesignService) *DesignHandler {
+	return &DesignHandler{designService: designService}
+}
+
+// ValidateDesign godoc
+// @Summary      Check that a panel and inverter work together
+// @Description  Sizes strings of the catalog panel for the catalog inverter so they stay below its maximum DC voltage at the record low and inside its MPPT window at the average high, and works out the DC/AC ratio and clipping risk. Temperatures default to estimates from the climate grid at latitude and longitude; where it has no data for them, pass record_low_c and average_high_c. An invalid design is still a 200 with valid set to false and the problems under errors.
+// @Tags         design
+// @Accept       json
+// @Produce      json
+// @Param        request  body      service.DesignInput  true  "Design to check"
+// @Success      200      {object}  service.DesignValidation
+// @Failure      400      {object}  ErrorResponse
+// @Failure      404      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Router       /api/design/validate [post]
+func (h *DesignHandler) ValidateDesign(w http.ResponseWriter, r *http.Request) {
+	var input service.DesignInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	result, err := h.designService.Validate(r.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidDesignInput):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, models.ErrPanelNotFound):
+			respondError(w, http.StatusNotFound, "Panel not found")
+		case errors.Is(err, models.ErrInverterNotFound):
+			respondError(w, http.StatusNotFound, "Inverter not found")
+		default:
+			log.Printf("Failed to validate design: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to validate design")
+		}
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// respondLeadHardwareError writes the response for a lead whose hardware
+// or design was rejected and reports whether err was such a rejection. A
+// missing panel, inverter or storage is the caller's mistake here, so it
+// is a 400 too.
+func respondLeadHardwareError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, models.ErrInvalidDesign), errors.Is(err, models.ErrInvalidDesignInput),
+		errors.Is(err, models.ErrInvalidLeadHardware), errors.Is(err, models.ErrHardwareNotMapped),
+		errors.Is(err, models.ErrHardwareInactive):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrPanelNotFound):
+		respondError(w, http.StatusBadRequest, "Panel not found")
+	case errors.Is(err, models.ErrInverterNotFound):
+		respondError(w, http.StatusBadRequest, "Inverter not found")
+	case errors.Is(err, models.ErrStorageNotFound):
+		respondError(w, http.StatusBadRequest, "Storage not found")
+	default:
+		return false
+	}
+	return true
+}
@@ -1,11 +1,16 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"github.com/Bilal-Cplusoft/sunready/internal/repo"
 	"encoding/json"
 	"github.com/Bilal-Cplusoft/sunready/internal/models"
-
+	"github.com/go-chi/chi/v5"
 )
 
 type HardwareHandler struct {
@@ -18,57 +23,180 @@ func NewHardwareHandler(hardwareRepo *repo.HardwareRepo) *HardwareHandler {
 
 
 // ListPanels godoc
-// @Summary List all panels
-// @Description Get a list of all panels
+// @Summary List panels
+// @Description Lists active panels a page at a time. Follow the cursor in the X-Next-Cursor header, or the Link header with rel="next", for the next page; neither is set on the last page. Responses carry an ETag and answer a matching If-None-Match with 304.
 // @Tags Hardware
 // @Produce json
+// @Param q query string false "Matches manufacturer or model, case-insensitive"
+// @Param manufacturer query string false "Matches manufacturer, case-insensitive"
+// @Param model query string false "Matches model, case-insensitive"
+// @Param min_wattage query number false "Minimum wattage"
+// @Param max_wattage query number false "Maximum wattage"
+// @Param sort query string false "id, manufacturer, model or wattage; prefix with - to sort descending" default(id)
+// @Param limit query int false "Page size, at most 200" default(50)
+// @Param cursor query string false "Cursor from the previous page"
 // @Success 200 {array} models.Panel
-// @Failure 500 {object} map[string]string
+// @Success 304
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
 // @Router /api/hardware/panels [get]
 func (h *HardwareHandler) ListPanels(w http.ResponseWriter, r *http.Request) {
-	panels, err := h.hardwareRepo.ListPanels()
+	filter, err := hardwareFilterParams(r, "wattage")
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to fetch users")
+		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	respondJSON(w, http.StatusOK, panels)
+	page, err := h.hardwareRepo.SearchPanels(r.Context(), filter)
+	if err != nil {
+		respondHardwareError(w, err, "Failed to list panels")
+		return
+	}
+	setNextPage(w, r, page.NextCursor)
+	respondCacheableJSON(w, r, page.Items)
 }
 
 // ListStorages godoc
-// @Summary List all storages
-// @Description Get a list of all storage units
+// @Summary List storage units
+// @Description Lists active storage units a page at a time. Follow the cursor in the X-Next-Cursor header, or the Link header with rel="next", for the next page; neither is set on the last page. Responses carry an ETag and answer a matching If-None-Match with 304.
 // @Tags Hardware
 // @Produce json
+// @Param q query string false "Matches manufacturer or model, case-insensitive"
+// @Param manufacturer query string false "Matches manufacturer, case-insensitive"
+// @Param model query string false "Matches model, case-insensitive"
+// @Param min_capacity query number false "Minimum nameplate capacity per unit in kWh"
+// @Param max_capacity query number false "Maximum nameplate capacity per unit in kWh"
+// @Param sort query string false "id, manufacturer, model or capacity; prefix with - to sort descending" default(id)
+// @Param limit query int false "Page size, at most 200" default(50)
+// @Param cursor query string false "Cursor from the previous page"
 // @Success 200 {array} models.Storage
-// @Failure 500 {object} map[string]string
+// @Success 304
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
 // @Router /api/hardware/storages [get]
 func (h *HardwareHandler) ListStorages(w http.ResponseWriter, r *http.Request) {
-	storages, err := h.hardwareRepo.ListStorages()
+	filter, err := hardwareFilterParams(r, "capacity")
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to fetch users")
+		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	respondJSON(w, http.StatusOK, storages)
+	page, err := h.hardwareRepo.SearchStorages(r.Context(), filter)
+	if err != nil {
+		respondHardwareError(w, err, "Failed to list storages")
+		return
+	}
+	setNextPage(w, r, page.NextCursor)
+	respondCacheableJSON(w, r, page.Items)
 }
 
 // ListInverters godoc
-// @Summary List all inverters
-// @Description Get a list of all inverters
+// @Summary List inverters
+// @Description Lists active inverters a page at a time. Follow the cursor in the X-Next-Cursor header, or the Link header with rel="next", for the next page; neither is set on the last page. Responses carry an ETag and answer a matching If-None-Match with 304.
 // @Tags Hardware
 // @Produce json
+// @Param q query string false "Matches manufacturer or model, case-insensitive"
+// @Param manufacturer query string false "Matches manufacturer, case-insensitive"
+// @Param model query string false "Matches model, case-insensitive"
+// @Param min_capacity query number false "Minimum AC capacity in kW"
+// @Param max_capacity query number false "Maximum AC capacity in kW"
+// @Param sort query string false "id, manufacturer, model or capacity; prefix with - to sort descending" default(id)
+// @Param limit query int false "Page size, at most 200" default(50)
+// @Param cursor query string false "Cursor from the previous page"
 // @Success 200 {array} models.Inverter
-// @Failure 500 {object} map[string]string
+// @Success 304
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
 // @Router /api/hardware/inverters [get]
 func (h *HardwareHandler) ListInverters(w http.ResponseWriter, r *http.Request) {
-	inverters, err := h.hardwareRepo.ListInverters()
+	filter, err := hardwareFilterParams(r, "capacity")
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to fetch users")
+		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	page, err := h.hardwareRepo.SearchInverters(r.Context(), filter)
+	if err != nil {
+		respondHardwareError(w, err, "Failed to list inverters")
+		return
+	}
+	setNextPage(w, r, page.NextCursor)
+	respondCacheableJSON(w, r, page.Items)
+}
 
-	respondJSON(w, http.StatusOK, inverters)
+// GetPanel godoc
+// @Summary Get a panel
+// @Description Returns a panel, including one removed from the catalog
+// @Tags Hardware
+// @Produce json
+// @Param id path int true "Panel ID"
+// @Success 200 {object} models.Panel
+// @Success 304
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/hardware/panels/{id} [get]
+func (h *HardwareHandler) GetPanel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid panel ID")
+		return
+	}
+	panel, err := h.hardwareRepo.GetPanelByID(r.Context(), id)
+	if err != nil {
+		respondHardwareError(w, err, "Failed to get panel")
+		return
+	}
+	respondCacheableJSON(w, r, panel)
+}
+
+// GetInverter godoc
+// @Summary Get an inverter
+// @Description Returns an inverter, including one removed from the catalog
+// @Tags Hardware
+// @Produce json
+// @Param id path int true "Inverter ID"
+// @Success 200 {object} models.Inverter
+// @Success 304
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/hardware/inverters/{id} [get]
+func (h *HardwareHandler) GetInverter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid inverter ID")
+		return
+	}
+	inverter, err := h.hardwareRepo.GetInverterByID(r.Context(), id)
+	if err != nil {
+		respondHardwareError(w, err, "Failed to get inverter")
+		return
+	}
+	respondCacheableJSON(w, r, inverter)
+}
+
+// GetStorage godoc
+// @Summary Get a storage unit
+// @Description Returns a storage unit, including one removed from the catalog
+// @Tags Hardware
+// @Produce json
+// @Param id path int true "Storage ID"
+// @Success 200 {object} models.Storage
+// @Success 304
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/hardware/storages/{id} [get]
+func (h *HardwareHandler) GetStorage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid storage ID")
+		return
+	}
+	storage, err := h.hardwareRepo.GetStorageByID(r.Context(), id)
+	if err != nil {
+		respondHardwareError(w, err, "Failed to get storage")
+		return
+	}
+	respondCacheableJSON(w, r, storage)
 }
 
 
@@ -91,7 +219,7 @@ func (h *HardwareHandler) AddPanel(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.hardwareRepo.CreatePanel(&panel); err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to create panel")
+		respondHardwareError(w, err, "Failed to create panel")
 		return
 	}
 
@@ -109,7 +237,7 @@ func (h *HardwareHandler) AddPanel(w http.ResponseWriter, r *http.Request) {
 // @Success 201 {object} models.Inverter
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
-// @Router /api/hardware/inverters [post]
+// @Router /admin/hardware/inverters [post]
 func (h *HardwareHandler) AddInverter(w http.ResponseWriter, r *http.Request) {
 	var inverter models.Inverter
 	if err := json.NewDecoder(r.Body).Decode(&inverter); err != nil {
@@ -118,7 +246,7 @@ func (h *HardwareHandler) AddInverter(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.hardwareRepo.CreateInverter(&inverter); err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to create inverter")
+		respondHardwareError(w, err, "Failed to create inverter")
 		return
 	}
 
@@ -145,9 +273,254 @@ func (h *HardwareHandler) AddStorage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.hardwareRepo.CreateStorage(&storage); err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to create storage")
+		respondHardwareError(w, err, "Failed to create storage")
 		return
 	}
 
 	respondJSON(w, http.StatusCreated, storage)
 }
+
+// UpdatePanel godoc
+// @Summary Update a panel
+// @Description Updates the fields present in the body; omitted fields keep their values. Set active to true to restore a removed panel.
+// @Tags Hardware
+// @Accept json
+// @Produce json
+// @Param id path int true "Panel ID"
+// @Param panel body models.Panel true "Panel fields"
+// @Success 200 {object} models.Panel
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/hardware/panels/{id} [put]
+func (h *HardwareHandler) UpdatePanel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid panel ID")
+		return
+	}
+	panel, err := h.hardwareRepo.GetPanelByID(r.Context(), id)
+	if err != nil {
+		respondHardwareError(w, err, "Failed to get panel")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(panel); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	panel.ID = id
+	if err := h.hardwareRepo.UpdatePanel(r.Context(), panel); err != nil {
+		respondHardwareError(w, err, "Failed to update panel")
+		return
+	}
+	respondJSON(w, http.StatusOK, panel)
+}
+
+// UpdateInverter godoc
+// @Summary Update an inverter
+// @Description Updates the fields present in the body; omitted fields keep their values. Set active to true to restore a removed inverter.
+// @Tags Hardware
+// @Accept json
+// @Produce json
+// @Param id path int true "Inverter ID"
+// @Param inverter body models.Inverter true "Inverter fields"
+// @Success 200 {object} models.Inverter
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/hardware/inverters/{id} [put]
+func (h *HardwareHandler) UpdateInverter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid inverter ID")
+		return
+	}
+	inverter, err := h.hardwareRepo.GetInverterByID(r.Context(), id)
+	if err != nil {
+		respondHardwareError(w, err, "Failed to get inverter")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(inverter); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	inverter.ID = id
+	if err := h.hardwareRepo.UpdateInverter(r.Context(), inverter); err != nil {
+		respondHardwareError(w, err, "Failed to update inverter")
+		return
+	}
+	respondJSON(w, http.StatusOK, inverter)
+}
+
+// UpdateStorage godoc
+// @Summary Update a storage unit
+// @Description Updates the fields present in the body; omitted fields keep their values. Set active to true to restore a removed storage unit.
+// @Tags Hardware
+// @Accept json
+// @Produce json
+// @Param id path int true "Storage ID"
+// @Param storage body models.Storage true "Storage fields"
+// @Success 200 {object} models.Storage
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/hardware/storages/{id} [put]
+func (h *HardwareHandler) UpdateStorage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid storage ID")
+		return
+	}
+	storage, err := h.hardwareRepo.GetStorageByID(r.Context(), id)
+	if err != nil {
+		respondHardwareError(w, err, "Failed to get storage")
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(storage); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	storage.ID = id
+	if err := h.hardwareRepo.UpdateStorage(r.Context(), storage); err != nil {
+		respondHardwareError(w, err, "Failed to update storage")
+		return
+	}
+	respondJSON(w, http.StatusOK, storage)
+}
+
+// DeletePanel godoc
+// @Summary Remove a panel from the catalog
+// @Description Marks the panel inactive so it no longer lists. Leads and quotes that use it keep resolving it.
+// @Tags Hardware
+// @Param id path int true "Panel ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/hardware/panels/{id} [delete]
+func (h *HardwareHandler) DeletePanel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid panel ID")
+		return
+	}
+	if err := h.hardwareRepo.DeactivatePanel(r.Context(), id); err != nil {
+		respondHardwareError(w, err, "Failed to delete panel")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteInverter godoc
+// @Summary Remove an inverter from the catalog
+// @Description Marks the inverter inactive so it no longer lists. Leads and quotes that use it keep resolving it.
+// @Tags Hardware
+// @Param id path int true "Inverter ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/hardware/inverters/{id} [delete]
+func (h *HardwareHandler) DeleteInverter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid inverter ID")
+		return
+	}
+	if err := h.hardwareRepo.DeactivateInverter(r.Context(), id); err != nil {
+		respondHardwareError(w, err, "Failed to delete inverter")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteStorage godoc
+// @Summary Remove a storage unit from the catalog
+// @Description Marks the storage unit inactive so it no longer lists or is recommended. Leads and quotes that use it keep resolving it.
+// @Tags Hardware
+// @Param id path int true "Storage ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/hardware/storages/{id} [delete]
+func (h *HardwareHandler) DeleteStorage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid storage ID")
+		return
+	}
+	if err := h.hardwareRepo.DeactivateStorage(r.Context(), id); err != nil {
+		respondHardwareError(w, err, "Failed to delete storage")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// hardwareFilterParams reads a catalog listing's query parameters. rating
+// names the range parameters: min_<rating> and max_<rating>.
+func hardwareFilterParams(r *http.Request, rating string) (repo.HardwareFilter, error) {
+	query := r.URL.Query()
+	filter := repo.HardwareFilter{
+		Query:        query.Get("q"),
+		Manufacturer: query.Get("manufacturer"),
+		Model:        query.Get("model"),
+		Sort:         query.Get("sort"),
+		Cursor:       query.Get("cursor"),
+	}
+	var err error
+	if filter.Min, err = optionalFloatParam(query.Get("min_" + rating)); err != nil {
+		return filter, fmt.Errorf("Invalid min_%s", rating)
+	}
+	if filter.Max, err = optionalFloatParam(query.Get("max_" + rating)); err != nil {
+		return filter, fmt.Errorf("Invalid max_%s", rating)
+	}
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 1 {
+			return filter, errors.New("Invalid limit")
+		}
+	}
+	return filter, nil
+}
+
+// setNextPage points the client at the page after this one.
+func setNextPage(w http.ResponseWriter, r *http.Request, cursor string) {
+	if cursor == "" {
+		return
+	}
+	query := r.URL.Query()
+	query.Set("cursor", cursor)
+	next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	w.Header().Set("X-Next-Cursor", cursor)
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+}
+
+func respondHardwareError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, models.ErrPanelNotFound):
+		respondError(w, http.StatusNotFound, "Panel not found")
+	case errors.Is(err, models.ErrInverterNotFound):
+		respondError(w, http.StatusNotFound, "Inverter not found")
+	case errors.Is(err, models.ErrStorageNotFound):
+		respondError(w, http.StatusNotFound, "Storage not found")
+	case errors.Is(err, models.ErrInvalidHardwareQuery),
+		errors.Is(err, models.ErrInvalidHardwareName),
+		errors.Is(err, models.ErrInvalidHardwareWarranty),
+		errors.Is(err, models.ErrInvalidPanelRating),
+		errors.Is(err, models.ErrInvalidPanelElectrical),
+		errors.Is(err, models.ErrInvalidPanelCoefficient),
+		errors.Is(err, models.ErrInvalidPanelFraction),
+		errors.Is(err, models.ErrInvalidInverterRating),
+		errors.Is(err, models.ErrInvalidInverterDCInput),
+		errors.Is(err, models.ErrInvalidInverterAC),
+		errors.Is(err, models.ErrInvalidInverterEfficiency),
+		errors.Is(err, models.ErrInvalidInverterType),
+		errors.Is(err, models.ErrInvalidStorageCapacity),
+		errors.Is(err, models.ErrInvalidStoragePower),
+		errors.Is(err, models.ErrInvalidStorageChemistry):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("%s: %v", message, err)
+		respondError(w, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+	"github.com/go-chi/chi/v5"
+)
+
+type IncentiveHandler struct {
+	incentiveRuleRepo *repo.IncentiveRuleRepo
+}
+
+func NewIncentiveHandler(incentiveRuleRepo *repo.IncentiveRuleRepo) *IncentiveHandler {
+	return &IncentiveHandler{incentiveRuleRepo: incentiveRuleRepo}
+}
+
+// ListIncentives godoc
+// @Summary List incentives
+// @Description Lists every incentive, including expired and future ones
+// @Tags incentives
+// @Produce json
+// @Success 200 {array} models.IncentiveRule
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/incentives [get]
+func (h *IncentiveHandler) ListIncentives(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.incentiveRuleRepo.List(r.Context())
+	if err != nil {
+		log.Printf("Failed to list incentives: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to list incentives")
+		return
+	}
+	respondJSON(w, http.StatusOK, rules)
+}
+
+// GetIncentive godoc
+// @Summary Get a incentive
+// @Tags incentives
+// @Produce json
+// @Param id path int true "Incentive ID"
+// @Success 200 {object} models.IncentiveRule
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/incentives/{id} [get]
+func (h *IncentiveHandler) GetIncentive(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid incentive ID")
+		return
+	}
+	rule, err := h.incentiveRuleRepo.GetByID(r.Context(), id)
+	if err != nil {
+		h.respondRepoError(w, err, "Failed to get incentive")
+		return
+	}
+	respondJSON(w, http.StatusOK, rule)
+}
+
+// CreateIncentive godoc
+// @Summary Create a incentive
+// @Description Creates a rebate or credit program that quotes in its state and utility pick up while it is in effect. Leave state empty to apply to every state and utility_id empty to apply to every utility.
+// @Tags incentives
+// @Accept json
+// @Produce json
+// @Param incentive body models.IncentiveRule true "Incentive"
+// @Success 201 {object} models.IncentiveRule
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/incentives [post]
+func (h *IncentiveHandler) CreateIncentive(w http.ResponseWriter, r *http.Request) {
+	var rule models.IncentiveRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	rule.ID = 0
+	if err := h.incentiveRuleRepo.Create(r.Context(), &rule); err != nil {
+		h.respondRepoError(w, err, "Failed to create incentive")
+		return
+	}
+	respondJSON(w, http.StatusCreated, rule)
+}
+
+// UpdateIncentive godoc
+// @Summary Update a incentive
+// @Description Replaces every field of an incentive
+// @Tags incentives
+// @Accept json
+// @Produce json
+// @Param id path int true "Incentive ID"
+// @Param incentive body models.IncentiveRule true "Incentive"
+// @Success 200 {object} models.IncentiveRule
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/incentives/{id} [put]
+func (h *IncentiveHandler) UpdateIncentive(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid incentive ID")
+		return
+	}
+	existing, err := h.incentiveRuleRepo.GetByID(r.Context(), id)
+	if err != nil {
+		h.respondRepoError(w, err, "Failed to get incentive")
+		return
+	}
+	var rule models.IncentiveRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	rule.ID = id
+	rule.CreatedAt = existing.CreatedAt
+	if err := h.incentiveRuleRepo.Update(r.Context(), &rule); err != nil {
+		h.respondRepoError(w, err, "Failed to update incentive")
+		return
+	}
+	respondJSON(w, http.StatusOK, rule)
+}
+
+// DeleteIncentive godoc
+// @Summary Delete a incentive
+// @Tags incentives
+// @Param id path int true "Incentive ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/incentives/{id} [delete]
+func (h *IncentiveHandler) DeleteIncentive(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid incentive ID")
+		return
+	}
+	if err := h.incentiveRuleRepo.Delete(r.Context(), id); err != nil {
+		h.respondRepoError(w, err, "Failed to delete incentive")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *IncentiveHandler) respondRepoError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, models.ErrIncentiveNotFound):
+		respondError(w, http.StatusNotFound, "Incentive not found")
+	case errors.Is(err, models.ErrInvalidIncentiveName),
+		errors.Is(err, models.ErrInvalidIncentiveState),
+		errors.Is(err, models.ErrInvalidIncentiveType),
+		errors.Is(err, models.ErrInvalidIncentiveAmount),
+		errors.Is(err, models.ErrInvalidIncentiveCap),
+		errors.Is(err, models.ErrInvalidIncentiveDates):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("%s: %v", message, err)
+		respondError(w, http.StatusInternalServerError, message)
+	}
+}
@@ -2,9 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
+
 	"github.com/Bilal-Cplusoft/sunready/internal/models"
 	"github.com/Bilal-Cplusoft/sunready/internal/repo"
 	"github.com/Bilal-Cplusoft/sunready/internal/service"
@@ -36,7 +40,7 @@ func NewLeadHandler(leadRepo *repo.LeadRepo, leadService *service.LeadService, u
 
 // CreateLead godoc
 // @Summary Create a new lead
-// @Description Creates a new lead and optionally initiates 3D model generation
+// @Description Creates a new lead in the initialized state and queues 3D model generation and tariff lookup. Its panel, inverter and optional storage are sent to LightFusion by their mapped LightFusion IDs; the lead is rejected when any of them is unknown, no longer offered or unmapped, or when the panel and inverter do not work together.
 // @Tags leads
 // @Accept json
 // @Produce json
@@ -62,6 +66,9 @@ func (h *LeadHandler) CreateLead(w http.ResponseWriter, r *http.Request) {
 	}
 	response, err := h.leadService.CreateLead(r.Context(), req, userID, req.ProjectID)
 	if err != nil {
+		if respondLeadHardwareError(w, err) {
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -80,9 +87,60 @@ func (h *LeadHandler) CreateLead(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path int true "Lead ID"
 // @Success 200 {object} LeadResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /api/leads/{id} [get]
 func (h *LeadHandler) GetLead(w http.ResponseWriter, r *http.Request) {
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, lead)
+}
+
+// GetLeadHistory godoc
+// @Summary Get a lead's state history
+// @Description Lists every state transition of a lead, oldest first, with who made it and why
+// @Tags leads
+// @Produce json
+// @Param id path int true "Lead ID"
+// @Success 200 {array} models.LeadStateHistory
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/leads/{id}/history [get]
+func (h *LeadHandler) GetLeadHistory(w http.ResponseWriter, r *http.Request) {
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
+		return
+	}
+	history, err := h.leadService.GetStateHistory(r.Context(), lead.ID)
+	if err != nil {
+		log.Printf("Failed to get lead history: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get lead history")
+		return
+	}
+	respondJSON(w, http.StatusOK, history)
+}
+
+// sseHeartbeatInterval keeps idle event streams from being closed by proxies.
+const sseHeartbeatInterval = 15 * time.Second
+
+// StreamLeadEvents godoc
+// @Summary Stream lead events
+// @Description Server-Sent Events stream of state changes, build progress, mesh file availability and quote updates for a lead. The first event is a "state" snapshot of the lead. Only the lead's owner or an admin may subscribe.
+// @Tags leads
+// @Produce text/event-stream
+// @Param id path int true "Lead ID"
+// @Success 200 {object} service.LeadEvent
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/leads/{id}/events [get]
+func (h *LeadHandler) StreamLeadEvents(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -99,7 +157,103 @@ func (h *LeadHandler) GetLead(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "Failed to get lead")
 		return
 	}
-	respondJSON(w, http.StatusOK, lead)
+	if !canAccessLead(r, lead) {
+		respondError(w, http.StatusForbidden, "Not allowed to view this lead")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	// Subscribe before sending the snapshot so no change can slip between them.
+	events, unsubscribe := h.leadService.Subscribe(lead.ID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	snapshot := service.LeadEvent{
+		Type:   service.LeadEventState,
+		LeadID: lead.ID,
+		Data: map[string]any{
+			"state":          lead.State,
+			"name":           lead.State.String(),
+			"build_progress": lead.BuildProgress,
+		},
+		Time: time.Now(),
+	}
+	if err := writeSSE(w, snapshot); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case event := <-events:
+			if err := writeSSE(w, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeSSE(w http.ResponseWriter, event service.LeadEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode lead event: %v", err)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+// canAccessLead reports whether the authenticated user owns the lead or is an
+// admin.
+func canAccessLead(r *http.Request, lead *models.Lead) bool {
+	if userType, ok := middleware.GetUserType(r.Context()); ok && models.UserType(userType) == models.UserTypeAdmin {
+		return true
+	}
+	userID, ok := middleware.GetUserID(r.Context())
+	return ok && lead.UserID != nil && *lead.UserID == userID
+}
+
+// authorizedLead loads the lead named by the id URL parameter and checks the
+// caller may see it. It writes the error response and returns false if not.
+func authorizedLead(w http.ResponseWriter, r *http.Request, leadRepo *repo.LeadRepo) (*models.Lead, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid lead ID")
+		return nil, false
+	}
+	lead, err := leadRepo.GetByID(r.Context(), id)
+	if err != nil {
+		if err == models.ErrLeadNotFound {
+			respondError(w, http.StatusNotFound, "Lead not found")
+			return nil, false
+		}
+		log.Printf("Failed to get lead: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get lead")
+		return nil, false
+	}
+	if !canAccessLead(r, lead) {
+		respondError(w, http.StatusForbidden, "Not allowed to view this lead")
+		return nil, false
+	}
+	return lead, true
 }
 
 // GetMeshFiles godoc
@@ -111,27 +265,27 @@ func (h *LeadHandler) GetLead(w http.ResponseWriter, r *http.Request) {
 // @Param        id   path      int  true  "Lead ID"
 // @Success      200  {object}  client.ProfilesFiles3DResponse
 // @Failure      400  {object}  ErrorResponse  "Invalid lead ID"
+// @Failure      403  {object}  ErrorResponse  "Not allowed to view this lead"
 // @Failure      404  {object}  ErrorResponse  "Lead not found"
+// @Failure      409  {object}  ErrorResponse  "3D project not created yet"
 // @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Failure      502  {object}  ErrorResponse  "Mesh files could not be downloaded"
 // @Router       /leads/{id}/mesh-files [get]
 func (h *LeadHandler) GetMeshFiles(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid lead ID")
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
 		return
 	}
-	lead, err := h.leadRepo.GetByID(r.Context(), id)
+	if lead.ExternalID == nil {
+		respondError(w, http.StatusConflict, "3D project has not been created yet")
+		return
+	}
+	files, err := h.leadService.GetMeshFiles(r.Context(), lead)
 	if err != nil {
-		if err == models.ErrLeadNotFound {
-			respondError(w, http.StatusNotFound, "Lead not found")
-			return
-		}
-		log.Printf("Failed to get lead: %v", err)
-		respondError(w, http.StatusInternalServerError, "Failed to get lead")
+		log.Printf("Failed to get mesh files: %v", err)
+		respondError(w, http.StatusBadGateway, err.Error())
 		return
 	}
-	files,err := h.leadService.GetMeshFiles(r.Context(),*lead.ExternalID)
 	respondJSON(w, http.StatusOK, files)
 }
 
@@ -190,33 +344,22 @@ func (h *LeadHandler) ListLeads(w http.ResponseWriter, r *http.Request) {
 
 // UpdateLead godoc
 // @Summary Update a lead
-// @Description Updates an existing lead
+// @Description Updates an existing lead. Changes to panel_id, inverter_id, storage_id or storage_quantity are checked as on creation: the hardware must be offered and known to LightFusion. Those and changes to inverter_quantity or panel_count are rejected when the panel and inverter would not work together. A null storage_id removes the storage.
 // @Tags leads
 // @Accept json
 // @Produce json
 // @Param id path int true "Lead ID"
-// @Param request body map[string]interface{} true "Lead updates"
+// @Param request body map[string]interface{} true "Lead updates; a state change may carry a reason"
 // @Success 200 {object} LeadResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/leads/{id} [put]
 func (h *LeadHandler) UpdateLead(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		respondError(w, http.StatusBadRequest, "Invalid lead ID")
-		return
-	}
-
-	lead, err := h.leadRepo.GetByID(r.Context(), id)
-	if err != nil {
-		if err == models.ErrLeadNotFound {
-			respondError(w, http.StatusNotFound, "Lead not found")
-			return
-		}
-		log.Printf("Failed to get lead: %v", err)
-		respondError(w, http.StatusInternalServerError, "Failed to get lead")
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
 		return
 	}
 
@@ -225,22 +368,66 @@ func (h *LeadHandler) UpdateLead(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
-	if kwhUsage, ok := updates["kwh_usage"].(float64); ok {
-		lead.KwhUsage = kwhUsage
+	// Only the columns named in the request are written, so state, build
+	// progress and sync fields the job worker or sync service changed in the
+	// meantime are left alone.
+	var update service.LeadUpdate
+	number := func(key string) *float64 {
+		if v, ok := updates[key].(float64); ok {
+			return &v
+		}
+		return nil
 	}
-	if systemSize, ok := updates["system_size"].(float64); ok {
-		lead.SystemSize = systemSize
+	integer := func(key string) *int {
+		if v, ok := updates[key].(float64); ok {
+			n := int(v)
+			return &n
+		}
+		return nil
 	}
-	if panelCount, ok := updates["panel_count"].(float64); ok {
-		lead.PanelCount = int(panelCount)
+	update.KwhUsage = number("kwh_usage")
+	update.SystemSize = number("system_size")
+	update.AnnualProduction = number("annual_production")
+	update.PanelCount = integer("panel_count")
+	update.PanelID = integer("panel_id")
+	update.InverterID = integer("inverter_id")
+	update.InverterQuantity = integer("inverter_quantity")
+	update.StorageQuantity = integer("storage_quantity")
+	if value, ok := updates["storage_id"]; ok {
+		switch value.(type) {
+		case nil:
+			update.ClearStorage = true
+		case float64:
+			update.StorageID = integer("storage_id")
+		default:
+			respondError(w, http.StatusBadRequest, "Invalid storage_id")
+			return
+		}
 	}
-	if annualProduction, ok := updates["annual_production"].(float64); ok {
-		lead.AnnualProduction = annualProduction
+	// State changes go through the state machine, which records them in
+	// the lead's history; the state column is never written here.
+	if state, ok := updates["state"].(float64); ok {
+		to := models.LeadState(state)
+		update.State = &to
 	}
+	update.Reason, _ = updates["reason"].(string)
 
-	if err := h.leadRepo.Update(r.Context(), lead); err != nil {
-		log.Printf("Failed to update lead: %v", err)
-		respondError(w, http.StatusInternalServerError, "Failed to update lead")
+	userID, _ := middleware.GetUserID(r.Context())
+	if err := h.leadService.UpdateLead(r.Context(), lead.ID, update, userID); err != nil {
+		if respondLeadHardwareError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, models.ErrInvalidLeadState), errors.Is(err, models.ErrInvalidLeadStateTransition):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, models.ErrLeadStateConflict):
+			respondError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, models.ErrLeadNotFound):
+			respondError(w, http.StatusNotFound, "Lead not found")
+		default:
+			log.Printf("Failed to update lead: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to update lead")
+		}
 		return
 	}
 
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+	"github.com/Bilal-Cplusoft/sunready/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type MilestoneHandler struct {
+	milestoneService *service.MilestoneService
+	leadRepo         *repo.LeadRepo
+}
+
+func NewMilestoneHandler(milestoneService *service.MilestoneService, leadRepo *repo.LeadRepo) *MilestoneHandler {
+	return &MilestoneHandler{milestoneService: milestoneService, leadRepo: leadRepo}
+}
+
+// GetMilestones godoc
+// @Summary Get installation milestones for a lead
+// @Description Returns the state of every milestone from welcome call through PTO, with prerequisites and the lead's current milestone
+// @Tags milestones
+// @Produce json
+// @Param id path int true "Lead ID"
+// @Success 200 {object} service.LeadMilestones
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/leads/{id}/milestones [get]
+func (h *MilestoneHandler) GetMilestones(w http.ResponseWriter, r *http.Request) {
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, h.milestoneService.GetMilestones(lead))
+}
+
+// UpdateMilestone godoc
+// @Summary Update a lead milestone
+// @Description Sets the state of one milestone. Milestones cannot start before their prerequisites are completed (e.g. no installation before permitting approval).
+// @Tags milestones
+// @Accept json
+// @Produce json
+// @Param id path int true "Lead ID"
+// @Param milestone path string true "Milestone" Enums(welcome_call, financing, utility_bill, site_photos, design_approved, permitting_approved, install_crew, installation, final_inspection, pto)
+// @Param request body service.UpdateMilestoneRequest true "Milestone update"
+// @Success 200 {object} service.LeadMilestones
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/leads/{id}/milestones/{milestone} [put]
+func (h *MilestoneHandler) UpdateMilestone(w http.ResponseWriter, r *http.Request) {
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
+		return
+	}
+	var req service.UpdateMilestoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	milestone := models.Milestone(chi.URLParam(r, "milestone"))
+	milestones, err := h.milestoneService.UpdateMilestone(r.Context(), lead.ID, milestone, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrLeadNotFound):
+			respondError(w, http.StatusNotFound, "Lead not found")
+		case errors.Is(err, models.ErrInvalidMilestone), errors.Is(err, models.ErrInvalidMilestoneState), errors.Is(err, models.ErrInvalidMilestoneDate):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, models.ErrMilestoneBlocked), errors.Is(err, models.ErrMilestoneHasDependents):
+			respondError(w, http.StatusConflict, err.Error())
+		default:
+			log.Printf("Failed to update milestone: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to update milestone")
+		}
+		return
+	}
+	respondJSON(w, http.StatusOK, milestones)
+}
+
+// Pipeline godoc
+// @Summary Installation pipeline
+// @Description Groups the leads by their current milestone, in milestone order. Each stage carries its full count and a page of its most recently updated leads; pass milestone to page through a single stage.
+// @Tags milestones
+// @Produce json
+// @Param milestone query string false "Only list this stage" Enums(welcome_call, financing, utility_bill, site_photos, design_approved, permitting_approved, install_crew, installation, final_inspection, pto, complete)
+// @Param limit query int false "Leads listed per stage" default(20)
+// @Param offset query int false "Leads to skip in each stage" default(0)
+// @Success 200 {array} service.PipelineStage
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/leads/pipeline [get]
+func (h *MilestoneHandler) Pipeline(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	milestone := models.Milestone(query.Get("milestone"))
+	if milestone != "" && milestone != models.MilestoneComplete && !milestone.IsValid() {
+		respondError(w, http.StatusBadRequest, models.ErrInvalidMilestone.Error())
+		return
+	}
+	limit, offset := 20, 0
+	if v := query.Get("limit"); v != "" {
+		if l, err := strconv.Atoi(v); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if o, err := strconv.Atoi(v); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+	stages, err := h.milestoneService.Pipeline(r.Context(), milestone, limit, offset)
+	if err != nil {
+		log.Printf("Failed to build pipeline: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to build pipeline")
+		return
+	}
+	respondJSON(w, http.StatusOK, stages)
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+	"github.com/go-chi/chi/v5"
+)
+
+type PricingProfileHandler struct {
+	pricingProfileRepo *repo.PricingProfileRepo
+}
+
+func NewPricingProfileHandler(pricingProfileRepo *repo.PricingProfileRepo) *PricingProfileHandler {
+	return &PricingProfileHandler{pricingProfileRepo: pricingProfileRepo}
+}
+
+// ListPricingProfiles godoc
+// @Summary List pricing profiles
+// @Description Lists every pricing profile, including expired and future ones
+// @Tags pricing
+// @Produce json
+// @Success 200 {array} models.PricingProfile
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/pricing-profiles [get]
+func (h *PricingProfileHandler) ListPricingProfiles(w http.ResponseWriter, r *http.Request) {
+	profiles, err := h.pricingProfileRepo.List(r.Context())
+	if err != nil {
+		log.Printf("Failed to list pricing profiles: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to list pricing profiles")
+		return
+	}
+	respondJSON(w, http.StatusOK, profiles)
+}
+
+// GetPricingProfile godoc
+// @Summary Get a pricing profile
+// @Tags pricing
+// @Produce json
+// @Param id path int true "Pricing profile ID"
+// @Success 200 {object} models.PricingProfile
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/pricing-profiles/{id} [get]
+func (h *PricingProfileHandler) GetPricingProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid pricing profile ID")
+		return
+	}
+	profile, err := h.pricingProfileRepo.GetByID(r.Context(), id)
+	if err != nil {
+		h.respondRepoError(w, err, "Failed to get pricing profile")
+		return
+	}
+	respondJSON(w, http.StatusOK, profile)
+}
+
+// CreatePricingProfile godoc
+// @Summary Create a pricing profile
+// @Description Creates a named set of quote assumptions. Leave company_id empty to apply to every company, state empty to apply to every state, and any value null to fall through to a less specific profile or the default.
+// @Tags pricing
+// @Accept json
+// @Produce json
+// @Param profile body models.PricingProfile true "Pricing profile"
+// @Success 201 {object} models.PricingProfile
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/pricing-profiles [post]
+func (h *PricingProfileHandler) CreatePricingProfile(w http.ResponseWriter, r *http.Request) {
+	var profile models.PricingProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	profile.ID = 0
+	if err := h.pricingProfileRepo.Create(r.Context(), &profile); err != nil {
+		h.respondRepoError(w, err, "Failed to create pricing profile")
+		return
+	}
+	respondJSON(w, http.StatusCreated, profile)
+}
+
+// UpdatePricingProfile godoc
+// @Summary Update a pricing profile
+// @Description Replaces every field of a pricing profile
+// @Tags pricing
+// @Accept json
+// @Produce json
+// @Param id path int true "Pricing profile ID"
+// @Param profile body models.PricingProfile true "Pricing profile"
+// @Success 200 {object} models.PricingProfile
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/pricing-profiles/{id} [put]
+func (h *PricingProfileHandler) UpdatePricingProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid pricing profile ID")
+		return
+	}
+	existing, err := h.pricingProfileRepo.GetByID(r.Context(), id)
+	if err != nil {
+		h.respondRepoError(w, err, "Failed to get pricing profile")
+		return
+	}
+	var profile models.PricingProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	profile.ID = id
+	profile.CreatedAt = existing.CreatedAt
+	if err := h.pricingProfileRepo.Update(r.Context(), &profile); err != nil {
+		h.respondRepoError(w, err, "Failed to update pricing profile")
+		return
+	}
+	respondJSON(w, http.StatusOK, profile)
+}
+
+// DeletePricingProfile godoc
+// @Summary Delete a pricing profile
+// @Tags pricing
+// @Param id path int true "Pricing profile ID"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/pricing-profiles/{id} [delete]
+func (h *PricingProfileHandler) DeletePricingProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid pricing profile ID")
+		return
+	}
+	if err := h.pricingProfileRepo.Delete(r.Context(), id); err != nil {
+		h.respondRepoError(w, err, "Failed to delete pricing profile")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *PricingProfileHandler) respondRepoError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, models.ErrPricingProfileNotFound):
+		respondError(w, http.StatusNotFound, "Pricing profile not found")
+	case errors.Is(err, models.ErrInvalidPricingProfileName),
+		errors.Is(err, models.ErrInvalidPricingProfileState),
+		errors.Is(err, models.ErrInvalidPricingProfileDates),
+		errors.Is(err, models.ErrInvalidPricingProfileValue):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("%s: %v", message, err)
+		respondError(w, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+	"github.com/Bilal-Cplusoft/sunready/internal/service"
+)
+
+type ProductionHandler struct {
+	productionService *service.ProductionService
+	leadRepo          *repo.LeadRepo
+}
+
+func NewProductionHandler(productionService *service.ProductionService, leadRepo *repo.LeadRepo) *ProductionHandler {
+	return &ProductionHandler{productionService: productionService, leadRepo: leadRepo}
+}
+
+// GetLeadProduction godoc
+// @Summary      Estimate a lead's solar production
+// @Description  Simulates a typical year of AC output for the lead's system at its coordinates from the climate grid, derated for the panel's temperature coefficient and clipped at the inverter capacity. Runs entirely offline. Needs the climate grid loaded from CLIMATE_GRID_FILE; without it, and for locations outside it, the answer is a 400.
+// @Tags         production
+// @Produce      json
+// @Param        id       path      int      true   "Lead ID"
+// @Param        tilt     query     number   false  "Array tilt in degrees (default 20)"
+// @Param        azimuth  query     number   false  "Array azimuth in degrees clockwise from north (default 180)"
+// @Param        hourly   query     bool     false  "Include the 8760 hourly values (default true)"
+// @Success      200      {object}  service.ProductionEstimate
+// @Failure      400      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse
+// @Failure      404      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Router       /api/leads/{id}/production [get]
+func (h *ProductionHandler) GetLeadProduction(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	tilt, err := optionalFloatParam(query.Get("tilt"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid tilt")
+		return
+	}
+	azimuth, err := optionalFloatParam(query.Get("azimuth"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid azimuth")
+		return
+	}
+	hourly := true
+	if v := query.Get("hourly"); v != "" {
+		if hourly, err = strconv.ParseBool(v); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid hourly flag")
+			return
+		}
+	}
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
+		return
+	}
+	estimate, err := h.productionService.EstimateForLead(r.Context(), lead, tilt, azimuth)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidProductionInput) || errors.Is(err, models.ErrInvalidLeadLatitude) ||
+			errors.Is(err, models.ErrInvalidLeadLongitude) || errors.Is(err, models.ErrInvalidQuoteSystemSize) ||
+			errors.Is(err, models.ErrNoClimateData) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Failed to estimate production: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to estimate production")
+		return
+	}
+	if !hourly {
+		estimate.HourlyKWh = nil
+	}
+	respondJSON(w, http.StatusOK, estimate)
+}
+
+func optionalFloatParam(v string) (*float64, error) {
+	if v == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/middleware"
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+	"github.com/Bilal-Cplusoft/sunready/internal/service"
+)
+
+type ProposalHandler struct {
+	proposalService *service.ProposalService
+	leadRepo        *repo.LeadRepo
+}
+
+func NewProposalHandler(proposalService *service.ProposalService, leadRepo *repo.LeadRepo) *ProposalHandler {
+	return &ProposalHandler{proposalService: proposalService, leadRepo: leadRepo}
+}
+
+// GetProposalPDF godoc
+// @Summary      Download a lead's proposal
+// @Description  Returns the customer-facing proposal PDF for a quote version of the lead, or for its latest quote. It shows the customer, the system and its catalog hardware, the roof render, costs, incentives, cash-flow charts and financing, styled with the company's LightFusion logo and colors. The PDF is rendered once per quote version and stored.
+// @Tags         proposal
+// @Produce      application/pdf
+// @Param        id       path      int  true   "Lead ID"
+// @Param        version  query     int  false  "Quote version (default latest)"
+// @Success      200      {file}    file
+// @Failure      400      {object}  ErrorResponse
+// @Failure      401      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse
+// @Failure      404      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Router       /api/leads/{id}/proposal.pdf [get]
+func (h *ProposalHandler) GetProposalPDF(w http.ResponseWriter, r *http.Request) {
+	version := 0
+	if v := r.URL.Query().Get("version"); v != "" {
+		var err error
+		if version, err = strconv.Atoi(v); err != nil || version < 1 {
+			respondError(w, http.StatusBadRequest, "Invalid quote version")
+			return
+		}
+	}
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
+		return
+	}
+	proposal, err := h.proposalService.GetProposal(r.Context(), lead, version, userID)
+	if err != nil {
+		if err == models.ErrQuoteNotFound {
+			respondError(w, http.StatusNotFound, "Quote not found")
+			return
+		}
+		log.Printf("Failed to get proposal for lead %d: %v", lead.ID, err)
+		respondError(w, http.StatusInternalServerError, "Failed to generate proposal")
+		return
+	}
+	writeProposalPDF(w, r, proposal)
+}
+
+func writeProposalPDF(w http.ResponseWriter, r *http.Request, proposal *models.Proposal) {
+	etag := `"` + proposal.SHA256 + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, proposal.FileName))
+	w.Header().Set("Content-Length", strconv.Itoa(len(proposal.Content)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(proposal.Content)
+}
+
+// ListLeadProposals godoc
+// @Summary      List a lead's proposals
+// @Description  Lists the proposals rendered for the lead's quote versions, without their content.
+// @Tags         proposal
+// @Produce      json
+// @Param        id   path      int  true  "Lead ID"
+// @Success      200  {array}   models.Proposal
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /api/leads/{id}/proposals [get]
+func (h *ProposalHandler) ListLeadProposals(w http.ResponseWriter, r *http.Request) {
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
+		return
+	}
+	proposals, err := h.proposalService.ListProposals(r.Context(), lead.ID)
+	if err != nil {
+		log.Printf("Failed to list proposals: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to list proposals")
+		return
+	}
+	respondJSON(w, http.StatusOK, proposals)
+}
@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/middleware"
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+	"github.com/Bilal-Cplusoft/sunready/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type ProposalShareHandler struct {
+	shareService *service.ProposalShareService
+	leadRepo     *repo.LeadRepo
+}
+
+func NewProposalShareHandler(shareService *service.ProposalShareService, leadRepo *repo.LeadRepo) *ProposalShareHandler {
+	return &ProposalShareHandler{shareService: shareService, leadRepo: leadRepo}
+}
+
+// CreateProposalShare godoc
+// @Summary      Share a lead's proposal
+// @Description  Creates a signed, expiring link a homeowner can open without an account at /p/{code} to view the lead's latest quote, hardware and 3D model and to accept it or request changes. Responses are emailed to the user who created the link.
+// @Tags         proposal
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                                  true   "Lead ID"
+// @Param        request  body      service.CreateProposalShareRequest  false  "Link options"
+// @Success      201      {object}  service.ProposalShareLink
+// @Failure      400      {object}  ErrorResponse
+// @Failure      401      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse
+// @Failure      404      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Router       /api/leads/{id}/shares [post]
+func (h *ProposalShareHandler) CreateProposalShare(w http.ResponseWriter, r *http.Request) {
+	var req service.CreateProposalShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
+		return
+	}
+	link, err := h.shareService.CreateShare(r.Context(), lead, userID, req)
+	if err != nil {
+		if err == models.ErrInvalidProposalShare {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Failed to share proposal for lead %d: %v", lead.ID, err)
+		respondError(w, http.StatusInternalServerError, "Failed to share proposal")
+		return
+	}
+	respondJSON(w, http.StatusCreated, link)
+}
+
+// ListProposalShares godoc
+// @Summary      List a lead's proposal links
+// @Description  Lists the lead's share links, newest first, with how often they were opened and the homeowner's response.
+// @Tags         proposal
+// @Produce      json
+// @Param        id   path      int  true  "Lead ID"
+// @Success      200  {array}   service.ProposalShareLink
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /api/leads/{id}/shares [get]
+func (h *ProposalShareHandler) ListProposalShares(w http.ResponseWriter, r *http.Request) {
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
+		return
+	}
+	links, err := h.shareService.ListShares(r.Context(), lead.ID)
+	if err != nil {
+		log.Printf("Failed to list proposal shares: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to list proposal links")
+		return
+	}
+	respondJSON(w, http.StatusOK, links)
+}
+
+// OpenProposalShare godoc
+// @Summary      Open a shared proposal
+// @Description  Public. Returns the read-only proposal behind a share link and counts the open. Browsers (Accept: text/html) or format=html get an HTML page with accept and request-changes forms.
+// @Tags         proposal
+// @Produce      json,html
+// @Param        code    path      string  true   "Share code"
+// @Param        format  query     string  false  "json or html"
+// @Success      200     {object}  service.ProposalShareView
+// @Failure      404     {object}  ErrorResponse
+// @Failure      410     {object}  ErrorResponse
+// @Failure      500     {object}  ErrorResponse
+// @Router       /p/{code} [get]
+func (h *ProposalShareHandler) OpenProposalShare(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	view, err := h.shareService.Open(r.Context(), code)
+	if err != nil {
+		h.shareError(w, err)
+		return
+	}
+	if !wantsHTML(r) {
+		respondJSON(w, http.StatusOK, view)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := proposalShareTemplate.Execute(w, struct {
+		*service.ProposalShareView
+		Code string
+	}{view, code}); err != nil {
+		log.Printf("Failed to render shared proposal: %v", err)
+	}
+}
+
+// GetSharedProposalPDF godoc
+// @Summary      Download a shared proposal
+// @Description  Public. Returns the proposal PDF of the lead's latest quote behind a share link.
+// @Tags         proposal
+// @Produce      application/pdf
+// @Param        code  path      string  true  "Share code"
+// @Success      200   {file}    file
+// @Failure      404   {object}  ErrorResponse
+// @Failure      410   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /p/{code}/proposal.pdf [get]
+func (h *ProposalShareHandler) GetSharedProposalPDF(w http.ResponseWriter, r *http.Request) {
+	proposal, err := h.shareService.ProposalPDF(r.Context(), chi.URLParam(r, "code"))
+	if err != nil {
+		h.shareError(w, err)
+		return
+	}
+	writeProposalPDF(w, r, proposal)
+}
+
+// AcceptProposalShare godoc
+// @Summary      Accept a shared proposal
+// @Description  Public. Accepts the lead's latest quote and notifies the rep who shared it. Takes JSON or a form post; form posts are redirected back to the HTML view.
+// @Tags         proposal
+// @Accept       json
+// @Produce      json
+// @Param        code      path      string                         true   "Share code"
+// @Param        response  body      service.ProposalShareResponse  false  "Homeowner name and message"
+// @Success      200       {object}  models.ProposalShare
+// @Failure      400       {object}  ErrorResponse
+// @Failure      404       {object}  ErrorResponse
+// @Failure      409       {object}  ErrorResponse
+// @Failure      410       {object}  ErrorResponse
+// @Failure      500       {object}  ErrorResponse
+// @Router       /p/{code}/accept [post]
+func (h *ProposalShareHandler) AcceptProposalShare(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, r, models.ProposalShareAccepted)
+}
+
+// RequestProposalChanges godoc
+// @Summary      Request changes to a shared proposal
+// @Description  Public. Sends the homeowner's requested changes to the rep who shared the proposal. A message is required. Takes JSON or a form post; form posts are redirected back to the HTML view.
+// @Tags         proposal
+// @Accept       json
+// @Produce      json
+// @Param        code      path      string                         true  "Share code"
+// @Param        response  body      service.ProposalShareResponse  true  "Homeowner name and message"
+// @Success      200       {object}  models.ProposalShare
+// @Failure      400       {object}  ErrorResponse
+// @Failure      404       {object}  ErrorResponse
+// @Failure      409       {object}  ErrorResponse
+// @Failure      410       {object}  ErrorResponse
+// @Failure      500       {object}  ErrorResponse
+// @Router       /p/{code}/request-changes [post]
+func (h *ProposalShareHandler) RequestProposalChanges(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, r, models.ProposalShareChangesRequested)
+}
+
+func (h *ProposalShareHandler) respond(w http.ResponseWriter, r *http.Request, status models.ProposalShareStatus) {
+	code := chi.URLParam(r, "code")
+	var resp service.ProposalShareResponse
+	form := strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+	if form {
+		resp.Name, resp.Message = r.FormValue("name"), r.FormValue("message")
+	} else if err := json.NewDecoder(r.Body).Decode(&resp); err != nil && err != io.EOF {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	share, err := h.shareService.Respond(r.Context(), code, status, resp)
+	if err != nil {
+		h.shareError(w, err)
+		return
+	}
+	if form {
+		http.Redirect(w, r, "/p/"+code+"?format=html", http.StatusSeeOther)
+		return
+	}
+	respondJSON(w, http.StatusOK, share)
+}
+
+func (h *ProposalShareHandler) shareError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrProposalShareNotFound), errors.Is(err, models.ErrLeadNotFound):
+		respondError(w, http.StatusNotFound, "Proposal link not found")
+	case errors.Is(err, models.ErrProposalShareExpired):
+		respondError(w, http.StatusGone, err.Error())
+	case errors.Is(err, models.ErrProposalShareResponded):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrQuoteNotFound):
+		respondError(w, http.StatusConflict, "There is no quote to accept yet")
+	case errors.Is(err, models.ErrInvalidShareResponse):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("Failed to handle shared proposal: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to load proposal")
+	}
+}
+
+func wantsHTML(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "html"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+var proposalShareTemplate = template.Must(template.New("proposal").Funcs(template.FuncMap{
+	"money": func(v float64) string { return fmt.Sprintf("$%.0f", v) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.CompanyName}} solar proposal</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; color: #111827; max-width: 760px; margin: 0 auto; padding: 24px; }
+header { border-bottom: 4px solid #f59e0b; margin-bottom: 24px; }
+table { width: 100%; border-collapse: collapse; margin-bottom: 24px; }
+td { padding: 6px 0; border-bottom: 1px solid #e5e7eb; }
+td:last-child { text-align: right; font-weight: bold; }
+img { max-width: 100%; }
+textarea, input { width: 100%; box-sizing: border-box; margin-bottom: 8px; padding: 6px; }
+button { padding: 10px 18px; margin-right: 8px; }
+.status { padding: 12px; background: #f3f4f6; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.CompanyName}}</h1>
+<p>Solar proposal{{with .Customer}} for {{.FirstName}} {{.LastName}}{{if .Street}}<br>{{.Street}}, {{.City}}, {{.State}} {{.PostalCode}}{{end}}{{end}}</p>
+</header>
+{{with .MeshFiles}}{{if .JPGURL}}<img src="{{.JPGURL}}" alt="Roof design">{{end}}{{end}}
+{{with .Quote}}
+<h2>Your system</h2>
+<table>
+<tr><td>System size</td><td>{{printf "%.2f" .SystemSizeKW}} kW</td></tr>
+<tr><td>Panels</td><td>{{.PanelCount}}</td></tr>
+<tr><td>Estimated annual production</td><td>{{printf "%.0f" .AnnualProductionKWh}} kWh</td></tr>
+<tr><td>Electricity offset</td><td>{{printf "%.0f" .ElectricalOffset}}%</td></tr>
+</table>
+<h2>Costs and savings</h2>
+<table>
+<tr><td>System cost before incentives</td><td>{{money .SystemCostBeforeIncentives}}</td></tr>
+<tr><td>Net system cost</td><td>{{money .SystemCostAfterIncentives}}</td></tr>
+<tr><td>Estimated monthly payment</td><td>{{money .EstimatedMonthlyPayment}}</td></tr>
+<tr><td>First-year savings</td><td>{{money .FirstYearSavings}}</td></tr>
+<tr><td>25-year savings</td><td>{{money .TwentyFiveYearSavings}}</td></tr>
+</table>
+<p>{{.Summary}}</p>
+{{else}}
+<p>Your proposal is still being prepared.</p>
+{{end}}
+{{if or .Panel .Inverter}}
+<h2>Equipment</h2>
+<table>
+{{with .Panel}}<tr><td>Panel</td><td>{{.Manufacturer}} {{.Model}} ({{printf "%.0f" .Wattage}} W)</td></tr>{{end}}
+{{with .Inverter}}<tr><td>Inverter</td><td>{{.Manufacturer}} {{.Model}}</td></tr>{{end}}
+</table>
+{{end}}
+{{if .ProposalURL}}<p><a href="{{.ProposalURL}}">Download the full proposal (PDF)</a></p>{{end}}
+{{if eq .Status "pending"}}
+<h2>Your decision</h2>
+<form method="post">
+<input name="name" placeholder="Your name">
+<textarea name="message" rows="4" placeholder="Questions or changes you would like"></textarea>
+{{if .Quote}}<button formaction="/p/{{.Code}}/accept">Accept proposal</button>{{end}}
+<button formaction="/p/{{.Code}}/request-changes">Request changes</button>
+</form>
+{{else if eq .Status "accepted"}}
+<p class="status">You accepted this proposal. Your representative will be in touch.</p>
+{{else}}
+<p class="status">You requested changes. Your representative will follow up with a revised proposal.</p>
+{{end}}
+</body>
+</html>
+`))
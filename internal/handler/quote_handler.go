@@ -2,28 +2,39 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
 
+	"github.com/Bilal-Cplusoft/sunready/internal/middleware"
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
 	"github.com/Bilal-Cplusoft/sunready/internal/service"
+	"github.com/go-chi/chi/v5"
 )
 
 type QuoteHandler struct {
 	quoteService *service.QuoteService
+	leadRepo     *repo.LeadRepo
 }
 
-func NewQuoteHandler(quoteService *service.QuoteService) *QuoteHandler {
-	return &QuoteHandler{quoteService: quoteService}
+func NewQuoteHandler(quoteService *service.QuoteService, leadRepo *repo.LeadRepo) *QuoteHandler {
+	return &QuoteHandler{quoteService: quoteService, leadRepo: leadRepo}
 }
 
 // GetQuote godoc
 // @Summary      Calculate solar quote
-// @Description  Takes input parameters for a solar system and returns a detailed quote with costs, savings, and payback period.
+// @Description  Takes input parameters for a solar system and returns a detailed quote with costs, savings, and payback period. Every quote is stored; when lead_id is given it becomes the lead's next quote version.
 // @Tags         quote
 // @Accept       json
 // @Produce      json
 // @Param        quote  body      service.QuoteInput  true  "Quote input payload"
 // @Success      200    {object}  service.QuoteResult
 // @Failure      400    {object}  map[string]string  "Invalid request payload"
+// @Failure      403    {object}  map[string]string  "Not allowed to quote this lead"
+// @Failure      404    {object}  map[string]string  "Lead not found"
 // @Failure      500    {object}  map[string]string  "Failed to calculate quote"
 // @Router       /api/quote [post]
 func (h *QuoteHandler) GetQuote(w http.ResponseWriter, r *http.Request) {
@@ -37,12 +48,240 @@ func (h *QuoteHandler) GetQuote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer r.Body.Close()
-	result, err := h.quoteService.CalculateQuote(input)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if input.LeadID != nil {
+		lead, err := h.leadRepo.GetByID(r.Context(), *input.LeadID)
+		if err != nil {
+			if err == models.ErrLeadNotFound {
+				http.Error(w, "lead not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "failed to get lead: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !canAccessLead(r, lead) {
+			http.Error(w, "not allowed to quote this lead", http.StatusForbidden)
+			return
+		}
+	}
+	result, err := h.quoteService.CalculateQuote(r.Context(), input, userID)
 	if err != nil {
-		http.Error(w, "failed to calculate quote: "+err.Error(), http.StatusInternalServerError)
+		switch {
+		case isQuoteInputError(err):
+			http.Error(w, "invalid request payload: "+err.Error(), http.StatusBadRequest)
+		case errors.Is(err, models.ErrLeadNotFound):
+			http.Error(w, "lead not found", http.StatusNotFound)
+		default:
+			http.Error(w, "failed to calculate quote: "+err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(result)
 }
+
+// QuoteSensitivity godoc
+// @Summary      Quote sensitivity analysis
+// @Description  Recalculates a quote across ranges of utility escalation, loan interest rate, cost per watt and production (a multiplier of the base production) and returns a sweep per variable plus tornado-chart bars of 25-year savings, widest swing first. With no ranges each variable gets a default range around its base value. Nothing is stored.
+// @Tags         quote
+// @Accept       json
+// @Produce      json
+// @Param        request  body      service.SensitivityRequest  true  "Base quote input and ranges"
+// @Success      200      {object}  service.SensitivityResult
+// @Failure      400      {object}  ErrorResponse
+// @Failure      401      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Router       /api/quote/sensitivity [post]
+func (h *QuoteHandler) QuoteSensitivity(w http.ResponseWriter, r *http.Request) {
+	var req service.SensitivityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	result, err := h.quoteService.Sensitivity(r.Context(), req, userID)
+	if err != nil {
+		if isQuoteInputError(err) || errors.Is(err, models.ErrInvalidSensitivityRange) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Failed to run quote sensitivity: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to run quote sensitivity")
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// QuoteLead godoc
+// @Summary      Quote a lead
+// @Description  Builds the quote input from the lead (system size, panel count, consumption, design production or a simulation at its coordinates, utility), its catalog panel and inverter, and its owner's state and average monthly bill, then stores the quote as the lead's next version. Any field in the body overrides the value taken from the lead; the body may be empty. The lead's tariff ID carries no rates, so pass a tariff to use the billing engine.
+// @Tags         quote
+// @Accept       json
+// @Produce      json
+// @Param        id         path      int                 true   "Lead ID"
+// @Param        overrides  body      service.QuoteInput  false  "Values overriding the lead"
+// @Success      201        {object}  service.QuoteResult
+// @Failure      400        {object}  ErrorResponse
+// @Failure      401        {object}  ErrorResponse
+// @Failure      403        {object}  ErrorResponse
+// @Failure      404        {object}  ErrorResponse
+// @Failure      500        {object}  ErrorResponse
+// @Router       /api/leads/{id}/quote [post]
+func (h *QuoteHandler) QuoteLead(w http.ResponseWriter, r *http.Request) {
+	var overrides service.QuoteInput
+	if err := json.NewDecoder(r.Body).Decode(&overrides); err != nil && err != io.EOF {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
+		return
+	}
+	result, err := h.quoteService.QuoteLead(r.Context(), lead, overrides, userID)
+	if err != nil {
+		if isQuoteInputError(err) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Failed to quote lead %d: %v", lead.ID, err)
+		respondError(w, http.StatusInternalServerError, "Failed to calculate quote")
+		return
+	}
+	respondJSON(w, http.StatusCreated, result)
+}
+
+// ListLeadQuotes godoc
+// @Summary      List a lead's quote versions
+// @Description  Lists every stored quote version of a lead, oldest first, with the inputs, resolved assumptions and result of each.
+// @Tags         quote
+// @Produce      json
+// @Param        id   path      int  true  "Lead ID"
+// @Success      200  {array}   service.QuoteVersion
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /api/leads/{id}/quotes [get]
+func (h *QuoteHandler) ListLeadQuotes(w http.ResponseWriter, r *http.Request) {
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
+		return
+	}
+	versions, err := h.quoteService.ListVersions(r.Context(), lead.ID)
+	if err != nil {
+		log.Printf("Failed to list quotes: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to list quotes")
+		return
+	}
+	respondJSON(w, http.StatusOK, versions)
+}
+
+// GetLeadQuote godoc
+// @Summary      Get a quote version
+// @Description  Returns one stored quote version of a lead.
+// @Tags         quote
+// @Produce      json
+// @Param        id       path      int  true  "Lead ID"
+// @Param        version  path      int  true  "Quote version"
+// @Success      200      {object}  service.QuoteVersion
+// @Failure      400      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse
+// @Failure      404      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Router       /api/leads/{id}/quotes/{version} [get]
+func (h *QuoteHandler) GetLeadQuote(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid quote version")
+		return
+	}
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
+		return
+	}
+	quote, err := h.quoteService.GetVersion(r.Context(), lead.ID, version)
+	if err != nil {
+		if err == models.ErrQuoteNotFound {
+			respondError(w, http.StatusNotFound, "Quote not found")
+			return
+		}
+		log.Printf("Failed to get quote: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to get quote")
+		return
+	}
+	respondJSON(w, http.StatusOK, quote)
+}
+
+// DiffLeadQuotes godoc
+// @Summary      Diff two quote versions
+// @Description  Lists the input, assumption and result fields that changed between two quote versions of a lead. Numeric changes include the delta.
+// @Tags         quote
+// @Produce      json
+// @Param        id    path      int  true  "Lead ID"
+// @Param        from  query     int  true  "Version to compare from"
+// @Param        to    query     int  true  "Version to compare to"
+// @Success      200   {object}  service.QuoteDiff
+// @Failure      400   {object}  ErrorResponse
+// @Failure      403   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /api/leads/{id}/quotes/diff [get]
+func (h *QuoteHandler) DiffLeadQuotes(w http.ResponseWriter, r *http.Request) {
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid from version")
+		return
+	}
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid to version")
+		return
+	}
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
+		return
+	}
+	diff, err := h.quoteService.DiffVersions(r.Context(), lead.ID, from, to)
+	if err != nil {
+		if err == models.ErrQuoteNotFound {
+			respondError(w, http.StatusNotFound, "Quote not found")
+			return
+		}
+		log.Printf("Failed to diff quotes: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to diff quotes")
+		return
+	}
+	respondJSON(w, http.StatusOK, diff)
+}
+
+// isQuoteInputError reports whether err rejects the quote input rather than
+// being a server failure.
+func isQuoteInputError(err error) bool {
+	for _, target := range []error{
+		models.ErrInvalidQuoteSystemSize, models.ErrInvalidQuoteMonthlyBill,
+		models.ErrInvalidFinancingScenario, models.ErrInvalidQuoteLifetime,
+		models.ErrPanelNotFound, models.ErrInverterNotFound,
+		models.ErrInvalidTariff, models.ErrInvalidBillingInput,
+		models.ErrInvalidProductionInput, models.ErrInvalidLeadLatitude,
+		models.ErrInvalidLeadLongitude,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+	"github.com/Bilal-Cplusoft/sunready/internal/service"
+)
+
+type StorageHandler struct {
+	storageService *service.StorageService
+	leadRepo       *repo.LeadRepo
+}
+
+func NewStorageHandler(storageService *service.StorageService, leadRepo *repo.LeadRepo) *StorageHandler {
+	return &StorageHandler{storageService: storageService, leadRepo: leadRepo}
+}
+
+// AnalyzeLeadStorage godoc
+// @Summary      Analyse battery storage for a lead
+// @Description  Simulates hourly battery state of charge on top of the lead's simulated solar production and reports the self-consumption gain, TOU arbitrage savings and hours of backup for the critical loads during an outage. Recommends a storage quantity of the selected unit, which defaults to the lead's storage, or from the whole catalog when there is none. quantity defaults to the lead's storage quantity and is at most 20.
+// @Tags         storage
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                             true  "Lead ID"
+// @Param        request  body      service.StorageAnalysisRequest  true  "Storage analysis options"
+// @Success      200      {object}  service.StorageAnalysis
+// @Failure      400      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse
+// @Failure      404      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Router       /api/leads/{id}/storage-analysis [post]
+func (h *StorageHandler) AnalyzeLeadStorage(w http.ResponseWriter, r *http.Request) {
+	var req service.StorageAnalysisRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	lead, ok := authorizedLead(w, r, h.leadRepo)
+	if !ok {
+		return
+	}
+	analysis, err := h.storageService.AnalyzeLead(r.Context(), lead, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrStorageNotFound):
+			respondError(w, http.StatusNotFound, "Storage not found")
+		case errors.Is(err, models.ErrInvalidStorageInput), errors.Is(err, models.ErrInvalidTariff),
+			errors.Is(err, models.ErrInvalidProductionInput), errors.Is(err, models.ErrInvalidLeadLatitude),
+			errors.Is(err, models.ErrInvalidLeadLongitude), errors.Is(err, models.ErrNoClimateData):
+			respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			log.Printf("Failed to analyse storage: %v", err)
+			respondError(w, http.StatusInternalServerError, "Failed to analyse storage")
+		}
+		return
+	}
+	respondJSON(w, http.StatusOK, analysis)
+}
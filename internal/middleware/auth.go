@@ -35,6 +35,7 @@ func AuthMiddleware(authService *service.AuthService) func(http.Handler) http.Ha
 				return
 			}
 			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, UserTypeKey, claims.UserType)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
@@ -72,7 +73,7 @@ func GetUserID(ctx context.Context) (int, bool) {
 	return userID, ok
 }
 
-func GetUserType(ctx context.Context) (string, bool) {
-	userType, ok := ctx.Value(UserTypeKey).(string)
+func GetUserType(ctx context.Context) (int, bool) {
+	userType, ok := ctx.Value(UserTypeKey).(int)
 	return userType, ok
 }
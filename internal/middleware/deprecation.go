@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+)
+
+// Deprecated marks a route kept for old clients: responses carry a
+// Deprecation header and a Link to successor, and each call is logged so
+// the remaining callers can be found before the route is removed.
+func Deprecated(successor string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log.Printf("Deprecated route %s %s called; use %s", r.Method, r.URL.Path, successor)
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -20,11 +20,83 @@ ErrDealNotFound                = errors.New("deal not found")
 ErrInvalidLeadLatitude  = errors.New("latitude must be between -90 and 90")
 ErrInvalidLeadLongitude = errors.New("longitude must be between -180 and 180")
 ErrLeadNotFound         = errors.New("lead not found")
+ErrInvalidLeadState     = errors.New("invalid lead state")
+ErrInvalidLeadStateTransition = errors.New("invalid lead state transition")
+ErrLeadStateConflict    = errors.New("lead state was changed concurrently")
+
+// Milestone errors
+ErrInvalidMilestone      = errors.New("unknown milestone")
+ErrInvalidMilestoneState = errors.New("milestone state must be one of: 0 (pending), 1 (in progress), 2 (completed)")
+ErrInvalidMilestoneDate  = errors.New("milestone date must be formatted as YYYY-MM-DD")
+ErrMilestoneBlocked      = errors.New("milestone prerequisites are not completed")
+ErrMilestoneHasDependents = errors.New("milestone cannot be reopened while later milestones depend on it")
+
+// Quote errors
+ErrInvalidQuoteSystemSize  = errors.New("system size must be greater than 0")
+ErrInvalidQuoteMonthlyBill = errors.New("monthly electric bill must be greater than 0")
+ErrQuoteNotFound           = errors.New("quote not found")
+ErrInvalidFinancingScenario = errors.New("invalid financing scenario")
+ErrInvalidTariff            = errors.New("invalid tariff")
+ErrInvalidBillingInput      = errors.New("invalid billing input")
+ErrInvalidProductionInput   = errors.New("invalid production input")
+ErrNoClimateData            = errors.New("no climate data for this location")
+ErrInvalidStorageInput      = errors.New("invalid storage analysis input")
+ErrInvalidSensitivityRange  = errors.New("invalid sensitivity range")
+ErrInvalidQuoteLifetime     = errors.New("degradation rates must be between 0 and 1, and replacement year and yearly costs must not be negative")
+
+// Pricing profile errors
+ErrInvalidPricingProfileName  = errors.New("pricing profile name must be between 1 and 250 characters")
+ErrInvalidPricingProfileState = errors.New("pricing profile state must be a two-letter state code")
+ErrInvalidPricingProfileDates = errors.New("pricing profile effective_to must be after effective_from")
+ErrInvalidPricingProfileValue = errors.New("pricing profile values must be positive, rates below 1, loan term 1-40 years and offset 1-200%")
+ErrPricingProfileNotFound     = errors.New("pricing profile not found")
+
+// Incentive errors
+ErrInvalidIncentiveName  = errors.New("incentive name must be between 1 and 250 characters")
+ErrInvalidIncentiveState = errors.New("incentive state must be a two-letter state code")
+ErrInvalidIncentiveType  = errors.New("incentive type must be one of: per_watt, per_kwh, flat, percentage")
+ErrInvalidIncentiveAmount = errors.New("incentive amount must be positive, percentages at most 1 and payment years 0-30")
+ErrInvalidIncentiveCap   = errors.New("incentive caps must be positive")
+ErrInvalidIncentiveDates = errors.New("incentive effective_to must be after effective_from")
+ErrIncentiveNotFound     = errors.New("incentive not found")
+
+// Hardware errors
+ErrPanelNotFound    = errors.New("panel not found")
+ErrInverterNotFound = errors.New("inverter not found")
+ErrStorageNotFound  = errors.New("storage not found")
+ErrInvalidHardwareQuery = errors.New("invalid hardware query")
+ErrInvalidCatalogFile   = errors.New("invalid catalog file")
+ErrInvalidCatalogKind   = errors.New("catalog kind must be one of: panels, inverters, storages")
+ErrInvalidHardwareName     = errors.New("manufacturer and model must be between 1 and 250 characters")
+ErrInvalidHardwareWarranty = errors.New("warranties must be between 0 and 50 years")
+ErrInvalidPanelRating      = errors.New("panel wattage must be between 1 and 2000 W, sides between 0 and 5 m and NOCT between 0 and 100 °C")
+ErrInvalidPanelElectrical  = errors.New("panel Voc, Isc, Vmp and Imp must be greater than 0, with Vmp below Voc and Imp below Isc")
+ErrInvalidPanelCoefficient = errors.New("panel temperature coefficients must be between -2 and 2 percent per °C")
+ErrInvalidPanelFraction    = errors.New("panel efficiency and degradation rates must be fractions between 0 and 1")
+ErrInvalidInverterRating   = errors.New("inverter capacity and max DC power must be greater than 0 and unit cost not negative")
+ErrInvalidInverterDCInput  = errors.New("inverter MPPT count must be between 1 and 24, and its voltages and input current greater than 0 with the MPPT window inside the max DC voltage")
+ErrInvalidInverterAC       = errors.New("inverter phase must be 1 or 3 and nominal AC voltage greater than 0")
+ErrInvalidInverterEfficiency = errors.New("inverter efficiency must be a fraction between 0 and 1")
+ErrInvalidInverterType       = errors.New("inverter type must be string or micro")
+ErrInvalidStorageCapacity  = errors.New("storage capacity must be between 0 and 1000 kWh and usable capacity between 0 and the capacity")
+ErrInvalidStoragePower     = errors.New("storage power must be greater than 0 and round-trip efficiency a fraction between 0 and 1")
+ErrInvalidStorageChemistry = errors.New("storage chemistry must be at most 100 characters")
+ErrInvalidDesignInput      = errors.New("invalid design input")
+ErrInvalidDesign           = errors.New("panel and inverter do not work together")
+ErrInvalidLightFusionID    = errors.New("lightfusion_id must be greater than 0")
+ErrHardwareNotMapped       = errors.New("hardware has no LightFusion mapping")
+ErrHardwareInactive        = errors.New("hardware is no longer offered")
+ErrInvalidLeadHardware     = errors.New("invalid lead hardware")
 
 // Proposal errors
 ErrInvalidProposalCode = errors.New("proposal code is required")
 ErrInvalidProposalCost = errors.New("system cost must be greater than or equal to 0")
 ErrProposalNotFound    = errors.New("proposal not found")
+ErrProposalShareNotFound  = errors.New("proposal link not found")
+ErrProposalShareExpired   = errors.New("proposal link has expired")
+ErrProposalShareResponded = errors.New("proposal has already been responded to")
+ErrInvalidProposalShare   = errors.New("proposal links must expire within 1 to 720 hours")
+ErrInvalidShareResponse   = errors.New("a message of up to 2000 characters is required to request changes")
 
 // Customer errors
 ErrInvalidCustomerFirstName = errors.New("customer first name must be between 1 and 100 characters")
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+type IncentiveType string
+
+const (
+	IncentivePerWatt    IncentiveType = "per_watt"
+	IncentivePerKWh     IncentiveType = "per_kwh"
+	IncentiveFlat       IncentiveType = "flat"
+	IncentivePercentage IncentiveType = "percentage"
+)
+
+// IncentiveRule is a rebate or credit program. A rule applies to one state,
+// or to every state when State is empty, and to one utility (LightFusion LSE
+// ID), or to every utility when UtilityID is nil.
+//
+// Amount is $/W DC for per_watt, $/kWh of production for per_kwh (paid for
+// PaymentYears years, default 1), dollars for flat and a fraction of the
+// system cost for percentage. MaxAmount caps the dollars paid and
+// MaxSystemSizeKW the system size the amount is computed on.
+//
+// ReducesITCBasis marks rebates that lower the cost the federal tax credit
+// is calculated on, as utility rebates generally do.
+type IncentiveRule struct {
+	ID              int           `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt       time.Time     `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt       time.Time     `json:"updated_at" gorm:"column:updated_at"`
+	Name            string        `json:"name" gorm:"column:name;not null" example:"SGIP Residential Rebate"`
+	State           string        `json:"state" gorm:"column:state;index" example:"CA"`
+	UtilityID       *int          `json:"utility_id" gorm:"column:utility_id;index" example:"734"`
+	Type            IncentiveType `json:"type" gorm:"column:type;not null" enums:"per_watt,per_kwh,flat,percentage" example:"per_watt"`
+	Amount          float64       `json:"amount" gorm:"column:amount" example:"0.2"`
+	PaymentYears    int           `json:"payment_years,omitempty" gorm:"column:payment_years" example:"5"`
+	MaxAmount       *float64      `json:"max_amount" gorm:"column:max_amount" example:"1500"`
+	MaxSystemSizeKW *float64      `json:"max_system_size_kw" gorm:"column:max_system_size_kw" example:"10"`
+	EffectiveFrom   *time.Time    `json:"effective_from" gorm:"column:effective_from"`
+	EffectiveTo     *time.Time    `json:"effective_to" gorm:"column:effective_to"`
+	ReducesITCBasis bool          `json:"reduces_itc_basis" gorm:"column:reduces_itc_basis;default:false"`
+}
+
+func (IncentiveRule) TableName() string {
+	return "incentive_rules"
+}
+
+func (r *IncentiveRule) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 250 {
+		return ErrInvalidIncentiveName
+	}
+	r.State = strings.ToUpper(strings.TrimSpace(r.State))
+	if r.State != "" && len(r.State) != 2 {
+		return ErrInvalidIncentiveState
+	}
+	switch r.Type {
+	case IncentivePerWatt, IncentivePerKWh, IncentiveFlat:
+		if r.Amount <= 0 {
+			return ErrInvalidIncentiveAmount
+		}
+	case IncentivePercentage:
+		if r.Amount <= 0 || r.Amount > 1 {
+			return ErrInvalidIncentiveAmount
+		}
+	default:
+		return ErrInvalidIncentiveType
+	}
+	if r.PaymentYears < 0 || r.PaymentYears > 30 {
+		return ErrInvalidIncentiveAmount
+	}
+	if (r.MaxAmount != nil && *r.MaxAmount <= 0) || (r.MaxSystemSizeKW != nil && *r.MaxSystemSizeKW <= 0) {
+		return ErrInvalidIncentiveCap
+	}
+	if r.EffectiveFrom != nil && r.EffectiveTo != nil && !r.EffectiveTo.After(*r.EffectiveFrom) {
+		return ErrInvalidIncentiveDates
+	}
+	return nil
+}
+
+// ActiveAt reports whether the program is open at t.
+func (r *IncentiveRule) ActiveAt(t time.Time) bool {
+	if r.EffectiveFrom != nil && t.Before(*r.EffectiveFrom) {
+		return false
+	}
+	if r.EffectiveTo != nil && !t.Before(*r.EffectiveTo) {
+		return false
+	}
+	return true
+}
@@ -9,6 +9,87 @@ type Inverter struct {
 	Manufacturer string    `json:"manufacturer" gorm:"column:manufacturer"`
 	Model        string    `json:"model" gorm:"column:model"`
 	Capacity     float64   `json:"capacity" gorm:"column:capacity"`
+	// Type is micro for microinverters, which take one panel each, and
+	// string for inverters that take strings of panels. It defaults to
+	// string.
+	Type string `json:"type" gorm:"column:type;not null;default:string" example:"string" enums:"string,micro"`
+	// UnitCost is the price of one inverter, used to cost its replacement
+	// once the warranty runs out.
+	UnitCost      *float64 `json:"unit_cost,omitempty" gorm:"column:unit_cost" example:"1800"`
+	WarrantyYears *int     `json:"warranty_years,omitempty" gorm:"column:warranty_years" example:"12"`
+	// DC input: the number of MPPT inputs, the operating voltage window of
+	// each, the highest voltage and, per input, the highest current the
+	// inverter accepts. MaxDCPowerKW is the largest array it takes.
+	MPPTCount       *int     `json:"mppt_count,omitempty" gorm:"column:mppt_count" example:"2"`
+	MPPTVoltageMin  *float64 `json:"mppt_voltage_min,omitempty" gorm:"column:mppt_voltage_min" example:"200"`
+	MPPTVoltageMax  *float64 `json:"mppt_voltage_max,omitempty" gorm:"column:mppt_voltage_max" example:"480"`
+	MaxDCVoltage    *float64 `json:"max_dc_voltage,omitempty" gorm:"column:max_dc_voltage" example:"600"`
+	MaxInputCurrent *float64 `json:"max_input_current,omitempty" gorm:"column:max_input_current" example:"13"`
+	MaxDCPowerKW    *float64 `json:"max_dc_power_kw,omitempty" gorm:"column:max_dc_power_kw" example:"9.6"`
+	// Efficiency is the CEC weighted efficiency as a fraction. Phase is 1
+	// or 3 and NominalACVoltage the grid voltage it connects at.
+	Efficiency       *float64 `json:"efficiency,omitempty" gorm:"column:efficiency" example:"0.97"`
+	Phase            *int     `json:"phase,omitempty" gorm:"column:phase" example:"1"`
+	NominalACVoltage *float64 `json:"nominal_ac_voltage,omitempty" gorm:"column:nominal_ac_voltage" example:"240"`
+	// CECID identifies the item's listing on the CEC equipment list it was
+	// imported from, so it is found again after the catalog renames it.
+	// Items added by hand have none.
+	CECID string `json:"cec_id,omitempty" gorm:"column:cec_id;not null;default:''" example:"Enphase Energy Inc. | IQ8PLUS-72-2-US [240V]"`
+	// LightFusionID is the item's ID in LightFusion's hardware catalog, which
+	// 3D projects are designed with. Items without one cannot be put on a
+	// lead.
+	LightFusionID *int `json:"lightfusion_id,omitempty" gorm:"column:lightfusion_id" example:"5557"`
+	// Active is false once the item is removed from the catalog. Removed
+	// items are kept for the leads and quotes that reference them.
+	Active       bool      `json:"active" gorm:"column:active;not null;default:true"`
 	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at"`
 	UpdatedAt    time.Time `json:"updated_at" gorm:"column:updated_at"`
 }
+
+// Inverter types.
+const (
+	InverterTypeString = "string"
+	InverterTypeMicro  = "micro"
+)
+
+func (i *Inverter) Validate() error {
+	if err := validHardwareName(&i.Manufacturer, &i.Model); err != nil {
+		return err
+	}
+	if i.Type == "" {
+		i.Type = InverterTypeString
+	}
+	if i.Type != InverterTypeString && i.Type != InverterTypeMicro {
+		return ErrInvalidInverterType
+	}
+	if i.LightFusionID != nil && *i.LightFusionID <= 0 {
+		return ErrInvalidLightFusionID
+	}
+	if i.Capacity <= 0 || (i.MaxDCPowerKW != nil && *i.MaxDCPowerKW <= 0) || (i.UnitCost != nil && *i.UnitCost < 0) {
+		return ErrInvalidInverterRating
+	}
+	if i.MPPTCount != nil && (*i.MPPTCount < 1 || *i.MPPTCount > 24) {
+		return ErrInvalidInverterDCInput
+	}
+	for _, v := range []*float64{i.MPPTVoltageMin, i.MPPTVoltageMax, i.MaxDCVoltage, i.MaxInputCurrent} {
+		if v != nil && *v <= 0 {
+			return ErrInvalidInverterDCInput
+		}
+	}
+	if i.MPPTVoltageMin != nil && i.MPPTVoltageMax != nil && *i.MPPTVoltageMin >= *i.MPPTVoltageMax {
+		return ErrInvalidInverterDCInput
+	}
+	if i.MPPTVoltageMax != nil && i.MaxDCVoltage != nil && *i.MPPTVoltageMax > *i.MaxDCVoltage {
+		return ErrInvalidInverterDCInput
+	}
+	if (i.Phase != nil && *i.Phase != 1 && *i.Phase != 3) || (i.NominalACVoltage != nil && *i.NominalACVoltage <= 0) {
+		return ErrInvalidInverterAC
+	}
+	if i.Efficiency != nil && (*i.Efficiency <= 0 || *i.Efficiency > 1) {
+		return ErrInvalidInverterEfficiency
+	}
+	if !validWarranty(i.WarrantyYears) {
+		return ErrInvalidHardwareWarranty
+	}
+	return nil
+}
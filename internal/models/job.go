@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+type JobStatus string
+
+const (
+	JobStatusPending JobStatus = "pending"
+	JobStatusRunning JobStatus = "running"
+)
+
+// Job is a unit of background work stored in Postgres so it survives
+// restarts. Workers claim jobs with SELECT ... FOR UPDATE SKIP LOCKED.
+type Job struct {
+	ID          int        `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"column:updated_at"`
+	Type        string     `json:"type" gorm:"column:type;not null;index"`
+	LeadID      *int       `json:"lead_id" gorm:"column:lead_id;index"`
+	Payload     string     `json:"payload" gorm:"column:payload;type:text"`
+	Status      JobStatus  `json:"status" gorm:"column:status;not null;index"`
+	Attempts    int        `json:"attempts" gorm:"column:attempts;not null"`
+	MaxAttempts int        `json:"max_attempts" gorm:"column:max_attempts;not null"`
+	RunAt       time.Time  `json:"run_at" gorm:"column:run_at;not null;index"`
+	LockedAt    *time.Time `json:"locked_at" gorm:"column:locked_at"`
+	LastError   string     `json:"last_error" gorm:"column:last_error;type:text"`
+}
+
+func (Job) TableName() string {
+	return "jobs"
+}
+
+// DeadJob is a job that exhausted its attempts. It is kept for inspection
+// and manual replay.
+type DeadJob struct {
+	ID        int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+	JobID     int       `json:"job_id" gorm:"column:job_id"`
+	Type      string    `json:"type" gorm:"column:type;not null"`
+	LeadID    *int      `json:"lead_id" gorm:"column:lead_id;index"`
+	Payload   string    `json:"payload" gorm:"column:payload;type:text"`
+	Attempts  int       `json:"attempts" gorm:"column:attempts"`
+	LastError string    `json:"last_error" gorm:"column:last_error;type:text"`
+}
+
+func (DeadJob) TableName() string {
+	return "dead_jobs"
+}
@@ -25,6 +25,51 @@ LeadStateErrored      LeadState = 2
 LeadStateInitialized  LeadState = 3
 )
 
+type SyncStatus string
+
+const (
+SyncStatusPending SyncStatus = "pending"
+SyncStatusSynced  SyncStatus = "synced"
+SyncStatusFailed  SyncStatus = "failed"
+)
+
+
+var leadStateNames = map[LeadState]string{
+LeadStateProgress:    "progress",
+LeadStateDone:        "done",
+LeadStateErrored:     "errored",
+LeadStateInitialized: "initialized",
+}
+
+// leadStateTransitions lists the states each state may move to.
+var leadStateTransitions = map[LeadState][]LeadState{
+LeadStateInitialized: {LeadStateProgress, LeadStateErrored},
+LeadStateProgress:    {LeadStateDone, LeadStateErrored},
+LeadStateErrored:     {LeadStateProgress},
+LeadStateDone:        {LeadStateProgress},
+}
+
+func (s LeadState) String() string {
+if name, ok := leadStateNames[s]; ok {
+		return name
+}
+return "unknown"
+}
+
+func (s LeadState) IsValid() bool {
+_, ok := leadStateNames[s]
+return ok
+}
+
+func (s LeadState) CanTransitionTo(to LeadState) bool {
+for _, allowed := range leadStateTransitions[s] {
+		if allowed == to {
+			return true
+		}
+}
+return false
+}
+
 
 type Lead struct {
 	ID        int       `json:"id" gorm:"primaryKey;column:id"`
@@ -41,7 +86,13 @@ type Lead struct {
 	KwhUsage     float64 `json:"kwh_usage" gorm:"column:kwh_usage" example:"12000"`
 	PanelId      int     `json:"panel_id" gorm:"column:panel_id" example:"1"`
 	InverterId   int     `json:"inverter_id" gorm:"column:inverter_id" example:"1"`
-	Consumption       []int   `json:"consumption" gorm:"-"`
+	// InverterQuantity is how many inverters the design has. It is nil
+	// until the installer or LightFusion gives it, and the design check
+	// then assumes one.
+	InverterQuantity *int `json:"inverter_quantity" gorm:"column:inverter_quantity" example:"1"`
+	StorageID       *int `json:"storage_id" gorm:"column:storage_id" example:"101"`
+	StorageQuantity *int `json:"storage_quantity" gorm:"column:storage_quantity" example:"1"`
+	Consumption       []int   `json:"consumption" gorm:"column:consumption;type:text;serializer:json"`
 	Period            string  `json:"period" gorm:"column:period"`
 	TargetSolarOffset int     `json:"target_solar_offset" gorm:"column:target_solar_offset"`
 	Mode              *string `json:"mode" gorm:"column:mode"`
@@ -50,6 +101,31 @@ type Lead struct {
     UtilityID     *int   `json:"utility_id" gorm:"column:utility_id" example:"1"`
     TariffID      *int   `json:"tariff_id" gorm:"column:tariff_id" example:"1"`
     ExternalID    *int   `json:"external_id" gorm:"column:external_id" example:"1"`
+	// State defaults to 0 so GORM, which leaves zero values with a default
+	// out of inserts, still stores LeadStateProgress. Rows that predate the
+	// column are backfilled by the migration, not left at the default.
+	State         LeadState `json:"state" gorm:"column:state;not null;default:0" example:"3"`
+	WelcomeCallState        MilestoneState `json:"welcome_call_state" gorm:"column:welcome_call_state;default:0"`
+	FinancingState          MilestoneState `json:"financing_state" gorm:"column:financing_state;default:0"`
+	UtilityBillState        MilestoneState `json:"utility_bill_state" gorm:"column:utility_bill_state;default:0"`
+	SitePhotosState         MilestoneState `json:"site_photos_state" gorm:"column:site_photos_state;default:0"`
+	DesignApprovedState     MilestoneState `json:"design_approved_state" gorm:"column:design_approved_state;default:0"`
+	PermittingApprovedState MilestoneState `json:"permitting_approved_state" gorm:"column:permitting_approved_state;default:0"`
+	InstallCrewState        MilestoneState `json:"install_crew_state" gorm:"column:install_crew_state;default:0"`
+	InstallationState       MilestoneState `json:"installation_state" gorm:"column:installation_state;default:0"`
+	FinalInspectionState    MilestoneState `json:"final_inspection_state" gorm:"column:final_inspection_state;default:0"`
+	PTOState                MilestoneState `json:"pto_state" gorm:"column:pto_state;default:0"`
+	InstallationDate *string `json:"installation_date" gorm:"column:installation_date" example:"2025-11-03"`
+	DateNTP          *string `json:"date_ntp" gorm:"column:date_ntp" example:"2025-10-20"`
+	DateInstalled    *string `json:"date_installed" gorm:"column:date_installed" example:"2025-11-04"`
+	BuildProgress int        `json:"build_progress" gorm:"column:build_progress;default:0" example:"60"`
+	SyncStatus    SyncStatus `json:"sync_status" gorm:"column:sync_status;default:pending" example:"synced"`
+	LastSyncedAt  *time.Time `json:"last_synced_at" gorm:"column:last_synced_at"`
+	SyncError     string     `json:"sync_error,omitempty" gorm:"column:sync_error;type:text"`
+	// SyncFailures counts the syncs that have failed in a row; the lead is
+	// not synced again before NextSyncAt.
+	SyncFailures int        `json:"sync_failures" gorm:"column:sync_failures;not null;default:0" example:"0"`
+	NextSyncAt   *time.Time `json:"next_sync_at,omitempty" gorm:"column:next_sync_at"`
 }
 
 func (Lead) TableName() string {
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// LeadStateHistory records a single lead state transition. FromState is nil
+// for the entry written when the lead is created; ActorID is nil when the
+// transition was made by the system (e.g. a background job).
+type LeadStateHistory struct {
+	ID        int        `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at"`
+	LeadID    int        `json:"lead_id" gorm:"column:lead_id;not null;index"`
+	FromState *LeadState `json:"from_state" gorm:"column:from_state" example:"3"`
+	ToState   LeadState  `json:"to_state" gorm:"column:to_state;not null" example:"0"`
+	ActorID   *int       `json:"actor_id" gorm:"column:actor_id" example:"1"`
+	Reason    string     `json:"reason" gorm:"column:reason;type:text" example:"3D project creation started"`
+}
+
+func (LeadStateHistory) TableName() string {
+	return "lead_state_history"
+}
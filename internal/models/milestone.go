@@ -0,0 +1,123 @@
+package models
+
+type Milestone string
+
+const (
+	MilestoneWelcomeCall        Milestone = "welcome_call"
+	MilestoneFinancing          Milestone = "financing"
+	MilestoneUtilityBill        Milestone = "utility_bill"
+	MilestoneSitePhotos         Milestone = "site_photos"
+	MilestoneDesignApproved     Milestone = "design_approved"
+	MilestonePermittingApproved Milestone = "permitting_approved"
+	MilestoneInstallCrew        Milestone = "install_crew"
+	MilestoneInstallation       Milestone = "installation"
+	MilestoneFinalInspection    Milestone = "final_inspection"
+	MilestonePTO                Milestone = "pto"
+)
+
+// MilestoneComplete is reported as the current milestone of a lead that has
+// completed every milestone.
+const MilestoneComplete Milestone = "complete"
+
+type MilestoneState int
+
+const (
+	MilestoneStatePending    MilestoneState = 0
+	MilestoneStateInProgress MilestoneState = 1
+	MilestoneStateCompleted  MilestoneState = 2
+)
+
+// Milestones lists the installation milestones in the order a project
+// normally moves through them.
+var Milestones = []Milestone{
+	MilestoneWelcomeCall,
+	MilestoneFinancing,
+	MilestoneUtilityBill,
+	MilestoneSitePhotos,
+	MilestoneDesignApproved,
+	MilestonePermittingApproved,
+	MilestoneInstallCrew,
+	MilestoneInstallation,
+	MilestoneFinalInspection,
+	MilestonePTO,
+}
+
+// MilestonePrerequisites lists the milestones that must be completed before
+// a milestone can be started.
+var MilestonePrerequisites = map[Milestone][]Milestone{
+	MilestoneFinancing:          {MilestoneWelcomeCall},
+	MilestoneDesignApproved:     {MilestoneUtilityBill, MilestoneSitePhotos},
+	MilestonePermittingApproved: {MilestoneDesignApproved},
+	MilestoneInstallCrew:        {MilestoneFinancing, MilestonePermittingApproved},
+	MilestoneInstallation:       {MilestonePermittingApproved, MilestoneInstallCrew},
+	MilestoneFinalInspection:    {MilestoneInstallation},
+	MilestonePTO:                {MilestoneFinalInspection},
+}
+
+func (m Milestone) IsValid() bool {
+	for _, milestone := range Milestones {
+		if milestone == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (s MilestoneState) IsValid() bool {
+	return s >= MilestoneStatePending && s <= MilestoneStateCompleted
+}
+
+// Milestone returns the state of the given milestone on the lead.
+func (l *Lead) Milestone(m Milestone) MilestoneState {
+	if field := l.milestoneField(m); field != nil {
+		return *field
+	}
+	return MilestoneStatePending
+}
+
+// SetMilestone sets the state of the given milestone on the lead.
+func (l *Lead) SetMilestone(m Milestone, state MilestoneState) error {
+	field := l.milestoneField(m)
+	if field == nil {
+		return ErrInvalidMilestone
+	}
+	*field = state
+	return nil
+}
+
+// CurrentMilestone returns the first milestone, in order, that is not yet
+// completed.
+func (l *Lead) CurrentMilestone() Milestone {
+	for _, m := range Milestones {
+		if l.Milestone(m) != MilestoneStateCompleted {
+			return m
+		}
+	}
+	return MilestoneComplete
+}
+
+func (l *Lead) milestoneField(m Milestone) *MilestoneState {
+	switch m {
+	case MilestoneWelcomeCall:
+		return &l.WelcomeCallState
+	case MilestoneFinancing:
+		return &l.FinancingState
+	case MilestoneUtilityBill:
+		return &l.UtilityBillState
+	case MilestoneSitePhotos:
+		return &l.SitePhotosState
+	case MilestoneDesignApproved:
+		return &l.DesignApprovedState
+	case MilestonePermittingApproved:
+		return &l.PermittingApprovedState
+	case MilestoneInstallCrew:
+		return &l.InstallCrewState
+	case MilestoneInstallation:
+		return &l.InstallationState
+	case MilestoneFinalInspection:
+		return &l.FinalInspectionState
+	case MilestonePTO:
+		return &l.PTOState
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
@@ -12,6 +13,100 @@ type Panel struct {
 	Wattage      float64  `json:"wattage" gorm:"column:wattage"`
 	LongSide     float64  `json:"longside" gorm:"column:longside"`
 	ShortSide    float64  `json:"shortside" gorm:"column:shortside"`
+	// Degradation rates are fractions of nameplate output: FirstYearDegradation
+	// is lost in year 1, AnnualDegradation every year after.
+	FirstYearDegradation *float64 `json:"first_year_degradation,omitempty" gorm:"column:first_year_degradation" example:"0.02"`
+	AnnualDegradation    *float64 `json:"annual_degradation,omitempty" gorm:"column:annual_degradation" example:"0.0055"`
+	// TempCoefficientPmax is the change in maximum power per °C above 25 °C,
+	// in percent; NOCT is the nominal operating cell temperature in °C.
+	TempCoefficientPmax *float64 `json:"temp_coefficient_pmax,omitempty" gorm:"column:temp_coefficient_pmax" example:"-0.34"`
+	NOCT                *float64 `json:"noct,omitempty" gorm:"column:noct" example:"44"`
+	// Electrical ratings at standard test conditions: open-circuit voltage,
+	// short-circuit current, and voltage and current at maximum power (what
+	// LightFusion calls max_power_voltage and max_power_current).
+	Voc *float64 `json:"voc,omitempty" gorm:"column:voc" example:"48.7"`
+	Isc *float64 `json:"isc,omitempty" gorm:"column:isc" example:"10.8"`
+	Vmp *float64 `json:"vmp,omitempty" gorm:"column:vmp" example:"40.6"`
+	Imp *float64 `json:"imp,omitempty" gorm:"column:imp" example:"10.1"`
+	// TempCoefficientVoc and TempCoefficientIsc are the change in Voc and
+	// Isc per °C above 25 °C, in percent.
+	TempCoefficientVoc *float64 `json:"temp_coefficient_voc,omitempty" gorm:"column:temp_coefficient_voc" example:"-0.24"`
+	TempCoefficientIsc *float64 `json:"temp_coefficient_isc,omitempty" gorm:"column:temp_coefficient_isc" example:"0.04"`
+	// Efficiency is the module efficiency as a fraction. WarrantyYears is
+	// the product warranty and PerformanceWarrantyYears the power output
+	// warranty.
+	Efficiency               *float64 `json:"efficiency,omitempty" gorm:"column:efficiency" example:"0.216"`
+	WarrantyYears            *int     `json:"warranty_years,omitempty" gorm:"column:warranty_years" example:"25"`
+	PerformanceWarrantyYears *int     `json:"performance_warranty_years,omitempty" gorm:"column:performance_warranty_years" example:"25"`
+	// CECID identifies the item's listing on the CEC equipment list it was
+	// imported from, so it is found again after the catalog renames it.
+	// Items added by hand have none.
+	CECID string `json:"cec_id,omitempty" gorm:"column:cec_id;not null;default:''" example:"REC Solar | REC400AA"`
+	// LightFusionID is the item's ID in LightFusion's hardware catalog, which
+	// 3D projects are designed with. Items without one cannot be put on a
+	// lead.
+	LightFusionID *int `json:"lightfusion_id,omitempty" gorm:"column:lightfusion_id" example:"289"`
+	// Active is false once the item is removed from the catalog. Removed
+	// items are kept for the leads and quotes that reference them.
+	Active       bool      `json:"active" gorm:"column:active;not null;default:true"`
 	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at"`
 	UpdatedAt    time.Time `json:"updated_at" gorm:"column:updated_at"`
 }
+
+// validHardwareName trims and checks the manufacturer and model shared by
+// every catalog item.
+func validHardwareName(manufacturer, model *string) error {
+	*manufacturer = strings.TrimSpace(*manufacturer)
+	*model = strings.TrimSpace(*model)
+	if *manufacturer == "" || len(*manufacturer) > 250 || *model == "" || len(*model) > 250 {
+		return ErrInvalidHardwareName
+	}
+	return nil
+}
+
+func validWarranty(years ...*int) bool {
+	for _, y := range years {
+		if y != nil && (*y < 0 || *y > 50) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Panel) Validate() error {
+	if err := validHardwareName(&p.Manufacturer, &p.Model); err != nil {
+		return err
+	}
+	if p.LightFusionID != nil && *p.LightFusionID <= 0 {
+		return ErrInvalidLightFusionID
+	}
+	if p.Wattage <= 0 || p.Wattage > 2000 || p.LongSide < 0 || p.LongSide > 5 || p.ShortSide < 0 || p.ShortSide > 5 ||
+		(p.NOCT != nil && (*p.NOCT <= 0 || *p.NOCT > 100)) {
+		return ErrInvalidPanelRating
+	}
+	for _, v := range []*float64{p.Voc, p.Isc, p.Vmp, p.Imp} {
+		if v != nil && *v <= 0 {
+			return ErrInvalidPanelElectrical
+		}
+	}
+	if (p.Vmp != nil && p.Voc != nil && *p.Vmp >= *p.Voc) || (p.Imp != nil && p.Isc != nil && *p.Imp >= *p.Isc) {
+		return ErrInvalidPanelElectrical
+	}
+	for _, v := range []*float64{p.TempCoefficientPmax, p.TempCoefficientVoc, p.TempCoefficientIsc} {
+		if v != nil && (*v < -2 || *v > 2) {
+			return ErrInvalidPanelCoefficient
+		}
+	}
+	if p.Efficiency != nil && (*p.Efficiency <= 0 || *p.Efficiency >= 1) {
+		return ErrInvalidPanelFraction
+	}
+	for _, v := range []*float64{p.FirstYearDegradation, p.AnnualDegradation} {
+		if v != nil && (*v < 0 || *v >= 1) {
+			return ErrInvalidPanelFraction
+		}
+	}
+	if !validWarranty(p.WarrantyYears, p.PerformanceWarrantyYears) {
+		return ErrInvalidHardwareWarranty
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// PricingProfile is a named set of quote assumptions. A profile applies to
+// one company, or to every company when CompanyID is nil, and to one state,
+// or to every state when State is empty. Nil values fall through to less
+// specific profiles and then to the system defaults. Rates are fractions
+// (0.03 is 3%); ElectricalOffsetPct is a percentage.
+type PricingProfile struct {
+	ID                    int        `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt             time.Time  `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt             time.Time  `json:"updated_at" gorm:"column:updated_at"`
+	Name                  string     `json:"name" gorm:"column:name;not null" example:"California 2025"`
+	CompanyID             *int       `json:"company_id" gorm:"column:company_id;index" example:"1"`
+	State                 string     `json:"state" gorm:"column:state;index" example:"CA"`
+	EffectiveFrom         *time.Time `json:"effective_from" gorm:"column:effective_from"`
+	EffectiveTo           *time.Time `json:"effective_to" gorm:"column:effective_to"`
+	CostPerWatt           *float64   `json:"cost_per_watt" gorm:"column:cost_per_watt" example:"2.85"`
+	UtilityRatePerKWh     *float64   `json:"utility_rate_per_kwh" gorm:"column:utility_rate_per_kwh" example:"0.32"`
+	AnnualUtilityIncrease *float64   `json:"annual_utility_increase" gorm:"column:annual_utility_increase" example:"0.04"`
+	FederalTaxCredit      *float64   `json:"federal_tax_credit" gorm:"column:federal_tax_credit" example:"0.3"`
+	LoanInterestRate      *float64   `json:"loan_interest_rate" gorm:"column:loan_interest_rate" example:"0.0599"`
+	LoanTermYears         *int       `json:"loan_term_years" gorm:"column:loan_term_years" example:"20"`
+	SunHoursPerDay        *float64   `json:"sun_hours_per_day" gorm:"column:sun_hours_per_day" example:"5.5"`
+	ElectricalOffsetPct   *float64   `json:"electrical_offset_pct" gorm:"column:electrical_offset_pct" example:"100"`
+}
+
+func (PricingProfile) TableName() string {
+	return "pricing_profiles"
+}
+
+func (p *PricingProfile) Validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" || len(p.Name) > 250 {
+		return ErrInvalidPricingProfileName
+	}
+	p.State = strings.ToUpper(strings.TrimSpace(p.State))
+	if p.State != "" && len(p.State) != 2 {
+		return ErrInvalidPricingProfileState
+	}
+	if p.EffectiveFrom != nil && p.EffectiveTo != nil && !p.EffectiveTo.After(*p.EffectiveFrom) {
+		return ErrInvalidPricingProfileDates
+	}
+	for _, v := range []*float64{p.CostPerWatt, p.UtilityRatePerKWh, p.SunHoursPerDay} {
+		if v != nil && *v <= 0 {
+			return ErrInvalidPricingProfileValue
+		}
+	}
+	for _, v := range []*float64{p.AnnualUtilityIncrease, p.FederalTaxCredit, p.LoanInterestRate} {
+		if v != nil && (*v < 0 || *v >= 1) {
+			return ErrInvalidPricingProfileValue
+		}
+	}
+	if p.LoanTermYears != nil && (*p.LoanTermYears <= 0 || *p.LoanTermYears > 40) {
+		return ErrInvalidPricingProfileValue
+	}
+	if p.ElectricalOffsetPct != nil && (*p.ElectricalOffsetPct <= 0 || *p.ElectricalOffsetPct > 200) {
+		return ErrInvalidPricingProfileValue
+	}
+	return nil
+}
+
+// ActiveAt reports whether the profile is in effect at t.
+func (p *PricingProfile) ActiveAt(t time.Time) bool {
+	if p.EffectiveFrom != nil && t.Before(*p.EffectiveFrom) {
+		return false
+	}
+	if p.EffectiveTo != nil && !t.Before(*p.EffectiveTo) {
+		return false
+	}
+	return true
+}
+
+// Specificity ranks how narrowly the profile applies: a company and state
+// profile beats a company-wide one, which beats a state-wide one, which
+// beats a global one.
+func (p *PricingProfile) Specificity() int {
+	score := 0
+	if p.CompanyID != nil {
+		score += 2
+	}
+	if p.State != "" {
+		score++
+	}
+	return score
+}
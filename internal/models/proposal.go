@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// Proposal is the customer-facing PDF rendered from one quote version. It
+// is rendered once and kept, so the document a customer saw can always be
+// produced again.
+type Proposal struct {
+	ID           int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at"`
+	LeadID       int       `json:"lead_id" gorm:"column:lead_id;not null;index" example:"42"`
+	QuoteID      int       `json:"quote_id" gorm:"column:quote_id;not null;uniqueIndex" example:"7"`
+	QuoteVersion int       `json:"quote_version" gorm:"column:quote_version;not null" example:"3"`
+	UserID       int       `json:"user_id" gorm:"column:user_id;not null" example:"1"`
+	FileName     string    `json:"file_name" gorm:"column:file_name;not null" example:"proposal-42-v3.pdf"`
+	SizeBytes    int       `json:"size_bytes" gorm:"column:size_bytes" example:"48211"`
+	SHA256       string    `json:"sha256" gorm:"column:sha256" example:"9f2c..."`
+	Content      []byte    `json:"-" gorm:"column:content;type:bytea;not null"`
+}
+
+func (Proposal) TableName() string {
+	return "proposals"
+}
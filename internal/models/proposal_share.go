@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+type ProposalShareStatus string
+
+const (
+	ProposalSharePending          ProposalShareStatus = "pending"
+	ProposalShareAccepted         ProposalShareStatus = "accepted"
+	ProposalShareChangesRequested ProposalShareStatus = "changes_requested"
+)
+
+// ProposalShare is a link a rep sends a homeowner to view the lead's
+// proposal without an account. The link's code is signed and carries
+// ExpiresAt; opens are counted and the homeowner can respond once.
+// QuoteVersion is the version that was shown when they responded.
+type ProposalShare struct {
+	ID              int                 `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt       time.Time           `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt       time.Time           `json:"updated_at" gorm:"column:updated_at"`
+	LeadID          int                 `json:"lead_id" gorm:"column:lead_id;not null;index" example:"42"`
+	CreatedBy       int                 `json:"created_by" gorm:"column:created_by;not null" example:"1"`
+	ExpiresAt       time.Time           `json:"expires_at" gorm:"column:expires_at;not null"`
+	OpenCount       int                 `json:"open_count" gorm:"column:open_count;not null;default:0" example:"3"`
+	FirstOpenedAt   *time.Time          `json:"first_opened_at" gorm:"column:first_opened_at"`
+	LastOpenedAt    *time.Time          `json:"last_opened_at" gorm:"column:last_opened_at"`
+	Status          ProposalShareStatus `json:"status" gorm:"column:status;not null;default:pending" enums:"pending,accepted,changes_requested" example:"pending"`
+	RespondedAt     *time.Time          `json:"responded_at" gorm:"column:responded_at"`
+	ResponderName   string              `json:"responder_name,omitempty" gorm:"column:responder_name" example:"Jane Doe"`
+	ResponseMessage string              `json:"response_message,omitempty" gorm:"column:response_message;type:text" example:"Could we add a battery?"`
+	QuoteVersion    *int                `json:"quote_version" gorm:"column:quote_version" example:"3"`
+}
+
+func (ProposalShare) TableName() string {
+	return "proposal_shares"
+}
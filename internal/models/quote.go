@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Quote is one immutable version of a solar quote. Input, Assumptions and
+// Result hold the JSON-encoded service.QuoteInput, service.QuoteAssumptions
+// and service.QuoteResult. Versions are numbered per lead starting at 1; a
+// quote that is not attached to a lead is always version 1.
+type Quote struct {
+	ID          int       `json:"id" gorm:"primaryKey;column:id"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at"`
+	LeadID      *int      `json:"lead_id" gorm:"column:lead_id;uniqueIndex:idx_quotes_lead_version" example:"42"`
+	UserID      int       `json:"user_id" gorm:"column:user_id;not null;index" example:"1"`
+	Version     int       `json:"version" gorm:"column:version;not null;uniqueIndex:idx_quotes_lead_version" example:"1"`
+	Input       string    `json:"-" gorm:"column:input;type:text;not null"`
+	Assumptions string    `json:"-" gorm:"column:assumptions;type:text;not null"`
+	Result      string    `json:"-" gorm:"column:result;type:text;not null"`
+}
+
+func (Quote) TableName() string {
+	return "quotes"
+}
@@ -1,14 +1,71 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
+// maxStorageCapacityKWh bounds one storage unit. Nothing sold for homes
+// comes close, so a larger value is a capacity given in Wh.
+const maxStorageCapacityKWh = 1000
+
+// MaxStorageQuantity is the most storage units a lead can have.
+const MaxStorageQuantity = 20
+
 type Storage struct {
 	ID           int       `json:"id" gorm:"primaryKey;column:id"`
 	Manufacturer string    `json:"manufacturer" gorm:"column:manufacturer"`
 	Model        string    `json:"model" gorm:"column:model"`
-	Capacity     float64   `json:"capacity" gorm:"column:capacity"`
+	// Capacity is the nameplate energy of one unit in kWh, never Wh.
+	Capacity     float64   `json:"capacity" gorm:"column:capacity" example:"13.5"`
+	// PowerKW is the continuous charge and discharge rating of one unit and
+	// RoundTripEfficiency the share of charged energy that comes back out.
+	PowerKW             *float64 `json:"power_kw,omitempty" gorm:"column:power_kw" example:"5"`
+	RoundTripEfficiency *float64 `json:"round_trip_efficiency,omitempty" gorm:"column:round_trip_efficiency" example:"0.9"`
+	// UsableKWh is the energy one unit can deliver, when less than its
+	// nameplate Capacity. Chemistry is the cell chemistry, e.g. LFP or NMC.
+	UsableKWh *float64 `json:"usable_kwh,omitempty" gorm:"column:usable_kwh" example:"13.5"`
+	Chemistry string   `json:"chemistry,omitempty" gorm:"column:chemistry" example:"LFP"`
+	// CECID identifies the item's listing on the CEC equipment list it was
+	// imported from, so it is found again after the catalog renames it.
+	// Items added by hand have none.
+	CECID string `json:"cec_id,omitempty" gorm:"column:cec_id;not null;default:''" example:"Tesla | 1707000-XX-Y [240V]"`
+	// LightFusionID is the item's ID in LightFusion's hardware catalog, which
+	// 3D projects are designed with. Items without one cannot be put on a
+	// lead.
+	LightFusionID *int `json:"lightfusion_id,omitempty" gorm:"column:lightfusion_id" example:"101"`
+	// Active is false once the item is removed from the catalog. Removed
+	// items are kept for the leads and quotes that reference them.
+	Active       bool      `json:"active" gorm:"column:active;not null;default:true"`
 	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at"`
 	UpdatedAt    time.Time `json:"updated_at" gorm:"column:updated_at"`
 }
+
+// UsableCapacity is the energy one unit delivers: UsableKWh when known,
+// otherwise the nameplate Capacity.
+func (s *Storage) UsableCapacity() float64 {
+	if s.UsableKWh != nil {
+		return *s.UsableKWh
+	}
+	return s.Capacity
+}
+
+func (s *Storage) Validate() error {
+	if err := validHardwareName(&s.Manufacturer, &s.Model); err != nil {
+		return err
+	}
+	if s.LightFusionID != nil && *s.LightFusionID <= 0 {
+		return ErrInvalidLightFusionID
+	}
+	if s.Capacity <= 0 || s.Capacity > maxStorageCapacityKWh || (s.UsableKWh != nil && (*s.UsableKWh <= 0 || *s.UsableKWh > s.Capacity)) {
+		return ErrInvalidStorageCapacity
+	}
+	if (s.PowerKW != nil && *s.PowerKW <= 0) || (s.RoundTripEfficiency != nil && (*s.RoundTripEfficiency <= 0 || *s.RoundTripEfficiency > 1)) {
+		return ErrInvalidStoragePower
+	}
+	s.Chemistry = strings.TrimSpace(s.Chemistry)
+	if len(s.Chemistry) > 100 {
+		return ErrInvalidStorageChemistry
+	}
+	return nil
+}
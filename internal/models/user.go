@@ -19,6 +19,7 @@ type User struct {
 	PostalCode         string    `json:"postal_code" gorm:"column:postal_code"`
 	Country            string    `json:"country" gorm:"column:country"`
 	UserType           UserType  `json:"user_type" gorm:"column:user_type"`
+	CompanyID          *int      `json:"company_id" gorm:"column:company_id" example:"1"`
 	HomeOwnershipType  string    `json:"home_ownership_type" gorm:"column:home_ownership_type" example:"owner"`
 	AverageMonthlyBill float64   `json:"average_monthly_bill" gorm:"column:average_monthly_bill" example:"150.00"`
 	UtilityProvider    string    `json:"utility_provider" gorm:"column:utility_provider" example:"PG&E"`
@@ -0,0 +1,87 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+)
+
+// Image is a raster image added to a document. JPEGs are embedded as they
+// are; other formats are decoded and stored deflated.
+type Image struct {
+	id            int
+	Width, Height int
+	colorSpace    string
+	filter        string
+	decode        string
+	data          []byte
+}
+
+func (img *Image) dict() string {
+	d := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s ",
+		img.Width, img.Height, img.colorSpace, img.filter)
+	if img.decode != "" {
+		d += "/Decode [" + img.decode + "] "
+	}
+	return d
+}
+
+// AddImage adds a JPEG or PNG image to the document.
+func (d *Document) AddImage(data []byte) (*Image, error) {
+	if bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return d.addJPEG(data)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return d.addRaster(decoded), nil
+}
+
+func (d *Document) addJPEG(data []byte) (*Image, error) {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JPEG: %w", err)
+	}
+	img := &Image{Width: cfg.Width, Height: cfg.Height, filter: "DCTDecode", data: data}
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		img.colorSpace = "DeviceGray"
+	case color.CMYKModel:
+		// Adobe writes CMYK JPEGs inverted.
+		img.colorSpace = "DeviceCMYK"
+		img.decode = "1 0 1 0 1 0 1 0"
+	default:
+		img.colorSpace = "DeviceRGB"
+	}
+	return d.add(img), nil
+}
+
+// addRaster stores src as deflated RGB, with any transparency flattened
+// onto white.
+func (d *Document) addRaster(src image.Image) *Image {
+	b := src.Bounds()
+	pixels := make([]byte, 0, b.Dx()*b.Dy()*3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := src.At(x, y).RGBA()
+			white := 0xffff - a
+			pixels = append(pixels, byte((r+white)>>8), byte((g+white)>>8), byte((bl+white)>>8))
+		}
+	}
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(pixels)
+	zw.Close()
+	return d.add(&Image{Width: b.Dx(), Height: b.Dy(), colorSpace: "DeviceRGB", filter: "FlateDecode", data: buf.Bytes()})
+}
+
+func (d *Document) add(img *Image) *Image {
+	img.id = len(d.images) + 1
+	d.images = append(d.images, img)
+	return img
+}
@@ -0,0 +1,232 @@
+// Package pdf writes simple PDF documents: text in the standard Helvetica
+// fonts, filled and stroked shapes and raster images. It covers what the
+// proposal renderer needs and nothing more.
+//
+// Coordinates are in points (1/72 inch) from the top-left corner of the
+// page, with y growing downwards.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// US Letter, in points.
+const (
+	PageWidth  = 612.0
+	PageHeight = 792.0
+)
+
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+var fontNames = [...]string{Helvetica: "Helvetica", HelveticaBold: "Helvetica-Bold"}
+
+// Color is an RGB color with components from 0 to 255.
+type Color struct {
+	R, G, B uint8
+}
+
+func (c Color) operands() string {
+	return fmt.Sprintf("%s %s %s", num(float64(c.R)/255), num(float64(c.G)/255), num(float64(c.B)/255))
+}
+
+// Document is a PDF being built. Pages are written in the order they were
+// added.
+type Document struct {
+	title  string
+	pages  []*Page
+	images []*Image
+}
+
+func New(title string) *Document {
+	return &Document{title: title}
+}
+
+// Page is one page of a document. Drawing calls append to its content
+// stream.
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+	images  map[*Image]bool
+}
+
+func (d *Document) AddPage() *Page {
+	p := &Page{doc: d, images: make(map[*Image]bool)}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Rect fills a rectangle with fill.
+func (p *Page) Rect(x, y, w, h float64, fill Color) {
+	fmt.Fprintf(&p.content, "%s rg %s %s %s %s re f\n", fill.operands(), num(x), num(PageHeight-y-h), num(w), num(h))
+}
+
+// Line strokes a line of the given width.
+func (p *Page) Line(x1, y1, x2, y2, width float64, stroke Color) {
+	fmt.Fprintf(&p.content, "%s RG %s w %s %s m %s %s l S\n", stroke.operands(), num(width),
+		num(x1), num(PageHeight-y1), num(x2), num(PageHeight-y2))
+}
+
+// Text draws s with its baseline at y, starting at x.
+func (p *Page) Text(x, y float64, font Font, size float64, c Color, s string) {
+	fmt.Fprintf(&p.content, "BT %s rg /F%d %s Tf %s %s Td (%s) Tj ET\n", c.operands(), font+1, num(size),
+		num(x), num(PageHeight-y), escape(encode(s)))
+}
+
+// TextRight draws s so that it ends at x.
+func (p *Page) TextRight(x, y float64, font Font, size float64, c Color, s string) {
+	p.Text(x-TextWidth(font, size, s), y, font, size, c, s)
+}
+
+// TextCenter draws s centred on x.
+func (p *Page) TextCenter(x, y float64, font Font, size float64, c Color, s string) {
+	p.Text(x-TextWidth(font, size, s)/2, y, font, size, c, s)
+}
+
+// Paragraph draws s wrapped to width from the baseline y down and returns
+// the baseline after the last line.
+func (p *Page) Paragraph(x, y, width float64, font Font, size, leading float64, c Color, s string) float64 {
+	for _, line := range Wrap(font, size, s, width) {
+		p.Text(x, y, font, size, c, line)
+		y += leading
+	}
+	return y
+}
+
+// Image draws img scaled into the w by h box whose top-left corner is x, y.
+func (p *Page) Image(img *Image, x, y, w, h float64) {
+	p.images[img] = true
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n", num(w), num(h), num(x), num(PageHeight-y-h), img.id)
+}
+
+// Wrap splits s into lines no wider than width. Words longer than width
+// get a line of their own.
+func Wrap(font Font, size float64, s string, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && TextWidth(font, size, candidate) > width {
+				lines = append(lines, line)
+				candidate = word
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// WriteTo writes the document.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	out := &pdfWriter{}
+	out.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	// Object numbers: 1 catalog, 2 page tree, 3 info, 4-5 fonts, then
+	// images, then a page and its content stream per page.
+	const fontObj = 4
+	imageObj := fontObj + len(fontNames)
+	pageObj := imageObj + len(d.images)
+
+	out.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageObj+2*i)
+	}
+	out.object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	out.object(3, fmt.Sprintf("<< /Title (%s) /Producer (SunReady) >>", escape(encode(d.title))))
+	for i, name := range fontNames {
+		out.object(fontObj+i, fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+	for i, img := range d.images {
+		out.stream(imageObj+i, img.dict(), img.data)
+	}
+
+	var fonts strings.Builder
+	for i := range fontNames {
+		fmt.Fprintf(&fonts, "/F%d %d 0 R ", i+1, fontObj+i)
+	}
+	for i, page := range d.pages {
+		var xobjects strings.Builder
+		for j, img := range d.images {
+			if page.images[img] {
+				fmt.Fprintf(&xobjects, "/Im%d %d 0 R ", img.id, imageObj+j)
+			}
+		}
+		out.object(pageObj+2*i, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %s>> /XObject << %s>> >> /Contents %d 0 R >>",
+			num(PageWidth), num(PageHeight), fonts.String(), xobjects.String(), pageObj+2*i+1))
+		out.stream(pageObj+2*i+1, "", page.content.Bytes())
+	}
+
+	objects := pageObj + 2*len(d.pages)
+	xref := out.buf.Len()
+	out.printf("xref\n0 %d\n0000000000 65535 f \n", objects)
+	for i := 1; i < objects; i++ {
+		out.printf("%010d 00000 n \n", out.offsets[i])
+	}
+	out.printf("trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", objects, xref)
+	return out.buf.WriteTo(w)
+}
+
+// Bytes returns the encoded document.
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets map[int]int
+}
+
+func (w *pdfWriter) printf(format string, args ...any) {
+	fmt.Fprintf(&w.buf, format, args...)
+}
+
+func (w *pdfWriter) object(n int, body string) {
+	if w.offsets == nil {
+		w.offsets = make(map[int]int)
+	}
+	w.offsets[n] = w.buf.Len()
+	w.printf("%d 0 obj\n%s\nendobj\n", n, body)
+}
+
+// stream writes a stream object; dict holds the entries besides /Length.
+func (w *pdfWriter) stream(n int, dict string, data []byte) {
+	if w.offsets == nil {
+		w.offsets = make(map[int]int)
+	}
+	w.offsets[n] = w.buf.Len()
+	w.printf("%d 0 obj\n<< %s/Length %d >>\nstream\n", n, dict, len(data))
+	w.buf.Write(data)
+	w.printf("\nendstream\nendobj\n")
+}
+
+// num formats a number without a trailing zero fraction, as PDF operands
+// are usually written.
+func num(v float64) string {
+	s := fmt.Sprintf("%.3f", v)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func escape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`, "\r", `\r`)
+	return r.Replace(s)
+}
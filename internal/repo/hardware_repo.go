@@ -1,6 +1,14 @@
 package repo
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
 	"github.com/Bilal-Cplusoft/sunready/internal/models"
 )
@@ -15,33 +23,20 @@ func NewHardwareRepo(db *gorm.DB) *HardwareRepo {
 }
 
 
-func (r *HardwareRepo) ListPanels() ([]*models.Panel, error) {
-	var panels []*models.Panel
-	if err := r.db.Find(&panels).Error; err != nil {
-		return nil, err
-	}
-	return panels, nil
-}
-
-
-func (r *HardwareRepo) ListInverters() ([]*models.Inverter, error) {
-	var inverters []*models.Inverter
-	if err := r.db.Find(&inverters).Error; err != nil {
-		return nil, err
-	}
-	return inverters, nil
-}
-
-func (r *HardwareRepo) ListStorages() ([]*models.Storage, error) {
+// ListActiveStorages returns every storage unit still in the catalog.
+func (r *HardwareRepo) ListActiveStorages(ctx context.Context) ([]*models.Storage, error) {
 	var storages []*models.Storage
-	if err := r.db.Find(&storages).Error; err != nil {
-		return nil, err
+	if err := r.db.WithContext(ctx).Where("active = ?", true).Order("id ASC").Find(&storages).Error; err != nil {
+		return nil, fmt.Errorf("failed to list storages: %w", err)
 	}
 	return storages, nil
 }
 
 
 func (r *HardwareRepo) CreatePanel(panel *models.Panel) error {
+	if err := panel.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
 	if err := r.db.Create(panel).Error; err != nil {
 		return err
 	}
@@ -50,6 +45,9 @@ func (r *HardwareRepo) CreatePanel(panel *models.Panel) error {
 
 
 func (r *HardwareRepo) CreateInverter(inverter *models.Inverter) error {
+	if err := inverter.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
 	if err := r.db.Create(inverter).Error; err != nil {
 		return err
 	}
@@ -58,8 +56,372 @@ func (r *HardwareRepo) CreateInverter(inverter *models.Inverter) error {
 
 
 func (r *HardwareRepo) CreateStorage(storage *models.Storage) error {
+	if err := storage.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
 	if err := r.db.Create(storage).Error; err != nil {
 		return err
 	}
 	return nil
 }
+
+
+func (r *HardwareRepo) GetPanelByID(ctx context.Context, id int) (*models.Panel, error) {
+	var panel models.Panel
+	if err := r.db.WithContext(ctx).First(&panel, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrPanelNotFound
+		}
+		return nil, fmt.Errorf("failed to get panel: %w", err)
+	}
+	return &panel, nil
+}
+
+func (r *HardwareRepo) GetInverterByID(ctx context.Context, id int) (*models.Inverter, error) {
+	var inverter models.Inverter
+	if err := r.db.WithContext(ctx).First(&inverter, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrInverterNotFound
+		}
+		return nil, fmt.Errorf("failed to get inverter: %w", err)
+	}
+	return &inverter, nil
+}
+
+func (r *HardwareRepo) GetStorageByID(ctx context.Context, id int) (*models.Storage, error) {
+	var storage models.Storage
+	if err := r.db.WithContext(ctx).First(&storage, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrStorageNotFound
+		}
+		return nil, fmt.Errorf("failed to get storage: %w", err)
+	}
+	return &storage, nil
+}
+
+func (r *HardwareRepo) UpdatePanel(ctx context.Context, panel *models.Panel) error {
+	if err := panel.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	result := r.db.WithContext(ctx).Model(panel).Select("*").Omit("created_at").Updates(panel)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update panel: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrPanelNotFound
+	}
+	return nil
+}
+
+func (r *HardwareRepo) UpdateInverter(ctx context.Context, inverter *models.Inverter) error {
+	if err := inverter.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	result := r.db.WithContext(ctx).Model(inverter).Select("*").Omit("created_at").Updates(inverter)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update inverter: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrInverterNotFound
+	}
+	return nil
+}
+
+func (r *HardwareRepo) UpdateStorage(ctx context.Context, storage *models.Storage) error {
+	if err := storage.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	result := r.db.WithContext(ctx).Model(storage).Select("*").Omit("created_at").Updates(storage)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update storage: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrStorageNotFound
+	}
+	return nil
+}
+
+// DeactivatePanel removes a panel from the catalog. The row is kept so
+// leads and quotes that reference it still resolve.
+func (r *HardwareRepo) DeactivatePanel(ctx context.Context, id int) error {
+	return r.deactivate(ctx, &models.Panel{}, id, "panel", models.ErrPanelNotFound)
+}
+
+// DeactivateInverter removes an inverter from the catalog, keeping the row.
+func (r *HardwareRepo) DeactivateInverter(ctx context.Context, id int) error {
+	return r.deactivate(ctx, &models.Inverter{}, id, "inverter", models.ErrInverterNotFound)
+}
+
+// DeactivateStorage removes a storage unit from the catalog, keeping the row.
+func (r *HardwareRepo) DeactivateStorage(ctx context.Context, id int) error {
+	return r.deactivate(ctx, &models.Storage{}, id, "storage", models.ErrStorageNotFound)
+}
+
+func (r *HardwareRepo) deactivate(ctx context.Context, model any, id int, name string, notFound error) error {
+	result := r.db.WithContext(ctx).Model(model).Where("id = ?", id).Updates(map[string]any{"active": false, "updated_at": time.Now()})
+	if result.Error != nil {
+		return fmt.Errorf("failed to deactivate %s: %w", name, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// ListAllPanels returns every panel, including those removed from the
+// catalog.
+func (r *HardwareRepo) ListAllPanels(ctx context.Context) ([]*models.Panel, error) {
+	var panels []*models.Panel
+	if err := r.db.WithContext(ctx).Order("id ASC").Find(&panels).Error; err != nil {
+		return nil, fmt.Errorf("failed to list panels: %w", err)
+	}
+	return panels, nil
+}
+
+// ListAllInverters returns every inverter, including those removed from
+// the catalog.
+func (r *HardwareRepo) ListAllInverters(ctx context.Context) ([]*models.Inverter, error) {
+	var inverters []*models.Inverter
+	if err := r.db.WithContext(ctx).Order("id ASC").Find(&inverters).Error; err != nil {
+		return nil, fmt.Errorf("failed to list inverters: %w", err)
+	}
+	return inverters, nil
+}
+
+// ListAllStorages returns every storage unit, including those removed from
+// the catalog.
+func (r *HardwareRepo) ListAllStorages(ctx context.Context) ([]*models.Storage, error) {
+	var storages []*models.Storage
+	if err := r.db.WithContext(ctx).Order("id ASC").Find(&storages).Error; err != nil {
+		return nil, fmt.Errorf("failed to list storages: %w", err)
+	}
+	return storages, nil
+}
+
+// ImportPanels creates and updates panels in one transaction.
+func (r *HardwareRepo) ImportPanels(ctx context.Context, creates, updates []*models.Panel) error {
+	return importHardware(ctx, r.db, "panels", creates, updates)
+}
+
+// ImportInverters creates and updates inverters in one transaction.
+func (r *HardwareRepo) ImportInverters(ctx context.Context, creates, updates []*models.Inverter) error {
+	return importHardware(ctx, r.db, "inverters", creates, updates)
+}
+
+// ImportStorages creates and updates storage units in one transaction.
+func (r *HardwareRepo) ImportStorages(ctx context.Context, creates, updates []*models.Storage) error {
+	return importHardware(ctx, r.db, "storages", creates, updates)
+}
+
+func importHardware[T any](ctx context.Context, db *gorm.DB, name string, creates, updates []*T) error {
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(creates) > 0 {
+			if err := tx.CreateInBatches(creates, 500).Error; err != nil {
+				return err
+			}
+		}
+		for _, item := range updates {
+			if err := tx.Model(item).Select("*").Omit("created_at").Updates(item).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %w", name, err)
+	}
+	return nil
+}
+
+// HardwareFilter narrows a catalog listing to active items. Query matches
+// manufacturer or model; Manufacturer and Model match their own column.
+// All three are case-insensitive substring matches. Min and Max bound the
+// rating: wattage for panels, capacity for inverters and storage.
+//
+// Sort names the column to order by (id, manufacturer, model, or the
+// rating column), prefixed with "-" for descending order. Cursor is the
+// NextCursor of the previous page and must be used with the same Sort.
+type HardwareFilter struct {
+	Query        string
+	Manufacturer string
+	Model        string
+	Min          *float64
+	Max          *float64
+	Sort         string
+	Cursor       string
+	Limit        int
+}
+
+const (
+	defaultHardwarePageSize = 50
+	maxHardwarePageSize     = 200
+)
+
+// HardwarePage is one page of a catalog listing. NextCursor is empty on
+// the last page.
+type HardwarePage[T any] struct {
+	Items      []*T
+	NextCursor string
+}
+
+// hardwareCursor marks where a page ended: the sort value and ID of its
+// last item, and the sort it was taken with.
+type hardwareCursor struct {
+	Sort  string `json:"s"`
+	Value any    `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func (r *HardwareRepo) SearchPanels(ctx context.Context, filter HardwareFilter) (*HardwarePage[models.Panel], error) {
+	return searchHardware(ctx, r.db, filter, "wattage", func(p *models.Panel, column string) (int, any) {
+		switch column {
+		case "manufacturer":
+			return p.ID, p.Manufacturer
+		case "model":
+			return p.ID, p.Model
+		case "wattage":
+			return p.ID, p.Wattage
+		}
+		return p.ID, p.ID
+	})
+}
+
+func (r *HardwareRepo) SearchInverters(ctx context.Context, filter HardwareFilter) (*HardwarePage[models.Inverter], error) {
+	return searchHardware(ctx, r.db, filter, "capacity", func(i *models.Inverter, column string) (int, any) {
+		switch column {
+		case "manufacturer":
+			return i.ID, i.Manufacturer
+		case "model":
+			return i.ID, i.Model
+		case "capacity":
+			return i.ID, i.Capacity
+		}
+		return i.ID, i.ID
+	})
+}
+
+func (r *HardwareRepo) SearchStorages(ctx context.Context, filter HardwareFilter) (*HardwarePage[models.Storage], error) {
+	return searchHardware(ctx, r.db, filter, "capacity", func(s *models.Storage, column string) (int, any) {
+		switch column {
+		case "manufacturer":
+			return s.ID, s.Manufacturer
+		case "model":
+			return s.ID, s.Model
+		case "capacity":
+			return s.ID, s.Capacity
+		}
+		return s.ID, s.ID
+	})
+}
+
+// searchHardware pages through active catalog items with keyset
+// pagination on (sort column, id), so pages stay stable while items are
+// added or removed. key returns an item's ID and its value in a column.
+func searchHardware[T any](ctx context.Context, db *gorm.DB, filter HardwareFilter, ratingColumn string, key func(*T, string) (int, any)) (*HardwarePage[T], error) {
+	column, desc := strings.CutPrefix(filter.Sort, "-")
+	sortExpr := ""
+	switch column {
+	case "", "id":
+		column, sortExpr = "id", "id"
+	case "manufacturer", "model":
+		sortExpr = fmt.Sprintf("COALESCE(%s, '')", column)
+	case ratingColumn:
+		sortExpr = fmt.Sprintf("COALESCE(%s, 0)", column)
+	default:
+		return nil, fmt.Errorf("%w: cannot sort by %q", models.ErrInvalidHardwareQuery, column)
+	}
+	sort := column
+	if desc {
+		sort = "-" + column
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultHardwarePageSize
+	}
+	if limit > maxHardwarePageSize {
+		limit = maxHardwarePageSize
+	}
+
+	query := db.WithContext(ctx).Model(new(T)).Where("active = ?", true)
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		like := "%" + escapeLike(q) + "%"
+		query = query.Where("(manufacturer ILIKE ? OR model ILIKE ?)", like, like)
+	}
+	if m := strings.TrimSpace(filter.Manufacturer); m != "" {
+		query = query.Where("manufacturer ILIKE ?", "%"+escapeLike(m)+"%")
+	}
+	if m := strings.TrimSpace(filter.Model); m != "" {
+		query = query.Where("model ILIKE ?", "%"+escapeLike(m)+"%")
+	}
+	if filter.Min != nil {
+		query = query.Where(ratingColumn+" >= ?", *filter.Min)
+	}
+	if filter.Max != nil {
+		query = query.Where(ratingColumn+" <= ?", *filter.Max)
+	}
+
+	dir, cmp := "ASC", ">"
+	if desc {
+		dir, cmp = "DESC", "<"
+	}
+	if filter.Cursor != "" {
+		cursor, err := decodeHardwareCursor(filter.Cursor)
+		if err == nil && cursor.Sort == sort {
+			_, isString := cursor.Value.(string)
+			if isString != (column == "manufacturer" || column == "model") {
+				err = errors.New("cursor value does not match its sort")
+			}
+		}
+		if err != nil || cursor.Sort != sort {
+			return nil, fmt.Errorf("%w: invalid cursor", models.ErrInvalidHardwareQuery)
+		}
+		if column == "id" {
+			query = query.Where("id "+cmp+" ?", cursor.ID)
+		} else {
+			query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", sortExpr, cmp), cursor.Value, cursor.ID)
+		}
+	}
+	if column != "id" {
+		query = query.Order(sortExpr + " " + dir)
+	}
+	query = query.Order("id " + dir)
+
+	var items []*T
+	if err := query.Limit(limit + 1).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to search hardware: %w", err)
+	}
+	page := &HardwarePage[T]{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		id, value := key(page.Items[limit-1], column)
+		page.NextCursor = encodeHardwareCursor(hardwareCursor{Sort: sort, Value: value, ID: id})
+	}
+	return page, nil
+}
+
+func encodeHardwareCursor(c hardwareCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeHardwareCursor(s string) (*hardwareCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c hardwareCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	switch c.Value.(type) {
+	case string, float64:
+	default:
+		return nil, errors.New("cursor value must be a string or number")
+	}
+	return &c, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -17,7 +17,7 @@ func NewHouseRepo(db *gorm.DB) *HouseRepo {
 }
 
 func (r *HouseRepo) Create(ctx context.Context, house *models.House) error {
-	if err := r.db.WithContext(ctx).Create(house).Error; err != nil {
+	if err := conn(ctx, r.db).Create(house).Error; err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,94 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"gorm.io/gorm"
+)
+
+type IncentiveRuleRepo struct {
+	db *gorm.DB
+}
+
+func NewIncentiveRuleRepo(db *gorm.DB) *IncentiveRuleRepo {
+	return &IncentiveRuleRepo{db: db}
+}
+
+func (r *IncentiveRuleRepo) Create(ctx context.Context, rule *models.IncentiveRule) error {
+	if err := rule.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	if err := r.db.WithContext(ctx).Create(rule).Error; err != nil {
+		return fmt.Errorf("failed to create incentive: %w", err)
+	}
+	return nil
+}
+
+func (r *IncentiveRuleRepo) GetByID(ctx context.Context, id int) (*models.IncentiveRule, error) {
+	var rule models.IncentiveRule
+	if err := r.db.WithContext(ctx).First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrIncentiveNotFound
+		}
+		return nil, fmt.Errorf("failed to get incentive: %w", err)
+	}
+	return &rule, nil
+}
+
+func (r *IncentiveRuleRepo) List(ctx context.Context) ([]*models.IncentiveRule, error) {
+	var rules []*models.IncentiveRule
+	if err := r.db.WithContext(ctx).Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to list incentives: %w", err)
+	}
+	return rules, nil
+}
+
+func (r *IncentiveRuleRepo) Update(ctx context.Context, rule *models.IncentiveRule) error {
+	if err := rule.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	result := r.db.WithContext(ctx).Model(rule).Select("*").Omit("created_at").Updates(rule)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update incentive: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrIncentiveNotFound
+	}
+	return nil
+}
+
+func (r *IncentiveRuleRepo) Delete(ctx context.Context, id int) error {
+	result := r.db.WithContext(ctx).Delete(&models.IncentiveRule{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete incentive: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrIncentiveNotFound
+	}
+	return nil
+}
+
+// ListApplicable returns the programs open at t in the state and for the
+// utility, including state-wide and nationwide programs. A nil utilityID
+// only matches programs open to every utility.
+func (r *IncentiveRuleRepo) ListApplicable(ctx context.Context, state string, utilityID *int, at time.Time) ([]*models.IncentiveRule, error) {
+	query := r.db.WithContext(ctx).
+		Where("state = '' OR state IS NULL OR UPPER(state) = UPPER(?)", state).
+		Where("effective_from IS NULL OR effective_from <= ?", at).
+		Where("effective_to IS NULL OR effective_to > ?", at)
+	if utilityID != nil {
+		query = query.Where("utility_id IS NULL OR utility_id = ?", *utilityID)
+	} else {
+		query = query.Where("utility_id IS NULL")
+	}
+
+	var rules []*models.IncentiveRule
+	if err := query.Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to list applicable incentives: %w", err)
+	}
+	return rules, nil
+}
@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepo struct {
+	db *gorm.DB
+}
+
+func NewJobRepo(db *gorm.DB) *JobRepo {
+	return &JobRepo{db: db}
+}
+
+func (r *JobRepo) Enqueue(ctx context.Context, job *models.Job) error {
+	if job.Status == "" {
+		job.Status = models.JobStatusPending
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if err := conn(ctx, r.db).Create(job).Error; err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return nil
+}
+
+// ClaimNext locks the next runnable job and marks it running. Jobs left in
+// the running state for longer than staleAfter (e.g. after a crash) are
+// considered runnable again. It returns nil when there is nothing to do.
+func (r *JobRepo) ClaimNext(ctx context.Context, staleAfter time.Duration) (*models.Job, error) {
+	var claimed *models.Job
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var job models.Job
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_at < ?)",
+				models.JobStatusPending, now, models.JobStatusRunning, now.Add(-staleAfter)).
+			Order("run_at").
+			First(&job).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		job.Status = models.JobStatusRunning
+		job.LockedAt = &now
+		job.Attempts++
+		if err := tx.Save(&job).Error; err != nil {
+			return err
+		}
+		claimed = &job
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+	return claimed, nil
+}
+
+func (r *JobRepo) Complete(ctx context.Context, id int) error {
+	if err := conn(ctx, r.db).Delete(&models.Job{}, id).Error; err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+	return nil
+}
+
+// Retry releases the job back to the queue to be run again at runAt.
+func (r *JobRepo) Retry(ctx context.Context, job *models.Job, runAt time.Time, lastErr string) error {
+	result := conn(ctx, r.db).Model(&models.Job{}).Where("id = ?", job.ID).Updates(map[string]any{
+		"status":     models.JobStatusPending,
+		"run_at":     runAt,
+		"locked_at":  nil,
+		"last_error": lastErr,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to reschedule job: %w", result.Error)
+	}
+	return nil
+}
+
+// Bury moves the job to the dead-letter table.
+func (r *JobRepo) Bury(ctx context.Context, job *models.Job, lastErr string) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		dead := models.DeadJob{
+			JobID:     job.ID,
+			Type:      job.Type,
+			LeadID:    job.LeadID,
+			Payload:   job.Payload,
+			Attempts:  job.Attempts,
+			LastError: lastErr,
+		}
+		if err := tx.Create(&dead).Error; err != nil {
+			return fmt.Errorf("failed to dead-letter job: %w", err)
+		}
+		if err := tx.Delete(&models.Job{}, job.ID).Error; err != nil {
+			return fmt.Errorf("failed to remove dead job: %w", err)
+		}
+		return nil
+	})
+}
@@ -3,9 +3,12 @@ package repo
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Bilal-Cplusoft/sunready/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 
@@ -24,7 +27,7 @@ func (r *LeadRepo) Create(ctx context.Context, lead *models.Lead) error {
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
-	result := r.db.WithContext(ctx).Create(lead)
+	result := conn(ctx, r.db).Create(lead)
 	if result.Error != nil {
 		return fmt.Errorf("failed to create lead: %w", result.Error)
 	}
@@ -35,7 +38,7 @@ func (r *LeadRepo) Create(ctx context.Context, lead *models.Lead) error {
 
 func (r *LeadRepo) GetByID(ctx context.Context, id int) (*models.Lead, error) {
 	var lead models.Lead
-	result := r.db.WithContext(ctx).First(&lead, id)
+	result := conn(ctx, r.db).First(&lead, id)
 
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
@@ -54,7 +57,7 @@ func (r *LeadRepo) Update(ctx context.Context, lead *models.Lead) error {
 		return fmt.Errorf("validation failed: %w", err)
 	}
 
-	result := r.db.WithContext(ctx).Save(lead)
+	result := conn(ctx, r.db).Save(lead)
 	if result.Error != nil {
 		return fmt.Errorf("failed to update lead: %w", result.Error)
 	}
@@ -67,8 +70,61 @@ func (r *LeadRepo) Update(ctx context.Context, lead *models.Lead) error {
 }
 
 
+// UpdateFields updates only the given columns, leaving concurrent changes to
+// other columns intact.
+func (r *LeadRepo) UpdateFields(ctx context.Context, id int, fields map[string]any) error {
+	result := conn(ctx, r.db).Model(&models.Lead{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update lead: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return models.ErrLeadNotFound
+	}
+
+	return nil
+}
+
+
+// TransitionState moves the lead from one state to another and records the
+// transition in the same transaction. It returns ErrLeadStateConflict if the
+// lead is no longer in the from state.
+func (r *LeadRepo) TransitionState(ctx context.Context, entry *models.LeadStateHistory) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.Lead{}).Where("id = ?", entry.LeadID)
+		if entry.FromState != nil {
+			query = query.Where("state = ?", *entry.FromState)
+		}
+		result := query.Update("state", entry.ToState)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update lead state: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrLeadStateConflict
+		}
+		if err := tx.Create(entry).Error; err != nil {
+			return fmt.Errorf("failed to record lead state history: %w", err)
+		}
+		return nil
+	})
+}
+
+
+func (r *LeadRepo) ListStateHistory(ctx context.Context, leadID int) ([]*models.LeadStateHistory, error) {
+	var history []*models.LeadStateHistory
+	err := conn(ctx, r.db).
+		Where("lead_id = ?", leadID).
+		Order("created_at ASC, id ASC").
+		Find(&history).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lead state history: %w", err)
+	}
+	return history, nil
+}
+
+
 func (r *LeadRepo) Delete(ctx context.Context, id int) error {
-	result := r.db.WithContext(ctx).Delete(&models.Lead{}, id)
+	result := conn(ctx, r.db).Delete(&models.Lead{}, id)
 	if result.Error != nil {
 		return fmt.Errorf("failed to delete lead: %w", result.Error)
 	}
@@ -85,7 +141,7 @@ func (r *LeadRepo) List(ctx context.Context, userId *int, limit, offset int) ([]
 	var leads []*models.Lead
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&models.Lead{})
+	query := conn(ctx, r.db).Model(&models.Lead{})
 
 	if userId != nil {
 		query = query.Where("creator_id = ?", *userId)
@@ -109,6 +165,104 @@ func (r *LeadRepo) List(ctx context.Context, userId *int, limit, offset int) ([]
 }
 
 
+// UpdateLocked loads the lead with its row locked, lets update check it and
+// return the columns to change, and writes them in the same transaction, so
+// checks against other columns cannot race with a concurrent update.
+func (r *LeadRepo) UpdateLocked(ctx context.Context, id int, update func(lead *models.Lead) (map[string]any, error)) (*models.Lead, error) {
+	var lead models.Lead
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&lead, id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return models.ErrLeadNotFound
+			}
+			return fmt.Errorf("failed to get lead: %w", err)
+		}
+		fields, err := update(&lead)
+		if err != nil || len(fields) == 0 {
+			return err
+		}
+		if err := tx.Model(&models.Lead{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+			return fmt.Errorf("failed to update lead: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &lead, nil
+}
+
+
+// currentMilestoneSQL is models.Lead.CurrentMilestone as a SQL expression.
+func currentMilestoneSQL() string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for _, m := range models.Milestones {
+		fmt.Fprintf(&b, " WHEN %s_state <> %d THEN '%s'", m, models.MilestoneStateCompleted, m)
+	}
+	fmt.Fprintf(&b, " ELSE '%s' END", models.MilestoneComplete)
+	return b.String()
+}
+
+
+// CountByMilestone counts the leads at each current milestone. Milestones
+// no lead is at are left out.
+func (r *LeadRepo) CountByMilestone(ctx context.Context) (map[models.Milestone]int64, error) {
+	var rows []struct {
+		Milestone models.Milestone
+		Count     int64
+	}
+	err := conn(ctx, r.db).Model(&models.Lead{}).
+		Select(currentMilestoneSQL() + " AS milestone, COUNT(*) AS count").
+		Group("milestone").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count leads by milestone: %w", err)
+	}
+	counts := make(map[models.Milestone]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Milestone] = row.Count
+	}
+	return counts, nil
+}
+
+
+// ListByMilestone returns a page of the leads whose current milestone is
+// milestone, most recently updated first.
+func (r *LeadRepo) ListByMilestone(ctx context.Context, milestone models.Milestone, limit, offset int) ([]*models.Lead, error) {
+	var leads []*models.Lead
+	err := conn(ctx, r.db).
+		Where(currentMilestoneSQL()+" = ?", milestone).
+		Order("updated_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&leads).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leads by milestone: %w", err)
+	}
+	return leads, nil
+}
+
+
+// ListForSync returns leads that have a LightFusion project, have not
+// reached a terminal state and are not backing off after failed syncs,
+// least recently synced first.
+func (r *LeadRepo) ListForSync(ctx context.Context, limit int) ([]*models.Lead, error) {
+	var leads []*models.Lead
+	err := conn(ctx, r.db).
+		Where("external_id IS NOT NULL").
+		Where("state IN ?", []models.LeadState{models.LeadStateInitialized, models.LeadStateProgress}).
+		Where("next_sync_at IS NULL OR next_sync_at <= ?", time.Now()).
+		Order("last_synced_at ASC NULLS FIRST").
+		Limit(limit).
+		Find(&leads).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leads for sync: %w", err)
+	}
+	return leads, nil
+}
+
+
 func (r *LeadRepo) GetLeadWithUserByLeadID(ctx context.Context, leadID int) (*models.Lead, error) {
 	var lead models.Lead
 	err := r.db.Preload("User").First(&lead, leadID).Error
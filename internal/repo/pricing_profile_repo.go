@@ -0,0 +1,102 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"gorm.io/gorm"
+)
+
+type PricingProfileRepo struct {
+	db *gorm.DB
+}
+
+func NewPricingProfileRepo(db *gorm.DB) *PricingProfileRepo {
+	return &PricingProfileRepo{db: db}
+}
+
+func (r *PricingProfileRepo) Create(ctx context.Context, profile *models.PricingProfile) error {
+	if err := profile.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	if err := r.db.WithContext(ctx).Create(profile).Error; err != nil {
+		return fmt.Errorf("failed to create pricing profile: %w", err)
+	}
+	return nil
+}
+
+func (r *PricingProfileRepo) GetByID(ctx context.Context, id int) (*models.PricingProfile, error) {
+	var profile models.PricingProfile
+	if err := r.db.WithContext(ctx).First(&profile, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrPricingProfileNotFound
+		}
+		return nil, fmt.Errorf("failed to get pricing profile: %w", err)
+	}
+	return &profile, nil
+}
+
+func (r *PricingProfileRepo) List(ctx context.Context) ([]*models.PricingProfile, error) {
+	var profiles []*models.PricingProfile
+	if err := r.db.WithContext(ctx).Order("id ASC").Find(&profiles).Error; err != nil {
+		return nil, fmt.Errorf("failed to list pricing profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+func (r *PricingProfileRepo) Update(ctx context.Context, profile *models.PricingProfile) error {
+	if err := profile.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	result := r.db.WithContext(ctx).Model(profile).Select("*").Omit("created_at").Updates(profile)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update pricing profile: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrPricingProfileNotFound
+	}
+	return nil
+}
+
+func (r *PricingProfileRepo) Delete(ctx context.Context, id int) error {
+	result := r.db.WithContext(ctx).Delete(&models.PricingProfile{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete pricing profile: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrPricingProfileNotFound
+	}
+	return nil
+}
+
+// ListApplicable returns the profiles in effect at t for the company and
+// state, including company-wide, state-wide and global profiles, most
+// specific first. Among equally specific profiles the most recently
+// effective comes first.
+func (r *PricingProfileRepo) ListApplicable(ctx context.Context, companyID *int, state string, at time.Time) ([]*models.PricingProfile, error) {
+	query := r.db.WithContext(ctx).
+		Where("state = '' OR state IS NULL OR UPPER(state) = UPPER(?)", state).
+		Where("effective_from IS NULL OR effective_from <= ?", at).
+		Where("effective_to IS NULL OR effective_to > ?", at)
+	if companyID != nil {
+		query = query.Where("company_id IS NULL OR company_id = ?", *companyID)
+	} else {
+		query = query.Where("company_id IS NULL")
+	}
+
+	var profiles []*models.PricingProfile
+	err := query.
+		Order("effective_from DESC NULLS LAST, id DESC").
+		Find(&profiles).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applicable pricing profiles: %w", err)
+	}
+	sort.SliceStable(profiles, func(i, j int) bool {
+		return profiles[i].Specificity() > profiles[j].Specificity()
+	})
+	return profiles, nil
+}
@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProposalRepo struct {
+	db *gorm.DB
+}
+
+func NewProposalRepo(db *gorm.DB) *ProposalRepo {
+	return &ProposalRepo{db: db}
+}
+
+// Create stores the proposal unless its quote already has one, in which
+// case proposal is replaced by the stored one. Proposals are never
+// re-rendered, so concurrent requests all end up with the same document.
+func (r *ProposalRepo) Create(ctx context.Context, proposal *models.Proposal) error {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "quote_id"}}, DoNothing: true}).
+		Create(proposal)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create proposal: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		existing, err := r.GetByQuoteID(ctx, proposal.QuoteID)
+		if err != nil {
+			return err
+		}
+		*proposal = *existing
+	}
+	return nil
+}
+
+func (r *ProposalRepo) GetByQuoteID(ctx context.Context, quoteID int) (*models.Proposal, error) {
+	var proposal models.Proposal
+	if err := r.db.WithContext(ctx).Where("quote_id = ?", quoteID).First(&proposal).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrProposalNotFound
+		}
+		return nil, fmt.Errorf("failed to get proposal: %w", err)
+	}
+	return &proposal, nil
+}
+
+// ListByLead returns the lead's proposals without their content, oldest
+// quote version first.
+func (r *ProposalRepo) ListByLead(ctx context.Context, leadID int) ([]*models.Proposal, error) {
+	var proposals []*models.Proposal
+	err := r.db.WithContext(ctx).
+		Omit("content").
+		Where("lead_id = ?", leadID).
+		Order("quote_version ASC").
+		Find(&proposals).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list proposals: %w", err)
+	}
+	return proposals, nil
+}
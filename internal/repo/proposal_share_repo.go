@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"gorm.io/gorm"
+)
+
+type ProposalShareRepo struct {
+	db *gorm.DB
+}
+
+func NewProposalShareRepo(db *gorm.DB) *ProposalShareRepo {
+	return &ProposalShareRepo{db: db}
+}
+
+func (r *ProposalShareRepo) Create(ctx context.Context, share *models.ProposalShare) error {
+	if err := r.db.WithContext(ctx).Create(share).Error; err != nil {
+		return fmt.Errorf("failed to create proposal share: %w", err)
+	}
+	return nil
+}
+
+func (r *ProposalShareRepo) GetByID(ctx context.Context, id int) (*models.ProposalShare, error) {
+	var share models.ProposalShare
+	if err := r.db.WithContext(ctx).First(&share, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrProposalShareNotFound
+		}
+		return nil, fmt.Errorf("failed to get proposal share: %w", err)
+	}
+	return &share, nil
+}
+
+func (r *ProposalShareRepo) ListByLead(ctx context.Context, leadID int) ([]*models.ProposalShare, error) {
+	var shares []*models.ProposalShare
+	err := r.db.WithContext(ctx).
+		Where("lead_id = ?", leadID).
+		Order("created_at DESC").
+		Find(&shares).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list proposal shares: %w", err)
+	}
+	return shares, nil
+}
+
+// RecordOpen counts an open of the share in a single statement so
+// concurrent opens are not lost.
+func (r *ProposalShareRepo) RecordOpen(ctx context.Context, id int, at time.Time) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.ProposalShare{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"open_count":      gorm.Expr("open_count + 1"),
+			"first_opened_at": gorm.Expr("COALESCE(first_opened_at, ?)", at),
+			"last_opened_at":  at,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to record proposal share open: %w", err)
+	}
+	return nil
+}
+
+// Respond stores the homeowner's response. Only a pending share can be
+// responded to; otherwise ErrProposalShareResponded is returned.
+func (r *ProposalShareRepo) Respond(ctx context.Context, share *models.ProposalShare) error {
+	result := conn(ctx, r.db).
+		Model(&models.ProposalShare{}).
+		Where("id = ? AND status = ?", share.ID, models.ProposalSharePending).
+		Updates(map[string]any{
+			"status":           share.Status,
+			"responded_at":     share.RespondedAt,
+			"responder_name":   share.ResponderName,
+			"response_message": share.ResponseMessage,
+			"quote_version":    share.QuoteVersion,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to respond to proposal share: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrProposalShareResponded
+	}
+	return nil
+}
@@ -1,7 +1,13 @@
 package repo
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type QuoteRepo struct {
@@ -11,3 +17,77 @@ type QuoteRepo struct {
 func NewQuoteRepo(db *gorm.DB) *QuoteRepo {
 	return &QuoteRepo{db: db}
 }
+
+// Create stores the quote as the next version for its lead. The lead row is
+// locked for the duration so concurrent quotes get distinct versions.
+func (r *QuoteRepo) Create(ctx context.Context, quote *models.Quote) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		quote.Version = 1
+		if quote.LeadID != nil {
+			var lead models.Lead
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Select("id").
+				First(&lead, *quote.LeadID).Error
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return models.ErrLeadNotFound
+				}
+				return fmt.Errorf("failed to lock lead: %w", err)
+			}
+			var latest int
+			err = tx.Model(&models.Quote{}).
+				Where("lead_id = ?", *quote.LeadID).
+				Select("COALESCE(MAX(version), 0)").
+				Scan(&latest).Error
+			if err != nil {
+				return fmt.Errorf("failed to get latest quote version: %w", err)
+			}
+			quote.Version = latest + 1
+		}
+		if err := tx.Create(quote).Error; err != nil {
+			return fmt.Errorf("failed to create quote: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *QuoteRepo) ListByLead(ctx context.Context, leadID int) ([]*models.Quote, error) {
+	var quotes []*models.Quote
+	err := r.db.WithContext(ctx).
+		Where("lead_id = ?", leadID).
+		Order("version ASC").
+		Find(&quotes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quotes: %w", err)
+	}
+	return quotes, nil
+}
+
+func (r *QuoteRepo) GetByLeadVersion(ctx context.Context, leadID, version int) (*models.Quote, error) {
+	var quote models.Quote
+	err := r.db.WithContext(ctx).
+		Where("lead_id = ? AND version = ?", leadID, version).
+		First(&quote).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrQuoteNotFound
+		}
+		return nil, fmt.Errorf("failed to get quote: %w", err)
+	}
+	return &quote, nil
+}
+
+func (r *QuoteRepo) GetLatestByLead(ctx context.Context, leadID int) (*models.Quote, error) {
+	var quote models.Quote
+	err := r.db.WithContext(ctx).
+		Where("lead_id = ?", leadID).
+		Order("version DESC").
+		First(&quote).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrQuoteNotFound
+		}
+		return nil, fmt.Errorf("failed to get latest quote: %w", err)
+	}
+	return &quote, nil
+}
@@ -0,0 +1,413 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+)
+
+type TariffType string
+
+const (
+	TariffFlat   TariffType = "flat"
+	TariffTiered TariffType = "tiered"
+	TariffTOU    TariffType = "tou"
+)
+
+type TrueUp string
+
+const (
+	TrueUpMonthly TrueUp = "monthly"
+	TrueUpAnnual  TrueUp = "annual"
+)
+
+// Tariff is a utility rate plan. Rates are in $/kWh.
+//
+// Flat tariffs charge FlatRate for every kWh. Tiered tariffs charge each
+// tier's rate for monthly usage up to its UpToKWh; the last tier should have
+// no limit. TOU tariffs charge the rate of the first period that matches the
+// hour, and FlatRate for hours no period covers.
+//
+// Exported kWh are credited at the first matching ExportPeriods rate, else
+// ExportCreditRate, else the retail rate of that hour (the first tier for
+// tiered tariffs), which is classic full-retail net metering.
+//
+// With a monthly true-up credits beyond a month's energy charges are lost;
+// with an annual true-up they carry forward and are lost at the end of the
+// year. The fixed monthly charge is never offset by credits.
+type Tariff struct {
+	Type               TariffType     `json:"type" enums:"flat,tiered,tou" example:"tou"`
+	FixedMonthlyCharge float64        `json:"fixed_monthly_charge" example:"15"`
+	FlatRate           float64        `json:"flat_rate,omitempty" example:"0.32"`
+	Tiers              []TariffTier   `json:"tiers,omitempty"`
+	Periods            []TariffPeriod `json:"periods,omitempty"`
+	ExportCreditRate   *float64       `json:"export_credit_rate,omitempty" example:"0.05"`
+	ExportPeriods      []TariffPeriod `json:"export_periods,omitempty"`
+	TrueUp             TrueUp         `json:"true_up" enums:"monthly,annual" example:"annual"`
+}
+
+type TariffTier struct {
+	UpToKWh *float64 `json:"up_to_kwh,omitempty" example:"300"`
+	Rate    float64  `json:"rate" example:"0.28"`
+}
+
+// TariffPeriod covers hours [StartHour, EndHour) of the given months (1-12,
+// empty for all) and days ("all", "weekdays" or "weekends"). A period whose
+// EndHour is before its StartHour wraps past midnight.
+type TariffPeriod struct {
+	Name      string  `json:"name" example:"peak"`
+	Rate      float64 `json:"rate" example:"0.48"`
+	Months    []int   `json:"months,omitempty"`
+	Days      string  `json:"days,omitempty" enums:"all,weekdays,weekends" example:"all"`
+	StartHour int     `json:"start_hour" example:"16"`
+	EndHour   int     `json:"end_hour" example:"21"`
+}
+
+// MonthlyBill compares one month's bill before and after solar.
+type MonthlyBill struct {
+	Month           int     `json:"month" example:"1"`
+	ConsumptionKWh  float64 `json:"consumption_kwh" example:"900"`
+	ProductionKWh   float64 `json:"production_kwh" example:"520"`
+	ImportKWh       float64 `json:"import_kwh" example:"610"`
+	ExportKWh       float64 `json:"export_kwh" example:"230"`
+	PreSolarBill    float64 `json:"pre_solar_bill" example:"285"`
+	ImportCost      float64 `json:"import_cost" example:"190"`
+	ExportCredit    float64 `json:"export_credit" example:"12"`
+	PostSolarBill   float64 `json:"post_solar_bill" example:"193"`
+	CreditCarryover float64 `json:"credit_carryover" example:"0"`
+}
+
+type BillingResult struct {
+	Months           []MonthlyBill `json:"months"`
+	AnnualPreSolar   float64       `json:"annual_pre_solar" example:"3420"`
+	AnnualPostSolar  float64       `json:"annual_post_solar" example:"1380"`
+	AnnualSavings    float64       `json:"annual_savings" example:"2040"`
+	CreditsForfeited float64       `json:"credits_forfeited" example:"0"`
+}
+
+// billingYear is the calendar used to lay out hourly profiles; it only
+// matters for which days are weekdays.
+const billingYear = 2023
+
+// residentialLoadShape is a typical weekday share of daily household use by
+// hour, with morning and evening peaks.
+var residentialLoadShape = [24]float64{
+	0.030, 0.027, 0.025, 0.024, 0.024, 0.027, 0.034, 0.041, 0.042, 0.039, 0.037, 0.036,
+	0.036, 0.036, 0.037, 0.040, 0.046, 0.054, 0.060, 0.061, 0.058, 0.052, 0.043, 0.035,
+}
+
+// defaultMonthlySolarShare splits annual production across months for a
+// typical mid-latitude northern hemisphere site.
+var defaultMonthlySolarShare = [12]float64{
+	0.055, 0.063, 0.083, 0.095, 0.104, 0.106, 0.108, 0.103, 0.089, 0.076, 0.060, 0.058,
+}
+
+// monthlyProductionProfile spreads annual production across months with
+// defaultMonthlySolarShare.
+func monthlyProductionProfile(annualKWh float64) []float64 {
+	months := make([]float64, 12)
+	for i, share := range defaultMonthlySolarShare {
+		months[i] = annualKWh * share
+	}
+	return months
+}
+
+func (t *Tariff) Validate() error {
+	if t.FixedMonthlyCharge < 0 || t.FlatRate < 0 {
+		return fmt.Errorf("%w: charges must not be negative", models.ErrInvalidTariff)
+	}
+	switch t.TrueUp {
+	case "", TrueUpMonthly, TrueUpAnnual:
+	default:
+		return fmt.Errorf("%w: unknown true-up %q", models.ErrInvalidTariff, t.TrueUp)
+	}
+	switch t.Type {
+	case TariffFlat:
+	case TariffTiered:
+		if len(t.Tiers) == 0 {
+			return fmt.Errorf("%w: tiered tariff needs tiers", models.ErrInvalidTariff)
+		}
+		last := 0.0
+		for i, tier := range t.Tiers {
+			if tier.Rate < 0 {
+				return fmt.Errorf("%w: tier rates must not be negative", models.ErrInvalidTariff)
+			}
+			if tier.UpToKWh == nil {
+				if i != len(t.Tiers)-1 {
+					return fmt.Errorf("%w: only the last tier may be unlimited", models.ErrInvalidTariff)
+				}
+				continue
+			}
+			if *tier.UpToKWh <= last {
+				return fmt.Errorf("%w: tier limits must increase", models.ErrInvalidTariff)
+			}
+			last = *tier.UpToKWh
+		}
+	case TariffTOU:
+		if len(t.Periods) == 0 {
+			return fmt.Errorf("%w: TOU tariff needs periods", models.ErrInvalidTariff)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", models.ErrInvalidTariff, t.Type)
+	}
+	if t.ExportCreditRate != nil && *t.ExportCreditRate < 0 {
+		return fmt.Errorf("%w: export credit rate must not be negative", models.ErrInvalidTariff)
+	}
+	for _, periods := range [][]TariffPeriod{t.Periods, t.ExportPeriods} {
+		for _, p := range periods {
+			if p.Rate < 0 || p.StartHour < 0 || p.StartHour > 23 || p.EndHour < 0 || p.EndHour > 24 || p.StartHour == p.EndHour {
+				return fmt.Errorf("%w: period %q needs a rate and hours within 0-24", models.ErrInvalidTariff, p.Name)
+			}
+			switch p.Days {
+			case "", "all", "weekdays", "weekends":
+			default:
+				return fmt.Errorf("%w: period %q has unknown days %q", models.ErrInvalidTariff, p.Name, p.Days)
+			}
+			for _, m := range p.Months {
+				if m < 1 || m > 12 {
+					return fmt.Errorf("%w: period %q has month %d", models.ErrInvalidTariff, p.Name, m)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p TariffPeriod) matches(t time.Time) bool {
+	if len(p.Months) > 0 {
+		found := false
+		for _, m := range p.Months {
+			if time.Month(m) == t.Month() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	weekend := t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+	if (p.Days == "weekdays" && weekend) || (p.Days == "weekends" && !weekend) {
+		return false
+	}
+	h := t.Hour()
+	if p.StartHour < p.EndHour {
+		return h >= p.StartHour && h < p.EndHour
+	}
+	return h >= p.StartHour || h < p.EndHour
+}
+
+// retailRate is the import rate at t. Tiered tariffs are priced per month
+// instead; for them it returns the first tier rate.
+func (t *Tariff) retailRate(at time.Time) float64 {
+	switch t.Type {
+	case TariffTiered:
+		return t.Tiers[0].Rate
+	case TariffTOU:
+		for _, p := range t.Periods {
+			if p.matches(at) {
+				return p.Rate
+			}
+		}
+	}
+	return t.FlatRate
+}
+
+func (t *Tariff) exportRate(at time.Time) float64 {
+	for _, p := range t.ExportPeriods {
+		if p.matches(at) {
+			return p.Rate
+		}
+	}
+	if t.ExportCreditRate != nil {
+		return *t.ExportCreditRate
+	}
+	return t.retailRate(at)
+}
+
+func (t *Tariff) tieredCost(kwh float64) float64 {
+	cost := 0.0
+	floor := 0.0
+	for _, tier := range t.Tiers {
+		if tier.UpToKWh == nil || kwh <= *tier.UpToKWh {
+			return cost + (kwh-floor)*tier.Rate
+		}
+		cost += (*tier.UpToKWh - floor) * tier.Rate
+		floor = *tier.UpToKWh
+	}
+	// Usage above the last limited tier is billed at its rate.
+	return cost + (kwh-floor)*t.Tiers[len(t.Tiers)-1].Rate
+}
+
+// hourlyLoadProfile spreads monthly consumption over every hour of the
+// billing year with residentialLoadShape.
+func hourlyLoadProfile(monthlyKWh []float64) []float64 {
+	return spreadMonthly(monthlyKWh, func(time.Time) []float64 { return residentialLoadShape[:] })
+}
+
+// hourlySolarProfile spreads monthly production over daylight hours with a
+// sine curve whose length follows the season.
+func hourlySolarProfile(monthlyKWh []float64) []float64 {
+	return spreadMonthly(monthlyKWh, func(day time.Time) []float64 {
+		dayLength := 12 + 2.5*math.Sin(2*math.Pi*float64(day.YearDay()-80)/365)
+		sunrise := 12.5 - dayLength/2
+		shape := make([]float64, 24)
+		for h := range shape {
+			x := (float64(h) + 0.5 - sunrise) / dayLength
+			if x > 0 && x < 1 {
+				shape[h] = math.Sin(math.Pi * x)
+			}
+		}
+		return shape
+	})
+}
+
+// spreadMonthly distributes each month's total evenly over its days and
+// within each day by the shape for that day.
+func spreadMonthly(monthlyKWh []float64, shapeFor func(day time.Time) []float64) []float64 {
+	hours := make([]float64, 0, 8760)
+	for day := time.Date(billingYear, 1, 1, 0, 0, 0, 0, time.UTC); day.Year() == billingYear; day = day.AddDate(0, 0, 1) {
+		daysInMonth := time.Date(billingYear, day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		daily := monthlyKWh[day.Month()-1] / float64(daysInMonth)
+		shape := shapeFor(day)
+		total := 0.0
+		for _, v := range shape {
+			total += v
+		}
+		for _, v := range shape {
+			hours = append(hours, daily*v/total)
+		}
+	}
+	return hours
+}
+
+// SimulateBilling computes monthly bills before and after solar. Usage and
+// production are netted hour by hour: each hour either imports the shortfall
+// or exports the surplus. hourlyProduction, when given, must have one value
+// per hour of the year and takes precedence over monthlyProduction.
+func SimulateBilling(tariff Tariff, monthlyConsumption, monthlyProduction, hourlyProduction []float64) (*BillingResult, error) {
+	if err := tariff.Validate(); err != nil {
+		return nil, err
+	}
+	if len(monthlyConsumption) != 12 {
+		return nil, fmt.Errorf("%w: consumption needs 12 monthly values", models.ErrInvalidBillingInput)
+	}
+	if !validEnergyValues(monthlyConsumption) {
+		return nil, fmt.Errorf("%w: consumption must be numbers of 0 or more", models.ErrInvalidBillingInput)
+	}
+	load := hourlyLoadProfile(monthlyConsumption)
+	solar := hourlyProduction
+	if solar == nil {
+		if len(monthlyProduction) != 12 {
+			return nil, fmt.Errorf("%w: production needs 12 monthly values", models.ErrInvalidBillingInput)
+		}
+		if !validEnergyValues(monthlyProduction) {
+			return nil, fmt.Errorf("%w: production must be numbers of 0 or more", models.ErrInvalidBillingInput)
+		}
+		solar = hourlySolarProfile(monthlyProduction)
+	}
+	if len(solar) != len(load) {
+		return nil, fmt.Errorf("%w: hourly production needs %d values", models.ErrInvalidBillingInput, len(load))
+	}
+	if !validEnergyValues(solar) {
+		return nil, fmt.Errorf("%w: hourly production must be numbers of 0 or more", models.ErrInvalidBillingInput)
+	}
+	grid := make([]float64, len(load))
+	for h := range load {
+		grid[h] = load[h] - solar[h]
+	}
+	return billHours(tariff, load, solar, grid), nil
+}
+
+// validEnergyValues reports whether every value is a finite amount of
+// energy of 0 or more.
+func validEnergyValues(values []float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// billHours bills a year of hourly usage. grid is the net flow at the meter
+// each hour: positive imports, negative exports. load alone prices the
+// pre-solar bill.
+func billHours(tariff Tariff, load, solar, grid []float64) *BillingResult {
+	months := make([]MonthlyBill, 12)
+	preEnergy := make([]float64, 12)
+	for i := range months {
+		months[i].Month = i + 1
+	}
+	at := time.Date(billingYear, 1, 1, 0, 0, 0, 0, time.UTC)
+	for h := range load {
+		m := &months[at.Month()-1]
+		m.ConsumptionKWh += load[h]
+		m.ProductionKWh += solar[h]
+		net := grid[h]
+		rate := tariff.retailRate(at)
+		if tariff.Type != TariffTiered {
+			preEnergy[at.Month()-1] += load[h] * rate
+		}
+		if net > 0 {
+			m.ImportKWh += net
+			if tariff.Type != TariffTiered {
+				m.ImportCost += net * rate
+			}
+		} else {
+			m.ExportKWh -= net
+			m.ExportCredit -= net * tariff.exportRate(at)
+		}
+		at = at.Add(time.Hour)
+	}
+
+	result := &BillingResult{Months: months}
+	carryover := 0.0
+	for i := range months {
+		m := &months[i]
+		if tariff.Type == TariffTiered {
+			preEnergy[i] = tariff.tieredCost(m.ConsumptionKWh)
+			m.ImportCost = tariff.tieredCost(m.ImportKWh)
+		}
+		m.PreSolarBill = tariff.FixedMonthlyCharge + preEnergy[i]
+		energy := m.ImportCost - m.ExportCredit
+		if tariff.TrueUp == TrueUpAnnual {
+			energy -= carryover
+			carryover = 0
+		}
+		if energy < 0 {
+			if tariff.TrueUp == TrueUpAnnual {
+				carryover = -energy
+			} else {
+				result.CreditsForfeited -= energy
+			}
+			energy = 0
+		}
+		m.PostSolarBill = tariff.FixedMonthlyCharge + energy
+		m.CreditCarryover = carryover
+		result.AnnualPreSolar += m.PreSolarBill
+		result.AnnualPostSolar += m.PostSolarBill
+	}
+	result.CreditsForfeited += carryover
+	result.AnnualSavings = result.AnnualPreSolar - result.AnnualPostSolar
+
+	for i := range months {
+		m := &months[i]
+		m.ConsumptionKWh = round2(m.ConsumptionKWh)
+		m.ProductionKWh = round2(m.ProductionKWh)
+		m.ImportKWh = round2(m.ImportKWh)
+		m.ExportKWh = round2(m.ExportKWh)
+		m.PreSolarBill = round2(m.PreSolarBill)
+		m.ImportCost = round2(m.ImportCost)
+		m.ExportCredit = round2(m.ExportCredit)
+		m.PostSolarBill = round2(m.PostSolarBill)
+		m.CreditCarryover = round2(m.CreditCarryover)
+	}
+	result.AnnualPreSolar = round2(result.AnnualPreSolar)
+	result.AnnualPostSolar = round2(result.AnnualPostSolar)
+	result.AnnualSavings = round2(result.AnnualSavings)
+	result.CreditsForfeited = round2(result.CreditsForfeited)
+	return result
+}
@@ -0,0 +1,360 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+)
+
+// The California Energy Commission publishes its PV module, inverter and
+// battery lists as spreadsheets. Exported to CSV they start with a few
+// rows of notes, then a header row, often a row of units, and the data.
+// Column titles drift between releases, so columns are found by their
+// normalized title rather than their position.
+
+// catalogTable is a CEC list read from CSV.
+type catalogTable struct {
+	titles []string // header cells, normalized
+	units  []string // header and unit row text per column, lowercased
+	rows   []catalogRow
+}
+
+type catalogRow struct {
+	line  int
+	cells []string
+}
+
+// readCatalogTable reads a CEC list, skipping everything before its
+// header row.
+func readCatalogTable(r io.Reader) (*catalogTable, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	table := &catalogTable{}
+	var header []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", models.ErrInvalidCatalogFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+		if header == nil {
+			if isCatalogHeader(record) {
+				header = record
+				table.titles = make([]string, len(record))
+				table.units = make([]string, len(record))
+				for i, cell := range record {
+					table.titles[i] = normalizeCatalogTitle(cell)
+					table.units[i] = strings.ToLower(cell)
+				}
+			} else if line > 100 {
+				break
+			}
+			continue
+		}
+		if len(table.rows) == 0 && table.isUnitRow(record) {
+			for i, cell := range record {
+				if i < len(table.units) {
+					table.units[i] += " " + strings.ToLower(strings.TrimSpace(cell))
+				}
+			}
+			continue
+		}
+		table.rows = append(table.rows, catalogRow{line: line, cells: record})
+	}
+	if header == nil {
+		return nil, fmt.Errorf("%w: no header row with manufacturer and model number columns", models.ErrInvalidCatalogFile)
+	}
+	return table, nil
+}
+
+func isCatalogHeader(record []string) bool {
+	var manufacturer, model bool
+	for _, cell := range record {
+		title := normalizeCatalogTitle(cell)
+		manufacturer = manufacturer || title == "manufacturer" || title == "manufacturername"
+		model = model || strings.HasPrefix(title, "modelnumber")
+	}
+	return manufacturer && model
+}
+
+// isUnitRow reports whether record is the unit row under the header: it
+// has no manufacturer or model, only short unit labels.
+func (t *catalogTable) isUnitRow(record []string) bool {
+	if catalogCell(record, t.manufacturerColumn()) != "" || catalogCell(record, t.modelColumn()) != "" {
+		return false
+	}
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCatalogTitle lowercases a column title and drops everything
+// but letters and digits, so "Nameplate Pmax (W)" becomes "nameplatepmaxw".
+func normalizeCatalogTitle(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// column returns the index of the first column whose title matches one of
+// names, preferring exact matches, then prefixes, then substrings; or -1.
+func (t *catalogTable) column(names ...string) int {
+	matchers := []func(title, name string) bool{
+		func(title, name string) bool { return title == name },
+		strings.HasPrefix,
+		strings.Contains,
+	}
+	for _, match := range matchers {
+		for _, name := range names {
+			for i, title := range t.titles {
+				if match(title, name) {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+func (t *catalogTable) manufacturerColumn() int {
+	return t.column("manufacturername", "manufacturer")
+}
+
+func (t *catalogTable) modelColumn() int {
+	return t.column("modelnumber")
+}
+
+// detectKind tells the three lists apart by their columns.
+func (t *catalogTable) detectKind() (string, error) {
+	switch {
+	case t.column("nameplatepmax") >= 0:
+		return CatalogPanels, nil
+	case t.column("energycapacity") >= 0:
+		return CatalogStorages, nil
+	case t.column("continuousoutputpower", "ratedoutputpower", "paco") >= 0:
+		return CatalogInverters, nil
+	}
+	return "", fmt.Errorf("%w: cannot tell whether the file lists panels, inverters or storages", models.ErrInvalidCatalogFile)
+}
+
+func catalogCell(cells []string, i int) string {
+	if i < 0 || i >= len(cells) {
+		return ""
+	}
+	return strings.Join(strings.Fields(cells[i]), " ")
+}
+
+var errCatalogValueMissing = errors.New("no value")
+
+// parseCatalogNumber reads a spreadsheet number, which may carry thousands
+// separators or a percent sign. Blank cells and placeholders such as
+// "N/A" are reported as errCatalogValueMissing.
+func parseCatalogNumber(cell string) (float64, error) {
+	s := strings.TrimSpace(cell)
+	s = strings.TrimSuffix(s, "%")
+	s = strings.ReplaceAll(s, ",", "")
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "", "-", "n/a", "na", "none":
+		return 0, errCatalogValueMissing
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// catalogField maps CEC columns onto a catalog model field.
+type catalogField struct {
+	field    string   // JSON name of the model field
+	titles   []string // normalized CEC column titles, best first
+	label    string   // how the column is named in skip reasons
+	required bool
+	signed   bool // whether negative values are valid
+	fraction bool // whether values above 1 are percentages
+	text     bool // whether the column holds text rather than a number
+	// scale converts a value to the model's unit given the lowercased
+	// header and unit text of its column.
+	scale func(unit string) float64
+}
+
+func sameUnit(string) float64 { return 1 }
+
+// hasUnit reports whether the header and unit text of a column names one
+// of units as a whole word.
+func hasUnit(text string, units ...string) bool {
+	for _, token := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && r != '%' }) {
+		for _, unit := range units {
+			if token == unit {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// kiloUnit scales a kW or kWh column that a list gives in W or Wh.
+func kiloUnit(unit string) float64 {
+	if !hasUnit(unit, "kw", "kwh", "kwac") && hasUnit(unit, "w", "wh", "wac") {
+		return 0.001
+	}
+	return 1
+}
+
+// wattUnit scales a W column that a list gives in kW.
+func wattUnit(unit string) float64 {
+	if hasUnit(unit, "kw", "kwdc") {
+		return 1000
+	}
+	return 1
+}
+
+// meterUnit scales a length in meters that a list gives in mm or cm.
+func meterUnit(unit string) float64 {
+	switch {
+	case hasUnit(unit, "mm"):
+		return 0.001
+	case hasUnit(unit, "cm"):
+		return 0.01
+	}
+	return 1
+}
+
+var catalogFields = map[string][]catalogField{
+	CatalogPanels: {
+		{field: "wattage", titles: []string{"nameplatepmax"}, label: "nameplate Pmax", required: true, scale: wattUnit},
+		{field: "longside", titles: []string{"longside"}, label: "long side", scale: meterUnit},
+		{field: "shortside", titles: []string{"shortside"}, label: "short side", scale: meterUnit},
+		{field: "noct", titles: []string{"averagenoct", "noct"}, label: "NOCT", scale: sameUnit},
+		{field: "temp_coefficient_pmax", titles: []string{"γpmax", "gammapmax", "temperaturecoefficientofpmax", "tempcoefficientpmax"}, label: "γPmax", signed: true, scale: sameUnit},
+		{field: "voc", titles: []string{"nameplatevoc", "voc"}, label: "Voc", scale: sameUnit},
+		{field: "isc", titles: []string{"nameplateisc", "isc"}, label: "Isc", scale: sameUnit},
+		{field: "vmp", titles: []string{"nameplatevpmax", "vpmax", "vmp"}, label: "Vpmax", scale: sameUnit},
+		{field: "imp", titles: []string{"nameplateipmax", "ipmax", "imp"}, label: "Ipmax", scale: sameUnit},
+		{field: "temp_coefficient_voc", titles: []string{"βvoc", "betavoc", "temperaturecoefficientofvoc"}, label: "βVoc", signed: true, scale: sameUnit},
+		{field: "temp_coefficient_isc", titles: []string{"αisc", "alphaisc", "temperaturecoefficientofisc"}, label: "αIsc", signed: true, scale: sameUnit},
+	},
+	CatalogInverters: {
+		{field: "capacity", titles: []string{"maximumcontinuousoutputpower", "continuousoutputpower", "ratedoutputpower", "paco"}, label: "continuous output power", required: true, scale: kiloUnit},
+		{field: "efficiency", titles: []string{"weightedefficiency", "cecefficiency"}, label: "weighted efficiency", fraction: true, scale: sameUnit},
+		{field: "nominal_ac_voltage", titles: []string{"nominalvoltage"}, label: "nominal voltage", scale: sameUnit},
+		{field: "mppt_voltage_min", titles: []string{"voltageminimum", "mpptlow", "vdcmin"}, label: "voltage minimum", scale: sameUnit},
+		{field: "mppt_voltage_max", titles: []string{"voltagemaximum", "mppthigh"}, label: "voltage maximum", scale: sameUnit},
+		{field: "max_dc_voltage", titles: []string{"maximumdcvoltage", "vdcmax"}, label: "maximum DC voltage", scale: sameUnit},
+	},
+	CatalogStorages: {
+		{field: "capacity", titles: []string{"nameplateenergycapacity", "energycapacity", "usableenergycapacity"}, label: "energy capacity", required: true, scale: kiloUnit},
+		{field: "usable_kwh", titles: []string{"usableenergycapacity", "usablecapacity"}, label: "usable energy capacity", scale: kiloUnit},
+		{field: "power_kw", titles: []string{"maximumcontinuousdischargerate", "continuousdischargepower", "continuouspoweroutput", "continuousdischargerate"}, label: "continuous discharge rate", scale: kiloUnit},
+		{field: "round_trip_efficiency", titles: []string{"roundtripefficiency"}, label: "round trip efficiency", fraction: true, scale: sameUnit},
+		{field: "chemistry", titles: []string{"chemistry", "technology"}, label: "chemistry", text: true, scale: sameUnit},
+	},
+}
+
+// catalogRecord is one item of a CEC list in the units of the catalog.
+// cecID is the list's own ID for the item when it has an ID column, else
+// the manufacturer and model number as listed.
+type catalogRecord struct {
+	line         int
+	cecID        string
+	manufacturer string
+	model        string
+	values       map[string]float64
+	texts        map[string]string
+}
+
+// records reads the items of a list of kind. Rows that cannot be
+// imported are returned as skips.
+func (t *catalogTable) records(kind string) ([]catalogRecord, []CatalogSkip) {
+	manufacturerCol, modelCol := t.manufacturerColumn(), t.modelColumn()
+	voltageCol := t.column("nominalvoltage")
+	idCol := t.column("cecid", "listingid")
+	type column struct {
+		catalogField
+		index int
+		scale float64
+	}
+	var columns []column
+	for _, f := range catalogFields[kind] {
+		index := t.column(f.titles...)
+		if index < 0 {
+			continue
+		}
+		columns = append(columns, column{catalogField: f, index: index, scale: f.scale(t.units[index])})
+	}
+
+	var records []catalogRecord
+	var skipped []CatalogSkip
+	for _, row := range t.rows {
+		manufacturer, model := catalogCell(row.cells, manufacturerCol), catalogCell(row.cells, modelCol)
+		if manufacturer == "" && model == "" {
+			continue
+		}
+		if manufacturer == "" || model == "" {
+			skipped = append(skipped, CatalogSkip{Line: row.line, Manufacturer: manufacturer, Model: model, Reason: "missing manufacturer or model number"})
+			continue
+		}
+		// Inverters and batteries are listed once per grid voltage; the
+		// catalog keeps each voltage as its own model, e.g. "X [240V]".
+		if voltage, err := parseCatalogNumber(catalogCell(row.cells, voltageCol)); err == nil && !strings.Contains(model, "[") {
+			model = fmt.Sprintf("%s [%sV]", model, strconv.FormatFloat(voltage, 'f', -1, 64))
+		}
+
+		cecID := catalogCell(row.cells, idCol)
+		if cecID == "" {
+			cecID = manufacturer + " | " + model
+		}
+		record := catalogRecord{line: row.line, cecID: cecID, manufacturer: manufacturer, model: model, values: map[string]float64{}, texts: map[string]string{}}
+		reason := ""
+		for _, c := range columns {
+			if c.text {
+				if v := catalogCell(row.cells, c.index); v != "" {
+					record.texts[c.field] = v
+				}
+				continue
+			}
+			v, err := parseCatalogNumber(catalogCell(row.cells, c.index))
+			if err == nil && (v >= 0 || c.signed) {
+				v *= c.scale
+				if c.fraction && v > 1 {
+					v /= 100
+				}
+				record.values[c.field] = v
+				continue
+			}
+			if c.required {
+				reason = fmt.Sprintf("invalid %s %q", c.label, catalogCell(row.cells, c.index))
+				break
+			}
+		}
+		if reason == "" {
+			for _, f := range catalogFields[kind] {
+				if f.required && record.values[f.field] <= 0 {
+					reason = fmt.Sprintf("missing %s", f.label)
+					break
+				}
+			}
+		}
+		if reason != "" {
+			skipped = append(skipped, CatalogSkip{Line: row.line, Manufacturer: manufacturer, Model: model, Reason: reason})
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, skipped
+}
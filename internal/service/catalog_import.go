@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+)
+
+// Catalog kinds, named after their hardware endpoints.
+const (
+	CatalogPanels    = "panels"
+	CatalogInverters = "inverters"
+	CatalogStorages  = "storages"
+)
+
+// CatalogImportReport describes what an import changed, or would change
+// on a dry run. Rows counts the data rows read from the file.
+type CatalogImportReport struct {
+	Kind      string              `json:"kind" example:"panels"`
+	DryRun    bool                `json:"dry_run" example:"true"`
+	Rows      int                 `json:"rows" example:"1520"`
+	Created   []CatalogItemChange `json:"created"`
+	Updated   []CatalogItemChange `json:"updated"`
+	Unchanged int                 `json:"unchanged" example:"1490"`
+	Skipped   []CatalogSkip       `json:"skipped"`
+}
+
+// CatalogItemChange is an item the import creates or updates. ID is unset
+// for items a dry run would create.
+type CatalogItemChange struct {
+	ID           int                  `json:"id,omitempty" example:"289"`
+	Line         int                  `json:"line" example:"18"`
+	Manufacturer string               `json:"manufacturer" example:"Anji Technology"`
+	Model        string               `json:"model" example:"AJP-M660-230"`
+	Changes      []CatalogFieldChange `json:"changes"`
+}
+
+// CatalogFieldChange is one field of an item the import sets. From is nil
+// for new items and fields that had no value.
+type CatalogFieldChange struct {
+	Field string `json:"field" example:"wattage"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// CatalogSkip is a row of the file the import left out.
+type CatalogSkip struct {
+	Line         int    `json:"line" example:"42"`
+	Manufacturer string `json:"manufacturer,omitempty" example:"Anji Technology"`
+	Model        string `json:"model,omitempty" example:"AJP-M660-230"`
+	Reason       string `json:"reason" example:"missing nameplate Pmax"`
+}
+
+type CatalogImportService struct {
+	hardwareRepo *repo.HardwareRepo
+}
+
+func NewCatalogImportService(hardwareRepo *repo.HardwareRepo) *CatalogImportService {
+	return &CatalogImportService{hardwareRepo: hardwareRepo}
+}
+
+// Import upserts the items of a CEC panel, inverter or battery list into
+// the catalog. Items are matched on their CEC ID, then, for items without
+// one, on manufacturer and model ignoring case and spacing, so an existing
+// item keeps its ID and the leads and quotes that reference it stay valid.
+// Removed items found in the file are restored; items missing from it are
+// left alone. kind may be empty to detect it from the file's columns. A dry
+// run reports the changes without saving them.
+func (s *CatalogImportService) Import(ctx context.Context, r io.Reader, kind string, dryRun bool) (*CatalogImportReport, error) {
+	table, err := readCatalogTable(r)
+	if err != nil {
+		return nil, err
+	}
+	if kind == "" {
+		if kind, err = table.detectKind(); err != nil {
+			return nil, err
+		}
+	} else if _, ok := catalogFields[kind]; !ok {
+		return nil, models.ErrInvalidCatalogKind
+	}
+
+	records, skipped := table.records(kind)
+	report := &CatalogImportReport{
+		Kind:    kind,
+		DryRun:  dryRun,
+		Rows:    len(records) + len(skipped),
+		Created: []CatalogItemChange{},
+		Updated: []CatalogItemChange{},
+		Skipped: skipped,
+	}
+	if report.Skipped == nil {
+		report.Skipped = []CatalogSkip{}
+	}
+
+	switch kind {
+	case CatalogPanels:
+		existing, err := s.hardwareRepo.ListAllPanels(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := importCatalog(ctx, report, records, existing, panelEntry, s.hardwareRepo.ImportPanels); err != nil {
+			return nil, err
+		}
+	case CatalogInverters:
+		existing, err := s.hardwareRepo.ListAllInverters(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := importCatalog(ctx, report, records, existing, inverterEntry, s.hardwareRepo.ImportInverters); err != nil {
+			return nil, err
+		}
+	case CatalogStorages:
+		existing, err := s.hardwareRepo.ListAllStorages(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := importCatalog(ctx, report, records, existing, storageEntry, s.hardwareRepo.ImportStorages); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// catalogEntry gives the import the fields of one panel, inverter or
+// storage that it matches on and writes. Fields are keyed by their JSON
+// names, as in catalogFields.
+type catalogEntry struct {
+	id           *int
+	manufacturer *string
+	model        *string
+	cecID        *string
+	active       *bool
+	values       map[string]*float64  // fields every item has
+	optional     map[string]**float64 // fields that may be unset
+	texts        map[string]*string
+	validate     func() error
+	// created fills in what the list does not give for a new item and
+	// returns the fields it set.
+	created func() []CatalogFieldChange
+}
+
+func panelEntry(p *models.Panel) catalogEntry {
+	return catalogEntry{
+		id: &p.ID, manufacturer: &p.Manufacturer, model: &p.Model, cecID: &p.CECID, active: &p.Active,
+		values: map[string]*float64{
+			"wattage":   &p.Wattage,
+			"longside":  &p.LongSide,
+			"shortside": &p.ShortSide,
+		},
+		optional: map[string]**float64{
+			"noct":                  &p.NOCT,
+			"temp_coefficient_pmax": &p.TempCoefficientPmax,
+			"voc":                   &p.Voc,
+			"isc":                   &p.Isc,
+			"vmp":                   &p.Vmp,
+			"imp":                   &p.Imp,
+			"temp_coefficient_voc":  &p.TempCoefficientVoc,
+			"temp_coefficient_isc":  &p.TempCoefficientIsc,
+		},
+		validate: p.Validate,
+	}
+}
+
+func inverterEntry(i *models.Inverter) catalogEntry {
+	return catalogEntry{
+		id: &i.ID, manufacturer: &i.Manufacturer, model: &i.Model, cecID: &i.CECID, active: &i.Active,
+		values: map[string]*float64{
+			"capacity": &i.Capacity,
+		},
+		optional: map[string]**float64{
+			"efficiency":         &i.Efficiency,
+			"nominal_ac_voltage": &i.NominalACVoltage,
+			"mppt_voltage_min":   &i.MPPTVoltageMin,
+			"mppt_voltage_max":   &i.MPPTVoltageMax,
+			"max_dc_voltage":     &i.MaxDCVoltage,
+		},
+		validate: i.Validate,
+		created: func() []CatalogFieldChange {
+			return []CatalogFieldChange{guessInverterType(i)}
+		},
+	}
+}
+
+func storageEntry(s *models.Storage) catalogEntry {
+	return catalogEntry{
+		id: &s.ID, manufacturer: &s.Manufacturer, model: &s.Model, cecID: &s.CECID, active: &s.Active,
+		values: map[string]*float64{
+			"capacity": &s.Capacity,
+		},
+		optional: map[string]**float64{
+			"usable_kwh":            &s.UsableKWh,
+			"power_kw":              &s.PowerKW,
+			"round_trip_efficiency": &s.RoundTripEfficiency,
+		},
+		texts: map[string]*string{
+			"chemistry": &s.Chemistry,
+		},
+		validate: s.Validate,
+	}
+}
+
+// importCatalog plans the upsert of records over the existing items into
+// report and, unless it is a dry run, saves it.
+func importCatalog[T any](ctx context.Context, report *CatalogImportReport, records []catalogRecord, existing []*T,
+	entry func(item *T) catalogEntry, save func(ctx context.Context, creates, updates []*T) error) error {
+	byCECID := make(map[string]*T, len(existing))
+	byKey := make(map[string]*T, len(existing))
+	for _, item := range existing {
+		e := entry(item)
+		if *e.cecID != "" {
+			byCECID[*e.cecID] = item
+			continue
+		}
+		byKey[catalogKey(*e.manufacturer, *e.model)] = item
+	}
+
+	var creates, updates []*T
+	seen := map[string]int{}
+	for _, record := range records {
+		key := catalogKey(record.manufacturer, record.model)
+		if line, ok := seen[key]; ok {
+			report.Skipped = append(report.Skipped, CatalogSkip{Line: record.line, Manufacturer: record.manufacturer, Model: record.model,
+				Reason: fmt.Sprintf("duplicate of line %d", line)})
+			continue
+		}
+		seen[key] = record.line
+
+		change := CatalogItemChange{Line: record.line, Manufacturer: record.manufacturer, Model: record.model}
+		item, ok := byCECID[record.cecID]
+		if !ok {
+			item, ok = byKey[key]
+			// An item is matched by name at most once, after which it
+			// carries the CEC ID.
+			delete(byKey, key)
+		}
+		if ok {
+			e := entry(item)
+			change.ID = *e.id
+			change.Manufacturer, change.Model = *e.manufacturer, *e.model
+			change.Changes = applyCatalogRecord(e, report.Kind, record, false)
+			if *e.cecID != record.cecID {
+				var from any
+				if *e.cecID != "" {
+					from = *e.cecID
+				}
+				*e.cecID = record.cecID
+				change.Changes = append(change.Changes, CatalogFieldChange{Field: "cec_id", From: from, To: record.cecID})
+			}
+			if !*e.active {
+				*e.active = true
+				change.Changes = append(change.Changes, CatalogFieldChange{Field: "active", From: false, To: true})
+			}
+			if len(change.Changes) == 0 {
+				report.Unchanged++
+				continue
+			}
+			if err := e.validate(); err != nil {
+				report.Skipped = append(report.Skipped, CatalogSkip{Line: record.line, Manufacturer: record.manufacturer, Model: record.model, Reason: err.Error()})
+				continue
+			}
+			updates = append(updates, item)
+			report.Updated = append(report.Updated, change)
+			continue
+		}
+
+		item = new(T)
+		e := entry(item)
+		*e.manufacturer, *e.model, *e.cecID, *e.active = record.manufacturer, record.model, record.cecID, true
+		change.Changes = append([]CatalogFieldChange{{Field: "cec_id", To: record.cecID}}, applyCatalogRecord(e, report.Kind, record, true)...)
+		if e.created != nil {
+			change.Changes = append(change.Changes, e.created()...)
+		}
+		if err := e.validate(); err != nil {
+			report.Skipped = append(report.Skipped, CatalogSkip{Line: record.line, Manufacturer: record.manufacturer, Model: record.model, Reason: err.Error()})
+			continue
+		}
+		creates = append(creates, item)
+		report.Created = append(report.Created, change)
+	}
+
+	sort.Slice(report.Skipped, func(i, j int) bool { return report.Skipped[i].Line < report.Skipped[j].Line })
+
+	if report.DryRun || len(creates)+len(updates) == 0 {
+		return nil
+	}
+	if err := save(ctx, creates, updates); err != nil {
+		return err
+	}
+	for i, item := range creates {
+		report.Created[i].ID = *entry(item).id
+	}
+	return nil
+}
+
+// guessInverterType sets the type of a new inverter, which the CEC list
+// does not give: units below 1 kW are taken to be microinverters. The guess
+// is listed with the item's changes and can be corrected afterwards.
+func guessInverterType(inverter *models.Inverter) CatalogFieldChange {
+	inverter.Type = models.InverterTypeString
+	if inverter.Capacity < 1 {
+		inverter.Type = models.InverterTypeMicro
+	}
+	return CatalogFieldChange{Field: "type", To: inverter.Type}
+}
+
+// applyCatalogRecord sets the fields the record has values for on the
+// item and returns those that changed, in catalogFields order.
+func applyCatalogRecord(e catalogEntry, kind string, record catalogRecord, created bool) []CatalogFieldChange {
+	var changes []CatalogFieldChange
+	for _, f := range catalogFields[kind] {
+		if f.text {
+			text, ok := record.texts[f.field]
+			field, known := e.texts[f.field]
+			if !ok || !known || *field == text {
+				continue
+			}
+			var from any
+			if *field != "" {
+				from = *field
+			}
+			*field = text
+			changes = append(changes, CatalogFieldChange{Field: f.field, From: from, To: text})
+			continue
+		}
+		value, ok := record.values[f.field]
+		if !ok {
+			continue
+		}
+		value = math.Round(value*1e6) / 1e6
+		var from any
+		if field, known := e.values[f.field]; known {
+			if !created {
+				if *field == value {
+					continue
+				}
+				from = *field
+			}
+			*field = value
+		} else if field, known := e.optional[f.field]; known {
+			if *field != nil {
+				if **field == value {
+					continue
+				}
+				from = **field
+			}
+			*field = &value
+		} else {
+			continue
+		}
+		changes = append(changes, CatalogFieldChange{Field: f.field, From: from, To: value})
+	}
+	return changes
+}
+
+func catalogKey(manufacturer, model string) string {
+	return strings.ToLower(strings.Join(strings.Fields(manufacturer), " ")) + "\x00" +
+		strings.ToLower(strings.Join(strings.Fields(model), " "))
+}
@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+)
+
+const (
+	// defaultTempCoefficientVoc is a typical crystalline-silicon βVoc in
+	// %/°C, used when the catalog panel has none.
+	defaultTempCoefficientVoc = -0.28
+	// Design temperatures assumed when neither the request nor the site's
+	// climate gives any.
+	defaultRecordLowC   = -10.0
+	defaultAverageHighC = 35.0
+	// designIrradiance is the plane-of-array irradiance, in W/m², the hot
+	// cell temperature is worked out at.
+	designIrradiance = 1000.0
+
+	// DC/AC ratios above which clipping becomes a risk, and above which the
+	// design is rejected.
+	dcACRatioModerate = 1.1
+	dcACRatioHigh     = 1.3
+	dcACRatioMax      = 1.5
+	// dcACRatioLow is the ratio below which the inverter is oversized.
+	dcACRatioLow = 0.8
+)
+
+// DesignInput describes a system to check. InverterCount is how many
+// inverters are installed; when omitted it is assumed to be one per panel
+// for microinverters and otherwise the fewest that keep the DC/AC ratio at
+// or below dcACRatioMax. RecordLowC and AverageHighC are the site's record
+// low and average summer high air temperatures; when omitted they are
+// estimated from the climate at Latitude and Longitude.
+type DesignInput struct {
+	PanelID       int      `json:"panel_id" example:"289"`
+	InverterID    int      `json:"inverter_id" example:"5557"`
+	PanelCount    int      `json:"panel_count" example:"24"`
+	InverterCount *int     `json:"inverter_count,omitempty" example:"1"`
+	RecordLowC    *float64 `json:"record_low_c,omitempty" example:"-12"`
+	AverageHighC  *float64 `json:"average_high_c,omitempty" example:"33"`
+	Latitude      *float64 `json:"latitude,omitempty" example:"37.7749"`
+	Longitude     *float64 `json:"longitude,omitempty" example:"-122.4194"`
+}
+
+// DesignIssue is a problem found with a design. Code is stable for
+// clients to match on; Message explains it.
+type DesignIssue struct {
+	Code    string `json:"code" example:"no_valid_string_length"`
+	Message string `json:"message" example:"No string length fits: at least 12 modules are needed to stay in the MPPT window at 33 °C, but at most 11 stay below 600 V at -12 °C"`
+}
+
+// DesignValidation is the outcome of checking a design. Valid is false
+// when it has errors; warnings do not make it invalid. String sizing is
+// left out when the catalog lacks the voltages it needs, which is reported
+// as a warning.
+type DesignValidation struct {
+	Valid             bool    `json:"valid" example:"true"`
+	PanelCount        int     `json:"panel_count" example:"24"`
+	DCSizeKW          float64 `json:"dc_size_kw" example:"9.6"`
+	ACSizeKW          float64 `json:"ac_size_kw" example:"7.6"`
+	InverterCount     int     `json:"inverter_count" example:"1"`
+	Microinverters    bool    `json:"microinverters" example:"false"`
+	DCACRatio         float64 `json:"dc_ac_ratio" example:"1.26"`
+	ClippingRisk      string  `json:"clipping_risk" example:"moderate" enums:"low,moderate,high"`
+	RecordLowC        float64 `json:"record_low_c" example:"-12"`
+	AverageHighC      float64 `json:"average_high_c" example:"33"`
+	TemperatureSource string  `json:"temperature_source" example:"input" enums:"input,climate,default"`
+	// Module voltages at the design temperatures: Voc on the coldest
+	// morning and Vmp with the cells at their hottest and coldest.
+	ModuleVocColdV  *float64 `json:"module_voc_cold_v,omitempty" example:"54.1"`
+	ModuleVmpHotV   *float64 `json:"module_vmp_hot_v,omitempty" example:"33.9"`
+	ModuleVmpColdV  *float64 `json:"module_vmp_cold_v,omitempty" example:"44.9"`
+	MinStringLength *int     `json:"min_string_length,omitempty" example:"6"`
+	MaxStringLength *int     `json:"max_string_length,omitempty" example:"11"`
+	// Strings lists the length of each string in the layout, and
+	// StringsPerMPPT how many share an MPPT input at most.
+	Strings        []int         `json:"strings,omitempty" example:"8,8,8"`
+	StringsPerMPPT *int          `json:"strings_per_mppt,omitempty" example:"1"`
+	Errors         []DesignIssue `json:"errors"`
+	Warnings       []DesignIssue `json:"warnings"`
+}
+
+func (v *DesignValidation) fail(code, format string, args ...any) {
+	v.Errors = append(v.Errors, DesignIssue{Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *DesignValidation) warn(code, format string, args ...any) {
+	v.Warnings = append(v.Warnings, DesignIssue{Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+type DesignService struct {
+	hardwareRepo HardwareStore
+}
+
+func NewDesignService(hardwareRepo HardwareStore) *DesignService {
+	return &DesignService{hardwareRepo: hardwareRepo}
+}
+
+// Validate checks that the panel and inverter work together for the panel
+// count at the site's temperatures.
+func (s *DesignService) Validate(ctx context.Context, input DesignInput) (*DesignValidation, error) {
+	if input.PanelCount <= 0 {
+		return nil, fmt.Errorf("%w: panel count must be greater than 0", models.ErrInvalidDesignInput)
+	}
+	if input.InverterCount != nil && *input.InverterCount <= 0 {
+		return nil, fmt.Errorf("%w: inverter count must be greater than 0", models.ErrInvalidDesignInput)
+	}
+	if (input.Latitude == nil) != (input.Longitude == nil) {
+		return nil, fmt.Errorf("%w: latitude and longitude go together", models.ErrInvalidDesignInput)
+	}
+	if input.Latitude != nil && (*input.Latitude < -90 || *input.Latitude > 90 || *input.Longitude < -180 || *input.Longitude > 180) {
+		return nil, fmt.Errorf("%w: latitude must be between -90 and 90 and longitude between -180 and 180", models.ErrInvalidDesignInput)
+	}
+	panel, err := s.hardwareRepo.GetPanelByID(ctx, input.PanelID)
+	if err != nil {
+		return nil, err
+	}
+	inverter, err := s.hardwareRepo.GetInverterByID(ctx, input.InverterID)
+	if err != nil {
+		return nil, err
+	}
+
+	low, high, source := defaultRecordLowC, defaultAverageHighC, "default"
+	switch {
+	case input.RecordLowC != nil || input.AverageHighC != nil:
+		if input.RecordLowC == nil || input.AverageHighC == nil {
+			return nil, fmt.Errorf("%w: record_low_c and average_high_c go together", models.ErrInvalidDesignInput)
+		}
+		low, high, source = *input.RecordLowC, *input.AverageHighC, "input"
+	case input.Latitude != nil:
+		low, high, err = siteDesignTemperatures(*input.Latitude, *input.Longitude)
+		if errors.Is(err, models.ErrNoClimateData) {
+			return nil, fmt.Errorf("%w: no climate data for the location, pass record_low_c and average_high_c", models.ErrInvalidDesignInput)
+		}
+		if err != nil {
+			return nil, err
+		}
+		source = "climate"
+	}
+	if low < -60 || low > 40 || high < -20 || high > 60 || low >= high {
+		return nil, fmt.Errorf("%w: record low must be between -60 and 40 °C, average high between -20 and 60 °C and above the record low", models.ErrInvalidDesignInput)
+	}
+
+	result := validateDesign(panel, inverter, input.PanelCount, input.InverterCount, low, high)
+	result.TemperatureSource = source
+	if source == "default" {
+		result.warn("temperatures_assumed", "No site temperatures were given; assumed a %.0f °C record low and %.0f °C average high", low, high)
+	}
+	return result, nil
+}
+
+// ValidateLead checks the lead's panel, inverter, panel count and inverter
+// quantity at its location. It returns an error wrapping models.ErrInvalidDesign that
+// lists the problems when the design is invalid. Leads without a panel,
+// inverter or panel count are not checked.
+func (s *DesignService) ValidateLead(ctx context.Context, lead *models.Lead) error {
+	if lead.PanelId <= 0 || lead.InverterId <= 0 || lead.PanelCount <= 0 {
+		return nil
+	}
+	input := DesignInput{
+		PanelID:       lead.PanelId,
+		InverterID:    lead.InverterId,
+		PanelCount:    lead.PanelCount,
+		InverterCount: lead.InverterQuantity,
+	}
+	// Leads have no design temperatures of their own, so where there is no
+	// climate data the defaults are assumed instead of failing the lead.
+	lat, lng := lead.Latitude, lead.Longitude
+	if _, _, err := siteDesignTemperatures(lat, lng); !errors.Is(err, models.ErrNoClimateData) {
+		input.Latitude, input.Longitude = &lat, &lng
+	}
+	result, err := s.Validate(ctx, input)
+	if err != nil {
+		return err
+	}
+	if result.Valid {
+		return nil
+	}
+	messages := make([]string, len(result.Errors))
+	for i, issue := range result.Errors {
+		messages[i] = issue.Message
+	}
+	return fmt.Errorf("%w: %s", models.ErrInvalidDesign, strings.Join(messages, "; "))
+}
+
+// siteDesignTemperatures estimates the record low and average summer high
+// from the climate grid's monthly means. Record lows in the US run about
+// 20 °C below the coldest month's mean and summer afternoon highs about
+// 8 °C above the warmest month's mean; both are rough, and an installer's
+// ASHRAE design values should be passed in when known.
+func siteDesignTemperatures(lat, lng float64) (low, high float64, err error) {
+	cell, err := climateFor(lat, lng)
+	if err != nil {
+		return 0, 0, err
+	}
+	coldest, warmest := cell.tempC[0], cell.tempC[0]
+	for _, t := range cell.tempC[1:] {
+		coldest = math.Min(coldest, t)
+		warmest = math.Max(warmest, t)
+	}
+	return round2(coldest - 20), round2(warmest + 8), nil
+}
+
+// validateDesign sizes strings for panelCount panels on inverterCount of
+// the inverter, or the assumed count when nil, at the design temperatures
+// and checks the DC/AC ratio.
+func validateDesign(panel *models.Panel, inverter *models.Inverter, panelCount int, inverterCount *int, lowC, highC float64) *DesignValidation {
+	v := &DesignValidation{
+		PanelCount:   panelCount,
+		RecordLowC:   lowC,
+		AverageHighC: highC,
+		Errors:       []DesignIssue{},
+		Warnings:     []DesignIssue{},
+	}
+	v.DCSizeKW = round2(float64(panelCount) * panel.Wattage / 1000)
+	v.Microinverters = inverter.Type == models.InverterTypeMicro
+	// Without a count, microinverters are assumed to take one panel each
+	// and string inverters to be the fewest that stay at or below
+	// dcACRatioMax. An assumed count cannot fail the ratio check, so it is
+	// reported.
+	switch {
+	case inverter.Capacity <= 0:
+	case inverterCount != nil:
+		v.InverterCount = *inverterCount
+	case v.Microinverters:
+		v.InverterCount = panelCount
+		v.warn("inverter_count_assumed", "No inverter count was given; assumed one microinverter per panel, %d in all", v.InverterCount)
+	default:
+		v.InverterCount = max(1, int(math.Ceil(v.DCSizeKW/(inverter.Capacity*dcACRatioMax))))
+		v.warn("inverter_count_assumed", "No inverter count was given; assumed %d, the fewest that keep the DC/AC ratio at or below %.2f", v.InverterCount, dcACRatioMax)
+	}
+	v.ACSizeKW = round2(inverter.Capacity * float64(v.InverterCount))
+	if v.ACSizeKW <= 0 {
+		v.fail("inverter_capacity_unknown", "Inverter %s %s has no AC capacity", inverter.Manufacturer, inverter.Model)
+		return v
+	}
+
+	v.DCACRatio = round2(v.DCSizeKW / v.ACSizeKW)
+	switch {
+	case v.DCACRatio > dcACRatioHigh:
+		v.ClippingRisk = "high"
+	case v.DCACRatio > dcACRatioModerate:
+		v.ClippingRisk = "moderate"
+	default:
+		v.ClippingRisk = "low"
+	}
+	switch {
+	case v.DCACRatio > dcACRatioMax:
+		v.fail("dc_ac_ratio_too_high", "DC/AC ratio of %.2f is above %.2f; the inverter would clip a large share of the array's output", v.DCACRatio, dcACRatioMax)
+	case v.DCACRatio > dcACRatioHigh:
+		v.warn("clipping_risk_high", "DC/AC ratio of %.2f will clip output around midday on clear days", v.DCACRatio)
+	case v.DCACRatio < dcACRatioLow:
+		v.warn("inverter_oversized", "DC/AC ratio of %.2f leaves the inverter mostly idle; a smaller inverter would do", v.DCACRatio)
+	}
+	if inverter.MaxDCPowerKW != nil {
+		perInverter := v.DCSizeKW / float64(v.InverterCount)
+		if perInverter > *inverter.MaxDCPowerKW {
+			v.fail("dc_power_too_high", "Each inverter would take %.2f kW DC, above its %.2f kW maximum", perInverter, *inverter.MaxDCPowerKW)
+		}
+	}
+
+	sizeStrings(v, panel, inverter, panelCount, lowC, highC)
+	v.Valid = len(v.Errors) == 0
+	return v
+}
+
+// sizeStrings works out how many modules may go in series so the string
+// stays below the inverter's maximum DC voltage on the coldest morning and
+// inside its MPPT window on the hottest afternoon, then lays the panels out
+// in strings of those lengths. Microinverters take one module each, so
+// only that module is checked.
+func sizeStrings(v *DesignValidation, panel *models.Panel, inverter *models.Inverter, panelCount int, lowC, highC float64) {
+	var missing []string
+	if panel.Voc == nil {
+		missing = append(missing, "panel Voc")
+	}
+	if panel.Vmp == nil {
+		missing = append(missing, "panel Vmp")
+	}
+	if inverter.MaxDCVoltage == nil {
+		missing = append(missing, "inverter max DC voltage")
+	}
+	if inverter.MPPTVoltageMin == nil {
+		missing = append(missing, "inverter MPPT voltage minimum")
+	}
+	if len(missing) > 0 {
+		v.warn("string_sizing_skipped", "String sizing was skipped; the catalog lacks the %s", strings.Join(missing, ", "))
+		return
+	}
+
+	betaVoc := defaultTempCoefficientVoc
+	if panel.TempCoefficientVoc != nil {
+		betaVoc = *panel.TempCoefficientVoc
+	}
+	// Vmp falls with temperature at about the rate power does.
+	gammaVmp := defaultTempCoefficientPmax
+	if panel.TempCoefficientPmax != nil {
+		gammaVmp = *panel.TempCoefficientPmax
+	}
+	noct := defaultNOCT
+	if panel.NOCT != nil {
+		noct = *panel.NOCT
+	}
+	hotCellC := highC + (noct-20)/800*designIrradiance
+	vocCold := *panel.Voc * (1 + betaVoc/100*(lowC-25))
+	vmpHot := *panel.Vmp * (1 + gammaVmp/100*(hotCellC-25))
+	vmpCold := *panel.Vmp * (1 + gammaVmp/100*(lowC-25))
+	v.ModuleVocColdV = ptr(round2(vocCold))
+	v.ModuleVmpHotV = ptr(round2(vmpHot))
+	v.ModuleVmpColdV = ptr(round2(vmpCold))
+
+	maxLen := int(math.Floor(*inverter.MaxDCVoltage / vocCold))
+	minLen := int(math.Ceil(*inverter.MPPTVoltageMin / vmpHot))
+	if v.Microinverters {
+		if maxLen < 1 {
+			v.fail("module_voltage_too_high", "The module's %.1f V Voc at %.0f °C is above the microinverter's %.0f V maximum", vocCold, lowC, *inverter.MaxDCVoltage)
+		}
+		if minLen > 1 {
+			v.fail("module_voltage_too_low", "The module's %.1f V Vmp on a %.0f °C day is below the microinverter's %.0f V MPPT minimum", vmpHot, highC, *inverter.MPPTVoltageMin)
+		}
+		checkMPPTCurrent(v, panel, inverter, 1)
+		return
+	}
+	v.MinStringLength, v.MaxStringLength = ptr(minLen), ptr(maxLen)
+	if maxLen < 1 {
+		v.fail("module_voltage_too_high", "A single module's %.1f V Voc at %.0f °C is above the inverter's %.0f V maximum", vocCold, lowC, *inverter.MaxDCVoltage)
+		return
+	}
+	if minLen > maxLen {
+		v.fail("no_valid_string_length", "No string length fits: at least %d modules are needed to stay in the MPPT window at %.0f °C, but at most %d stay below %.0f V at %.0f °C",
+			minLen, highC, maxLen, *inverter.MaxDCVoltage, lowC)
+		return
+	}
+
+	count := int(math.Ceil(float64(panelCount) / float64(maxLen)))
+	if count*minLen > panelCount {
+		v.fail("panel_count_not_stringable", "%d panels cannot be split into strings of %d to %d modules", panelCount, minLen, maxLen)
+		return
+	}
+	v.Strings = make([]int, count)
+	for i := range v.Strings {
+		v.Strings[i] = panelCount / count
+		if i < panelCount%count {
+			v.Strings[i]++
+		}
+	}
+
+	if inverter.MPPTVoltageMax != nil {
+		if top := float64(v.Strings[0]) * vmpCold; top > *inverter.MPPTVoltageMax {
+			v.warn("above_mppt_window", "Strings of %d modules run at %.0f V on cold days, above the %.0f V MPPT window; the inverter will track below peak power",
+				v.Strings[0], top, *inverter.MPPTVoltageMax)
+		}
+	}
+	inputs := v.InverterCount
+	if inverter.MPPTCount != nil {
+		inputs *= *inverter.MPPTCount
+	}
+	perMPPT := int(math.Ceil(float64(count) / float64(inputs)))
+	v.StringsPerMPPT = ptr(perMPPT)
+	if perMPPT > 1 && v.Strings[0] != v.Strings[count-1] {
+		v.warn("mismatched_strings", "Strings of %d and %d modules share an MPPT input; parallel strings should be the same length", v.Strings[count-1], v.Strings[0])
+	}
+	checkMPPTCurrent(v, panel, inverter, perMPPT)
+}
+
+// checkMPPTCurrent warns when the strings sharing an MPPT input carry
+// more current than it accepts; the inverter limits it and loses the rest.
+func checkMPPTCurrent(v *DesignValidation, panel *models.Panel, inverter *models.Inverter, perMPPT int) {
+	if panel.Imp == nil || inverter.MaxInputCurrent == nil {
+		return
+	}
+	if current := float64(perMPPT) * *panel.Imp; current > *inverter.MaxInputCurrent {
+		v.warn("mppt_current_too_high", "%d strings on one MPPT input carry %.1f A, above its %.1f A limit", perMPPT, current, *inverter.MaxInputCurrent)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
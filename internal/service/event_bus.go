@@ -0,0 +1,85 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+type LeadEventType string
+
+const (
+	LeadEventState         LeadEventType = "state"
+	LeadEventBuildProgress LeadEventType = "build_progress"
+	LeadEventMeshFiles     LeadEventType = "mesh_files"
+	LeadEventQuote         LeadEventType = "quote"
+	// LeadEventProposalResponse carries the *models.ProposalShare a
+	// homeowner accepted or requested changes on.
+	LeadEventProposalResponse LeadEventType = "proposal_response"
+)
+
+type LeadEvent struct {
+	Type   LeadEventType `json:"type" example:"state"`
+	LeadID int           `json:"lead_id" example:"42"`
+	Data   any           `json:"data"`
+	Time   time.Time     `json:"time"`
+}
+
+const eventSubscriberBuffer = 32
+
+// EventBus is an in-process pub/sub bus for lead events. Publishing never
+// blocks: a subscriber that falls behind misses events rather than stalling
+// the publisher. A nil *EventBus discards everything.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[int]map[chan LeadEvent]struct{}
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[int]map[chan LeadEvent]struct{})}
+}
+
+// Subscribe returns a channel of events for the lead and a function that
+// must be called to unsubscribe.
+func (b *EventBus) Subscribe(leadID int) (<-chan LeadEvent, func()) {
+	ch := make(chan LeadEvent, eventSubscriberBuffer)
+	if b == nil {
+		return ch, func() {}
+	}
+
+	b.mu.Lock()
+	if b.subscribers[leadID] == nil {
+		b.subscribers[leadID] = make(map[chan LeadEvent]struct{})
+	}
+	b.subscribers[leadID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers[leadID], ch)
+			if len(b.subscribers[leadID]) == 0 {
+				delete(b.subscribers, leadID)
+			}
+			b.mu.Unlock()
+		})
+	}
+}
+
+func (b *EventBus) Publish(leadID int, eventType LeadEventType, data any) {
+	if b == nil {
+		return
+	}
+	event := LeadEvent{Type: eventType, LeadID: leadID, Data: data, Time: time.Now()}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subscribers[leadID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Warning: dropping %s event for lead %d, subscriber is not keeping up", eventType, leadID)
+		}
+	}
+}
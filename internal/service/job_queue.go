@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+)
+
+const (
+	defaultJobMaxAttempts = 5
+	jobBaseBackoff        = 5 * time.Second
+	jobMaxBackoff         = 10 * time.Minute
+	jobPollInterval       = 2 * time.Second
+	jobStaleAfter         = 15 * time.Minute
+	jobTimeout            = 2 * time.Minute
+)
+
+// JobHandler runs a single job. Returning an error schedules a retry,
+// unless it is wrapped with permanentJobError.
+type JobHandler func(ctx context.Context, job *models.Job) error
+
+// permanentJobError is a failure a retry would not fix or would make
+// worse, such as one after an external side effect that must not be
+// repeated. The job is moved to the dead-letter table at once.
+type permanentJobError struct {
+	err error
+}
+
+func (e permanentJobError) Error() string { return e.err.Error() }
+func (e permanentJobError) Unwrap() error { return e.err }
+
+// JobFailureHandler is called once a job has exhausted its attempts and
+// has been moved to the dead-letter table.
+type JobFailureHandler func(ctx context.Context, job *models.Job, err error)
+
+type jobRegistration struct {
+	handle      JobHandler
+	onDead      JobFailureHandler
+	maxAttempts int
+}
+
+// JobQueue is a Postgres-backed job queue with a pool of workers.
+type JobQueue struct {
+	jobRepo  JobStore
+	workers  int
+	mu       sync.RWMutex
+	handlers map[string]jobRegistration
+}
+
+func NewJobQueue(jobRepo JobStore, workers int) *JobQueue {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &JobQueue{
+		jobRepo:  jobRepo,
+		workers:  workers,
+		handlers: make(map[string]jobRegistration),
+	}
+}
+
+// Register associates a job type with its handler. onDead may be nil.
+func (q *JobQueue) Register(jobType string, maxAttempts int, handle JobHandler, onDead JobFailureHandler) {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultJobMaxAttempts
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = jobRegistration{handle: handle, onDead: onDead, maxAttempts: maxAttempts}
+}
+
+// Enqueue stores a new job of the given type. payload is encoded as JSON.
+func (q *JobQueue) Enqueue(ctx context.Context, jobType string, leadID *int, payload any) error {
+	q.mu.RLock()
+	reg, ok := q.handlers[jobType]
+	q.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no handler registered for job type %q", jobType)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+	return q.jobRepo.Enqueue(ctx, &models.Job{
+		Type:        jobType,
+		LeadID:      leadID,
+		Payload:     string(data),
+		MaxAttempts: reg.maxAttempts,
+	})
+}
+
+// Start runs the worker pool until ctx is cancelled.
+func (q *JobQueue) Start(ctx context.Context) {
+	log.Printf("Starting job queue with %d workers", q.workers)
+	var wg sync.WaitGroup
+	for i := 0; i < q.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+	log.Println("Job queue stopped")
+}
+
+func (q *JobQueue) work(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		job, err := q.jobRepo.ClaimNext(ctx, jobStaleAfter)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(jobPollInterval):
+			}
+			continue
+		}
+		q.run(ctx, job)
+	}
+}
+
+func (q *JobQueue) run(ctx context.Context, job *models.Job) {
+	q.mu.RLock()
+	reg, ok := q.handlers[job.Type]
+	q.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("no handler registered for job type %q", job.Type)
+	} else {
+		jobCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+		err = safeRunJob(jobCtx, reg.handle, job)
+		cancel()
+	}
+
+	if err == nil {
+		if err := q.jobRepo.Complete(ctx, job.ID); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		return
+	}
+
+	var permanent permanentJobError
+	if job.Attempts < job.MaxAttempts && ok && !errors.As(err, &permanent) {
+		delay := jobBackoff(job.Attempts)
+		log.Printf("Job %d (%s) failed on attempt %d/%d, retrying in %s: %v", job.ID, job.Type, job.Attempts, job.MaxAttempts, delay, err)
+		if err := q.jobRepo.Retry(ctx, job, time.Now().Add(delay), err.Error()); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		return
+	}
+
+	log.Printf("Job %d (%s) failed permanently after %d attempts: %v", job.ID, job.Type, job.Attempts, err)
+	if buryErr := q.jobRepo.Bury(ctx, job, err.Error()); buryErr != nil {
+		log.Printf("Warning: %v", buryErr)
+		return
+	}
+	if ok && reg.onDead != nil {
+		reg.onDead(ctx, job, err)
+	}
+}
+
+func safeRunJob(ctx context.Context, handle JobHandler, job *models.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handle(ctx, job)
+}
+
+// jobBackoff returns an exponential delay for the given attempt number
+// (1-based), jittered between half and the full delay.
+func jobBackoff(attempt int) time.Duration {
+	backoff := jobBaseBackoff << uint(attempt-1)
+	if backoff <= 0 || backoff > jobMaxBackoff {
+		backoff = jobMaxBackoff
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
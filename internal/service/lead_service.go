@@ -2,12 +2,13 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/Bilal-Cplusoft/sunready/internal/client"
 	"github.com/Bilal-Cplusoft/sunready/internal/models"
-	"github.com/Bilal-Cplusoft/sunready/internal/repo"
 )
 
 type CreateLeadResponse struct {
@@ -17,11 +18,27 @@ type CreateLeadResponse struct {
 }
 
 type LeadService struct {
-	leadRepo          *repo.LeadRepo
-	userRepo          *repo.UserRepo
-	houseRepo         *repo.HouseRepo
+	transactor        Transactor
+	leadRepo          LeadStore
+	userRepo          UserStore
+	houseRepo         HouseStore
 	genabilityClient  *client.Agent
-	lightFusionClient *client.LightFusionClient
+	lightFusionClient client.LightFusion
+	jobQueue          *JobQueue
+	stateMachine      *LeadStateMachine
+	eventBus          *EventBus
+	designService     *DesignService
+	hardwareRepo      HardwareStore
+	hardwareDefaults  LightFusionHardwareDefaults
+}
+
+// LightFusionHardwareDefaults are the LightFusion IDs sent for catalog items
+// that have no lightfusion_id yet, so leads keep working while admins fill
+// the mapping in. A zero ID leaves items of that kind unmapped.
+type LightFusionHardwareDefaults struct {
+	PanelID    int
+	InverterID int
+	StorageID  int
 }
 
 type CreateLead struct {
@@ -36,13 +53,25 @@ type CreateLead struct {
 	Consumption       []int   `json:"consumption,omitempty"`
 	PanelId           int     `json:"panel_id" example:"1"`
 	InverterId        int     `json:"inverter_id" example:"1"`
-	Period            string  `json:"period"`
+	InverterQuantity  *int    `json:"inverter_quantity,omitempty" example:"1"`
+	StorageId         *int    `json:"storage_id,omitempty" example:"101"`
+	StorageQuantity   *int    `json:"storage_quantity,omitempty" example:"1"`
+	Period            string  `json:"period" example:"year"`
 	TargetSolarOffset int     `json:"target_solar_offset"`
 	Mode              *string `json:"mode,omitempty"`
-	Unit              string  `json:"unit"`
+	Unit              string  `json:"unit" example:"kwh"`
+}
+
+const (
+	JobTypeCreate3DProject = "lead.create_3d_project"
+	JobTypeEnrichTariff    = "lead.enrich_tariff"
+)
+
+type create3DProjectPayload struct {
+	Request client.Create3DProjectRequest `json:"request"`
 }
 
-func NewLeadService(leadRepo *repo.LeadRepo, houseRepo *repo.HouseRepo, lightFusionClient *client.LightFusionClient, userRepo *repo.UserRepo) *LeadService {
+func NewLeadService(transactor Transactor, leadRepo LeadStore, houseRepo HouseStore, lightFusionClient client.LightFusion, userRepo UserStore, jobQueue *JobQueue, stateMachine *LeadStateMachine, eventBus *EventBus, designService *DesignService, hardwareRepo HardwareStore, hardwareDefaults LightFusionHardwareDefaults) *LeadService {
 	var genClient *client.Agent
 
 	defer func() {
@@ -53,15 +82,31 @@ func NewLeadService(leadRepo *repo.LeadRepo, houseRepo *repo.HouseRepo, lightFus
 
 	genClient = client.NewAgent()
 
-	return &LeadService{
+	s := &LeadService{
+		transactor:        transactor,
 		leadRepo:          leadRepo,
 		houseRepo:         houseRepo,
 		genabilityClient:  genClient,
 		userRepo:          userRepo,
 		lightFusionClient: lightFusionClient,
+		jobQueue:          jobQueue,
+		stateMachine:      stateMachine,
+		eventBus:          eventBus,
+		designService:     designService,
+		hardwareRepo:      hardwareRepo,
+		hardwareDefaults:  hardwareDefaults,
 	}
+	jobQueue.Register(JobTypeCreate3DProject, 0, s.runCreate3DProject, s.failLead)
+	jobQueue.Register(JobTypeEnrichTariff, 0, s.runEnrichTariff, s.failLead)
+	return s
 }
 
+// CreateLead stores the lead in the initialized state and queues the
+// LightFusion 3D project and tariff enrichment jobs. The lead moves to
+// progress once the project is being created, and to done or errored when
+// LeadSyncService sees the LightFusion build finish or a job gives up. A
+// lead whose panel and inverter do not work together is rejected with
+// models.ErrInvalidDesign.
 func (s *LeadService) CreateLead(ctx context.Context, req CreateLead, userID int, projectID int) (*CreateLeadResponse, error) {
 	if _, err := s.userRepo.ExistsByID(ctx, userID); err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
@@ -85,6 +130,28 @@ func (s *LeadService) CreateLead(ctx context.Context, req CreateLead, userID int
 		TargetSolarOffset: req.TargetSolarOffset,
 		PanelId: req.PanelId,
 		InverterId: req.InverterId,
+		InverterQuantity: req.InverterQuantity,
+		StorageID: req.StorageId,
+		StorageQuantity: req.StorageQuantity,
+		HardwareType: req.HardwareType,
+		Mode: req.Mode,
+		Period: req.Period,
+		Unit: req.Unit,
+		State: models.LeadStateInitialized,
+	}
+	// LightFusion has always been sent yearly kWh when nothing was asked for.
+	if lead.Unit == "" {
+		lead.Unit = "kwh"
+	}
+	if lead.Period == "" {
+		lead.Period = "year"
+	}
+	hardware, err := s.lightFusionHardware(ctx, &lead)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.designService.ValidateLead(ctx, &lead); err != nil {
+		return nil, err
 	}
 	User, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
@@ -98,10 +165,6 @@ func (s *LeadService) CreateLead(ctx context.Context, req CreateLead, userID int
 		PostalCode: User.PostalCode,
 		Country:    User.Country,
 	}
-	hardware := client.HardwareDetails{
-		PanelID: 156,
-		InverterID: 324,
-	}
 	owner := client.HomeownerDetails{
 		FirstName: User.FirstName,
 		LastName:  User.LastName,
@@ -115,78 +178,378 @@ func (s *LeadService) CreateLead(ctx context.Context, req CreateLead, userID int
 		Address:           addressDetails,
 		TargetSolarOffset: req.TargetSolarOffset,
 		Consumption:       req.Consumption,
-        Unit: "kwh",
-        Period: "year",
-        Hardware: hardware,
-        Homeowner: owner,
+		Unit:              lead.Unit,
+		Period:            lead.Period,
+		Mode:              lead.Mode,
+		HardwareType:      lead.HardwareType,
+		Hardware:          hardware,
+		Homeowner:         owner,
+	}
+
+	// The lead is only kept together with its house and the job that
+	// provisions it; a lead without the job would never leave initialized.
+	err = s.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.leadRepo.Create(ctx, &lead); err != nil {
+			return fmt.Errorf("failed to create lead: %w", err)
+		}
+		if err := s.stateMachine.Initialize(ctx, &lead, &userID, "lead created"); err != nil {
+			return err
+		}
+		if err := s.houseRepo.Create(ctx, &house); err != nil {
+			return fmt.Errorf("failed to create house: %w", err)
+		}
+		if err := s.jobQueue.Enqueue(ctx, JobTypeCreate3DProject, &lead.ID, create3DProjectPayload{Request: reqClient}); err != nil {
+			return fmt.Errorf("failed to queue 3D project creation: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	externalID, err := s.lightFusionClient.Create3DProject(ctx, reqClient)
+	return &CreateLeadResponse{
+		Success: true,
+		LeadID:  lead.ID,
+		HouseID: int(house.ID),
+	}, nil
+}
+
+func (s *LeadService) runCreate3DProject(ctx context.Context, job *models.Job) error {
+	if job.LeadID == nil {
+		return fmt.Errorf("job %d has no lead", job.ID)
+	}
+	lead, err := s.leadRepo.GetByID(ctx, *job.LeadID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create 3D project: %w", err)
+		return err
 	}
-	if externalID == nil || externalID.LeadID == 0 {
-		return nil, fmt.Errorf("failed to create 3D project: invalid response (nil or missing LeadID)")
+	if lead.ExternalID != nil {
+		// A previous attempt created the project but failed afterwards.
+		return s.jobQueue.Enqueue(ctx, JobTypeEnrichTariff, &lead.ID, struct{}{})
 	}
 
-	lead.ExternalID = &externalID.LeadID
+	var payload create3DProjectPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return fmt.Errorf("invalid job payload: %w", err)
+	}
 
-	if err := s.leadRepo.Create(ctx, &lead); err != nil {
-		return nil, fmt.Errorf("failed to create lead: %w", err)
+	if err := s.stateMachine.Transition(ctx, lead.ID, models.LeadStateProgress, nil, "3D project creation started"); err != nil {
+		return err
 	}
-	if err := s.houseRepo.Create(ctx, &house); err != nil {
-		return nil, err
+
+	externalID, err := s.lightFusionClient.Create3DProject(ctx, payload.Request)
+	if err != nil {
+		return fmt.Errorf("failed to create 3D project: %w", err)
+	}
+	if externalID == nil || externalID.LeadID == 0 {
+		return fmt.Errorf("failed to create 3D project: invalid response (nil or missing LeadID)")
 	}
 
-	houseID := house.ID
+	// Retrying the job would create a second LightFusion project, so only
+	// the write is retried here. If it still fails the job is not retried
+	// and the lead is marked errored with the project ID in the reason.
+	err = retryLeadWrite(ctx, func() error {
+		return s.transactor.InTransaction(ctx, func(ctx context.Context) error {
+			if err := s.leadRepo.UpdateFields(ctx, lead.ID, map[string]any{"external_id": externalID.LeadID}); err != nil {
+				return err
+			}
+			return s.jobQueue.Enqueue(ctx, JobTypeEnrichTariff, &lead.ID, struct{}{})
+		})
+	})
+	if err != nil {
+		return permanentJobError{fmt.Errorf("created LightFusion project %d but failed to record it: %w", externalID.LeadID, err)}
+	}
+	return nil
+}
+
+// leadWriteAttempts and leadWriteBackoff bound retryLeadWrite.
+const (
+	leadWriteAttempts = 4
+	leadWriteBackoff  = time.Second
+)
+
+// retryLeadWrite runs write until it succeeds, doubling the wait between
+// attempts, for writes that follow an external call the job must not
+// repeat.
+func retryLeadWrite(ctx context.Context, write func() error) error {
+	delay := leadWriteBackoff
+	var err error
+	for attempt := 1; attempt <= leadWriteAttempts; attempt++ {
+		if err = write(); err == nil {
+			return nil
+		}
+		if attempt == leadWriteAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return err
+}
 
-	Lead, err := s.leadRepo.GetLeadWithUserByLeadID(ctx, lead.ID)
+func (s *LeadService) runEnrichTariff(ctx context.Context, job *models.Job) error {
+	if job.LeadID == nil {
+		return fmt.Errorf("job %d has no lead", job.ID)
+	}
+	Lead, err := s.leadRepo.GetLeadWithUserByLeadID(ctx, *job.LeadID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get lead with user by user id: %w", err)
+		return fmt.Errorf("failed to get lead with user by lead id: %w", err)
 	}
 
+	fields := map[string]any{}
 	if s.genabilityClient != nil {
+		// The account is keyed by the lead, so a retried job updates the
+		// account the previous attempt created instead of adding another.
 		accountInput := client.Account{
+			ProviderAccountID: fmt.Sprintf("sunready-lead-%d", Lead.ID),
 			Address: client.AccountAddress{
 				String:    Lead.User.Street,
-				Latitude:  lead.Latitude,
-				Longitude: lead.Longitude,
+				Latitude:  Lead.Latitude,
+				Longitude: Lead.Longitude,
 			},
 		}
 
 		accounts := client.NewAccounts(s.genabilityClient)
-		genAcc, err := accounts.Create(ctx, accountInput)
+		genAcc, err := accounts.Upsert(ctx, accountInput)
 		if err != nil {
-			log.Printf("Warning: Failed to create Genability account: %v", err)
-		} else if genAcc != nil {
-			tariffs := client.NewTariffs(s.genabilityClient)
-			tariff, err := tariffs.GetCurrent(ctx, genAcc.ID)
-			if err != nil {
-				log.Printf("Warning: Failed to get current tariff: %v", err)
-			} else if tariff != nil {
-				utilityID := int(tariff.LseID)
-				tariffID := int(tariff.ID)
-				lead.UtilityID = &utilityID
-				lead.TariffID = &tariffID
-
-				if err := s.leadRepo.Update(ctx, &lead); err != nil {
-					log.Printf("Warning: Failed to update lead with Genability data: %v", err)
-				}
-			}
+			return fmt.Errorf("failed to create Genability account: %w", err)
+		}
+		tariffs := client.NewTariffs(s.genabilityClient)
+		tariff, err := tariffs.GetCurrent(ctx, genAcc.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get current tariff: %w", err)
 		}
+		fields["utility_id"] = int(tariff.LseID)
+		fields["tariff_id"] = int(tariff.ID)
 	}
 
-	return &CreateLeadResponse{
-		Success: true,
-		LeadID:  lead.ID,
-		HouseID: int(houseID),
-	}, nil
+	if len(fields) == 0 {
+		return nil
+	}
+	return s.leadRepo.UpdateFields(ctx, Lead.ID, fields)
 }
 
-func (s *LeadService) GetMeshFiles(ctx context.Context, lead_external_id int) (*client.ProfilesFiles3DResponse, error) {
-	resp, err := s.lightFusionClient.GetProjectFiles(ctx, lead_external_id)
+func (s *LeadService) failLead(ctx context.Context, job *models.Job, cause error) {
+	if job.LeadID == nil {
+		return
+	}
+	reason := fmt.Sprintf("%s failed after %d attempts: %v", job.Type, job.Attempts, cause)
+	if err := s.stateMachine.Transition(ctx, *job.LeadID, models.LeadStateErrored, nil, reason); err != nil {
+		log.Printf("Warning: failed to mark lead %d as errored: %v", *job.LeadID, err)
+	}
+}
+
+// lightFusionHardware checks that the lead's panel, inverter and optional
+// storage are in the catalog and still offered, and returns their
+// LightFusion IDs. Items without a lightfusion_id get the configured
+// default for their kind, and are rejected when there is none. A storage
+// quantity defaults to 1.
+func (s *LeadService) lightFusionHardware(ctx context.Context, lead *models.Lead) (client.HardwareDetails, error) {
+	var hardware client.HardwareDetails
+	if lead.PanelId <= 0 || lead.InverterId <= 0 {
+		return hardware, fmt.Errorf("%w: panel_id and inverter_id are required", models.ErrInvalidLeadHardware)
+	}
+	if lead.StorageID == nil && lead.StorageQuantity != nil {
+		return hardware, fmt.Errorf("%w: storage_quantity needs a storage_id", models.ErrInvalidLeadHardware)
+	}
+	if lead.StorageID != nil && lead.StorageQuantity == nil {
+		one := 1
+		lead.StorageQuantity = &one
+	}
+	if lead.StorageQuantity != nil && (*lead.StorageQuantity < 1 || *lead.StorageQuantity > models.MaxStorageQuantity) {
+		return hardware, fmt.Errorf("%w: storage_quantity must be between 1 and %d", models.ErrInvalidLeadHardware, models.MaxStorageQuantity)
+	}
+
+	panel, err := s.hardwareRepo.GetPanelByID(ctx, lead.PanelId)
+	if err != nil {
+		return hardware, err
+	}
+	if hardware.PanelID, err = lightFusionID("panel", panel.ID, panel.Active, panel.LightFusionID, s.hardwareDefaults.PanelID); err != nil {
+		return hardware, err
+	}
+	inverter, err := s.hardwareRepo.GetInverterByID(ctx, lead.InverterId)
+	if err != nil {
+		return hardware, err
+	}
+	if hardware.InverterID, err = lightFusionID("inverter", inverter.ID, inverter.Active, inverter.LightFusionID, s.hardwareDefaults.InverterID); err != nil {
+		return hardware, err
+	}
+	if lead.StorageID != nil {
+		storage, err := s.hardwareRepo.GetStorageByID(ctx, *lead.StorageID)
+		if err != nil {
+			return hardware, err
+		}
+		storageID, err := lightFusionID("storage", storage.ID, storage.Active, storage.LightFusionID, s.hardwareDefaults.StorageID)
+		if err != nil {
+			return hardware, err
+		}
+		hardware.StorageID = &storageID
+		hardware.StorageQuantity = lead.StorageQuantity
+	}
+	return hardware, nil
+}
+
+func lightFusionID(kind string, id int, active bool, lightFusionID *int, fallback int) (int, error) {
+	if !active {
+		return 0, fmt.Errorf("%w: %s %d", models.ErrHardwareInactive, kind, id)
+	}
+	if lightFusionID == nil {
+		if fallback <= 0 {
+			return 0, fmt.Errorf("%w: %s %d", models.ErrHardwareNotMapped, kind, id)
+		}
+		log.Printf("Warning: %s %d has no lightfusion_id; sending the default LightFusion %s %d", kind, id, kind, fallback)
+		return fallback, nil
+	}
+	return *lightFusionID, nil
+}
+
+// ValidateDesign checks the lead's panel, inverter, panel count and
+// inverter quantity, as CreateLead does. Call it before saving changes to
+// the counts.
+func (s *LeadService) ValidateDesign(ctx context.Context, lead *models.Lead) error {
+	return s.designService.ValidateLead(ctx, lead)
+}
+
+// ValidateHardware checks the lead's panel, inverter and storage the way
+// CreateLead does: each must be offered and known to LightFusion, and the
+// design must work. A storage quantity defaults to 1. Call it before saving
+// changes to any of them.
+func (s *LeadService) ValidateHardware(ctx context.Context, lead *models.Lead) error {
+	if _, err := s.lightFusionHardware(ctx, lead); err != nil {
+		return err
+	}
+	return s.designService.ValidateLead(ctx, lead)
+}
+
+// LeadUpdate is a partial update of a lead; nil fields are left alone.
+// ClearStorage removes the lead's storage. State, when set, moves the lead
+// through the state machine, recording Reason.
+type LeadUpdate struct {
+	KwhUsage         *float64
+	SystemSize       *float64
+	AnnualProduction *float64
+	PanelCount       *int
+	PanelID          *int
+	InverterID       *int
+	InverterQuantity *int
+	StorageID        *int
+	ClearStorage     bool
+	StorageQuantity  *int
+	State            *models.LeadState
+	Reason           string
+}
+
+// UpdateLead applies update on behalf of a user. Changed hardware is checked
+// as on creation and changed counts against the design; both checks run
+// with the lead locked, so two concurrent updates cannot each pass them and
+// together store a design that fails. Only the changed columns are written,
+// in the same transaction as the state change: either both are stored or
+// neither is.
+func (s *LeadService) UpdateLead(ctx context.Context, leadID int, update LeadUpdate, actorID int) error {
+	return s.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		_, err := s.leadRepo.UpdateLocked(ctx, leadID, func(lead *models.Lead) (map[string]any, error) {
+			return s.applyLeadUpdate(ctx, lead, update)
+		})
+		if err != nil {
+			return err
+		}
+		if update.State != nil {
+			return s.stateMachine.Transition(ctx, leadID, *update.State, &actorID, update.Reason)
+		}
+		return nil
+	})
+}
+
+// applyLeadUpdate sets the fields of update on lead, checks the result and
+// returns the columns to write.
+func (s *LeadService) applyLeadUpdate(ctx context.Context, lead *models.Lead, update LeadUpdate) (map[string]any, error) {
+	fields := map[string]any{}
+	if update.KwhUsage != nil {
+		fields["kwh_usage"] = *update.KwhUsage
+	}
+	if update.SystemSize != nil {
+		fields["system_size"] = *update.SystemSize
+	}
+	if update.AnnualProduction != nil {
+		fields["annual_production"] = *update.AnnualProduction
+	}
+	designChanged, hardwareChanged, storageChanged := false, false, false
+	if update.PanelCount != nil {
+		designChanged = *update.PanelCount != lead.PanelCount
+		lead.PanelCount = *update.PanelCount
+		fields["panel_count"] = lead.PanelCount
+	}
+	if update.PanelID != nil {
+		hardwareChanged = *update.PanelID != lead.PanelId
+		lead.PanelId = *update.PanelID
+		fields["panel_id"] = lead.PanelId
+	}
+	if update.InverterID != nil {
+		hardwareChanged = hardwareChanged || *update.InverterID != lead.InverterId
+		lead.InverterId = *update.InverterID
+		fields["inverter_id"] = lead.InverterId
+	}
+	switch {
+	case update.ClearStorage:
+		lead.StorageID, lead.StorageQuantity = nil, nil
+		storageChanged = true
+	case update.StorageID != nil:
+		lead.StorageID = update.StorageID
+		storageChanged = true
+	}
+	if update.StorageQuantity != nil {
+		lead.StorageQuantity = update.StorageQuantity
+		storageChanged = true
+	}
+	if update.InverterQuantity != nil {
+		designChanged = designChanged || lead.InverterQuantity == nil || *update.InverterQuantity != *lead.InverterQuantity
+		lead.InverterQuantity = update.InverterQuantity
+		fields["inverter_quantity"] = *update.InverterQuantity
+	}
+
+	var err error
+	switch {
+	case hardwareChanged || storageChanged:
+		err = s.ValidateHardware(ctx, lead)
+	case designChanged:
+		err = s.ValidateDesign(ctx, lead)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if storageChanged {
+		// The check defaults a missing storage quantity to 1.
+		fields["storage_id"], fields["storage_quantity"] = nil, nil
+		if lead.StorageID != nil {
+			fields["storage_id"], fields["storage_quantity"] = *lead.StorageID, *lead.StorageQuantity
+		}
+	}
+	return fields, nil
+}
+
+func (s *LeadService) GetStateHistory(ctx context.Context, leadID int) ([]*models.LeadStateHistory, error) {
+	return s.stateMachine.History(ctx, leadID)
+}
+
+// GetMeshFiles downloads the lead's 3D mesh files and announces them to
+// event subscribers.
+func (s *LeadService) GetMeshFiles(ctx context.Context, lead *models.Lead) (*client.ProfilesFiles3DResponse, error) {
+	if lead.ExternalID == nil {
+		return nil, fmt.Errorf("lead %d has no LightFusion project", lead.ID)
+	}
+	resp, err := s.lightFusionClient.GetProjectFiles(ctx, *lead.ExternalID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get mesh files: %w", err)
 	}
+	s.eventBus.Publish(lead.ID, LeadEventMeshFiles, resp)
 	return resp, nil
 }
+
+// Subscribe streams events for the lead until the returned function is
+// called.
+func (s *LeadService) Subscribe(leadID int) (<-chan LeadEvent, func()) {
+	return s.eventBus.Subscribe(leadID)
+}
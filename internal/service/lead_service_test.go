@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/client"
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+)
+
+// leadTestEnv is a LeadService wired to in-memory stores and the fake
+// LightFusion API, with the jobs run by hand.
+type leadTestEnv struct {
+	fake        *client.FakeLightFusion
+	leadRepo    *memoryLeads
+	jobRepo     *memoryJobs
+	hardware    *memoryHardware
+	jobQueue    *JobQueue
+	leadService *LeadService
+	user        *models.User
+}
+
+// newLeadTestEnv needs neither a database nor LightFusion.
+func newLeadTestEnv(t *testing.T, defaults LightFusionHardwareDefaults) *leadTestEnv {
+	t.Helper()
+	// The Genability client refuses to start without credentials; the
+	// tariff job it runs is not part of these tests.
+	t.Setenv("GENABILITY_ID", "test")
+	t.Setenv("GENABILITY_KEY", "test")
+
+	fake := client.NewFakeLightFusion()
+	t.Cleanup(fake.Close)
+
+	users := newMemoryUsers()
+	env := &leadTestEnv{
+		fake:     fake,
+		leadRepo: newMemoryLeads(users),
+		jobRepo:  newMemoryJobs(),
+		hardware: newMemoryHardware(),
+	}
+	eventBus := NewEventBus()
+	env.jobQueue = NewJobQueue(env.jobRepo, 1)
+	env.leadService = NewLeadService(memoryTransactor{}, env.leadRepo, &memoryHouses{}, fake.Client(t.TempDir()), users, env.jobQueue,
+		NewLeadStateMachine(env.leadRepo, eventBus), eventBus, NewDesignService(env.hardware), env.hardware, defaults)
+
+	env.user = &models.User{
+		FirstName:  "Test",
+		LastName:   "Homeowner",
+		Email:      "lead-test@sunready.test",
+		Street:     "1 Market St",
+		City:       "San Francisco",
+		State:      "CA",
+		PostalCode: "94105",
+		Country:    "US",
+	}
+	users.Create(env.user)
+	return env
+}
+
+// addHardware adds a 400 W panel and a microinverter to the catalog with
+// the given LightFusion IDs, which may be nil.
+func (env *leadTestEnv) addHardware(panelLF, inverterLF *int) (*models.Panel, *models.Inverter) {
+	panel := &models.Panel{Manufacturer: "REC", Model: "REC400AA", Wattage: 400, LightFusionID: panelLF, Active: true}
+	env.hardware.CreatePanel(panel)
+	inverter := &models.Inverter{Manufacturer: "Enphase", Model: "IQ8PLUS", Capacity: 0.29, Type: models.InverterTypeMicro, LightFusionID: inverterLF, Active: true}
+	env.hardware.CreateInverter(inverter)
+	return panel, inverter
+}
+
+// runJobs runs queued jobs until the lead has a LightFusion project.
+func (env *leadTestEnv) runJobs(t *testing.T, leadID int) *models.Lead {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		lead, err := env.leadRepo.GetByID(ctx, leadID)
+		if err != nil {
+			t.Fatalf("get lead: %v", err)
+		}
+		if lead.ExternalID != nil {
+			return lead
+		}
+		job, err := env.jobRepo.ClaimNext(ctx, jobStaleAfter)
+		if err != nil {
+			t.Fatalf("claim job: %v", err)
+		}
+		if job == nil {
+			break
+		}
+		env.jobQueue.run(ctx, job)
+	}
+	t.Fatalf("lead %d never got a LightFusion project", leadID)
+	return nil
+}
+
+func TestCreateLeadThroughMeshDownload(t *testing.T) {
+	env := newLeadTestEnv(t, LightFusionHardwareDefaults{})
+	ctx := context.Background()
+	panel, inverter := env.addHardware(ptr(156), ptr(324))
+	mode := "offset"
+
+	resp, err := env.leadService.CreateLead(ctx, CreateLead{
+		ProjectID:         1,
+		Latitude:          37.7749,
+		Longitude:         -122.4194,
+		PanelCount:        25,
+		KwhUsage:          12000,
+		PanelId:           panel.ID,
+		InverterId:        inverter.ID,
+		TargetSolarOffset: 100,
+		Mode:              &mode,
+	}, env.user.ID, 1)
+	if err != nil {
+		t.Fatalf("CreateLead: %v", err)
+	}
+	lead, err := env.leadRepo.GetByID(ctx, resp.LeadID)
+	if err != nil {
+		t.Fatalf("get lead: %v", err)
+	}
+	if lead.State != models.LeadStateInitialized {
+		t.Errorf("new lead is in state %v, want initialized", lead.State)
+	}
+
+	lead = env.runJobs(t, resp.LeadID)
+	if lead.State != models.LeadStateProgress {
+		t.Errorf("lead is in state %v after project creation, want progress", lead.State)
+	}
+	projects := env.fake.CreatedProjects()
+	if len(projects) != 1 {
+		t.Fatalf("fake received %d projects, want 1", len(projects))
+	}
+	sent := projects[0]
+	if sent.Hardware.PanelID != 156 || sent.Hardware.InverterID != 324 {
+		t.Errorf("sent hardware %+v, want panel 156 and inverter 324", sent.Hardware)
+	}
+	if sent.Unit != "kwh" || sent.Period != "year" || sent.Mode == nil || *sent.Mode != mode {
+		t.Errorf("sent unit %q, period %q, mode %v", sent.Unit, sent.Period, sent.Mode)
+	}
+	if sent.Address.City != "San Francisco" || sent.Homeowner.Email != env.user.Email {
+		t.Errorf("sent address %+v, homeowner %+v", sent.Address, sent.Homeowner)
+	}
+
+	files, err := env.leadService.GetMeshFiles(ctx, lead)
+	if err != nil {
+		t.Fatalf("GetMeshFiles: %v", err)
+	}
+	if !files.Downloaded || files.ProjectID != *lead.ExternalID {
+		t.Errorf("mesh files %+v", files)
+	}
+}
+
+func TestCreateLeadUnmappedHardware(t *testing.T) {
+	ctx := context.Background()
+	req := CreateLead{ProjectID: 1, Latitude: 37.7749, Longitude: -122.4194, PanelCount: 25, TargetSolarOffset: 100}
+
+	env := newLeadTestEnv(t, LightFusionHardwareDefaults{})
+	panel, inverter := env.addHardware(nil, ptr(324))
+	req.PanelId, req.InverterId = panel.ID, inverter.ID
+	if _, err := env.leadService.CreateLead(ctx, req, env.user.ID, 1); !errors.Is(err, models.ErrHardwareNotMapped) {
+		t.Fatalf("CreateLead with an unmapped panel returned %v, want ErrHardwareNotMapped", err)
+	}
+
+	env = newLeadTestEnv(t, LightFusionHardwareDefaults{PanelID: 156, InverterID: 324})
+	env.addHardware(nil, ptr(324))
+	resp, err := env.leadService.CreateLead(ctx, req, env.user.ID, 1)
+	if err != nil {
+		t.Fatalf("CreateLead with a default panel: %v", err)
+	}
+	env.runJobs(t, resp.LeadID)
+	if projects := env.fake.CreatedProjects(); len(projects) != 1 || projects[0].Hardware.PanelID != 156 {
+		t.Errorf("fake received %+v, want the default panel 156", projects)
+	}
+}
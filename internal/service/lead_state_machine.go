@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+)
+
+// LeadStateMachine is the only place lead state should change. It rejects
+// transitions not allowed by models.LeadState and records every change in
+// lead_state_history. Successful changes are published on the event bus.
+type LeadStateMachine struct {
+	leadRepo LeadStore
+	eventBus *EventBus
+}
+
+type LeadStateEvent struct {
+	From   *models.LeadState `json:"from,omitempty" swaggertype:"integer"`
+	To     models.LeadState  `json:"to" swaggertype:"integer"`
+	Name   string            `json:"name" example:"progress"`
+	Reason string            `json:"reason,omitempty"`
+}
+
+func NewLeadStateMachine(leadRepo LeadStore, eventBus *EventBus) *LeadStateMachine {
+	return &LeadStateMachine{leadRepo: leadRepo, eventBus: eventBus}
+}
+
+// Initialize records the state a newly created lead starts in.
+func (m *LeadStateMachine) Initialize(ctx context.Context, lead *models.Lead, actorID *int, reason string) error {
+	return m.record(ctx, &models.LeadStateHistory{
+		LeadID:  lead.ID,
+		ToState: lead.State,
+		ActorID: actorID,
+		Reason:  reason,
+	})
+}
+
+// Transition moves the lead to the given state. Moving a lead to the state
+// it is already in is a no-op and is not recorded.
+func (m *LeadStateMachine) Transition(ctx context.Context, leadID int, to models.LeadState, actorID *int, reason string) error {
+	if !to.IsValid() {
+		return models.ErrInvalidLeadState
+	}
+	lead, err := m.leadRepo.GetByID(ctx, leadID)
+	if err != nil {
+		return err
+	}
+	from := lead.State
+	if from == to {
+		return nil
+	}
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: %s -> %s", models.ErrInvalidLeadStateTransition, from, to)
+	}
+	return m.record(ctx, &models.LeadStateHistory{
+		LeadID:    leadID,
+		FromState: &from,
+		ToState:   to,
+		ActorID:   actorID,
+		Reason:    reason,
+	})
+}
+
+func (m *LeadStateMachine) record(ctx context.Context, history *models.LeadStateHistory) error {
+	if err := m.leadRepo.TransitionState(ctx, history); err != nil {
+		return err
+	}
+	m.eventBus.Publish(history.LeadID, LeadEventState, LeadStateEvent{
+		From:   history.FromState,
+		To:     history.ToState,
+		Name:   history.ToState.String(),
+		Reason: history.Reason,
+	})
+	return nil
+}
+
+func (m *LeadStateMachine) History(ctx context.Context, leadID int) ([]*models.LeadStateHistory, error) {
+	if _, err := m.leadRepo.GetByID(ctx, leadID); err != nil {
+		return nil, err
+	}
+	return m.leadRepo.ListStateHistory(ctx, leadID)
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/client"
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+)
+
+const (
+	leadSyncBatchSize = 100
+	// A lead whose sync fails waits leadSyncBaseBackoff before the next
+	// try, doubling with each failure in a row up to leadSyncMaxBackoff.
+	leadSyncBaseBackoff = time.Minute
+	leadSyncMaxBackoff  = 6 * time.Hour
+)
+
+// lightFusionBuildStates maps LightFusion build states onto lead states.
+var lightFusionBuildStates = map[int]models.LeadState{
+	client.BuildStateInProgress:  models.LeadStateProgress,
+	client.BuildStateDone:        models.LeadStateDone,
+	client.BuildStateFailed:      models.LeadStateErrored,
+	client.BuildStateInitialized: models.LeadStateInitialized,
+}
+
+// LeadSyncService periodically pulls build progress and design results for
+// leads from LightFusion and writes them back to the lead.
+type LeadSyncService struct {
+	leadRepo          *repo.LeadRepo
+	lightFusionClient client.LightFusion
+	stateMachine      *LeadStateMachine
+	eventBus          *EventBus
+}
+
+type BuildProgressEvent struct {
+	Progress int `json:"progress" example:"42"`
+}
+
+func NewLeadSyncService(leadRepo *repo.LeadRepo, lightFusionClient client.LightFusion, stateMachine *LeadStateMachine, eventBus *EventBus) *LeadSyncService {
+	return &LeadSyncService{
+		leadRepo:          leadRepo,
+		lightFusionClient: lightFusionClient,
+		stateMachine:      stateMachine,
+		eventBus:          eventBus,
+	}
+}
+
+// Start syncs every interval until ctx is cancelled.
+func (s *LeadSyncService) Start(ctx context.Context, interval time.Duration) {
+	log.Printf("Starting lead sync every %s", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.SyncOnce(ctx); err != nil {
+			log.Printf("Warning: lead sync failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			log.Println("Lead sync stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncOnce syncs one batch of non-terminal leads that have a LightFusion
+// project.
+func (s *LeadSyncService) SyncOnce(ctx context.Context) error {
+	leads, err := s.leadRepo.ListForSync(ctx, leadSyncBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, lead := range leads {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := s.SyncLead(ctx, lead); err != nil {
+			log.Printf("Warning: failed to sync lead %d: %v", lead.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *LeadSyncService) SyncLead(ctx context.Context, lead *models.Lead) error {
+	if lead.ExternalID == nil {
+		return fmt.Errorf("lead %d has no LightFusion project", lead.ID)
+	}
+	now := time.Now()
+
+	data, err := s.lightFusionClient.GetLeadCompletion(ctx, *lead.ExternalID)
+	if err != nil {
+		s.recordSyncFailure(ctx, lead, now, err)
+		return err
+	}
+	buildState, ok := lightFusionBuildStates[data.Build.State]
+	if !ok {
+		err := fmt.Errorf("unknown LightFusion build state %d", data.Build.State)
+		s.recordSyncFailure(ctx, lead, now, err)
+		return err
+	}
+
+	fields := map[string]any{
+		"build_progress": data.Build.Progress,
+		"sync_status":    models.SyncStatusSynced,
+		"sync_error":     "",
+		"sync_failures":  0,
+		"next_sync_at":   nil,
+		"last_synced_at": now,
+	}
+	if data.Production.Annual > 0 {
+		fields["annual_production"] = data.Production.Annual
+	}
+	if data.House.SystemSize > 0 {
+		// LightFusion reports system size in watts.
+		fields["system_size"] = float64(data.House.SystemSize) / 1000
+	}
+	if data.House.PanelCount > 0 {
+		fields["panel_count"] = data.House.PanelCount
+	}
+	if data.House.InverterCount > 0 {
+		fields["inverter_quantity"] = data.House.InverterCount
+	}
+	if data.CurrentProvider.UtilityID != 0 {
+		fields["utility_id"] = data.CurrentProvider.UtilityID
+	}
+	if data.CurrentProvider.TariffID != 0 {
+		fields["tariff_id"] = data.CurrentProvider.TariffID
+	}
+	if err := s.leadRepo.UpdateFields(ctx, lead.ID, fields); err != nil {
+		return err
+	}
+	if data.Build.Progress != lead.BuildProgress {
+		s.eventBus.Publish(lead.ID, LeadEventBuildProgress, BuildProgressEvent{Progress: data.Build.Progress})
+	}
+
+	switch buildState {
+	case models.LeadStateDone:
+		return s.stateMachine.Transition(ctx, lead.ID, models.LeadStateDone, nil, "LightFusion build completed")
+	case models.LeadStateErrored:
+		return s.stateMachine.Transition(ctx, lead.ID, models.LeadStateErrored, nil, "LightFusion build failed")
+	case models.LeadStateProgress:
+		if lead.State == models.LeadStateInitialized {
+			return s.stateMachine.Transition(ctx, lead.ID, models.LeadStateProgress, nil, "LightFusion build started")
+		}
+	}
+	return nil
+}
+
+// recordSyncFailure marks the lead's sync as failed and holds it back from
+// the next syncs for a backoff that grows with each failure in a row.
+func (s *LeadSyncService) recordSyncFailure(ctx context.Context, lead *models.Lead, now time.Time, syncErr error) {
+	failures := lead.SyncFailures + 1
+	err := s.leadRepo.UpdateFields(ctx, lead.ID, map[string]any{
+		"sync_status":    models.SyncStatusFailed,
+		"sync_error":     syncErr.Error(),
+		"sync_failures":  failures,
+		"next_sync_at":   now.Add(leadSyncBackoff(failures)),
+		"last_synced_at": now,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to record sync failure for lead %d: %v", lead.ID, err)
+	}
+}
+
+// leadSyncBackoff returns the wait after the given number of failed syncs
+// in a row.
+func leadSyncBackoff(failures int) time.Duration {
+	backoff := leadSyncBaseBackoff << uint(failures-1)
+	if backoff <= 0 || backoff > leadSyncMaxBackoff {
+		backoff = leadSyncMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+)
+
+type MilestoneService struct {
+	leadRepo *repo.LeadRepo
+}
+
+type MilestoneStatus struct {
+	Milestone     models.Milestone      `json:"milestone" example:"installation"`
+	State         models.MilestoneState `json:"state" example:"0"`
+	Prerequisites []models.Milestone    `json:"prerequisites"`
+	Blocked       bool                  `json:"blocked"`
+}
+
+type LeadMilestones struct {
+	LeadID           int               `json:"lead_id" example:"42"`
+	Current          models.Milestone  `json:"current" example:"permitting_approved"`
+	Milestones       []MilestoneStatus `json:"milestones"`
+	InstallationDate *string           `json:"installation_date"`
+	DateNTP          *string           `json:"date_ntp"`
+	DateInstalled    *string           `json:"date_installed"`
+}
+
+type UpdateMilestoneRequest struct {
+	State models.MilestoneState `json:"state" example:"2"`
+	// Date is stored as the notice-to-proceed date for financing, the
+	// scheduled installation date for install_crew and the installed date
+	// for installation. It is ignored for other milestones.
+	Date *string `json:"date,omitempty" example:"2025-11-03"`
+}
+
+type PipelineLead struct {
+	LeadID           int                   `json:"lead_id" example:"42"`
+	UserID           *int                  `json:"user_id" example:"1"`
+	State            models.LeadState      `json:"state" example:"1"`
+	MilestoneState   models.MilestoneState `json:"milestone_state" example:"1"`
+	InstallationDate *string               `json:"installation_date"`
+	UpdatedAt        time.Time             `json:"updated_at"`
+}
+
+type PipelineStage struct {
+	Milestone models.Milestone `json:"milestone" example:"installation"`
+	Count     int              `json:"count" example:"3"`
+	Leads     []PipelineLead   `json:"leads"`
+}
+
+func NewMilestoneService(leadRepo *repo.LeadRepo) *MilestoneService {
+	return &MilestoneService{leadRepo: leadRepo}
+}
+
+func (s *MilestoneService) GetMilestones(lead *models.Lead) *LeadMilestones {
+	return buildLeadMilestones(lead)
+}
+
+// UpdateMilestone changes the state of one milestone. A milestone can only
+// be started once its prerequisites are completed, and a completed
+// milestone cannot be reopened while a milestone that depends on it has
+// already been started.
+func (s *MilestoneService) UpdateMilestone(ctx context.Context, leadID int, milestone models.Milestone, req UpdateMilestoneRequest) (*LeadMilestones, error) {
+	if !milestone.IsValid() {
+		return nil, models.ErrInvalidMilestone
+	}
+	if !req.State.IsValid() {
+		return nil, models.ErrInvalidMilestoneState
+	}
+	if req.Date != nil {
+		if _, err := time.Parse("2006-01-02", *req.Date); err != nil {
+			return nil, models.ErrInvalidMilestoneDate
+		}
+	}
+
+	// The prerequisites are checked and the milestone written with the lead
+	// locked, so two concurrent updates cannot both pass their checks.
+	lead, err := s.leadRepo.UpdateLocked(ctx, leadID, func(lead *models.Lead) (map[string]any, error) {
+		if req.State != models.MilestoneStatePending {
+			for _, prerequisite := range models.MilestonePrerequisites[milestone] {
+				if lead.Milestone(prerequisite) != models.MilestoneStateCompleted {
+					return nil, fmt.Errorf("%w: %s requires %s", models.ErrMilestoneBlocked, milestone, prerequisite)
+				}
+			}
+		}
+		if req.State != models.MilestoneStateCompleted {
+			for _, dependent := range milestoneDependents(milestone) {
+				if lead.Milestone(dependent) != models.MilestoneStatePending {
+					return nil, fmt.Errorf("%w: %s has been started", models.ErrMilestoneHasDependents, dependent)
+				}
+			}
+		}
+
+		if err := lead.SetMilestone(milestone, req.State); err != nil {
+			return nil, err
+		}
+		fields := map[string]any{string(milestone) + "_state": req.State}
+		if req.Date != nil {
+			switch milestone {
+			case models.MilestoneFinancing:
+				lead.DateNTP = req.Date
+				fields["date_ntp"] = *req.Date
+			case models.MilestoneInstallCrew:
+				lead.InstallationDate = req.Date
+				fields["installation_date"] = *req.Date
+			case models.MilestoneInstallation:
+				lead.DateInstalled = req.Date
+				fields["date_installed"] = *req.Date
+			}
+		}
+		return fields, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buildLeadMilestones(lead), nil
+}
+
+// Pipeline groups the leads by their current milestone, in milestone
+// order. Every stage has its full count but lists at most limit leads,
+// after skipping offset; only is set to list a single stage.
+func (s *MilestoneService) Pipeline(ctx context.Context, only models.Milestone, limit, offset int) ([]PipelineStage, error) {
+	counts, err := s.leadRepo.CountByMilestone(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stages := make([]PipelineStage, 0, len(models.Milestones)+1)
+	for _, m := range append(append([]models.Milestone{}, models.Milestones...), models.MilestoneComplete) {
+		if only != "" && m != only {
+			continue
+		}
+		stage := PipelineStage{Milestone: m, Count: int(counts[m]), Leads: []PipelineLead{}}
+		if stage.Count > offset {
+			leads, err := s.leadRepo.ListByMilestone(ctx, m, limit, offset)
+			if err != nil {
+				return nil, err
+			}
+			for _, lead := range leads {
+				stage.Leads = append(stage.Leads, PipelineLead{
+					LeadID:           lead.ID,
+					UserID:           lead.UserID,
+					State:            lead.State,
+					MilestoneState:   lead.Milestone(m),
+					InstallationDate: lead.InstallationDate,
+					UpdatedAt:        lead.UpdatedAt,
+				})
+			}
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+func buildLeadMilestones(lead *models.Lead) *LeadMilestones {
+	result := &LeadMilestones{
+		LeadID:           lead.ID,
+		Current:          lead.CurrentMilestone(),
+		Milestones:       make([]MilestoneStatus, 0, len(models.Milestones)),
+		InstallationDate: lead.InstallationDate,
+		DateNTP:          lead.DateNTP,
+		DateInstalled:    lead.DateInstalled,
+	}
+	for _, m := range models.Milestones {
+		prerequisites := models.MilestonePrerequisites[m]
+		blocked := false
+		for _, prerequisite := range prerequisites {
+			if lead.Milestone(prerequisite) != models.MilestoneStateCompleted {
+				blocked = true
+				break
+			}
+		}
+		if prerequisites == nil {
+			prerequisites = []models.Milestone{}
+		}
+		result.Milestones = append(result.Milestones, MilestoneStatus{
+			Milestone:     m,
+			State:         lead.Milestone(m),
+			Prerequisites: prerequisites,
+			Blocked:       blocked,
+		})
+	}
+	return result
+}
+
+func milestoneDependents(milestone models.Milestone) []models.Milestone {
+	var dependents []models.Milestone
+	for _, m := range models.Milestones {
+		for _, prerequisite := range models.MilestonePrerequisites[m] {
+			if prerequisite == milestone {
+				dependents = append(dependents, m)
+				break
+			}
+		}
+	}
+	return dependents
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+)
+
+type ProductionService struct {
+	hardwareRepo *repo.HardwareRepo
+}
+
+func NewProductionService(hardwareRepo *repo.HardwareRepo) *ProductionService {
+	return &ProductionService{hardwareRepo: hardwareRepo}
+}
+
+// EstimateForLead simulates a typical year of production for the lead's
+// system at its coordinates, using the lead's panel and inverter from the
+// catalog when they are known.
+func (s *ProductionService) EstimateForLead(ctx context.Context, lead *models.Lead, tilt, azimuth *float64) (*ProductionEstimate, error) {
+	if lead.SystemSize <= 0 {
+		return nil, fmt.Errorf("%w: lead has no system size", models.ErrInvalidProductionInput)
+	}
+	panel, inverter, err := s.leadHardware(ctx, lead)
+	if err != nil {
+		return nil, err
+	}
+	return SimulateProduction(productionInputFor(lead.Latitude, lead.Longitude, lead.SystemSize, lead.PanelCount, lead.InverterQuantity, tilt, azimuth, panel, inverter))
+}
+
+// leadHardware loads the lead's catalog panel and inverter. Hardware that is
+// not in the catalog is returned as nil so the simulator falls back to its
+// defaults.
+func (s *ProductionService) leadHardware(ctx context.Context, lead *models.Lead) (*models.Panel, *models.Inverter, error) {
+	var panel *models.Panel
+	var inverter *models.Inverter
+	var err error
+	if lead.PanelId > 0 {
+		if panel, err = s.hardwareRepo.GetPanelByID(ctx, lead.PanelId); err != nil && err != models.ErrPanelNotFound {
+			return nil, nil, err
+		}
+	}
+	if lead.InverterId > 0 {
+		if inverter, err = s.hardwareRepo.GetInverterByID(ctx, lead.InverterId); err != nil && err != models.ErrInverterNotFound {
+			return nil, nil, err
+		}
+	}
+	return panel, inverter, nil
+}
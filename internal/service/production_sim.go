@@ -0,0 +1,226 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+)
+
+// Defaults for ProductionInput fields left at zero.
+const (
+	defaultTempCoefficientPmax = -0.37 // %/°C, typical mono-Si
+	defaultNOCT                = 45.0  // °C
+	defaultSystemLosses        = 0.14  // soiling, wiring, mismatch, availability
+	defaultInverterEfficiency  = 0.96
+	defaultGroundAlbedo        = 0.2
+	defaultTiltDeg             = 20.0
+	solarConstant              = 1367.0 // W/m²
+)
+
+// ProductionInput describes a PV system to simulate. Azimuth is degrees
+// clockwise from north (180 faces south). A nil TiltDeg or AzimuthDeg
+// defaults to 20° facing the equator. InverterCapacityKW of 0 disables
+// clipping.
+type ProductionInput struct {
+	Latitude            float64  `json:"latitude" example:"37.7749"`
+	Longitude           float64  `json:"longitude" example:"-122.4194"`
+	SystemSizeKW        float64  `json:"system_size_kw" example:"8"`
+	TiltDeg             *float64 `json:"tilt_deg,omitempty" example:"20"`
+	AzimuthDeg          *float64 `json:"azimuth_deg,omitempty" example:"180"`
+	TempCoefficientPmax float64  `json:"temp_coefficient_pmax,omitempty" example:"-0.34"`
+	NOCT                float64  `json:"noct,omitempty" example:"45"`
+	InverterCapacityKW  float64  `json:"inverter_capacity_kw,omitempty" example:"7.25"`
+	SystemLosses        *float64 `json:"system_losses,omitempty" example:"0.14"`
+}
+
+// ProductionEstimate is a simulated typical year of AC output. HourlyKWh
+// starts at midnight on 1 January local standard time and has 8760 values.
+type ProductionEstimate struct {
+	Latitude           float64   `json:"latitude" example:"37.7749"`
+	Longitude          float64   `json:"longitude" example:"-122.4194"`
+	TiltDeg            float64   `json:"tilt_deg" example:"20"`
+	AzimuthDeg         float64   `json:"azimuth_deg" example:"180"`
+	SystemSizeKW       float64   `json:"system_size_kw" example:"8"`
+	InverterCapacityKW float64   `json:"inverter_capacity_kw" example:"7.25"`
+	ClimateCell        string    `json:"climate_cell" example:"35,-125"`
+	AnnualKWh          float64   `json:"annual_kwh" example:"12150"`
+	SpecificYield      float64   `json:"specific_yield_kwh_per_kw" example:"1518.75"`
+	ClippedKWh         float64   `json:"clipped_kwh" example:"42.5"`
+	MonthlyKWh         []float64 `json:"monthly_kwh"`
+	HourlyKWh          []float64 `json:"hourly_kwh,omitempty"`
+}
+
+// SimulateProduction models a typical year hour by hour: solar position,
+// horizontal irradiance from the cell's monthly clearness index, Erbs
+// diffuse split, isotropic-sky plane-of-array transposition, NOCT cell
+// temperature derating, system losses and inverter clipping.
+func SimulateProduction(in ProductionInput) (*ProductionEstimate, error) {
+	if in.Latitude < -90 || in.Latitude > 90 {
+		return nil, models.ErrInvalidLeadLatitude
+	}
+	if in.Longitude < -180 || in.Longitude > 180 {
+		return nil, models.ErrInvalidLeadLongitude
+	}
+	if in.SystemSizeKW <= 0 {
+		return nil, models.ErrInvalidQuoteSystemSize
+	}
+	tilt := defaultTiltDeg
+	if in.TiltDeg != nil {
+		tilt = *in.TiltDeg
+	}
+	azimuth := 180.0
+	if in.Latitude < 0 {
+		azimuth = 0
+	}
+	if in.AzimuthDeg != nil {
+		azimuth = *in.AzimuthDeg
+	}
+	if tilt < 0 || tilt > 90 || azimuth < 0 || azimuth >= 360 {
+		return nil, fmt.Errorf("%w: tilt must be 0-90 and azimuth 0-359 degrees", models.ErrInvalidProductionInput)
+	}
+	gamma := in.TempCoefficientPmax
+	if gamma == 0 {
+		gamma = defaultTempCoefficientPmax
+	}
+	noct := in.NOCT
+	if noct == 0 {
+		noct = defaultNOCT
+	}
+	losses := defaultSystemLosses
+	if in.SystemLosses != nil {
+		losses = *in.SystemLosses
+	}
+	if losses < 0 || losses >= 1 {
+		return nil, fmt.Errorf("%w: system losses must be between 0 and 1", models.ErrInvalidProductionInput)
+	}
+	cell, err := climateFor(in.Latitude, in.Longitude)
+	if err != nil {
+		return nil, err
+	}
+
+	rad := math.Pi / 180
+	lat := in.Latitude * rad
+	beta := tilt * rad
+	panelAz := azimuth * rad
+	normalE, normalN, normalU := math.Sin(beta)*math.Sin(panelAz), math.Sin(beta)*math.Cos(panelAz), math.Cos(beta)
+	// Hours are local standard time for the nominal time zone.
+	tzMeridian := 15 * math.Round(in.Longitude/15)
+
+	est := &ProductionEstimate{
+		Latitude:           in.Latitude,
+		Longitude:          in.Longitude,
+		TiltDeg:            tilt,
+		AzimuthDeg:         azimuth,
+		SystemSizeKW:       in.SystemSizeKW,
+		InverterCapacityKW: in.InverterCapacityKW,
+		ClimateCell:        cell.name(),
+		MonthlyKWh:         make([]float64, 12),
+		HourlyKWh:          make([]float64, 0, 8760),
+	}
+	start := time.Date(billingYear, 1, 1, 0, 0, 0, 0, time.UTC)
+	for at := start; at.Year() == billingYear; at = at.Add(time.Hour) {
+		month := int(at.Month()) - 1
+		n := float64(at.YearDay())
+
+		decl := 23.45 * rad * math.Sin(2*math.Pi*(284+n)/365)
+		b := 2 * math.Pi * (n - 81) / 364
+		eot := 9.87*math.Sin(2*b) - 7.53*math.Cos(b) - 1.5*math.Sin(b)
+		solarTime := float64(at.Hour()) + 0.5 + (4*(in.Longitude-tzMeridian)+eot)/60
+		omega := 15 * rad * (solarTime - 12)
+
+		sunE := -math.Cos(decl) * math.Sin(omega)
+		sunN := math.Sin(decl)*math.Cos(lat) - math.Cos(decl)*math.Sin(lat)*math.Cos(omega)
+		cosZenith := math.Sin(lat)*math.Sin(decl) + math.Cos(lat)*math.Cos(decl)*math.Cos(omega)
+		if cosZenith <= 0 {
+			est.HourlyKWh = append(est.HourlyKWh, 0)
+			continue
+		}
+
+		kt := cell.kt[month]
+		ghi := kt * solarConstant * (1 + 0.033*math.Cos(2*math.Pi*n/365)) * cosZenith
+		dhi := erbsDiffuseFraction(kt) * ghi
+		dni := 0.0
+		if cosZenith > 0.05 {
+			dni = (ghi - dhi) / cosZenith
+		} else {
+			dhi = ghi
+		}
+		cosAOI := sunE*normalE + sunN*normalN + cosZenith*normalU
+		poa := dni*math.Max(0, cosAOI) + dhi*(1+math.Cos(beta))/2 + ghi*defaultGroundAlbedo*(1-math.Cos(beta))/2
+
+		// Ambient temperature swings ±5 °C around the monthly mean, peaking
+		// at 15:00.
+		ambient := cell.tempC[month] + 5*math.Cos(2*math.Pi*(float64(at.Hour())-15)/24)
+		cellTemp := ambient + poa/800*(noct-20)
+		dc := in.SystemSizeKW * poa / 1000 * (1 + gamma/100*(cellTemp-25)) * (1 - losses)
+		ac := math.Max(0, dc*defaultInverterEfficiency)
+		if in.InverterCapacityKW > 0 && ac > in.InverterCapacityKW {
+			est.ClippedKWh += ac - in.InverterCapacityKW
+			ac = in.InverterCapacityKW
+		}
+		est.HourlyKWh = append(est.HourlyKWh, ac)
+		est.MonthlyKWh[month] += ac
+		est.AnnualKWh += ac
+	}
+
+	for i := range est.MonthlyKWh {
+		est.MonthlyKWh[i] = round2(est.MonthlyKWh[i])
+	}
+	est.SpecificYield = round2(est.AnnualKWh / in.SystemSizeKW)
+	est.AnnualKWh = round2(est.AnnualKWh)
+	est.ClippedKWh = round2(est.ClippedKWh)
+	return est, nil
+}
+
+// erbsDiffuseFraction is the Erbs et al. correlation for the diffuse share
+// of global horizontal irradiance.
+func erbsDiffuseFraction(kt float64) float64 {
+	switch {
+	case kt <= 0.22:
+		return 1 - 0.09*kt
+	case kt <= 0.80:
+		return 0.9511 - 0.1604*kt + 4.388*kt*kt - 16.638*kt*kt*kt + 12.336*kt*kt*kt*kt
+	default:
+		return 0.165
+	}
+}
+
+// inverterCapacityKW works out the AC capacity of inverterCount inverters.
+// Without a count, microinverters are assumed to be one per panel and
+// string inverters sized for a 1.2 DC/AC ratio.
+func inverterCapacityKW(inverter *models.Inverter, inverterCount *int, systemSizeKW float64, panelCount int) float64 {
+	if inverter == nil || inverter.Capacity <= 0 {
+		return 0
+	}
+	if inverterCount != nil {
+		return inverter.Capacity * float64(*inverterCount)
+	}
+	if inverter.Type == models.InverterTypeMicro && panelCount > 0 {
+		return inverter.Capacity * float64(panelCount)
+	}
+	return inverter.Capacity * math.Ceil(systemSizeKW/(inverter.Capacity*1.2))
+}
+
+// productionInputFor fills a simulation input from catalog hardware. Either
+// may be nil, as may inverterCount.
+func productionInputFor(lat, lng, systemSizeKW float64, panelCount int, inverterCount *int, tilt, azimuth *float64, panel *models.Panel, inverter *models.Inverter) ProductionInput {
+	in := ProductionInput{
+		Latitude:           lat,
+		Longitude:          lng,
+		SystemSizeKW:       systemSizeKW,
+		TiltDeg:            tilt,
+		AzimuthDeg:         azimuth,
+		InverterCapacityKW: inverterCapacityKW(inverter, inverterCount, systemSizeKW, panelCount),
+	}
+	if panel != nil {
+		if panel.TempCoefficientPmax != nil {
+			in.TempCoefficientPmax = *panel.TempCoefficientPmax
+		}
+		if panel.NOCT != nil {
+			in.NOCT = *panel.NOCT
+		}
+	}
+	return in
+}
@@ -0,0 +1,442 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/pdf"
+)
+
+// proposalBrand is the company styling a proposal is rendered with.
+type proposalBrand struct {
+	name      string
+	logo      []byte
+	primary   pdf.Color
+	secondary pdf.Color
+}
+
+func defaultProposalBrand() proposalBrand {
+	return proposalBrand{
+		name:      "SunReady",
+		primary:   pdf.Color{R: 245, G: 158, B: 11},
+		secondary: pdf.Color{R: 31, G: 41, B: 55},
+	}
+}
+
+var hexColor = regexp.MustCompile(`#([0-9a-fA-F]{6})\b`)
+
+// parseBrandColors reads the first two hex colors of a LightFusion
+// company's colors setting as the primary and secondary color.
+func (b *proposalBrand) parseBrandColors(colors string) {
+	for i, match := range hexColor.FindAllStringSubmatch(colors, 2) {
+		var c pdf.Color
+		fmt.Sscanf(match[1], "%02x%02x%02x", &c.R, &c.G, &c.B)
+		if i == 0 {
+			b.primary = c
+		} else {
+			b.secondary = c
+		}
+	}
+}
+
+// proposalData is everything a proposal shows. owner, panel, inverter and
+// scene are optional.
+type proposalData struct {
+	lead        *models.Lead
+	quote       *QuoteVersion
+	owner       *models.User
+	panel       *models.Panel
+	inverter    *models.Inverter
+	scene       []byte
+	brand       proposalBrand
+	generatedAt time.Time
+}
+
+var (
+	proposalText  = pdf.Color{R: 17, G: 24, B: 39}
+	proposalMuted = pdf.Color{R: 107, G: 114, B: 128}
+	proposalRule  = pdf.Color{R: 229, G: 231, B: 235}
+	proposalTile  = pdf.Color{R: 243, G: 244, B: 246}
+	proposalWhite = pdf.Color{R: 255, G: 255, B: 255}
+	proposalGain  = pdf.Color{R: 22, G: 163, B: 74}
+	proposalLoss  = pdf.Color{R: 220, G: 38, B: 38}
+	proposalGrey  = pdf.Color{R: 156, G: 163, B: 175}
+)
+
+const (
+	proposalMargin = 48.0
+	proposalWidth  = pdf.PageWidth - 2*proposalMargin
+)
+
+// renderProposal lays the proposal out on three pages: the customer and
+// system, the costs and cumulative savings, and the yearly costs,
+// financing and assumptions.
+func renderProposal(d proposalData) []byte {
+	r := d.quote.Result
+	doc := pdf.New(fmt.Sprintf("%s solar proposal", d.brand.name))
+
+	var logo, scene *pdf.Image
+	if len(d.brand.logo) > 0 {
+		img, err := doc.AddImage(d.brand.logo)
+		if err != nil {
+			log.Printf("Warning: skipping proposal logo: %v", err)
+		}
+		logo = img
+	}
+	if len(d.scene) > 0 {
+		img, err := doc.AddImage(d.scene)
+		if err != nil {
+			log.Printf("Warning: skipping proposal roof render: %v", err)
+		}
+		scene = img
+	}
+
+	// Page 1: customer and system.
+	page := doc.AddPage()
+	y := d.header(page, logo, "Solar Proposal")
+	y = d.customer(page, y)
+	if scene != nil {
+		w, h := fitImage(scene, proposalWidth, 240)
+		page.Image(scene, proposalMargin+(proposalWidth-w)/2, y, w, h)
+		y += h + 8
+		page.TextCenter(pdf.PageWidth/2, y+8, pdf.Helvetica, 8, proposalMuted, "Roof design")
+		y += 24
+	}
+	y = d.section(page, y, "Your system")
+	rows := [][2]string{
+		{"System size", fmt.Sprintf("%s kW DC", formatNumber(r.SystemSizeKW, 2))},
+		{"Panels", fmt.Sprintf("%d", r.PanelCount)},
+		{"Estimated annual production", fmt.Sprintf("%s kWh", formatNumber(r.AnnualProductionKWh, 0))},
+		{"Electricity offset", fmt.Sprintf("%s%%", formatNumber(r.ElectricalOffset, 0))},
+		{"Production estimate", productionSourceLabel(r.ProductionSource)},
+	}
+	if d.panel != nil {
+		rows = append(rows, [2]string{"Panel", fmt.Sprintf("%s %s, %s W", d.panel.Manufacturer, d.panel.Model, formatNumber(d.panel.Wattage, 0))})
+	}
+	if d.inverter != nil {
+		rows = append(rows, [2]string{"Inverter", fmt.Sprintf("%s %s, %s kW", d.inverter.Manufacturer, d.inverter.Model, formatNumber(d.inverter.Capacity, 2))})
+	}
+	d.table(page, y, rows)
+	d.footer(page, 1)
+
+	// Page 2: costs, savings and cash flow.
+	page = doc.AddPage()
+	y = d.header(page, logo, "Costs & Savings")
+	tiles := [][2]string{
+		{"Net system cost", formatMoney(r.SystemCostAfterIncentives)},
+		{"Monthly payment", formatMoney(r.EstimatedMonthlyPayment)},
+		{"First-year savings", formatMoney(r.FirstYearSavings)},
+		{"25-year savings", formatMoney(r.TwentyFiveYearSavings)},
+	}
+	tileWidth := (proposalWidth - 3*10) / 4
+	for i, tile := range tiles {
+		x := proposalMargin + float64(i)*(tileWidth+10)
+		page.Rect(x, y, tileWidth, 52, proposalTile)
+		page.Rect(x, y, 3, 52, d.brand.primary)
+		page.Text(x+12, y+20, pdf.Helvetica, 8, proposalMuted, tile[0])
+		page.Text(x+12, y+40, pdf.HelveticaBold, 15, proposalText, tile[1])
+	}
+	y += 72
+
+	y = d.section(page, y, "System cost")
+	rows = [][2]string{{"System cost before incentives", formatMoney(r.SystemCostBeforeIncentives)}}
+	rows = append(rows, [2]string{"Federal tax credit", "-" + formatMoney(r.FederalTaxCredit)})
+	for _, incentive := range r.Incentives {
+		if incentive.PaymentYears == 0 {
+			rows = append(rows, [2]string{incentive.Name, "-" + formatMoney(incentive.Amount)})
+		}
+	}
+	rows = append(rows, [2]string{"Net system cost", formatMoney(r.SystemCostAfterIncentives)})
+	for _, incentive := range r.Incentives {
+		if incentive.PaymentYears > 0 {
+			rows = append(rows, [2]string{fmt.Sprintf("%s, paid over %d years", incentive.Name, incentive.PaymentYears), formatMoney(incentive.Amount)})
+		}
+	}
+	y = d.table(page, y, rows) + 10
+
+	y = d.section(page, y, "Your electric bill")
+	payback := "-"
+	if r.SimplePaybackYears > 0 {
+		payback = fmt.Sprintf("%s years", formatNumber(r.SimplePaybackYears, 1))
+	}
+	y = d.table(page, y, [][2]string{
+		{"Current monthly bill", formatMoney(r.CurrentMonthlyBill)},
+		{"Estimated monthly bill with solar", formatMoney(r.EstimatedNewMonthlyBill)},
+		{"Monthly savings", formatMoney(r.MonthlySavings)},
+		{"Simple payback", payback},
+	}) + 10
+
+	cumulative := make([]float64, len(r.CashFlow))
+	without := make([]float64, len(r.CashFlow))
+	with := make([]float64, len(r.CashFlow))
+	for i, year := range r.CashFlow {
+		cumulative[i] = year.CumulativeSavings
+		without[i] = year.BillWithoutSolar
+		with[i] = year.BillWithSolar + year.LoanPayments + year.OperatingCosts
+	}
+	y = d.section(page, y, "Cumulative savings")
+	if len(r.CashFlow) > 0 {
+		barChart(page, y, 130, [][]float64{cumulative}, []pdf.Color{proposalGain}, true)
+	} else {
+		page.Text(proposalMargin, y+12, pdf.Helvetica, 9, proposalMuted, "A cash-flow projection is not available for this quote version.")
+	}
+	d.footer(page, 2)
+
+	// Page 3: yearly costs, financing and assumptions.
+	page = doc.AddPage()
+	y = d.header(page, logo, "Financing & Assumptions")
+	if len(r.CashFlow) > 0 {
+		y = d.section(page, y, "Yearly cost: utility only vs. with solar")
+		y = barChart(page, y, 130, [][]float64{without, with}, []pdf.Color{proposalGrey, d.brand.primary}, false)
+		legend(page, y+12, []string{"Utility only", "With solar (bill, payments and upkeep)"}, []pdf.Color{proposalGrey, d.brand.primary})
+		y += 36
+	}
+	if len(r.Financing) > 0 {
+		y = d.section(page, y, "Financing options")
+		columns := []string{"Option", "Upfront", "Monthly", "Term", "Lifetime savings", "Break-even"}
+		widths := []float64{156, 70, 70, 50, 100, 70}
+		x := proposalMargin
+		for i, column := range columns {
+			page.Text(x, y, pdf.HelveticaBold, 8, proposalMuted, column)
+			x += widths[i]
+		}
+		y += 6
+		for _, option := range r.Financing {
+			page.Line(proposalMargin, y, proposalMargin+proposalWidth, y, 0.5, proposalRule)
+			term, breakEven := "-", "-"
+			if option.TermYears > 0 {
+				term = fmt.Sprintf("%d yr", option.TermYears)
+			}
+			if option.BreakEvenYear > 0 {
+				breakEven = fmt.Sprintf("Year %d", option.BreakEvenYear)
+			}
+			cells := []string{option.Name, formatMoney(option.UpfrontCost), formatMoney(option.MonthlyPayment), term, formatMoney(option.LifetimeSavings), breakEven}
+			x = proposalMargin
+			for i, cell := range cells {
+				page.Text(x, y+14, pdf.Helvetica, 9, proposalText, cell)
+				x += widths[i]
+			}
+			y += 20
+		}
+		y += 14
+	}
+	a := d.quote.Assumptions
+	y = d.section(page, y, "Assumptions")
+	y = d.table(page, y, [][2]string{
+		{"Cost per watt", formatMoney2(a.CostPerWatt)},
+		{"Utility rate", fmt.Sprintf("%s/kWh", formatMoney2(a.UtilityRatePerKWh))},
+		{"Annual utility rate increase", formatPercent(a.AnnualUtilityIncrease)},
+		{"Federal tax credit", formatPercent(a.FederalTaxCredit)},
+		{"Loan", fmt.Sprintf("%s over %d years", formatPercent(a.LoanInterestRate), a.LoanTermYears)},
+		{"Panel degradation", fmt.Sprintf("%s in year 1, %s per year after", formatPercent(a.FirstYearDegradation), formatPercent(a.AnnualDegradation))},
+	}) + 16
+	page.Paragraph(proposalMargin, y, proposalWidth, pdf.Helvetica, 9, 13, proposalText, r.Summary)
+	d.footer(page, 3)
+
+	return doc.Bytes()
+}
+
+// header draws the branded band at the top of a page and returns where the
+// content starts.
+func (d proposalData) header(page *pdf.Page, logo *pdf.Image, title string) float64 {
+	page.Rect(0, 0, pdf.PageWidth, 84, d.brand.secondary)
+	page.Rect(0, 84, pdf.PageWidth, 4, d.brand.primary)
+	x := proposalMargin
+	if logo != nil {
+		w, h := fitImage(logo, 120, 44)
+		page.Image(logo, x, 42-h/2, w, h)
+		x += w + 14
+	}
+	page.Text(x, 40, pdf.HelveticaBold, 18, proposalWhite, d.brand.name)
+	page.Text(x, 58, pdf.Helvetica, 9, proposalWhite, fmt.Sprintf("Proposal %d, quote version %d", d.lead.ID, d.quote.Version))
+	page.TextRight(pdf.PageWidth-proposalMargin, 40, pdf.HelveticaBold, 16, proposalWhite, title)
+	page.TextRight(pdf.PageWidth-proposalMargin, 58, pdf.Helvetica, 9, proposalWhite, d.generatedAt.Format("January 2, 2006"))
+	return 120
+}
+
+func (d proposalData) customer(page *pdf.Page, y float64) float64 {
+	page.Text(proposalMargin, y, pdf.Helvetica, 8, proposalMuted, "PREPARED FOR")
+	y += 18
+	if d.owner == nil {
+		page.Text(proposalMargin, y, pdf.HelveticaBold, 14, proposalText, fmt.Sprintf("Lead %d", d.lead.ID))
+		return y + 28
+	}
+	o := d.owner
+	page.Text(proposalMargin, y, pdf.HelveticaBold, 14, proposalText, strings.TrimSpace(o.FirstName+" "+o.LastName))
+	y += 16
+	lines := []string{o.Street, strings.TrimSpace(strings.Trim(fmt.Sprintf("%s, %s %s", o.City, o.State, o.PostalCode), ", ")), o.Email, o.PhoneNumber}
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		page.Text(proposalMargin, y, pdf.Helvetica, 10, proposalText, line)
+		y += 14
+	}
+	return y + 16
+}
+
+func (d proposalData) section(page *pdf.Page, y float64, title string) float64 {
+	page.Text(proposalMargin, y+12, pdf.HelveticaBold, 12, d.brand.secondary, title)
+	page.Line(proposalMargin, y+18, proposalMargin+proposalWidth, y+18, 1, d.brand.primary)
+	return y + 26
+}
+
+// table draws label and value rows and returns the y below the last row.
+func (d proposalData) table(page *pdf.Page, y float64, rows [][2]string) float64 {
+	for i, row := range rows {
+		if i > 0 {
+			page.Line(proposalMargin, y, proposalMargin+proposalWidth, y, 0.5, proposalRule)
+		}
+		page.Text(proposalMargin, y+13, pdf.Helvetica, 10, proposalText, row[0])
+		page.TextRight(proposalMargin+proposalWidth, y+13, pdf.HelveticaBold, 10, proposalText, row[1])
+		y += 19
+	}
+	return y
+}
+
+func (d proposalData) footer(page *pdf.Page, number int) {
+	y := pdf.PageHeight - 36
+	page.Line(proposalMargin, y-12, proposalMargin+proposalWidth, y-12, 0.5, proposalRule)
+	page.Text(proposalMargin, y, pdf.Helvetica, 7, proposalMuted,
+		"Savings are estimates based on the assumptions listed in this proposal and are not guaranteed.")
+	page.TextRight(proposalMargin+proposalWidth, y, pdf.Helvetica, 7, proposalMuted, fmt.Sprintf("%s  |  Page %d", d.brand.name, number))
+}
+
+// barChart draws one bar per year for each series side by side, with a
+// labelled value axis, and returns the y below the year labels. signed
+// colors negative values of a single series as losses.
+func barChart(page *pdf.Page, y, height float64, series [][]float64, colors []pdf.Color, signed bool) float64 {
+	const axisWidth = 52.0
+	lo, hi := 0.0, 0.0
+	for _, values := range series {
+		for _, v := range values {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	step := niceStep((hi - lo) / 4)
+	lo, hi = math.Floor(lo/step)*step, math.Ceil(hi/step)*step
+	if hi == lo {
+		hi = lo + step
+	}
+	plotX, plotWidth := proposalMargin+axisWidth, proposalWidth-axisWidth
+	scale := height / (hi - lo)
+	valueY := func(v float64) float64 { return y + (hi-v)*scale }
+
+	for v := lo; v <= hi+step/2; v += step {
+		page.Line(plotX, valueY(v), plotX+plotWidth, valueY(v), 0.5, proposalRule)
+		page.TextRight(plotX-6, valueY(v)+3, pdf.Helvetica, 7, proposalMuted, formatMoneyShort(v))
+	}
+	years := len(series[0])
+	slot := plotWidth / float64(years)
+	barWidth := slot * 0.7 / float64(len(series))
+	for i := 0; i < years; i++ {
+		for s, values := range series {
+			v := values[i]
+			c := colors[s]
+			if signed && v < 0 {
+				c = proposalLoss
+			}
+			top, bottom := valueY(math.Max(v, 0)), valueY(math.Min(v, 0))
+			page.Rect(plotX+float64(i)*slot+slot*0.15+float64(s)*barWidth, top, barWidth, bottom-top, c)
+		}
+		if years <= 12 || (i+1)%5 == 0 || i == 0 {
+			page.TextCenter(plotX+(float64(i)+0.5)*slot, y+height+11, pdf.Helvetica, 7, proposalMuted, fmt.Sprintf("%d", i+1))
+		}
+	}
+	page.Line(plotX, valueY(0), plotX+plotWidth, valueY(0), 0.8, proposalMuted)
+	page.TextCenter(plotX+plotWidth/2, y+height+22, pdf.Helvetica, 7, proposalMuted, "Year")
+	return y + height + 24
+}
+
+func legend(page *pdf.Page, y float64, labels []string, colors []pdf.Color) {
+	x := proposalMargin + 52
+	for i, label := range labels {
+		page.Rect(x, y-7, 8, 8, colors[i])
+		page.Text(x+12, y, pdf.Helvetica, 8, proposalText, label)
+		x += 24 + pdf.TextWidth(pdf.Helvetica, 8, label)
+	}
+}
+
+// niceStep rounds a raw axis step up to 1, 2 or 5 times a power of ten.
+func niceStep(raw float64) float64 {
+	if raw <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if raw <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// fitImage scales img to fit in a w by h box, keeping its aspect ratio.
+func fitImage(img *pdf.Image, w, h float64) (float64, float64) {
+	scale := math.Min(w/float64(img.Width), h/float64(img.Height))
+	return float64(img.Width) * scale, float64(img.Height) * scale
+}
+
+func productionSourceLabel(source string) string {
+	switch source {
+	case ProductionSourceRequest:
+		return "System design"
+	case ProductionSourceSimulation:
+		return "Hourly weather simulation"
+	default:
+		return "Average sun hours"
+	}
+}
+
+// formatNumber formats v with thousands separators.
+func formatNumber(v float64, decimals int) string {
+	s := fmt.Sprintf("%.*f", decimals, math.Abs(v))
+	whole, fraction, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		b.WriteString("." + fraction)
+	}
+	return b.String()
+}
+
+func formatMoney(v float64) string {
+	s := formatNumber(v, 0)
+	if strings.HasPrefix(s, "-") {
+		return "-$" + s[1:]
+	}
+	return "$" + s
+}
+
+func formatMoney2(v float64) string {
+	return "$" + formatNumber(v, 2)
+}
+
+// formatMoneyShort formats axis values as $12k.
+func formatMoneyShort(v float64) string {
+	if math.Abs(v) < 1000 {
+		return formatMoney(v)
+	}
+	s := strings.TrimSuffix(formatNumber(v/1000, 1), ".0") + "k"
+	if strings.HasPrefix(s, "-") {
+		return "-$" + s[1:]
+	}
+	return "$" + s
+}
+
+func formatPercent(fraction float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", fraction*100), "0"), ".") + "%"
+}
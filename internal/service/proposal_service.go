@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/client"
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+)
+
+type ProposalService struct {
+	proposalRepo      *repo.ProposalRepo
+	quoteRepo         *repo.QuoteRepo
+	userRepo          *repo.UserRepo
+	hardwareRepo      *repo.HardwareRepo
+	lightFusionClient client.LightFusion
+}
+
+func NewProposalService(proposalRepo *repo.ProposalRepo, quoteRepo *repo.QuoteRepo, userRepo *repo.UserRepo, hardwareRepo *repo.HardwareRepo, lightFusionClient client.LightFusion) *ProposalService {
+	return &ProposalService{
+		proposalRepo:      proposalRepo,
+		quoteRepo:         quoteRepo,
+		userRepo:          userRepo,
+		hardwareRepo:      hardwareRepo,
+		lightFusionClient: lightFusionClient,
+	}
+}
+
+// GetProposal returns the proposal for a quote version of the lead, or for
+// its latest quote when version is 0. The PDF is rendered and stored the
+// first time a version is asked for and served from storage afterwards.
+func (s *ProposalService) GetProposal(ctx context.Context, lead *models.Lead, version, userID int) (*models.Proposal, error) {
+	var quote *models.Quote
+	var err error
+	if version > 0 {
+		quote, err = s.quoteRepo.GetByLeadVersion(ctx, lead.ID, version)
+	} else {
+		quote, err = s.quoteRepo.GetLatestByLead(ctx, lead.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := s.proposalRepo.GetByQuoteID(ctx, quote.ID)
+	if err == nil {
+		return proposal, nil
+	}
+	if err != models.ErrProposalNotFound {
+		return nil, err
+	}
+
+	v, err := decodeQuote(quote)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.proposalData(ctx, lead, v)
+	if err != nil {
+		return nil, err
+	}
+	content := renderProposal(data)
+	sum := sha256.Sum256(content)
+	proposal = &models.Proposal{
+		LeadID:       lead.ID,
+		QuoteID:      quote.ID,
+		QuoteVersion: quote.Version,
+		UserID:       userID,
+		FileName:     fmt.Sprintf("proposal-%d-v%d.pdf", lead.ID, quote.Version),
+		SizeBytes:    len(content),
+		SHA256:       hex.EncodeToString(sum[:]),
+		Content:      content,
+	}
+	if err := s.proposalRepo.Create(ctx, proposal); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+func (s *ProposalService) ListProposals(ctx context.Context, leadID int) ([]*models.Proposal, error) {
+	return s.proposalRepo.ListByLead(ctx, leadID)
+}
+
+// proposalData gathers what the proposal shows. Hardware missing from the
+// catalog is left out; branding and the roof render come from LightFusion
+// and fall back to the SunReady defaults and no render when it cannot
+// provide them.
+func (s *ProposalService) proposalData(ctx context.Context, lead *models.Lead, v *QuoteVersion) (proposalData, error) {
+	d := proposalData{lead: lead, quote: v, brand: defaultProposalBrand(), generatedAt: time.Now()}
+	if lead.UserID != nil {
+		owner, err := s.userRepo.GetByID(ctx, *lead.UserID)
+		if err != nil {
+			return proposalData{}, fmt.Errorf("failed to get lead owner: %w", err)
+		}
+		d.owner = owner
+	}
+
+	var err error
+	if d.panel, d.inverter, err = quotedHardware(ctx, s.hardwareRepo, lead, &v.Input); err != nil {
+		return proposalData{}, err
+	}
+
+	if lead.ExternalID == nil {
+		return d, nil
+	}
+	if completion, err := s.lightFusionClient.GetLeadCompletion(ctx, *lead.ExternalID); err != nil {
+		log.Printf("Warning: rendering proposal for lead %d without company branding: %v", lead.ID, err)
+	} else {
+		company := completion.Company
+		if company.Name != "" {
+			d.brand.name = company.Name
+		}
+		if company.Colors != nil {
+			d.brand.parseBrandColors(*company.Colors)
+		}
+		if company.LogoPath != "" {
+			if logo, err := s.lightFusionClient.GetAsset(ctx, company.LogoPath); err != nil {
+				log.Printf("Warning: rendering proposal for lead %d without company logo: %v", lead.ID, err)
+			} else {
+				d.brand.logo = logo
+			}
+		}
+	}
+	files, err := s.lightFusionClient.GetProjectFiles(ctx, *lead.ExternalID)
+	if err != nil || files.JPGPath == "" {
+		log.Printf("Warning: rendering proposal for lead %d without roof render: %v", lead.ID, err)
+		return d, nil
+	}
+	if d.scene, err = os.ReadFile(files.JPGPath); err != nil {
+		log.Printf("Warning: rendering proposal for lead %d without roof render: %v", lead.ID, err)
+	}
+	return d, nil
+}
+
+// quotedHardware loads the catalog panel and inverter a quote was made
+// with, falling back to the lead's when the quote names none. Hardware
+// missing from the catalog is returned as nil. input may be nil.
+func quotedHardware(ctx context.Context, hardwareRepo *repo.HardwareRepo, lead *models.Lead, input *QuoteInput) (*models.Panel, *models.Inverter, error) {
+	panelID, inverterID := lead.PanelId, lead.InverterId
+	if input != nil && input.PanelID != nil {
+		panelID = *input.PanelID
+	}
+	if input != nil && input.InverterID != nil {
+		inverterID = *input.InverterID
+	}
+	var panel *models.Panel
+	var inverter *models.Inverter
+	var err error
+	if panelID > 0 {
+		if panel, err = hardwareRepo.GetPanelByID(ctx, panelID); err != nil && err != models.ErrPanelNotFound {
+			return nil, nil, err
+		}
+	}
+	if inverterID > 0 {
+		if inverter, err = hardwareRepo.GetInverterByID(ctx, inverterID); err != nil && err != models.ErrInverterNotFound {
+			return nil, nil, err
+		}
+	}
+	return panel, inverter, nil
+}
@@ -0,0 +1,453 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/client"
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+)
+
+const JobTypeNotifyProposalResponse = "proposal.notify_response"
+
+const (
+	defaultProposalShareHours = 7 * 24
+	maxProposalShareHours     = 30 * 24
+	maxShareResponseLength    = 2000
+	// LightFusion details shown on shared proposals are fetched at most
+	// once per shareDetailsTTL for each lead, or shareDetailsRetry after
+	// LightFusion failed to provide them.
+	shareDetailsTTL   = 15 * time.Minute
+	shareDetailsRetry = time.Minute
+)
+
+type ProposalShareService struct {
+	transactor        *repo.Transactor
+	shareRepo         *repo.ProposalShareRepo
+	leadRepo          *repo.LeadRepo
+	quoteRepo         *repo.QuoteRepo
+	userRepo          *repo.UserRepo
+	hardwareRepo      *repo.HardwareRepo
+	proposalService   *ProposalService
+	lightFusionClient client.LightFusion
+	sendGridClient    *client.SendGridClient
+	jobQueue          *JobQueue
+	eventBus          *EventBus
+	key               []byte
+
+	detailsMu sync.Mutex
+	details   map[int]*shareDetails
+}
+
+// shareDetails is what a shared proposal shows from LightFusion for a
+// project, cached because share links are opened anonymously and often.
+type shareDetails struct {
+	expires     time.Time
+	accessCode  string
+	companyName string
+	meshFiles   *ProposalMeshFiles
+}
+
+// NewProposalShareService signs share codes with a key derived from
+// secret, so codes stay valid exactly as long as the secret does.
+func NewProposalShareService(transactor *repo.Transactor, shareRepo *repo.ProposalShareRepo, leadRepo *repo.LeadRepo, quoteRepo *repo.QuoteRepo, userRepo *repo.UserRepo, hardwareRepo *repo.HardwareRepo, proposalService *ProposalService, lightFusionClient client.LightFusion, sendGridClient *client.SendGridClient, jobQueue *JobQueue, eventBus *EventBus, secret string) *ProposalShareService {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("proposal-share"))
+	s := &ProposalShareService{
+		transactor:        transactor,
+		shareRepo:         shareRepo,
+		leadRepo:          leadRepo,
+		quoteRepo:         quoteRepo,
+		userRepo:          userRepo,
+		hardwareRepo:      hardwareRepo,
+		proposalService:   proposalService,
+		lightFusionClient: lightFusionClient,
+		sendGridClient:    sendGridClient,
+		jobQueue:          jobQueue,
+		eventBus:          eventBus,
+		key:               mac.Sum(nil),
+		details:           map[int]*shareDetails{},
+	}
+	jobQueue.Register(JobTypeNotifyProposalResponse, 0, s.runNotifyResponse, nil)
+	return s
+}
+
+type CreateProposalShareRequest struct {
+	// ExpiresInHours defaults to 168 (one week); at most 720.
+	ExpiresInHours int `json:"expires_in_hours,omitempty" example:"168"`
+}
+
+// ProposalShareLink is a share with its code and the path homeowners open.
+type ProposalShareLink struct {
+	*models.ProposalShare
+	Code string `json:"code" example:"AAAAAAAAAAcAAAAAaQ3a8A.Yk1n2b7x0Qf4sUeR9mCzLw"`
+	URL  string `json:"url" example:"/p/AAAAAAAAAAcAAAAAaQ3a8A.Yk1n2b7x0Qf4sUeR9mCzLw"`
+}
+
+// ProposalShareResponse is a homeowner's answer to a shared proposal.
+// Message is required when requesting changes.
+type ProposalShareResponse struct {
+	Name    string `json:"name,omitempty" example:"Jane Doe"`
+	Message string `json:"message,omitempty" example:"Could we add a battery?"`
+}
+
+// ProposalCustomer is the homeowner as shown on a shared proposal.
+type ProposalCustomer struct {
+	FirstName  string `json:"first_name" example:"Jane"`
+	LastName   string `json:"last_name" example:"Doe"`
+	Street     string `json:"street" example:"1 Market St"`
+	City       string `json:"city" example:"San Francisco"`
+	State      string `json:"state" example:"CA"`
+	PostalCode string `json:"postal_code" example:"94105"`
+}
+
+// ProposalMeshFiles are the URLs of the lead's 3D roof model.
+type ProposalMeshFiles struct {
+	JPGURL string `json:"jpg_url,omitempty" example:"/media/1001/scene.jpg"`
+	OBJURL string `json:"obj_url,omitempty" example:"/media/1001/scene.obj"`
+	PLYURL string `json:"ply_url,omitempty" example:"/media/1001/scene.ply"`
+	MTLURL string `json:"mtl_url,omitempty" example:"/media/1001/scene.mtl"`
+}
+
+// ProposalShareView is the read-only proposal a share link opens: the
+// lead's latest quote, its hardware and 3D model. Quote is nil while the
+// lead has no quote, and has no pricing assumptions, nor schedules unless
+// it was requested detailed. MeshFiles and AccessCode are left out when
+// LightFusion cannot provide them.
+type ProposalShareView struct {
+	LeadID       int                        `json:"lead_id" example:"42"`
+	CompanyName  string                     `json:"company_name" example:"SunReady Solar"`
+	Status       models.ProposalShareStatus `json:"status" example:"pending"`
+	ExpiresAt    time.Time                  `json:"expires_at"`
+	RespondedAt  *time.Time                 `json:"responded_at,omitempty"`
+	Customer     *ProposalCustomer          `json:"customer,omitempty"`
+	QuoteVersion int                        `json:"quote_version,omitempty" example:"3"`
+	Quote        *QuoteResult               `json:"quote,omitempty"`
+	Panel        *models.Panel              `json:"panel,omitempty"`
+	Inverter     *models.Inverter           `json:"inverter,omitempty"`
+	MeshFiles    *ProposalMeshFiles         `json:"mesh_files,omitempty"`
+	// AccessCode opens the lead in LightFusion's own viewer.
+	AccessCode  string `json:"access_code,omitempty" example:"X7K2PQ"`
+	ProposalURL string `json:"proposal_url,omitempty" example:"/p/AAAAAAAAAAcAAAAAaQ3a8A.Yk1n2b7x0Qf4sUeR9mCzLw/proposal.pdf"`
+}
+
+type proposalResponsePayload struct {
+	ShareID int `json:"share_id"`
+}
+
+func (s *ProposalShareService) CreateShare(ctx context.Context, lead *models.Lead, userID int, req CreateProposalShareRequest) (*ProposalShareLink, error) {
+	hours := req.ExpiresInHours
+	if hours == 0 {
+		hours = defaultProposalShareHours
+	}
+	if hours < 1 || hours > maxProposalShareHours {
+		return nil, models.ErrInvalidProposalShare
+	}
+	share := &models.ProposalShare{
+		LeadID:    lead.ID,
+		CreatedBy: userID,
+		// Codes carry whole seconds.
+		ExpiresAt: time.Now().Add(time.Duration(hours) * time.Hour).Truncate(time.Second),
+		Status:    models.ProposalSharePending,
+	}
+	if err := s.shareRepo.Create(ctx, share); err != nil {
+		return nil, err
+	}
+	return s.link(share), nil
+}
+
+func (s *ProposalShareService) ListShares(ctx context.Context, leadID int) ([]*ProposalShareLink, error) {
+	shares, err := s.shareRepo.ListByLead(ctx, leadID)
+	if err != nil {
+		return nil, err
+	}
+	links := make([]*ProposalShareLink, len(shares))
+	for i, share := range shares {
+		links[i] = s.link(share)
+	}
+	return links, nil
+}
+
+// Open records an open of the share and returns the proposal it shows.
+func (s *ProposalShareService) Open(ctx context.Context, code string) (*ProposalShareView, error) {
+	share, err := s.resolve(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.shareRepo.RecordOpen(ctx, share.ID, time.Now()); err != nil {
+		return nil, err
+	}
+	lead, err := s.leadRepo.GetByID(ctx, share.LeadID)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &ProposalShareView{
+		LeadID:      lead.ID,
+		CompanyName: defaultProposalBrand().name,
+		Status:      share.Status,
+		ExpiresAt:   share.ExpiresAt,
+		RespondedAt: share.RespondedAt,
+	}
+	if lead.UserID != nil {
+		owner, err := s.userRepo.GetByID(ctx, *lead.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get lead owner: %w", err)
+		}
+		view.Customer = &ProposalCustomer{
+			FirstName:  owner.FirstName,
+			LastName:   owner.LastName,
+			Street:     owner.Street,
+			City:       owner.City,
+			State:      owner.State,
+			PostalCode: owner.PostalCode,
+		}
+	}
+
+	var input *QuoteInput
+	quote, err := s.quoteRepo.GetLatestByLead(ctx, lead.ID)
+	switch {
+	case err == nil:
+		v, err := decodeQuote(quote)
+		if err != nil {
+			return nil, err
+		}
+		// The pricing assumptions behind the quote are internal.
+		v.Result.Assumptions = nil
+		if !v.Input.Detailed {
+			v.Result.dropDetail()
+		}
+		view.QuoteVersion = v.Version
+		view.Quote = &v.Result
+		view.ProposalURL = "/p/" + code + "/proposal.pdf"
+		input = &v.Input
+	case err != models.ErrQuoteNotFound:
+		return nil, err
+	}
+	if view.Panel, view.Inverter, err = quotedHardware(ctx, s.hardwareRepo, lead, input); err != nil {
+		return nil, err
+	}
+
+	if lead.ExternalID != nil {
+		details := s.lightFusionDetails(ctx, lead.ID, *lead.ExternalID)
+		view.AccessCode = details.accessCode
+		view.MeshFiles = details.meshFiles
+		if details.companyName != "" {
+			view.CompanyName = details.companyName
+		}
+	}
+	return view, nil
+}
+
+// lightFusionDetails returns the access code, company name and mesh files
+// of the LightFusion project from the cache, fetching them when they are
+// missing or stale. Whatever LightFusion cannot provide is left empty.
+func (s *ProposalShareService) lightFusionDetails(ctx context.Context, leadID, projectID int) *shareDetails {
+	now := time.Now()
+	s.detailsMu.Lock()
+	cached, ok := s.details[projectID]
+	s.detailsMu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached
+	}
+
+	details := &shareDetails{expires: now.Add(shareDetailsTTL)}
+	if completion, err := s.lightFusionClient.GetLeadCompletion(ctx, projectID); err != nil {
+		log.Printf("Warning: showing shared proposal for lead %d without LightFusion details: %v", leadID, err)
+		details.expires = now.Add(shareDetailsRetry)
+	} else {
+		details.accessCode = completion.AccessCode
+		details.companyName = completion.Company.Name
+	}
+	if files, err := s.lightFusionClient.GetProjectFiles(ctx, projectID); err != nil {
+		log.Printf("Warning: showing shared proposal for lead %d without mesh files: %v", leadID, err)
+		details.expires = now.Add(shareDetailsRetry)
+	} else {
+		details.meshFiles = &ProposalMeshFiles{JPGURL: files.JPGURL, OBJURL: files.OBJURL, PLYURL: files.PLYURL, MTLURL: files.MTLURL}
+		if len(files.Errors) > 0 {
+			log.Printf("Warning: showing shared proposal for lead %d without some mesh files: %s", leadID, strings.Join(files.Errors, "; "))
+			details.expires = now.Add(shareDetailsRetry)
+		}
+	}
+
+	s.detailsMu.Lock()
+	defer s.detailsMu.Unlock()
+	for id, d := range s.details {
+		if !now.Before(d.expires) {
+			delete(s.details, id)
+		}
+	}
+	s.details[projectID] = details
+	return details
+}
+
+// ProposalPDF returns the proposal PDF of the lead's latest quote.
+func (s *ProposalShareService) ProposalPDF(ctx context.Context, code string) (*models.Proposal, error) {
+	share, err := s.resolve(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	lead, err := s.leadRepo.GetByID(ctx, share.LeadID)
+	if err != nil {
+		return nil, err
+	}
+	return s.proposalService.GetProposal(ctx, lead, 0, share.CreatedBy)
+}
+
+// Respond records the homeowner accepting the proposal or requesting
+// changes, announces it to lead subscribers and queues an email to the rep
+// who shared it. A share can be responded to once.
+func (s *ProposalShareService) Respond(ctx context.Context, code string, status models.ProposalShareStatus, resp ProposalShareResponse) (*models.ProposalShare, error) {
+	resp.Name = strings.TrimSpace(resp.Name)
+	resp.Message = strings.TrimSpace(resp.Message)
+	if len(resp.Message) > maxShareResponseLength || len(resp.Name) > 200 ||
+		(status == models.ProposalShareChangesRequested && resp.Message == "") {
+		return nil, models.ErrInvalidShareResponse
+	}
+	share, err := s.resolve(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if share.Status != models.ProposalSharePending {
+		return nil, models.ErrProposalShareResponded
+	}
+	quote, err := s.quoteRepo.GetLatestByLead(ctx, share.LeadID)
+	switch {
+	case err == nil:
+		share.QuoteVersion = &quote.Version
+	case err != models.ErrQuoteNotFound || status == models.ProposalShareAccepted:
+		// There is nothing to accept before the lead is quoted.
+		return nil, err
+	}
+
+	now := time.Now()
+	share.Status = status
+	share.RespondedAt = &now
+	share.ResponderName = resp.Name
+	share.ResponseMessage = resp.Message
+	// The response is only kept together with the job that notifies the
+	// rep of it.
+	err = s.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.shareRepo.Respond(ctx, share); err != nil {
+			return err
+		}
+		if err := s.jobQueue.Enqueue(ctx, JobTypeNotifyProposalResponse, &share.LeadID, proposalResponsePayload{ShareID: share.ID}); err != nil {
+			return fmt.Errorf("failed to queue proposal response notification: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.eventBus.Publish(share.LeadID, LeadEventProposalResponse, share)
+	return share, nil
+}
+
+func (s *ProposalShareService) runNotifyResponse(ctx context.Context, job *models.Job) error {
+	var payload proposalResponsePayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return fmt.Errorf("invalid job payload: %w", err)
+	}
+	if s.sendGridClient == nil {
+		log.Printf("Warning: SendGrid is not configured, not notifying share %d response", payload.ShareID)
+		return nil
+	}
+	share, err := s.shareRepo.GetByID(ctx, payload.ShareID)
+	if err != nil {
+		return err
+	}
+	rep, err := s.userRepo.GetByID(ctx, share.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to get rep: %w", err)
+	}
+	customer := share.ResponderName
+	if customer == "" {
+		customer = "The homeowner"
+		lead, err := s.leadRepo.GetByID(ctx, share.LeadID)
+		if err != nil {
+			return err
+		}
+		if lead.UserID != nil {
+			if owner, err := s.userRepo.GetByID(ctx, *lead.UserID); err == nil {
+				if name := strings.TrimSpace(owner.FirstName + " " + owner.LastName); name != "" {
+					customer = name
+				}
+			}
+		}
+	}
+	notice := client.ProposalResponseNotice{
+		LeadID:   share.LeadID,
+		Customer: customer,
+		Accepted: share.Status == models.ProposalShareAccepted,
+		Message:  share.ResponseMessage,
+	}
+	if share.QuoteVersion != nil {
+		notice.QuoteVersion = *share.QuoteVersion
+	}
+	return s.sendGridClient.SendProposalResponse(rep.Email, strings.TrimSpace(rep.FirstName+" "+rep.LastName), notice)
+}
+
+// resolve checks the code's signature and expiry and loads its share.
+func (s *ProposalShareService) resolve(ctx context.Context, code string) (*models.ProposalShare, error) {
+	id, expires, ok := s.verify(code)
+	if !ok {
+		return nil, models.ErrProposalShareNotFound
+	}
+	share, err := s.shareRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if share.ExpiresAt.Unix() != expires {
+		return nil, models.ErrProposalShareNotFound
+	}
+	if !time.Now().Before(share.ExpiresAt) {
+		return nil, models.ErrProposalShareExpired
+	}
+	return share, nil
+}
+
+func (s *ProposalShareService) link(share *models.ProposalShare) *ProposalShareLink {
+	code := s.sign(share.ID, share.ExpiresAt.Unix())
+	return &ProposalShareLink{ProposalShare: share, Code: code, URL: "/p/" + code}
+}
+
+// sign encodes the share ID and expiry with a truncated HMAC-SHA256 of
+// them, as base64url payload and signature joined by a dot.
+func (s *ProposalShareService) sign(id int, expires int64) string {
+	payload := make([]byte, 16)
+	binary.BigEndian.PutUint64(payload[:8], uint64(id))
+	binary.BigEndian.PutUint64(payload[8:], uint64(expires))
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+func (s *ProposalShareService) verify(code string) (int, int64, bool) {
+	encodedPayload, encodedSig, ok := strings.Cut(code, ".")
+	if !ok {
+		return 0, 0, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil || len(payload) != 16 {
+		return 0, 0, false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !hmac.Equal(sig, s.mac(payload)) {
+		return 0, 0, false
+	}
+	return int(binary.BigEndian.Uint64(payload[:8])), int64(binary.BigEndian.Uint64(payload[8:])), true
+}
+
+func (s *ProposalShareService) mac(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(payload)
+	return mac.Sum(nil)[:16]
+}
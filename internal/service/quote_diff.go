@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// QuoteFieldChange is one field that differs between two quote versions.
+// Field is the JSON path of the value; Delta is set for numeric fields.
+type QuoteFieldChange struct {
+	Field string   `json:"field" example:"system_size_kw"`
+	From  any      `json:"from"`
+	To    any      `json:"to"`
+	Delta *float64 `json:"delta,omitempty" example:"1.2"`
+}
+
+type QuoteDiff struct {
+	LeadID      int                `json:"lead_id" example:"42"`
+	FromVersion int                `json:"from_version" example:"1"`
+	ToVersion   int                `json:"to_version" example:"2"`
+	Input       []QuoteFieldChange `json:"input"`
+	Assumptions []QuoteFieldChange `json:"assumptions"`
+	Result      []QuoteFieldChange `json:"result"`
+}
+
+// quoteDiffIgnored lists result fields that always differ between versions
+// or are already diffed in their own section.
+var quoteDiffIgnored = map[string]bool{
+	"quote_id":    true,
+	"lead_id":     true,
+	"version":     true,
+	"assumptions": true,
+}
+
+// DiffVersions compares two quote versions of a lead.
+func (s *QuoteService) DiffVersions(ctx context.Context, leadID, fromVersion, toVersion int) (*QuoteDiff, error) {
+	from, err := s.GetVersion(ctx, leadID, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.GetVersion(ctx, leadID, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &QuoteDiff{LeadID: leadID, FromVersion: fromVersion, ToVersion: toVersion}
+	if diff.Input, err = diffJSON(from.Input, to.Input, nil); err != nil {
+		return nil, err
+	}
+	if diff.Assumptions, err = diffJSON(from.Assumptions, to.Assumptions, nil); err != nil {
+		return nil, err
+	}
+	if diff.Result, err = diffJSON(from.Result, to.Result, quoteDiffIgnored); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// diffJSON compares the JSON encodings of a and b field by field. Nested
+// objects are flattened into dotted paths and arrays into indexed ones, so
+// monthly_consumption_kwh[3] is reported on its own when only April changed.
+func diffJSON(a, b any, ignored map[string]bool) ([]QuoteFieldChange, error) {
+	fromFields, err := flattenJSON(a)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := flattenJSON(b)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool, len(fromFields)+len(toFields))
+	for k := range fromFields {
+		keys[k] = true
+	}
+	for k := range toFields {
+		keys[k] = true
+	}
+	fields := make([]string, 0, len(keys))
+	for k := range keys {
+		top := k
+		if i := strings.IndexAny(k, ".["); i >= 0 {
+			top = k[:i]
+		}
+		if !ignored[top] {
+			fields = append(fields, k)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return diffSortKey(fields[i]) < diffSortKey(fields[j]) })
+
+	changes := []QuoteFieldChange{}
+	for _, field := range fields {
+		fromValue, toValue := fromFields[field], toFields[field]
+		if reflect.DeepEqual(fromValue, toValue) {
+			continue
+		}
+		change := QuoteFieldChange{Field: field, From: fromValue, To: toValue}
+		fromNum, fromOK := fromValue.(float64)
+		toNum, toOK := toValue.(float64)
+		if fromOK && toOK {
+			delta := math.Round((toNum-fromNum)*100) / 100
+			change.Delta = &delta
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// diffSortKey pads array indexes so element 10 sorts after element 9.
+func diffSortKey(field string) string {
+	return diffIndexPattern.ReplaceAllStringFunc(field, func(index string) string {
+		return fmt.Sprintf("[%06s]", index[1:len(index)-1])
+	})
+}
+
+var diffIndexPattern = regexp.MustCompile(`\[\d+\]`)
+
+func flattenJSON(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode quote for diff: %w", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode quote for diff: %w", err)
+	}
+	fields := make(map[string]any)
+	flattenInto(fields, "", decoded)
+	return fields, nil
+}
+
+func flattenInto(fields map[string]any, prefix string, m map[string]any) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		flattenValue(fields, key, v)
+	}
+}
+
+func flattenValue(fields map[string]any, key string, v any) {
+	switch nested := v.(type) {
+	case map[string]any:
+		flattenInto(fields, key, nested)
+	case []any:
+		for i, elem := range nested {
+			flattenValue(fields, fmt.Sprintf("%s[%d]", key, i), elem)
+		}
+	default:
+		fields[key] = v
+	}
+}
@@ -0,0 +1,360 @@
+package service
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/client"
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+)
+
+type FinancingType string
+
+const (
+	FinancingCash  FinancingType = "cash"
+	FinancingLoan  FinancingType = "loan"
+	FinancingLease FinancingType = "lease"
+	FinancingPPA   FinancingType = "ppa"
+)
+
+// quoteAnalysisYears is how far lifetime costs and savings are projected;
+// quoteBreakEvenYears is how far the break-even search looks.
+const (
+	quoteAnalysisYears  = 25
+	quoteBreakEvenYears = 30
+)
+
+// FinancingScenario describes one way of paying for the system. Rates and
+// fees are fractions (0.0699 is 6.99%). Fields that do not apply to the
+// type are ignored.
+//
+// A loan may instead take its terms from a LightFusion financing option,
+// whose interest rate is a percentage and whose duration is in years.
+type FinancingScenario struct {
+	Name string        `json:"name" example:"25 year loan"`
+	Type FinancingType `json:"type" enums:"cash,loan,lease,ppa" example:"loan"`
+
+	// Cash and loan
+	DownPayment float64 `json:"down_payment,omitempty" example:"0"`
+
+	// Loan
+	InterestRate      *float64                `json:"interest_rate,omitempty" example:"0.0699"`
+	TermYears         int                     `json:"term_years,omitempty" example:"25"`
+	DealerFeePct      float64                 `json:"dealer_fee_pct,omitempty" example:"0.2"`
+	DealerFeeFixed    float64                 `json:"dealer_fee_fixed,omitempty" example:"0"`
+	LightFusionOption *client.FinancingOption `json:"lightfusion_option,omitempty"`
+
+	// Lease
+	MonthlyPayment float64 `json:"monthly_payment,omitempty" example:"139"`
+
+	// PPA
+	RatePerKWh float64 `json:"rate_per_kwh,omitempty" example:"0.17"`
+
+	// Lease and PPA
+	EscalatorPct float64 `json:"escalator_pct,omitempty" example:"0.029"`
+}
+
+// FinancingResult compares one financing scenario against staying with the
+// utility over the analysis period. LifetimeCost includes the remaining
+// utility bills and, for owned systems, ownership costs and is net of the
+// federal tax credit and incentives, which only owners receive. Incentives
+// are those paid at installation; per-kWh incentives count in the years
+// they are paid.
+type FinancingResult struct {
+	Name             string        `json:"name" example:"25 year loan"`
+	Type             FinancingType `json:"type" example:"loan"`
+	UpfrontCost      float64       `json:"upfront_cost"`
+	AmountFinanced   float64       `json:"amount_financed,omitempty"`
+	InterestRate     float64       `json:"interest_rate,omitempty"`
+	TermYears        int           `json:"term_years,omitempty"`
+	MonthlyPayment   float64       `json:"monthly_payment"`
+	TotalPayments    float64       `json:"total_payments"`
+	FederalTaxCredit float64       `json:"federal_tax_credit"`
+	Incentives       float64       `json:"incentives"`
+	LifetimeCost     float64       `json:"lifetime_cost"`
+	LifetimeSavings  float64       `json:"lifetime_savings"`
+	FirstYearSavings float64       `json:"first_year_savings"`
+	BreakEvenYear    int           `json:"break_even_year"`
+
+	// Set in detailed mode only.
+	Amortization []AmortizationRow `json:"amortization,omitempty"`
+	CashFlow     []CashFlowYear    `json:"cash_flow,omitempty"`
+}
+
+// FinancingScenarioFromOption builds a loan scenario from a LightFusion
+// financing option.
+func FinancingScenarioFromOption(option client.FinancingOption) FinancingScenario {
+	rate := option.InterestRate / 100
+	fee := option.LoanFee
+	if fee > 1 {
+		// Some lenders send the dealer fee as a percentage.
+		fee /= 100
+	}
+	return FinancingScenario{
+		Name:           option.Name,
+		Type:           FinancingLoan,
+		InterestRate:   &rate,
+		TermYears:      option.Duration,
+		DealerFeePct:   fee,
+		DealerFeeFixed: option.LoanFeeFixed,
+	}
+}
+
+// quoteBaseline is the year-by-year picture financing scenarios are
+// evaluated against. Index 0 is year 1. ownershipCosts are the O&M,
+// insurance and inverter replacement costs an owner pays and
+// incentivePayments the incentives paid to an owner after installation; a
+// lease or PPA provider carries both instead.
+type quoteBaseline struct {
+	cost              quoteCost
+	billWithoutSolar  []float64
+	billWithSolar     []float64
+	productionKWh     []float64
+	ownershipCosts    []float64
+	incentivePayments []float64
+}
+
+// annualBills returns the annual utility bill without and with solar at
+// today's rates, given the share of nameplate output the system delivers.
+type annualBills func(output float64) (withoutSolar, withSolar float64)
+
+// quoteBillModel picks how bills are estimated: the billing engine when the
+// input has a tariff, otherwise the flat offset of the monthly bill. The
+// billing engine's first-year result is returned for display. Simulated
+// hourly production, when given, is billed as is instead of a seasonal
+// split of the annual total.
+//
+// The billing engine runs once, for year 1. Later years scale its bill
+// savings by the output left after degradation, as the flat offset does,
+// rather than billing every year of the analysis hour by hour.
+func quoteBillModel(input QuoteInput, a QuoteAssumptions, annualProductionKWh float64, hourlyProduction []float64) (annualBills, *BillingResult, error) {
+	if input.Tariff == nil {
+		annualBill := input.MonthlyElectricBill * 12
+		offsetRatio := a.ElectricalOffsetPct / 100
+		return func(output float64) (float64, float64) {
+			return annualBill, annualBill * math.Max(0, 1-offsetRatio*output)
+		}, nil, nil
+	}
+
+	var firstYear *BillingResult
+	var err error
+	if hourlyProduction != nil {
+		firstYear, err = SimulateBilling(*input.Tariff, input.MonthlyConsumptionKWh, nil, hourlyProduction)
+	} else {
+		production := input.MonthlyProductionKWh
+		if len(production) != 12 {
+			production = monthlyProductionProfile(annualProductionKWh)
+		}
+		firstYear, err = SimulateBilling(*input.Tariff, input.MonthlyConsumptionKWh, production, nil)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	withoutSolar := firstYear.AnnualPreSolar
+	savings := firstYear.AnnualPreSolar - firstYear.AnnualPostSolar
+	return func(output float64) (float64, float64) {
+		return withoutSolar, withoutSolar - savings*output
+	}, firstYear, nil
+}
+
+// newQuoteBaseline projects bills, production and ownership costs. Output
+// is nameplate in year 1, loses FirstYearDegradation going into year 2 and
+// AnnualDegradation every year after that; bill savings shrink with it.
+func newQuoteBaseline(a QuoteAssumptions, bills annualBills, annualProductionKWh float64, cost quoteCost, years int) quoteBaseline {
+	b := quoteBaseline{cost: cost}
+	for year := 0; year < years; year++ {
+		output := a.outputFactor(year)
+		withoutSolar, withSolar := bills(output)
+		escalation := math.Pow(1+a.AnnualUtilityIncrease, float64(year))
+		costs := a.AnnualOMCost + a.AnnualInsuranceCost
+		if year+1 == a.InverterReplacementYear {
+			costs += a.InverterReplacementCost
+		}
+		b.billWithoutSolar = append(b.billWithoutSolar, withoutSolar*escalation)
+		b.billWithSolar = append(b.billWithSolar, withSolar*escalation)
+		b.productionKWh = append(b.productionKWh, annualProductionKWh*output)
+		b.ownershipCosts = append(b.ownershipCosts, costs)
+		b.incentivePayments = append(b.incentivePayments, cost.incentivePayment(year))
+	}
+	return b
+}
+
+// outputFactor is the share of nameplate output delivered in the zero-based
+// year.
+func (a QuoteAssumptions) outputFactor(year int) float64 {
+	if year == 0 {
+		return 1
+	}
+	return (1 - a.FirstYearDegradation) * math.Pow(1-a.AnnualDegradation, float64(year-1))
+}
+
+func (b quoteBaseline) firstYears(n int) quoteBaseline {
+	b.billWithoutSolar = b.billWithoutSolar[:n]
+	b.billWithSolar = b.billWithSolar[:n]
+	b.productionKWh = b.productionKWh[:n]
+	b.ownershipCosts = b.ownershipCosts[:n]
+	b.incentivePayments = b.incentivePayments[:n]
+	return b
+}
+
+func (a QuoteAssumptions) validateLifetime() error {
+	if a.FirstYearDegradation < 0 || a.FirstYearDegradation >= 1 ||
+		a.AnnualDegradation < 0 || a.AnnualDegradation >= 1 ||
+		a.InverterReplacementYear < 0 || a.InverterReplacementCost < 0 ||
+		a.AnnualOMCost < 0 || a.AnnualInsuranceCost < 0 {
+		return models.ErrInvalidQuoteLifetime
+	}
+	return nil
+}
+
+// resolved returns the scenario with a loan's LightFusion option applied:
+// the option's terms replace the scenario's, which keeps its name and down
+// payment.
+func (s FinancingScenario) resolved() FinancingScenario {
+	if s.Type != FinancingLoan || s.LightFusionOption == nil {
+		return s
+	}
+	fromOption := FinancingScenarioFromOption(*s.LightFusionOption)
+	fromOption.DownPayment = s.DownPayment
+	if s.Name != "" {
+		fromOption.Name = s.Name
+	}
+	return fromOption
+}
+
+// validate checks a resolved scenario, so a loan's terms are checked
+// whether they were given or came from a LightFusion option.
+func (s FinancingScenario) validate() error {
+	if s.DownPayment < 0 || s.DealerFeePct < 0 || s.DealerFeePct >= 1 || s.DealerFeeFixed < 0 || s.EscalatorPct < 0 {
+		return fmt.Errorf("%w: %q", models.ErrInvalidFinancingScenario, s.Name)
+	}
+	switch s.Type {
+	case FinancingCash:
+	case FinancingLoan:
+		if s.InterestRate == nil || *s.InterestRate < 0 || s.TermYears <= 0 {
+			return fmt.Errorf("%w: loan %q needs an interest rate of 0 or more and a term of at least a year", models.ErrInvalidFinancingScenario, s.Name)
+		}
+	case FinancingLease:
+		if s.MonthlyPayment <= 0 || s.TermYears < 0 {
+			return fmt.Errorf("%w: lease %q needs monthly_payment", models.ErrInvalidFinancingScenario, s.Name)
+		}
+	case FinancingPPA:
+		if s.RatePerKWh <= 0 || s.TermYears < 0 {
+			return fmt.Errorf("%w: PPA %q needs rate_per_kwh", models.ErrInvalidFinancingScenario, s.Name)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", models.ErrInvalidFinancingScenario, s.Type)
+	}
+	return nil
+}
+
+// evaluateFinancing projects the cash flows of one scenario. The customer
+// owns the system, and so claims the tax credit in year 1, only for cash and
+// loan purchases. Leases and PPAs default to the analysis period as term.
+// In detailed mode the amortization schedule and cash-flow table are
+// included.
+func evaluateFinancing(s FinancingScenario, b quoteBaseline, detailed bool) FinancingResult {
+	s = s.resolved()
+	years := len(b.billWithoutSolar)
+	yearlyPayments := make([]float64, years)
+	ownershipCosts := make([]float64, years)
+	incentivePayments := make([]float64, years)
+	r := FinancingResult{Name: s.Name, Type: s.Type}
+	if s.Type == FinancingCash || s.Type == FinancingLoan {
+		copy(ownershipCosts, b.ownershipCosts)
+		copy(incentivePayments, b.incentivePayments)
+	}
+
+	switch s.Type {
+	case FinancingCash:
+		r.UpfrontCost = b.cost.system
+		r.FederalTaxCredit = b.cost.federalTaxCredit
+		r.Incentives = b.cost.incentives
+	case FinancingLoan:
+		r.UpfrontCost = math.Min(s.DownPayment, b.cost.system)
+		r.AmountFinanced = (b.cost.system-r.UpfrontCost)*(1+s.DealerFeePct) + s.DealerFeeFixed
+		r.InterestRate = *s.InterestRate
+		r.TermYears = s.TermYears
+		r.MonthlyPayment = amortizedPayment(r.AmountFinanced, r.InterestRate, r.TermYears)
+		r.FederalTaxCredit = b.cost.federalTaxCredit
+		r.Incentives = b.cost.incentives
+		for year := 0; year < years && year < r.TermYears; year++ {
+			yearlyPayments[year] = r.MonthlyPayment * 12
+		}
+	case FinancingLease:
+		r.UpfrontCost = s.DownPayment
+		r.TermYears = leaseTerm(s.TermYears, years)
+		r.MonthlyPayment = s.MonthlyPayment
+		for year := 0; year < r.TermYears; year++ {
+			yearlyPayments[year] = s.MonthlyPayment * 12 * math.Pow(1+s.EscalatorPct, float64(year))
+		}
+	case FinancingPPA:
+		r.UpfrontCost = s.DownPayment
+		r.TermYears = leaseTerm(s.TermYears, years)
+		for year := 0; year < r.TermYears; year++ {
+			yearlyPayments[year] = b.productionKWh[year] * s.RatePerKWh * math.Pow(1+s.EscalatorPct, float64(year))
+		}
+		r.MonthlyPayment = yearlyPayments[0] / 12
+	}
+
+	cumulative := r.FederalTaxCredit + r.Incentives - r.UpfrontCost
+	totalWithoutSolar := 0.0
+	for year := 0; year < years; year++ {
+		totalWithoutSolar += b.billWithoutSolar[year]
+		r.TotalPayments += yearlyPayments[year]
+		savings := b.billWithoutSolar[year] - b.billWithSolar[year] - yearlyPayments[year] - ownershipCosts[year] + incentivePayments[year]
+		if year == 0 {
+			r.FirstYearSavings = savings
+			if r.Type == FinancingCash || r.Type == FinancingLoan {
+				r.FirstYearSavings += r.FederalTaxCredit + r.Incentives
+			}
+		}
+		cumulative += savings
+		r.LifetimeCost += b.billWithSolar[year] + yearlyPayments[year] + ownershipCosts[year] - incentivePayments[year]
+		if cumulative >= 0 && r.BreakEvenYear == 0 {
+			r.BreakEvenYear = year + 1
+		}
+	}
+	r.LifetimeCost += r.UpfrontCost - r.FederalTaxCredit - r.Incentives
+	r.LifetimeSavings = totalWithoutSolar - r.LifetimeCost
+	if detailed {
+		if r.Type == FinancingLoan {
+			r.Amortization = amortizationSchedule(r.AmountFinanced, r.InterestRate, r.TermYears)
+		}
+		r.CashFlow = cashFlowTable(b, yearlyPayments, ownershipCosts, incentivePayments, r.FederalTaxCredit+r.Incentives-r.UpfrontCost)
+	}
+
+	r.UpfrontCost = round2(r.UpfrontCost)
+	r.AmountFinanced = round2(r.AmountFinanced)
+	r.MonthlyPayment = round2(r.MonthlyPayment)
+	r.TotalPayments = round2(r.TotalPayments)
+	r.FederalTaxCredit = round2(r.FederalTaxCredit)
+	r.Incentives = round2(r.Incentives)
+	r.LifetimeCost = round2(r.LifetimeCost)
+	r.LifetimeSavings = round2(r.LifetimeSavings)
+	r.FirstYearSavings = round2(r.FirstYearSavings)
+	return r
+}
+
+func amortizedPayment(principal, annualRate float64, termYears int) float64 {
+	if principal <= 0 || termYears <= 0 {
+		return 0
+	}
+	n := float64(termYears * 12)
+	if annualRate <= 0 {
+		return principal / n
+	}
+	monthlyRate := annualRate / 12
+	return principal * (monthlyRate * math.Pow(1+monthlyRate, n)) / (math.Pow(1+monthlyRate, n) - 1)
+}
+
+func leaseTerm(termYears, analysisYears int) int {
+	if termYears <= 0 || termYears > analysisYears {
+		return analysisYears
+	}
+	return termYears
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
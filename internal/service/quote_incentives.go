@@ -0,0 +1,150 @@
+package service
+
+import (
+	"math"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+)
+
+// QuoteIncentive is one incentive program applied to a quote. Capped is set
+// when MaxAmount or MaxSystemSizeKW limited the amount, or when the
+// incentives were cut to stay within the system cost.
+type QuoteIncentive struct {
+	RuleID          int                  `json:"rule_id" example:"4"`
+	Name            string               `json:"name" example:"SGIP Residential Rebate"`
+	Type            models.IncentiveType `json:"type" example:"per_watt"`
+	Amount          float64              `json:"amount" example:"1500"`
+	ReducesITCBasis bool                 `json:"reduces_itc_basis"`
+	Capped          bool                 `json:"capped"`
+	// PaymentYears is set for per-kWh programs, which pay Amount out over
+	// that many years of production rather than at installation.
+	PaymentYears int `json:"payment_years,omitempty" example:"5"`
+
+	// payments is what a per-kWh program pays in each year, year 1 first.
+	payments []float64
+}
+
+// scale multiplies the incentive and its payments by factor.
+func (i *QuoteIncentive) scale(factor float64) {
+	i.Amount *= factor
+	for year := range i.payments {
+		i.payments[year] *= factor
+	}
+}
+
+// evaluateIncentives prices every rule for the system. productionKWh is the
+// expected production of each year; per-kWh programs are paid on it in
+// each of their payment years. Other incentives are received at
+// installation.
+func evaluateIncentives(rules []*models.IncentiveRule, systemSizeKW, systemCost float64, productionKWh []float64) []QuoteIncentive {
+	var incentives []QuoteIncentive
+	for _, rule := range rules {
+		item := QuoteIncentive{RuleID: rule.ID, Name: rule.Name, Type: rule.Type, ReducesITCBasis: rule.ReducesITCBasis}
+		share := 1.0
+		if rule.MaxSystemSizeKW != nil && systemSizeKW > *rule.MaxSystemSizeKW {
+			share = *rule.MaxSystemSizeKW / systemSizeKW
+			item.Capped = true
+		}
+		switch rule.Type {
+		case models.IncentivePerWatt:
+			item.Amount = rule.Amount * systemSizeKW * 1000 * share
+		case models.IncentivePerKWh:
+			item.PaymentYears = min(max(rule.PaymentYears, 1), len(productionKWh))
+			item.payments = make([]float64, item.PaymentYears)
+			for year := range item.payments {
+				item.payments[year] = rule.Amount * productionKWh[year] * share
+				item.Amount += item.payments[year]
+			}
+		case models.IncentiveFlat:
+			item.Amount = rule.Amount
+		case models.IncentivePercentage:
+			item.Amount = rule.Amount * systemCost * share
+		}
+		if rule.MaxAmount != nil && item.Amount > *rule.MaxAmount {
+			item.scale(*rule.MaxAmount / item.Amount)
+			item.Capped = true
+		}
+		item.Amount = round2(math.Max(0, item.Amount))
+		incentives = append(incentives, item)
+	}
+	return incentives
+}
+
+// quoteCost is what an owner pays for the system and what comes back to
+// them: the federal tax credit and incentives at installation, and the
+// incentives paid over the following years. incentivePayments[i] is paid
+// in year i+1.
+type quoteCost struct {
+	system            float64
+	federalTaxCredit  float64
+	incentives        float64
+	incentivePayments []float64
+}
+
+// newQuoteCost computes the federal tax credit on the system cost less the
+// incentives that reduce its basis, and splits the incentives into those
+// paid at installation and those paid over time. The incentives and the
+// credit together never exceed the system cost; when they would, every
+// incentive, itemized ones included, is cut by the same share.
+func newQuoteCost(systemCost, taxCreditRate float64, incentives []QuoteIncentive) (quoteCost, float64) {
+	var reducing, other float64
+	for _, incentive := range incentives {
+		if incentive.ReducesITCBasis {
+			reducing += incentive.Amount
+		} else {
+			other += incentive.Amount
+		}
+	}
+	// Cut by share k, the incentives leave a credit of
+	// rate × (cost − k × reducing), so they fit while
+	// k × (reducing × (1 − rate) + other) ≤ cost × (1 − rate).
+	if total := reducing*(1-taxCreditRate) + other; total > systemCost*(1-taxCreditRate) {
+		k := math.Max(0, systemCost*(1-taxCreditRate)/total)
+		reducing *= k
+		for i := range incentives {
+			incentives[i].scale(k)
+			incentives[i].Amount = round2(incentives[i].Amount)
+			incentives[i].Capped = true
+		}
+	}
+
+	c := quoteCost{system: systemCost}
+	for _, incentive := range incentives {
+		if incentive.payments == nil {
+			c.incentives += incentive.Amount
+			continue
+		}
+		for year, amount := range incentive.payments {
+			for len(c.incentivePayments) <= year {
+				c.incentivePayments = append(c.incentivePayments, 0)
+			}
+			c.incentivePayments[year] += amount
+		}
+	}
+	basis := math.Max(0, systemCost-reducing)
+	c.federalTaxCredit = basis * taxCreditRate
+	return c, basis
+}
+
+// afterIncentives is the cost once the credit and the incentives paid at
+// installation are taken off.
+func (c quoteCost) afterIncentives() float64 {
+	return c.system - c.federalTaxCredit - c.incentives
+}
+
+// totalIncentives is every incentive, paid at installation or later.
+func (c quoteCost) totalIncentives() float64 {
+	total := c.incentives
+	for _, amount := range c.incentivePayments {
+		total += amount
+	}
+	return total
+}
+
+// incentivePayment is the incentive paid in the zero-based year.
+func (c quoteCost) incentivePayment(year int) float64 {
+	if year < len(c.incentivePayments) {
+		return c.incentivePayments[year]
+	}
+	return 0
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+)
+
+// LeadQuoteInput builds the quote input for a lead. Fields set in overrides
+// win; the rest come from the lead, its owner and the catalog:
+//
+//   - system size, panel count and consumption from the lead
+//   - panel and inverter from the lead when they are in the catalog
+//   - production from the lead's design, else simulated at its coordinates;
+//     tilt, azimuth or location overrides always simulate
+//   - state and average monthly bill from the lead's owner
+//   - utility from the lead, for incentives
+//
+// The lead's tariff ID names a Genability tariff whose rates are not
+// stored here, so a rate structure has to come from overrides.Tariff.
+func (s *QuoteService) LeadQuoteInput(ctx context.Context, lead *models.Lead, overrides QuoteInput) (QuoteInput, error) {
+	input := overrides
+	input.LeadID = &lead.ID
+	if input.SystemSizeKW == 0 {
+		input.SystemSizeKW = lead.SystemSize
+	}
+	if input.PanelCount == 0 {
+		input.PanelCount = lead.PanelCount
+	}
+	if input.MonthlyConsumptionKWh == nil {
+		consumption, err := leadMonthlyConsumption(lead)
+		if err != nil {
+			return QuoteInput{}, fmt.Errorf("%w: %w", models.ErrInvalidBillingInput, err)
+		}
+		input.MonthlyConsumptionKWh = consumption
+	}
+	if input.UtilityID == nil {
+		input.UtilityID = lead.UtilityID
+	}
+
+	if input.PanelID == nil && lead.PanelId > 0 {
+		if _, err := s.hardwareRepo.GetPanelByID(ctx, lead.PanelId); err == nil {
+			input.PanelID = &lead.PanelId
+		} else if err != models.ErrPanelNotFound {
+			return QuoteInput{}, err
+		}
+	}
+	if input.InverterID == nil && lead.InverterId > 0 {
+		if _, err := s.hardwareRepo.GetInverterByID(ctx, lead.InverterId); err == nil {
+			input.InverterID = &lead.InverterId
+		} else if err != models.ErrInverterNotFound {
+			return QuoteInput{}, err
+		}
+	}
+
+	if input.AnnualProductionKWh == 0 && len(input.MonthlyProductionKWh) == 0 {
+		// Any array or location override asks for a fresh simulation.
+		simulate := input.TiltDeg != nil || input.AzimuthDeg != nil || input.Latitude != nil || input.Longitude != nil
+		if lead.AnnualProduction > 0 && !simulate {
+			input.AnnualProductionKWh = lead.AnnualProduction
+		} else {
+			if input.Latitude == nil {
+				input.Latitude = &lead.Latitude
+			}
+			if input.Longitude == nil {
+				input.Longitude = &lead.Longitude
+			}
+		}
+	}
+
+	if lead.UserID != nil && (input.State == "" || input.MonthlyElectricBill == 0) {
+		owner, err := s.userRepo.GetByID(ctx, *lead.UserID)
+		if err != nil {
+			return QuoteInput{}, fmt.Errorf("failed to get lead owner: %w", err)
+		}
+		if input.State == "" {
+			input.State = owner.State
+		}
+		if input.MonthlyElectricBill == 0 {
+			input.MonthlyElectricBill = owner.AverageMonthlyBill
+		}
+	}
+	return input, nil
+}
+
+// QuoteLead calculates a quote from the lead and stores it as the lead's
+// next quote version.
+func (s *QuoteService) QuoteLead(ctx context.Context, lead *models.Lead, overrides QuoteInput, userID int) (*QuoteResult, error) {
+	input, err := s.LeadQuoteInput(ctx, lead, overrides)
+	if err != nil {
+		return nil, err
+	}
+	return s.CalculateQuote(ctx, input, userID)
+}
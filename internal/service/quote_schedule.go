@@ -0,0 +1,81 @@
+package service
+
+import "math"
+
+// AmortizationRow is one monthly loan payment.
+type AmortizationRow struct {
+	Month     int     `json:"month" example:"1"`
+	Payment   float64 `json:"payment" example:"141.23"`
+	Principal float64 `json:"principal" example:"24.73"`
+	Interest  float64 `json:"interest" example:"116.5"`
+	Balance   float64 `json:"balance" example:"19975.27"`
+}
+
+// CashFlowYear is one year of the savings projection. Savings is the
+// utility bill avoided less financing payments and operating costs (O&M,
+// insurance and inverter replacement), plus incentives paid that year;
+// CumulativeSavings also counts the upfront cost, the tax credit and the
+// incentives paid at installation where they apply.
+type CashFlowYear struct {
+	Year              int     `json:"year" example:"1"`
+	ProductionKWh     float64 `json:"production_kwh" example:"10950"`
+	BillWithoutSolar  float64 `json:"bill_without_solar" example:"2400"`
+	BillWithSolar     float64 `json:"bill_with_solar" example:"120"`
+	LoanPayments      float64 `json:"loan_payments" example:"1694.76"`
+	OperatingCosts    float64 `json:"operating_costs" example:"250"`
+	Incentives        float64 `json:"incentives" example:"0"`
+	Savings           float64 `json:"savings" example:"585.24"`
+	CumulativeSavings float64 `json:"cumulative_savings" example:"585.24"`
+}
+
+func amortizationSchedule(principal, annualRate float64, termYears int) []AmortizationRow {
+	payment := amortizedPayment(principal, annualRate, termYears)
+	if payment == 0 {
+		return nil
+	}
+	months := termYears * 12
+	rows := make([]AmortizationRow, 0, months)
+	balance := principal
+	for month := 1; month <= months; month++ {
+		interest := balance * annualRate / 12
+		principalPaid := payment - interest
+		if month == months {
+			// Absorb rounding drift so the loan ends at exactly zero.
+			principalPaid = balance
+		}
+		balance = math.Max(0, balance-principalPaid)
+		rows = append(rows, AmortizationRow{
+			Month:     month,
+			Payment:   round2(principalPaid + interest),
+			Principal: round2(principalPaid),
+			Interest:  round2(interest),
+			Balance:   round2(balance),
+		})
+	}
+	return rows
+}
+
+// cashFlowTable lays out the baseline year by year. payments[i] and costs[i]
+// are what the customer pays for financing and upkeep in year i+1 on top of
+// the remaining utility bill, and incentives[i] what they are paid;
+// startingBalance is the cumulative position before year 1.
+func cashFlowTable(b quoteBaseline, payments, costs, incentives []float64, startingBalance float64) []CashFlowYear {
+	rows := make([]CashFlowYear, 0, len(b.billWithoutSolar))
+	cumulative := startingBalance
+	for year := range b.billWithoutSolar {
+		savings := b.billWithoutSolar[year] - b.billWithSolar[year] - payments[year] - costs[year] + incentives[year]
+		cumulative += savings
+		rows = append(rows, CashFlowYear{
+			Year:              year + 1,
+			ProductionKWh:     round2(b.productionKWh[year]),
+			BillWithoutSolar:  round2(b.billWithoutSolar[year]),
+			BillWithSolar:     round2(b.billWithSolar[year]),
+			LoanPayments:      round2(payments[year]),
+			OperatingCosts:    round2(costs[year]),
+			Incentives:        round2(incentives[year]),
+			Savings:           round2(savings),
+			CumulativeSavings: round2(cumulative),
+		})
+	}
+	return rows
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+)
+
+const (
+	SensitivityUtilityEscalation = "annual_utility_increase"
+	SensitivityInterestRate      = "loan_interest_rate"
+	SensitivityCostPerWatt       = "cost_per_watt"
+	SensitivityProduction        = "production"
+)
+
+const (
+	defaultSensitivitySteps = 5
+	maxSensitivitySteps     = 11
+)
+
+// SensitivityRange is the span a variable is swept over, in Steps evenly
+// spaced values from Low to High inclusive (default 5).
+type SensitivityRange struct {
+	Low   float64 `json:"low" example:"0.02"`
+	High  float64 `json:"high" example:"0.05"`
+	Steps int     `json:"steps,omitempty" example:"4"`
+}
+
+// SensitivityRequest sweeps a base quote over ranges of its assumptions.
+// Escalation and interest are fractions and cost is $/W, as in the quote
+// assumptions; production is a multiplier of the base production (0.9 is
+// 10% less). Variables without a range are held at the base value; with no
+// ranges at all each variable gets a default range around its base value.
+type SensitivityRequest struct {
+	Input                 QuoteInput        `json:"input"`
+	AnnualUtilityIncrease *SensitivityRange `json:"annual_utility_increase,omitempty"`
+	LoanInterestRate      *SensitivityRange `json:"loan_interest_rate,omitempty"`
+	CostPerWatt           *SensitivityRange `json:"cost_per_watt,omitempty"`
+	Production            *SensitivityRange `json:"production,omitempty"`
+}
+
+// SensitivityOutcome is the part of a quote result sensitivity tracks.
+type SensitivityOutcome struct {
+	TwentyFiveYearSavings float64 `json:"twenty_five_year_savings" example:"38250.4"`
+	SimplePaybackYears    float64 `json:"simple_payback_years" example:"8.4"`
+	BreakEvenYear         int     `json:"break_even_year" example:"11"`
+}
+
+// SensitivityPoint is the quote outcome at one value of a variable.
+type SensitivityPoint struct {
+	Value float64 `json:"value" example:"0.02"`
+	SensitivityOutcome
+}
+
+// SensitivitySeries is the sweep of one variable with the others at base.
+type SensitivitySeries struct {
+	Variable string             `json:"variable" example:"annual_utility_increase"`
+	Points   []SensitivityPoint `json:"points"`
+}
+
+// TornadoBar is one bar of a tornado chart: 25-year savings at the low and
+// high end of a variable's range. Swing is the distance between them.
+type TornadoBar struct {
+	Variable     string           `json:"variable" example:"annual_utility_increase"`
+	BaseValue    float64          `json:"base_value" example:"0.03"`
+	Low          SensitivityPoint `json:"low"`
+	High         SensitivityPoint `json:"high"`
+	SavingsSwing float64          `json:"savings_swing" example:"14200.5"`
+}
+
+// SensitivityResult holds the base quote outcome, a sweep per variable and
+// the tornado bars ordered from the widest swing to the narrowest.
+type SensitivityResult struct {
+	Base        SensitivityOutcome  `json:"base"`
+	Assumptions QuoteAssumptions    `json:"assumptions"`
+	Series      []SensitivitySeries `json:"series"`
+	Tornado     []TornadoBar        `json:"tornado"`
+}
+
+// Sensitivity sweeps the base quote over the requested ranges through the
+// same calculation as CalculateQuote. Nothing is stored.
+func (s *QuoteService) Sensitivity(ctx context.Context, req SensitivityRequest, userID int) (*SensitivityResult, error) {
+	a, qc, err := s.prepareQuote(ctx, req.Input, userID)
+	if err != nil {
+		return nil, err
+	}
+	return quoteSensitivity(req, a, qc)
+}
+
+type sensitivityVariable struct {
+	name  string
+	rng   *SensitivityRange
+	base  float64
+	apply func(input *QuoteInput, a *QuoteAssumptions, qc *quoteContext, value float64)
+}
+
+func quoteSensitivity(req SensitivityRequest, a QuoteAssumptions, qc quoteContext) (*SensitivityResult, error) {
+	base, err := calculateQuote(req.Input, a, qc)
+	if err != nil {
+		return nil, err
+	}
+	variables := []sensitivityVariable{
+		{
+			name: SensitivityUtilityEscalation, rng: req.AnnualUtilityIncrease, base: a.AnnualUtilityIncrease,
+			apply: func(_ *QuoteInput, a *QuoteAssumptions, _ *quoteContext, v float64) { a.AnnualUtilityIncrease = v },
+		},
+		{
+			name: SensitivityInterestRate, rng: req.LoanInterestRate, base: a.LoanInterestRate,
+			apply: func(_ *QuoteInput, a *QuoteAssumptions, _ *quoteContext, v float64) { a.LoanInterestRate = v },
+		},
+		{
+			name: SensitivityCostPerWatt, rng: req.CostPerWatt, base: a.CostPerWatt,
+			apply: func(_ *QuoteInput, a *QuoteAssumptions, _ *quoteContext, v float64) { a.CostPerWatt = v },
+		},
+		{
+			name: SensitivityProduction, rng: req.Production, base: 1,
+			apply: func(input *QuoteInput, a *QuoteAssumptions, qc *quoteContext, v float64) {
+				scaleQuoteProduction(input, qc, base.AnnualProductionKWh, v)
+				// Without a tariff bills follow the offset, not the kWh.
+				if input.Tariff == nil {
+					a.ElectricalOffsetPct *= v
+				}
+			},
+		},
+	}
+	if req.AnnualUtilityIncrease == nil && req.LoanInterestRate == nil && req.CostPerWatt == nil && req.Production == nil {
+		variables[0].rng = &SensitivityRange{Low: math.Max(0, a.AnnualUtilityIncrease-0.02), High: a.AnnualUtilityIncrease + 0.02}
+		variables[1].rng = &SensitivityRange{Low: math.Max(0, a.LoanInterestRate-0.02), High: a.LoanInterestRate + 0.02}
+		variables[2].rng = &SensitivityRange{Low: a.CostPerWatt * 0.85, High: a.CostPerWatt * 1.15}
+		variables[3].rng = &SensitivityRange{Low: 0.85, High: 1.15}
+	}
+
+	result := &SensitivityResult{Base: sensitivityOutcome(base), Assumptions: a}
+	for _, variable := range variables {
+		if variable.rng == nil {
+			continue
+		}
+		if variable.name == SensitivityProduction && variable.rng.Low <= 0 {
+			return nil, fmt.Errorf("%w: production multipliers must be greater than 0", models.ErrInvalidSensitivityRange)
+		}
+		values, err := variable.rng.values(variable.name)
+		if err != nil {
+			return nil, err
+		}
+		series := SensitivitySeries{Variable: variable.name}
+		for _, value := range values {
+			input, va, vqc := req.Input, a, qc
+			variable.apply(&input, &va, &vqc, value)
+			// The sweep only moves numbers the base quote already
+			// validated, so an error here is a bad range value.
+			r, err := calculateQuote(input, va, vqc)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s %v: %v", models.ErrInvalidSensitivityRange, variable.name, value, err)
+			}
+			series.Points = append(series.Points, SensitivityPoint{Value: value, SensitivityOutcome: sensitivityOutcome(r)})
+		}
+		result.Series = append(result.Series, series)
+		low, high := series.Points[0], series.Points[len(series.Points)-1]
+		result.Tornado = append(result.Tornado, TornadoBar{
+			Variable:     variable.name,
+			BaseValue:    variable.base,
+			Low:          low,
+			High:         high,
+			SavingsSwing: round2(math.Abs(high.TwentyFiveYearSavings - low.TwentyFiveYearSavings)),
+		})
+	}
+	sort.SliceStable(result.Tornado, func(i, j int) bool {
+		return result.Tornado[i].SavingsSwing > result.Tornado[j].SavingsSwing
+	})
+	return result, nil
+}
+
+func (r SensitivityRange) values(name string) ([]float64, error) {
+	steps := r.Steps
+	if steps == 0 {
+		steps = defaultSensitivitySteps
+	}
+	if steps < 2 || steps > maxSensitivitySteps {
+		return nil, fmt.Errorf("%w: %s steps must be 2-%d", models.ErrInvalidSensitivityRange, name, maxSensitivitySteps)
+	}
+	if r.Low < 0 || r.High < r.Low {
+		return nil, fmt.Errorf("%w: %s needs 0 <= low <= high", models.ErrInvalidSensitivityRange, name)
+	}
+	values := make([]float64, steps)
+	for i := range values {
+		values[i] = math.Round((r.Low+(r.High-r.Low)*float64(i)/float64(steps-1))*1e6) / 1e6
+	}
+	return values, nil
+}
+
+// scaleQuoteProduction scales the production the quote is calculated with,
+// whichever source it comes from, by factor.
+func scaleQuoteProduction(input *QuoteInput, qc *quoteContext, baseAnnualKWh, factor float64) {
+	switch {
+	case input.AnnualProductionKWh > 0:
+		input.AnnualProductionKWh *= factor
+	case len(input.MonthlyProductionKWh) > 0:
+		monthly := make([]float64, len(input.MonthlyProductionKWh))
+		for i, kwh := range input.MonthlyProductionKWh {
+			monthly[i] = kwh * factor
+		}
+		input.MonthlyProductionKWh = monthly
+	case qc.production != nil:
+		scaled := *qc.production
+		scaled.AnnualKWh *= factor
+		scaled.MonthlyKWh = scaleValues(scaled.MonthlyKWh, factor)
+		scaled.HourlyKWh = scaleValues(scaled.HourlyKWh, factor)
+		qc.production = &scaled
+	default:
+		input.AnnualProductionKWh = baseAnnualKWh * factor
+	}
+}
+
+func scaleValues(values []float64, factor float64) []float64 {
+	scaled := make([]float64, len(values))
+	for i, v := range values {
+		scaled[i] = v * factor
+	}
+	return scaled
+}
+
+func sensitivityOutcome(r *QuoteResult) SensitivityOutcome {
+	return SensitivityOutcome{
+		TwentyFiveYearSavings: r.TwentyFiveYearSavings,
+		SimplePaybackYears:    r.SimplePaybackYears,
+		BreakEvenYear:         r.BreakEvenYear,
+	}
+}
@@ -1,16 +1,85 @@
 package service
 
 import (
-	"github.com/Bilal-Cplusoft/sunready/internal/repo"
-	"math"
+	"context"
+	"errors"
+	"encoding/json"
 	"fmt"
+	"math"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
 )
 
 type QuoteService struct {
-	quoteRepo *repo.QuoteRepo
+	quoteRepo          *repo.QuoteRepo
+	pricingProfileRepo *repo.PricingProfileRepo
+	incentiveRuleRepo  *repo.IncentiveRuleRepo
+	userRepo           *repo.UserRepo
+	hardwareRepo       *repo.HardwareRepo
+	eventBus           *EventBus
 }
 
+// QuoteInput is decoded from snake_case fields. The PascalCase names the
+// first fields were sent with before they were tagged are still accepted;
+// see UnmarshalJSON.
 type QuoteInput struct {
+	LeadID              *int    `json:"lead_id,omitempty"`
+	SystemSizeKW        float64 `json:"system_size_kw" example:"8"`
+	AnnualProductionKWh float64 `json:"annual_production_kwh" example:"11000"`
+	MonthlyElectricBill float64 `json:"monthly_electric_bill" example:"180"`
+	ElectricalOffsetPct float64 `json:"electrical_offset_pct" example:"95"`
+	PanelCount          int     `json:"panel_count" example:"20"`
+	State               string  `json:"state" example:"CA"`
+	// UtilityID is the LightFusion LSE ID of the utility; with State it
+	// selects the incentive programs that apply.
+	UtilityID             *int     `json:"utility_id,omitempty" example:"734"`
+	CostPerWatt           *float64 `json:"cost_per_watt,omitempty" example:"2.5"`
+	UtilityRatePerKWh     *float64 `json:"utility_rate_per_kwh,omitempty" example:"0.25"`
+	AnnualUtilityIncrease *float64 `json:"annual_utility_increase,omitempty" example:"0.03"`
+	FederalTaxCredit      *float64 `json:"federal_tax_credit,omitempty" example:"0.3"`
+	LoanInterestRate      *float64 `json:"loan_interest_rate,omitempty" example:"0.0699"`
+	LoanTermYears         *int     `json:"loan_term_years,omitempty" example:"25"`
+	// Financing lists scenarios to compare side by side in the result.
+	Financing []FinancingScenario `json:"financing,omitempty"`
+	// Detailed adds the loan amortization schedule and the year-by-year
+	// cash-flow table to the result.
+	Detailed bool `json:"detailed,omitempty"`
+
+	// Tariff switches bill savings from the flat-rate offset estimate to
+	// the billing engine. It needs 12 monthly consumption values; monthly
+	// production defaults to a seasonal split of annual production.
+	// Without a tariff, consumption priced at the utility rate stands in
+	// for a missing monthly bill.
+	Tariff                *Tariff   `json:"tariff,omitempty"`
+	MonthlyConsumptionKWh []float64 `json:"monthly_consumption_kwh,omitempty"`
+	MonthlyProductionKWh  []float64 `json:"monthly_production_kwh,omitempty"`
+
+	// Latitude and Longitude, when no production is given, switch the
+	// production estimate from sun hours to the offline simulator. TiltDeg
+	// and AzimuthDeg default to 20° facing south.
+	Latitude   *float64 `json:"latitude,omitempty" example:"37.7749"`
+	Longitude  *float64 `json:"longitude,omitempty" example:"-122.4194"`
+	TiltDeg    *float64 `json:"tilt_deg,omitempty" example:"20"`
+	AzimuthDeg *float64 `json:"azimuth_deg,omitempty" example:"180"`
+
+	// PanelID and InverterID select catalog hardware whose degradation and
+	// warranty data default the lifetime assumptions below.
+	PanelID                 *int     `json:"panel_id,omitempty" example:"1"`
+	InverterID              *int     `json:"inverter_id,omitempty" example:"1"`
+	FirstYearDegradation    *float64 `json:"first_year_degradation,omitempty" example:"0.02"`
+	AnnualDegradation       *float64 `json:"annual_degradation,omitempty" example:"0.0055"`
+	InverterReplacementYear *int     `json:"inverter_replacement_year,omitempty" example:"13"`
+	InverterReplacementCost *float64 `json:"inverter_replacement_cost,omitempty" example:"2500"`
+	AnnualOMCost            *float64 `json:"annual_om_cost,omitempty" example:"150"`
+	AnnualInsuranceCost     *float64 `json:"annual_insurance_cost,omitempty" example:"100"`
+}
+
+// quoteInputLegacy has the untagged field names QuoteInput used to be
+// decoded from. Older clients still send them and quote versions saved
+// before the fields were tagged are stored with them.
+type quoteInputLegacy struct {
 	SystemSizeKW          float64
 	AnnualProductionKWh   float64
 	MonthlyElectricBill   float64
@@ -25,112 +94,598 @@ type QuoteInput struct {
 	LoanTermYears         *int
 }
 
+// UnmarshalJSON reads the legacy field names first, so the snake_case
+// fields win when a body has both.
+func (in *QuoteInput) UnmarshalJSON(data []byte) error {
+	var legacy quoteInputLegacy
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	in.SystemSizeKW = legacy.SystemSizeKW
+	in.AnnualProductionKWh = legacy.AnnualProductionKWh
+	in.MonthlyElectricBill = legacy.MonthlyElectricBill
+	in.ElectricalOffsetPct = legacy.ElectricalOffsetPct
+	in.PanelCount = legacy.PanelCount
+	in.State = legacy.State
+	in.CostPerWatt = legacy.CostPerWatt
+	in.UtilityRatePerKWh = legacy.UtilityRatePerKWh
+	in.AnnualUtilityIncrease = legacy.AnnualUtilityIncrease
+	in.FederalTaxCredit = legacy.FederalTaxCredit
+	in.LoanInterestRate = legacy.LoanInterestRate
+	in.LoanTermYears = legacy.LoanTermYears
+
+	type plain QuoteInput
+	return json.Unmarshal(data, (*plain)(in))
+}
+
+const (
+	AssumptionSourceRequest = "request"
+	AssumptionSourceProfile = "profile"
+	AssumptionSourceCatalog = "catalog"
+	AssumptionSourceDefault = "default"
+)
+
+// Where a quote's annual production came from.
+const (
+	ProductionSourceRequest    = "request"
+	ProductionSourceSimulation = "simulation"
+	ProductionSourceSunHours   = "sun_hours"
+)
+
+// AssumptionSource says where a quote assumption came from. ProfileID and
+// ProfileName are set when Source is "profile".
+type AssumptionSource struct {
+	Source      string `json:"source" example:"profile"`
+	ProfileID   *int   `json:"profile_id,omitempty" example:"3"`
+	ProfileName string `json:"profile_name,omitempty" example:"California 2025"`
+}
+
+// QuoteAssumptions are the values a quote was calculated with. Each value is
+// taken from the request if given, else from the most specific pricing
+// profile that sets it, else from the selected catalog hardware, else from
+// the system default. Sources is keyed by the JSON name of each value.
+// An InverterReplacementYear of 0 means the inverter is not replaced within
+// the analysis period.
+type QuoteAssumptions struct {
+	CostPerWatt           float64 `json:"cost_per_watt" example:"2.5"`
+	UtilityRatePerKWh     float64 `json:"utility_rate_per_kwh" example:"0.25"`
+	AnnualUtilityIncrease float64 `json:"annual_utility_increase" example:"0.03"`
+	FederalTaxCredit      float64 `json:"federal_tax_credit" example:"0.3"`
+	LoanInterestRate      float64 `json:"loan_interest_rate" example:"0.0699"`
+	LoanTermYears         int     `json:"loan_term_years" example:"25"`
+	SunHoursPerDay        float64 `json:"sun_hours_per_day" example:"5"`
+	ElectricalOffsetPct   float64 `json:"electrical_offset_pct" example:"95"`
+
+	FirstYearDegradation    float64 `json:"first_year_degradation" example:"0.02"`
+	AnnualDegradation       float64 `json:"annual_degradation" example:"0.005"`
+	InverterReplacementYear int     `json:"inverter_replacement_year" example:"13"`
+	InverterReplacementCost float64 `json:"inverter_replacement_cost" example:"2500"`
+	AnnualOMCost            float64 `json:"annual_om_cost" example:"150"`
+	AnnualInsuranceCost     float64 `json:"annual_insurance_cost" example:"100"`
+
+	Sources map[string]AssumptionSource `json:"sources"`
+}
+
 type QuoteResult struct {
-	SystemCostBeforeIncentives float64 `json:"system_cost_before_incentives"`
-	FederalTaxCredit           float64 `json:"federal_tax_credit"`
-	SystemCostAfterIncentives  float64 `json:"system_cost_after_incentives"`
-	EstimatedMonthlyPayment    float64 `json:"estimated_monthly_payment"`
-	CurrentMonthlyBill         float64 `json:"current_monthly_bill"`
-	EstimatedNewMonthlyBill    float64 `json:"estimated_new_monthly_bill"`
-	MonthlySavings             float64 `json:"monthly_savings"`
-	FirstYearSavings           float64 `json:"first_year_savings"`
-	TwentyFiveYearSavings      float64 `json:"twenty_five_year_savings"`
-	SystemSizeKW               float64 `json:"system_size_kw"`
-	AnnualProductionKWh        float64 `json:"annual_production_kwh"`
-	PanelCount                 int     `json:"panel_count"`
-	ElectricalOffset           float64 `json:"electrical_offset_pct"`
-	CostPerWatt                float64 `json:"cost_per_watt"`
-	SimplePaybackYears         float64 `json:"simple_payback_years"`
-	BreakEvenYear              int     `json:"break_even_year"`
-	Summary                    string  `json:"summary"`
+	QuoteID                    *int              `json:"quote_id,omitempty" example:"7"`
+	LeadID                     *int              `json:"lead_id,omitempty" example:"42"`
+	Version                    int               `json:"version,omitempty" example:"3"`
+	SystemCostBeforeIncentives float64           `json:"system_cost_before_incentives"`
+	FederalTaxCredit           float64           `json:"federal_tax_credit"`
+	TaxCreditBasis             float64           `json:"tax_credit_basis"`
+	Incentives                 []QuoteIncentive  `json:"incentives,omitempty"`
+	// TotalIncentives includes per-kWh incentives paid over the years after
+	// installation; SystemCostAfterIncentives only takes off those paid at
+	// installation.
+	TotalIncentives            float64           `json:"total_incentives"`
+	SystemCostAfterIncentives  float64           `json:"system_cost_after_incentives"`
+	EstimatedMonthlyPayment    float64           `json:"estimated_monthly_payment"`
+	CurrentMonthlyBill         float64           `json:"current_monthly_bill"`
+	EstimatedNewMonthlyBill    float64           `json:"estimated_new_monthly_bill"`
+	MonthlySavings             float64           `json:"monthly_savings"`
+	FirstYearSavings           float64           `json:"first_year_savings"`
+	TwentyFiveYearSavings      float64           `json:"twenty_five_year_savings"`
+	SystemSizeKW               float64           `json:"system_size_kw"`
+	AnnualProductionKWh        float64           `json:"annual_production_kwh"`
+	ProductionSource           string            `json:"production_source" example:"simulation"`
+	PanelCount                 int               `json:"panel_count"`
+	ElectricalOffset           float64           `json:"electrical_offset_pct"`
+	CostPerWatt                float64           `json:"cost_per_watt"`
+	SimplePaybackYears         float64           `json:"simple_payback_years"`
+	BreakEvenYear              int               `json:"break_even_year"`
+	Summary                    string            `json:"summary"`
+	Assumptions                *QuoteAssumptions `json:"assumptions,omitempty"`
+	Financing                  []FinancingResult `json:"financing,omitempty"`
+	// Production is the simulated year, without hourly values, when the
+	// production source is the simulator.
+	Production *ProductionEstimate `json:"production,omitempty"`
+	// Billing holds the first-year monthly bills when a tariff was given.
+	Billing *BillingResult `json:"billing,omitempty"`
+
+	// Set in detailed mode only; they describe the default loan.
+	Amortization []AmortizationRow `json:"amortization,omitempty"`
+	CashFlow     []CashFlowYear    `json:"cash_flow,omitempty"`
+}
+
+// QuoteVersion is a stored quote with its JSON columns decoded.
+type QuoteVersion struct {
+	ID          int              `json:"id" example:"7"`
+	LeadID      *int             `json:"lead_id" example:"42"`
+	UserID      int              `json:"user_id" example:"1"`
+	Version     int              `json:"version" example:"3"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Input       QuoteInput       `json:"input"`
+	Assumptions QuoteAssumptions `json:"assumptions"`
+	Result      QuoteResult      `json:"result"`
+}
+
+func NewQuoteService(quoteRepo *repo.QuoteRepo, pricingProfileRepo *repo.PricingProfileRepo, incentiveRuleRepo *repo.IncentiveRuleRepo, userRepo *repo.UserRepo, hardwareRepo *repo.HardwareRepo, eventBus *EventBus) *QuoteService {
+	return &QuoteService{
+		quoteRepo:          quoteRepo,
+		pricingProfileRepo: pricingProfileRepo,
+		incentiveRuleRepo:  incentiveRuleRepo,
+		userRepo:           userRepo,
+		hardwareRepo:       hardwareRepo,
+		eventBus:           eventBus,
+	}
+}
+
+func defaultQuoteAssumptions() QuoteAssumptions {
+	return QuoteAssumptions{
+		CostPerWatt:           2.50,
+		UtilityRatePerKWh:     0.25,
+		AnnualUtilityIncrease: 0.03,
+		FederalTaxCredit:      0.30,
+		LoanInterestRate:      0.0699,
+		LoanTermYears:         25,
+		SunHoursPerDay:        5.0,
+		ElectricalOffsetPct:   95.0,
+		FirstYearDegradation:  0.02,
+		AnnualDegradation:     0.005,
+	}
+}
+
+// quoteHardware is the catalog hardware selected for a quote. Either may be
+// nil.
+type quoteHardware struct {
+	panel    *models.Panel
+	inverter *models.Inverter
+}
+
+// resolveAssumptions resolves every assumption from the request overrides
+// in input, then profiles (most specific first), then the catalog hardware,
+// then the defaults.
+func resolveAssumptions(input QuoteInput, profiles []*models.PricingProfile, hw quoteHardware) QuoteAssumptions {
+	a := defaultQuoteAssumptions()
+	a.Sources = make(map[string]AssumptionSource)
+
+	loanTermYears := input.LoanTermYears
+	if loanTermYears != nil && *loanTermYears <= 0 {
+		loanTermYears = nil
+	}
+	var offsetPct *float64
+	if input.ElectricalOffsetPct > 0 {
+		offsetPct = &input.ElectricalOffsetPct
+	}
+
+	resolveAssumption(a.Sources, "cost_per_watt", &a.CostPerWatt, input.CostPerWatt, profiles,
+		func(p *models.PricingProfile) *float64 { return p.CostPerWatt }, nil)
+	resolveAssumption(a.Sources, "utility_rate_per_kwh", &a.UtilityRatePerKWh, input.UtilityRatePerKWh, profiles,
+		func(p *models.PricingProfile) *float64 { return p.UtilityRatePerKWh }, nil)
+	resolveAssumption(a.Sources, "annual_utility_increase", &a.AnnualUtilityIncrease, input.AnnualUtilityIncrease, profiles,
+		func(p *models.PricingProfile) *float64 { return p.AnnualUtilityIncrease }, nil)
+	resolveAssumption(a.Sources, "federal_tax_credit", &a.FederalTaxCredit, input.FederalTaxCredit, profiles,
+		func(p *models.PricingProfile) *float64 { return p.FederalTaxCredit }, nil)
+	resolveAssumption(a.Sources, "loan_interest_rate", &a.LoanInterestRate, input.LoanInterestRate, profiles,
+		func(p *models.PricingProfile) *float64 { return p.LoanInterestRate }, nil)
+	resolveAssumption(a.Sources, "loan_term_years", &a.LoanTermYears, loanTermYears, profiles,
+		func(p *models.PricingProfile) *int { return p.LoanTermYears }, nil)
+	resolveAssumption(a.Sources, "sun_hours_per_day", &a.SunHoursPerDay, nil, profiles,
+		func(p *models.PricingProfile) *float64 { return p.SunHoursPerDay }, nil)
+	resolveAssumption(a.Sources, "electrical_offset_pct", &a.ElectricalOffsetPct, offsetPct, profiles,
+		func(p *models.PricingProfile) *float64 { return p.ElectricalOffsetPct }, nil)
+
+	var firstYearDegradation, annualDegradation *float64
+	if hw.panel != nil {
+		firstYearDegradation, annualDegradation = hw.panel.FirstYearDegradation, hw.panel.AnnualDegradation
+	}
+	var replacementYear *int
+	var replacementCost *float64
+	if hw.inverter != nil && hw.inverter.WarrantyYears != nil && *hw.inverter.WarrantyYears < quoteAnalysisYears {
+		year := *hw.inverter.WarrantyYears + 1
+		replacementYear = &year
+		if hw.inverter.UnitCost != nil {
+			units := 1.0
+			if hw.inverter.Capacity > 0 {
+				units = math.Ceil(input.SystemSizeKW / hw.inverter.Capacity)
+			}
+			cost := *hw.inverter.UnitCost * units
+			replacementCost = &cost
+		}
+	}
+	resolveAssumption(a.Sources, "first_year_degradation", &a.FirstYearDegradation, input.FirstYearDegradation, nil, nil, firstYearDegradation)
+	resolveAssumption(a.Sources, "annual_degradation", &a.AnnualDegradation, input.AnnualDegradation, nil, nil, annualDegradation)
+	resolveAssumption(a.Sources, "inverter_replacement_year", &a.InverterReplacementYear, input.InverterReplacementYear, nil, nil, replacementYear)
+	resolveAssumption(a.Sources, "inverter_replacement_cost", &a.InverterReplacementCost, input.InverterReplacementCost, nil, nil, replacementCost)
+	resolveAssumption(a.Sources, "annual_om_cost", &a.AnnualOMCost, input.AnnualOMCost, nil, nil, nil)
+	resolveAssumption(a.Sources, "annual_insurance_cost", &a.AnnualInsuranceCost, input.AnnualInsuranceCost, nil, nil, nil)
+	return a
+}
+
+func resolveAssumption[T any](sources map[string]AssumptionSource, key string, dst *T, override *T, profiles []*models.PricingProfile, fromProfile func(*models.PricingProfile) *T, catalog *T) {
+	if override != nil {
+		*dst = *override
+		sources[key] = AssumptionSource{Source: AssumptionSourceRequest}
+		return
+	}
+	if fromProfile != nil {
+		for _, p := range profiles {
+			if v := fromProfile(p); v != nil {
+				*dst = *v
+				id := p.ID
+				sources[key] = AssumptionSource{Source: AssumptionSourceProfile, ProfileID: &id, ProfileName: p.Name}
+				return
+			}
+		}
+	}
+	if catalog != nil {
+		*dst = *catalog
+		sources[key] = AssumptionSource{Source: AssumptionSourceCatalog}
+		return
+	}
+	sources[key] = AssumptionSource{Source: AssumptionSourceDefault}
+}
+
+// ResolveAssumptions resolves the quote assumptions for input against the
+// pricing profiles of the user's company in effect now and the selected
+// catalog hardware.
+func (s *QuoteService) ResolveAssumptions(ctx context.Context, input QuoteInput, userID int) (QuoteAssumptions, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return QuoteAssumptions{}, fmt.Errorf("failed to get user: %w", err)
+	}
+	profiles, err := s.pricingProfileRepo.ListApplicable(ctx, user.CompanyID, input.State, time.Now())
+	if err != nil {
+		return QuoteAssumptions{}, err
+	}
+	hw, err := s.loadHardware(ctx, input)
+	if err != nil {
+		return QuoteAssumptions{}, err
+	}
+	return resolveAssumptions(input, profiles, hw), nil
+}
+
+func (s *QuoteService) loadHardware(ctx context.Context, input QuoteInput) (quoteHardware, error) {
+	var hw quoteHardware
+	var err error
+	if input.PanelID != nil {
+		if hw.panel, err = s.hardwareRepo.GetPanelByID(ctx, *input.PanelID); err != nil {
+			return quoteHardware{}, err
+		}
+	}
+	if input.InverterID != nil {
+		if hw.inverter, err = s.hardwareRepo.GetInverterByID(ctx, *input.InverterID); err != nil {
+			return quoteHardware{}, err
+		}
+	}
+	return hw, nil
+}
+
+// simulateQuoteProduction runs the production simulator when the input has
+// coordinates but no production of its own; otherwise it returns nil. It
+// also returns nil where there is no climate data, so the quote falls back
+// to sun hours.
+func (s *QuoteService) simulateQuoteProduction(ctx context.Context, input QuoteInput) (*ProductionEstimate, error) {
+	if input.Latitude == nil || input.Longitude == nil || input.SystemSizeKW <= 0 ||
+		input.AnnualProductionKWh > 0 || len(input.MonthlyProductionKWh) > 0 {
+		return nil, nil
+	}
+	hw, err := s.loadHardware(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	production, err := SimulateProduction(productionInputFor(*input.Latitude, *input.Longitude, input.SystemSizeKW, input.PanelCount, nil,
+		input.TiltDeg, input.AzimuthDeg, hw.panel, hw.inverter))
+	if errors.Is(err, models.ErrNoClimateData) {
+		return nil, nil
+	}
+	return production, err
 }
 
+// prepareQuote gathers everything calculateQuote needs for input: the
+// resolved assumptions, the simulated production and the incentives.
+func (s *QuoteService) prepareQuote(ctx context.Context, input QuoteInput, userID int) (QuoteAssumptions, quoteContext, error) {
+	assumptions, err := s.ResolveAssumptions(ctx, input, userID)
+	if err != nil {
+		return QuoteAssumptions{}, quoteContext{}, err
+	}
+	production, err := s.simulateQuoteProduction(ctx, input)
+	if err != nil {
+		return QuoteAssumptions{}, quoteContext{}, err
+	}
+	incentiveRules, err := s.incentiveRuleRepo.ListApplicable(ctx, input.State, input.UtilityID, time.Now())
+	if err != nil {
+		return QuoteAssumptions{}, quoteContext{}, err
+	}
+	return assumptions, quoteContext{production: production, incentiveRules: incentiveRules}, nil
+}
 
-func NewQuoteService(quoteRepo *repo.QuoteRepo) *QuoteService {
-	return &QuoteService{quoteRepo: quoteRepo}
+// CalculateQuote calculates a quote and stores it as a new version, attached
+// to input.LeadID when set. The stored result is always detailed so that
+// proposals can chart its cash flow.
+func (s *QuoteService) CalculateQuote(ctx context.Context, input QuoteInput, userID int) (*QuoteResult, error) {
+	assumptions, qc, err := s.prepareQuote(ctx, input, userID)
+	if err != nil {
+		return nil, err
+	}
+	detailed := input
+	detailed.Detailed = true
+	result, err := calculateQuote(detailed, assumptions, qc)
+	if err != nil {
+		return nil, err
+	}
+
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode quote input: %w", err)
+	}
+	assumptionsJSON, err := json.Marshal(assumptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode quote assumptions: %w", err)
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode quote result: %w", err)
+	}
+	quote := &models.Quote{
+		LeadID:      input.LeadID,
+		UserID:      userID,
+		Input:       string(inputJSON),
+		Assumptions: string(assumptionsJSON),
+		Result:      string(resultJSON),
+	}
+	if err := s.quoteRepo.Create(ctx, quote); err != nil {
+		return nil, err
+	}
+
+	result.QuoteID = &quote.ID
+	result.LeadID = quote.LeadID
+	result.Version = quote.Version
+	if !input.Detailed {
+		result.dropDetail()
+	}
+	if quote.LeadID != nil {
+		s.eventBus.Publish(*quote.LeadID, LeadEventQuote, result)
+	}
+	return result, nil
+}
+
+func (s *QuoteService) ListVersions(ctx context.Context, leadID int) ([]*QuoteVersion, error) {
+	quotes, err := s.quoteRepo.ListByLead(ctx, leadID)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]*QuoteVersion, 0, len(quotes))
+	for _, quote := range quotes {
+		version, err := decodeQuote(quote)
+		if err != nil {
+			return nil, err
+		}
+		if !version.Input.Detailed {
+			version.Result.dropDetail()
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func (s *QuoteService) GetVersion(ctx context.Context, leadID, version int) (*QuoteVersion, error) {
+	quote, err := s.quoteRepo.GetByLeadVersion(ctx, leadID, version)
+	if err != nil {
+		return nil, err
+	}
+	v, err := decodeQuote(quote)
+	if err != nil {
+		return nil, err
+	}
+	if !v.Input.Detailed {
+		v.Result.dropDetail()
+	}
+	return v, nil
+}
+
+// dropDetail removes the schedules only detailed mode returns.
+func (r *QuoteResult) dropDetail() {
+	r.Amortization = nil
+	r.CashFlow = nil
+	for i := range r.Financing {
+		r.Financing[i].Amortization = nil
+		r.Financing[i].CashFlow = nil
+	}
+}
+
+func decodeQuote(quote *models.Quote) (*QuoteVersion, error) {
+	v := &QuoteVersion{
+		ID:        quote.ID,
+		LeadID:    quote.LeadID,
+		UserID:    quote.UserID,
+		Version:   quote.Version,
+		CreatedAt: quote.CreatedAt,
+	}
+	if err := json.Unmarshal([]byte(quote.Input), &v.Input); err != nil {
+		return nil, fmt.Errorf("failed to decode input of quote %d: %w", quote.ID, err)
+	}
+	if err := json.Unmarshal([]byte(quote.Assumptions), &v.Assumptions); err != nil {
+		return nil, fmt.Errorf("failed to decode assumptions of quote %d: %w", quote.ID, err)
+	}
+	if err := json.Unmarshal([]byte(quote.Result), &v.Result); err != nil {
+		return nil, fmt.Errorf("failed to decode result of quote %d: %w", quote.ID, err)
+	}
+	v.Result.QuoteID = &v.ID
+	v.Result.LeadID = v.LeadID
+	v.Result.Version = v.Version
+	return v, nil
 }
 
+// quoteContext is what a quote needs beyond its input and assumptions.
+// production is the simulated year, if any; incentiveRules are the
+// programs the quote qualifies for.
+type quoteContext struct {
+	production     *ProductionEstimate
+	incentiveRules []*models.IncentiveRule
+}
 
-func (s *QuoteService) CalculateQuote(input QuoteInput) (*QuoteResult, error) {
+// calculateQuote is the pure quote calculation; it has no side effects.
+// Production given in the input wins over the simulated production, which
+// wins over the sun-hours estimate.
+func calculateQuote(input QuoteInput, a QuoteAssumptions, qc quoteContext) (*QuoteResult, error) {
 	if input.SystemSizeKW <= 0 {
-		return nil, fmt.Errorf("system size must be greater than 0")
-	}
-	if input.MonthlyElectricBill <= 0 {
-		return nil, fmt.Errorf("monthly electric bill must be greater than 0")
-	}
-	costPerWatt := 2.50
-	utilityRate := 0.25
-	annualIncrease := 0.03
-	taxCredit := 0.30
-	interestRate := 0.0699
-	loanTermYears := 25
-	sunHoursPerDay := 5.0
-	electricalOffsetPct := 95.0
+		return nil, models.ErrInvalidQuoteSystemSize
+	}
+	if input.Tariff == nil && input.MonthlyElectricBill <= 0 && len(input.MonthlyConsumptionKWh) == 12 {
+		for _, kwh := range input.MonthlyConsumptionKWh {
+			input.MonthlyElectricBill += kwh * a.UtilityRatePerKWh / 12
+		}
+	}
+	if input.Tariff == nil && input.MonthlyElectricBill <= 0 {
+		return nil, models.ErrInvalidQuoteMonthlyBill
+	}
+	if input.Tariff != nil && len(input.MonthlyConsumptionKWh) != 12 {
+		return nil, fmt.Errorf("%w: a tariff needs 12 monthly_consumption_kwh values", models.ErrInvalidBillingInput)
+	}
+	if len(input.MonthlyProductionKWh) > 0 && len(input.MonthlyProductionKWh) != 12 {
+		return nil, fmt.Errorf("%w: monthly_production_kwh needs 12 values", models.ErrInvalidBillingInput)
+	}
+	if !validEnergyValues(input.MonthlyConsumptionKWh) || !validEnergyValues(input.MonthlyProductionKWh) {
+		return nil, fmt.Errorf("%w: monthly_consumption_kwh and monthly_production_kwh must be numbers of 0 or more", models.ErrInvalidBillingInput)
+	}
+	for _, scenario := range input.Financing {
+		if err := scenario.resolved().validate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.validateLifetime(); err != nil {
+		return nil, err
+	}
+	costPerWatt := a.CostPerWatt
+	utilityRate := a.UtilityRatePerKWh
+	taxCredit := a.FederalTaxCredit
+	interestRate := a.LoanInterestRate
+	loanTermYears := a.LoanTermYears
+	sunHoursPerDay := a.SunHoursPerDay
+	electricalOffsetPct := a.ElectricalOffsetPct
 	annualProductionKWh := input.SystemSizeKW * sunHoursPerDay * 365 * 0.75
+	productionSource := ProductionSourceSunHours
+	production := qc.production
+	var hourlyProduction []float64
+	if input.AnnualProductionKWh > 0 {
+		annualProductionKWh = input.AnnualProductionKWh
+		productionSource = ProductionSourceRequest
+		production = nil
+	} else if len(input.MonthlyProductionKWh) == 12 {
+		annualProductionKWh = 0
+		for _, kwh := range input.MonthlyProductionKWh {
+			annualProductionKWh += kwh
+		}
+		productionSource = ProductionSourceRequest
+		production = nil
+	} else if production != nil {
+		annualProductionKWh = production.AnnualKWh
+		productionSource = ProductionSourceSimulation
+		hourlyProduction = production.HourlyKWh
+		summary := *production
+		summary.HourlyKWh = nil
+		production = &summary
+	}
 	panelCount := input.PanelCount
 	systemSizeWatts := input.SystemSizeKW * 1000
 	systemCostBeforeIncentives := systemSizeWatts * costPerWatt
-	federalTaxCreditAmount := systemCostBeforeIncentives * taxCredit
-	systemCostAfterIncentives := systemCostBeforeIncentives - federalTaxCreditAmount
-	monthlyRate := interestRate / 12
-	numPayments := float64(loanTermYears * 12)
-	monthlyPayment := 0.0
-	if interestRate > 0 {
-		monthlyPayment = systemCostBeforeIncentives *
-			(monthlyRate * math.Pow(1+monthlyRate, numPayments)) /
-			(math.Pow(1+monthlyRate, numPayments) - 1)
-	} else {
-		monthlyPayment = systemCostBeforeIncentives / numPayments
-	}
-
-	annualCurrentBill := input.MonthlyElectricBill * 12
-	offsetRatio := electricalOffsetPct / 100.0
-	annualSolarSavings := annualProductionKWh * utilityRate
-	remainingUsagePct := math.Max(0, 1.0-offsetRatio)
-	newMonthlyBill := input.MonthlyElectricBill * remainingUsagePct
-	monthlySavingsFromSolar := input.MonthlyElectricBill - newMonthlyBill
-	netMonthlySavings := monthlySavingsFromSolar - monthlyPayment
-	annualSavingsFromReducedBill := (input.MonthlyElectricBill - newMonthlyBill) * 12
-	firstYearSavings := annualSavingsFromReducedBill - (monthlyPayment * 12)
+	productionByYear := make([]float64, quoteAnalysisYears)
+	for year := range productionByYear {
+		productionByYear[year] = annualProductionKWh * a.outputFactor(year)
+	}
+	incentives := evaluateIncentives(qc.incentiveRules, input.SystemSizeKW, systemCostBeforeIncentives, productionByYear)
+	cost, taxCreditBasis := newQuoteCost(systemCostBeforeIncentives, taxCredit, incentives)
+	federalTaxCreditAmount := cost.federalTaxCredit
+	systemCostAfterIncentives := cost.afterIncentives()
+	monthlyPayment := amortizedPayment(systemCostBeforeIncentives, interestRate, loanTermYears)
 
+	bills, billing, err := quoteBillModel(input, a, annualProductionKWh, hourlyProduction)
+	if err != nil {
+		return nil, err
+	}
+	annualCurrentBill, annualNewBill := bills(1)
+	currentMonthlyBill := annualCurrentBill / 12
+	newMonthlyBill := annualNewBill / 12
+	monthlySavingsFromSolar := currentMonthlyBill - newMonthlyBill
+	annualOwnershipCost := a.AnnualOMCost + a.AnnualInsuranceCost
+	netMonthlySavings := monthlySavingsFromSolar - monthlyPayment - annualOwnershipCost/12
+	if billing != nil {
+		consumption := 0.0
+		for _, kwh := range input.MonthlyConsumptionKWh {
+			consumption += kwh
+		}
+		if consumption > 0 {
+			electricalOffsetPct = math.Round(annualProductionKWh/consumption*10000) / 100
+		}
+	}
+
+	baseline := newQuoteBaseline(a, bills, annualProductionKWh, cost, quoteBreakEvenYears)
+	loanPayments := make([]float64, quoteBreakEvenYears)
+	for year := 0; year < quoteBreakEvenYears && year < loanTermYears; year++ {
+		loanPayments[year] = monthlyPayment * 12
+	}
+	firstYearSavings := baseline.billWithoutSolar[0] - baseline.billWithSolar[0] - loanPayments[0] - baseline.ownershipCosts[0] +
+		baseline.incentivePayments[0]
 
 	totalUtilityCostWithoutSolar := 0.0
 	totalCostWithSolar := 0.0
-	for year := 1; year <= 25; year++ {
-		yearlyBill := annualCurrentBill * math.Pow(1+annualIncrease, float64(year-1))
-		totalUtilityCostWithoutSolar += yearlyBill
-
-		yearlyNewBill := newMonthlyBill * 12 * math.Pow(1+annualIncrease, float64(year-1))
-		if year <= loanTermYears {
-			totalCostWithSolar += (monthlyPayment * 12) + yearlyNewBill
-		} else {
-			totalCostWithSolar += yearlyNewBill
-		}
+	for year := 0; year < quoteAnalysisYears; year++ {
+		totalUtilityCostWithoutSolar += baseline.billWithoutSolar[year]
+		totalCostWithSolar += loanPayments[year] + baseline.billWithSolar[year] + baseline.ownershipCosts[year] -
+			baseline.incentivePayments[year]
 	}
 	twentyFiveYearSavings := totalUtilityCostWithoutSolar - totalCostWithSolar
-	simplePayback := systemCostAfterIncentives / annualSolarSavings
+
+	annualSolarSavings := annualProductionKWh*utilityRate - annualOwnershipCost
+	if billing != nil {
+		annualSolarSavings = billing.AnnualSavings - annualOwnershipCost
+	}
+	simplePayback := 0.0
+	if annualSolarSavings > 0 {
+		simplePayback = systemCostAfterIncentives / annualSolarSavings
+	}
+	// Break-even is the year the savings pay back the system's net cost:
+	// the tax credit and upfront incentives are taken off the price, and
+	// loan payments are left out since they pay for that same system.
 	breakEvenYear := 0
 	cumulativeSavings := 0.0
-	for year := 1; year <= 30; year++ {
-		yearlyBill := annualCurrentBill * math.Pow(1+annualIncrease, float64(year-1))
-		yearlySolarSavings := yearlyBill * offsetRatio
-
-		if year <= loanTermYears {
-			cumulativeSavings += yearlySolarSavings - (monthlyPayment * 12)
-		} else {
-			cumulativeSavings += yearlySolarSavings
-		}
-
-		if cumulativeSavings >= systemCostBeforeIncentives && breakEvenYear == 0 {
-			breakEvenYear = year
+	for year := 0; year < quoteBreakEvenYears; year++ {
+		cumulativeSavings += baseline.billWithoutSolar[year] - baseline.billWithSolar[year] -
+			baseline.ownershipCosts[year] + baseline.incentivePayments[year]
+		if cumulativeSavings >= systemCostAfterIncentives {
+			breakEvenYear = year + 1
 			break
 		}
 	}
 
+	analysis := baseline.firstYears(quoteAnalysisYears)
+	var financing []FinancingResult
+	for _, scenario := range input.Financing {
+		financing = append(financing, evaluateFinancing(scenario, analysis, input.Detailed))
+	}
+
+	var amortization []AmortizationRow
+	var cashFlow []CashFlowYear
+	if input.Detailed {
+		amortization = amortizationSchedule(systemCostBeforeIncentives, interestRate, loanTermYears)
+		cashFlow = cashFlowTable(analysis, loanPayments[:quoteAnalysisYears], analysis.ownershipCosts, analysis.incentivePayments, 0)
+	}
+
 	summary := fmt.Sprintf(
 		"This %.2f kW solar system with %d panels will produce approximately %.0f kWh annually, "+
 			"offsetting %.0f%% of your electricity usage. "+
-			"The system costs $%.2f before incentives ($%.2f after federal tax credit). "+
+			"The system costs $%.2f before incentives ($%.2f after the federal tax credit and incentives). "+
 			"Your estimated monthly payment is $%.2f, and you'll save approximately $%.2f in the first year. "+
 			"Over 25 years, your total savings are estimated at $%.2f.",
 		input.SystemSizeKW,
@@ -147,20 +702,30 @@ func (s *QuoteService) CalculateQuote(input QuoteInput) (*QuoteResult, error) {
 	return &QuoteResult{
 		SystemCostBeforeIncentives: math.Round(systemCostBeforeIncentives*100) / 100,
 		FederalTaxCredit:           math.Round(federalTaxCreditAmount*100) / 100,
+		TaxCreditBasis:             round2(taxCreditBasis),
+		Incentives:                 incentives,
+		TotalIncentives:            round2(cost.totalIncentives()),
 		SystemCostAfterIncentives:  math.Round(systemCostAfterIncentives*100) / 100,
 		EstimatedMonthlyPayment:    math.Round(monthlyPayment*100) / 100,
-		CurrentMonthlyBill:         input.MonthlyElectricBill,
+		CurrentMonthlyBill:         round2(currentMonthlyBill),
 		EstimatedNewMonthlyBill:    math.Round(newMonthlyBill*100) / 100,
 		MonthlySavings:             math.Round(netMonthlySavings*100) / 100,
 		FirstYearSavings:           math.Round(firstYearSavings*100) / 100,
 		TwentyFiveYearSavings:      math.Round(twentyFiveYearSavings*100) / 100,
 		SystemSizeKW:               input.SystemSizeKW,
 		AnnualProductionKWh:        math.Round(annualProductionKWh*100) / 100,
+		ProductionSource:           productionSource,
+		Production:                 production,
 		PanelCount:                 panelCount,
 		ElectricalOffset:           electricalOffsetPct,
 		CostPerWatt:                costPerWatt,
 		SimplePaybackYears:         math.Round(simplePayback*100) / 100,
 		BreakEvenYear:              breakEvenYear,
 		Summary:                    summary,
+		Assumptions:                &a,
+		Financing:                  financing,
+		Billing:                    billing,
+		Amortization:               amortization,
+		CashFlow:                   cashFlow,
 	}, nil
 }
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+)
+
+// The production simulator, storage sizing and design temperatures read
+// monthly clearness index (kt) and mean air temperature from a grid of 5°
+// cells keyed by the cell's south-west corner. No grid is bundled: it must
+// be derived from typical-meteorological-year data, such as NSRDB TMY files
+// aggregated by month, and loaded with UseClimateGridFile. Until one is,
+// every location reports ErrNoClimateData and quotes keep to sun hours.
+const climateCellDeg = 5.0
+
+type climateCell struct {
+	latMin, lngMin float64
+	kt             [12]float64
+	tempC          [12]float64
+}
+
+func (c climateCell) name() string {
+	return fmt.Sprintf("%.0f,%.0f", c.latMin, c.lngMin)
+}
+
+var climateCells []climateCell
+
+// UseClimateGridFile loads the climate grid from the CSV at path, which has
+// a header row then one row per cell: latitude and longitude of the cell's
+// south-west corner, 12 monthly kt values and 12 monthly mean temperatures
+// in °C. It must be called before the first simulation.
+func UseClimateGridFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read climate grid: %w", err)
+	}
+	cells, err := parseClimateGrid(data)
+	if err != nil {
+		return err
+	}
+	if len(cells) == 0 {
+		return fmt.Errorf("climate grid %s is empty", path)
+	}
+	climateCells = cells
+	return nil
+}
+
+func parseClimateGrid(data []byte) ([]climateCell, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read climate grid: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	var cells []climateCell
+	for i, record := range records[1:] {
+		if len(record) != 26 {
+			return nil, fmt.Errorf("climate grid row %d has %d columns", i+2, len(record))
+		}
+		values := make([]float64, len(record))
+		for j, field := range record {
+			if values[j], err = strconv.ParseFloat(field, 64); err != nil {
+				return nil, fmt.Errorf("climate grid row %d: %w", i+2, err)
+			}
+		}
+		cell := climateCell{latMin: values[0], lngMin: values[1]}
+		copy(cell.kt[:], values[2:14])
+		copy(cell.tempC[:], values[14:26])
+		cells = append(cells, cell)
+	}
+	return cells, nil
+}
+
+// climateFallbackDeg is how far outside the grid the nearest cell is still
+// used; beyond it there is no climate data for the location.
+const climateFallbackDeg = 2 * climateCellDeg
+
+// climateFor returns the grid cell containing the location, or the nearest
+// cell when it lies just outside the grid. Locations further out, and every
+// location when no grid is loaded, return ErrNoClimateData rather than a
+// guessed climate.
+func climateFor(lat, lng float64) (climateCell, error) {
+	cells := climateCells
+	if len(cells) == 0 {
+		return climateCell{}, fmt.Errorf("%w: no climate grid is loaded", models.ErrNoClimateData)
+	}
+	best := cells[0]
+	bestDist := math.Inf(1)
+	for _, c := range cells {
+		dLat := lat - (c.latMin + climateCellDeg/2)
+		dLng := (lng - (c.lngMin + climateCellDeg/2)) * math.Cos(lat*math.Pi/180)
+		if d := dLat*dLat + dLng*dLng; d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	if math.Sqrt(bestDist) > climateFallbackDeg {
+		return climateCell{}, models.ErrNoClimateData
+	}
+	return best, nil
+}
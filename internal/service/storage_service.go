@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+)
+
+// Defaults for StorageAnalysisRequest fields left at zero.
+const (
+	defaultCriticalLoadPct   = 50.0
+	defaultBackupReservePct  = 20.0
+	defaultTargetBackupHours = 12.0
+	// catalogRecommendationQuantity bounds the banks tried for each unit
+	// when recommending from the whole catalog; a selected unit is tried
+	// up to models.MaxStorageQuantity.
+	catalogRecommendationQuantity = 4
+)
+
+type StorageService struct {
+	hardwareRepo      *repo.HardwareRepo
+	productionService *ProductionService
+}
+
+// StorageAnalysisRequest selects the storage to analyse for a lead.
+// StorageID and Quantity default to the lead's storage; for a lead without
+// one, only a recommendation from the whole catalog is made. Without a
+// tariff the lead is billed at the default flat utility rate with full net
+// metering, under which storage saves nothing and only backup matters.
+// MonthlyConsumptionKWh defaults to the lead's consumption.
+type StorageAnalysisRequest struct {
+	StorageID             *int      `json:"storage_id,omitempty" example:"2"`
+	Quantity              int       `json:"quantity,omitempty" example:"2"`
+	CriticalLoadPct       float64   `json:"critical_load_pct,omitempty" example:"50"`
+	BackupReservePct      *float64  `json:"backup_reserve_pct,omitempty" example:"20"`
+	TargetBackupHours     float64   `json:"target_backup_hours,omitempty" example:"12"`
+	AllowGridCharging     bool      `json:"allow_grid_charging,omitempty"`
+	Tariff                *Tariff   `json:"tariff,omitempty"`
+	MonthlyConsumptionKWh []float64 `json:"monthly_consumption_kwh,omitempty"`
+	TiltDeg               *float64  `json:"tilt_deg,omitempty" example:"20"`
+	AzimuthDeg            *float64  `json:"azimuth_deg,omitempty" example:"180"`
+}
+
+// StorageOption is the simulation of a quantity of one catalog storage unit.
+type StorageOption struct {
+	StorageID    int    `json:"storage_id" example:"2"`
+	Manufacturer string `json:"manufacturer" example:"Tesla"`
+	Model        string `json:"model" example:"Powerwall 3"`
+	Quantity     int    `json:"quantity" example:"2"`
+	StorageSimulation
+}
+
+type StorageAnalysis struct {
+	LeadID               int     `json:"lead_id" example:"42"`
+	AnnualConsumptionKWh float64 `json:"annual_consumption_kwh" example:"12000"`
+	AnnualProductionKWh  float64 `json:"annual_production_kwh" example:"11800"`
+	CriticalLoadPct      float64 `json:"critical_load_pct" example:"50"`
+	TargetBackupHours    float64 `json:"target_backup_hours" example:"12"`
+	// Selected is the requested storage, when one was given.
+	Selected *StorageOption `json:"selected,omitempty"`
+	// Recommendation is the smallest bank whose average backup meets the
+	// target, or the longest-lasting one if none does.
+	Recommendation       *StorageOption `json:"recommendation"`
+	RecommendationReason string         `json:"recommendation_reason" example:"Smallest bank averaging at least 12 hours of backup"`
+}
+
+func NewStorageService(hardwareRepo *repo.HardwareRepo, productionService *ProductionService) *StorageService {
+	return &StorageService{hardwareRepo: hardwareRepo, productionService: productionService}
+}
+
+// AnalyzeLead simulates storage on top of the lead's solar system and
+// recommends a storage quantity. The selected unit is the only candidate
+// for the recommendation when given; otherwise every catalog unit is.
+func (s *StorageService) AnalyzeLead(ctx context.Context, lead *models.Lead, req StorageAnalysisRequest) (*StorageAnalysis, error) {
+	consumption := req.MonthlyConsumptionKWh
+	if consumption == nil {
+		var err error
+		if consumption, err = leadMonthlyConsumption(lead); err != nil {
+			return nil, fmt.Errorf("%w: %w", models.ErrInvalidStorageInput, err)
+		}
+	}
+	if len(consumption) != 12 {
+		return nil, fmt.Errorf("%w: 12 monthly consumption values are needed and the lead has none", models.ErrInvalidStorageInput)
+	}
+	if req.StorageID == nil {
+		req.StorageID = lead.StorageID
+		if req.Quantity == 0 && lead.StorageQuantity != nil {
+			req.Quantity = *lead.StorageQuantity
+		}
+	}
+	if req.Quantity < 0 || req.Quantity > models.MaxStorageQuantity {
+		return nil, fmt.Errorf("%w: quantity must be 1-%d", models.ErrInvalidStorageInput, models.MaxStorageQuantity)
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.CriticalLoadPct == 0 {
+		req.CriticalLoadPct = defaultCriticalLoadPct
+	}
+	reservePct := defaultBackupReservePct
+	if req.BackupReservePct != nil {
+		reservePct = *req.BackupReservePct
+	}
+	if req.TargetBackupHours < 0 {
+		return nil, fmt.Errorf("%w: target backup hours must not be negative", models.ErrInvalidStorageInput)
+	}
+	if req.TargetBackupHours == 0 {
+		req.TargetBackupHours = defaultTargetBackupHours
+	}
+	tariff := Tariff{Type: TariffFlat, FlatRate: defaultQuoteAssumptions().UtilityRatePerKWh, TrueUp: TrueUpAnnual}
+	if req.Tariff != nil {
+		tariff = *req.Tariff
+	}
+
+	production, err := s.productionService.EstimateForLead(ctx, lead, req.TiltDeg, req.AzimuthDeg)
+	if err != nil {
+		return nil, err
+	}
+	base := StorageSimInput{
+		Tariff:            tariff,
+		LoadKWh:           hourlyLoadProfile(consumption),
+		SolarKWh:          production.HourlyKWh,
+		CriticalLoadPct:   req.CriticalLoadPct,
+		AllowGridCharging: req.AllowGridCharging,
+	}
+	simulate := func(unit *models.Storage, quantity int) (*StorageOption, error) {
+		in := base
+		in.Storage = storageConfigFor(unit, quantity, reservePct/100)
+		sim, err := SimulateStorage(in)
+		if err != nil {
+			return nil, err
+		}
+		return &StorageOption{
+			StorageID:         unit.ID,
+			Manufacturer:      unit.Manufacturer,
+			Model:             unit.Model,
+			Quantity:          quantity,
+			StorageSimulation: *sim,
+		}, nil
+	}
+
+	analysis := &StorageAnalysis{
+		LeadID:              lead.ID,
+		AnnualProductionKWh: production.AnnualKWh,
+		CriticalLoadPct:     req.CriticalLoadPct,
+		TargetBackupHours:   req.TargetBackupHours,
+	}
+	for _, kwh := range consumption {
+		analysis.AnnualConsumptionKWh += kwh
+	}
+	analysis.AnnualConsumptionKWh = round2(analysis.AnnualConsumptionKWh)
+
+	var candidates []*models.Storage
+	maxQuantity := catalogRecommendationQuantity
+	if req.StorageID != nil {
+		unit, err := s.hardwareRepo.GetStorageByID(ctx, *req.StorageID)
+		if err != nil {
+			return nil, err
+		}
+		if analysis.Selected, err = simulate(unit, req.Quantity); err != nil {
+			return nil, err
+		}
+		candidates = []*models.Storage{unit}
+		maxQuantity = models.MaxStorageQuantity
+	} else {
+		storages, err := s.hardwareRepo.ListActiveStorages(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, unit := range storages {
+			if unit.Capacity > 0 {
+				candidates = append(candidates, unit)
+			}
+		}
+	}
+
+	var options []*StorageOption
+	for _, unit := range candidates {
+		for quantity := 1; quantity <= maxQuantity; quantity++ {
+			option, err := simulate(unit, quantity)
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, option)
+		}
+	}
+	analysis.Recommendation, analysis.RecommendationReason = recommendStorage(options, req.TargetBackupHours, maxQuantity)
+	return analysis, nil
+}
+
+// recommendStorage picks the smallest bank whose average backup meets the
+// target, preferring higher savings between equal sizes. If none meets it
+// the longest-lasting bank of up to maxQuantity units is picked.
+func recommendStorage(options []*StorageOption, targetHours float64, maxQuantity int) (*StorageOption, string) {
+	if len(options) == 0 {
+		return nil, "No storage units with a capacity are in the catalog"
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		a, b := options[i], options[j]
+		if a.Storage.CapacityKWh != b.Storage.CapacityKWh {
+			return a.Storage.CapacityKWh < b.Storage.CapacityKWh
+		}
+		return a.AnnualSavings > b.AnnualSavings
+	})
+	for _, option := range options {
+		if option.BackupHoursAvg >= targetHours {
+			return option, fmt.Sprintf("Smallest bank averaging at least %.0f hours of backup", targetHours)
+		}
+	}
+	longest := options[0]
+	for _, option := range options[1:] {
+		if option.BackupHoursAvg > longest.BackupHoursAvg {
+			longest = option
+		}
+	}
+	return longest, fmt.Sprintf("No bank of up to %d units averages %.0f hours of backup; this one lasts longest", maxQuantity, targetHours)
+}
+
+// storageConfigFor sizes a bank of quantity units, filling specs the catalog
+// lacks with typical lithium-ion values.
+func storageConfigFor(unit *models.Storage, quantity int, reserveFraction float64) StorageConfig {
+	capacity := unit.UsableCapacity()
+	power := capacity * defaultStorageCRate
+	if unit.PowerKW != nil {
+		power = *unit.PowerKW
+	}
+	efficiency := defaultStorageRoundTripEfficiency
+	if unit.RoundTripEfficiency != nil {
+		efficiency = *unit.RoundTripEfficiency
+	}
+	return StorageConfig{
+		CapacityKWh:         capacity * float64(quantity),
+		PowerKW:             power * float64(quantity),
+		RoundTripEfficiency: efficiency,
+		ReserveFraction:     reserveFraction,
+	}
+}
+
+// leadMonthlyConsumption returns the lead's consumption as 12 monthly kWh
+// values. Consumption is given per the lead's period: 12 values are the
+// months, and a single value is the usage of one period, spread evenly over
+// the year. Without consumption, the kWh usage is taken as the annual
+// total. It returns nil if the lead has neither, and an error when the
+// consumption is in a unit other than kWh or an unknown period.
+func leadMonthlyConsumption(lead *models.Lead) ([]float64, error) {
+	annual := lead.KwhUsage
+	if len(lead.Consumption) > 0 {
+		if unit := strings.ToLower(lead.Unit); unit != "" && unit != "kwh" {
+			return nil, fmt.Errorf("lead consumption is in %q; only kwh can be simulated", lead.Unit)
+		}
+		periodsPerYear := 0.0
+		switch strings.ToLower(lead.Period) {
+		case "", "year":
+			periodsPerYear = 1
+		case "month":
+			periodsPerYear = 12
+		default:
+			return nil, fmt.Errorf("lead consumption period %q is neither year nor month", lead.Period)
+		}
+		switch len(lead.Consumption) {
+		case 12:
+			months := make([]float64, 12)
+			for i, kwh := range lead.Consumption {
+				months[i] = float64(kwh)
+			}
+			return months, nil
+		case 1:
+			annual = float64(lead.Consumption[0]) * periodsPerYear
+		}
+	}
+	if annual <= 0 {
+		return nil, nil
+	}
+	months := make([]float64, 12)
+	for i := range months {
+		months[i] = annual / 12
+	}
+	return months, nil
+}
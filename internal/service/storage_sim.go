@@ -0,0 +1,258 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+)
+
+const (
+	StorageDispatchSelfConsumption = "self_consumption"
+	StorageDispatchTOU             = "tou"
+)
+
+// Defaults for storage specs the catalog leaves empty.
+const (
+	defaultStorageCRate               = 0.4 // continuous kW per kWh of capacity
+	defaultStorageRoundTripEfficiency = 0.9
+	// Backup runs are simulated from 6 pm, when solar is gone and the
+	// evening peak begins, and capped at three days.
+	backupStartHour = 18
+	maxBackupHours  = 72
+)
+
+// StorageConfig is an installed battery bank. ReserveFraction of the
+// capacity is held back from daily cycling for outages.
+type StorageConfig struct {
+	CapacityKWh         float64 `json:"capacity_kwh" example:"27"`
+	PowerKW             float64 `json:"power_kw" example:"10"`
+	RoundTripEfficiency float64 `json:"round_trip_efficiency" example:"0.9"`
+	ReserveFraction     float64 `json:"reserve_fraction" example:"0.2"`
+}
+
+// StorageSimInput is a year of hourly load and solar production, both with
+// 8760 values starting at midnight on 1 January.
+type StorageSimInput struct {
+	Tariff            Tariff
+	LoadKWh           []float64
+	SolarKWh          []float64
+	Storage           StorageConfig
+	CriticalLoadPct   float64
+	AllowGridCharging bool
+}
+
+// StorageSimulation compares a year with solar alone against solar plus
+// storage. Savings are first-year bill savings at today's rates.
+type StorageSimulation struct {
+	Storage                   StorageConfig `json:"storage"`
+	Dispatch                  string        `json:"dispatch" enums:"self_consumption,tou" example:"tou"`
+	SelfConsumptionPct        float64       `json:"self_consumption_pct" example:"78.4"`
+	SelfConsumptionWithoutPct float64       `json:"self_consumption_without_storage_pct" example:"46.2"`
+	SelfConsumptionGainPct    float64       `json:"self_consumption_gain_pct" example:"32.2"`
+	AnnualBillSolarOnly       float64       `json:"annual_bill_solar_only" example:"1380"`
+	AnnualBillWithStorage     float64       `json:"annual_bill_with_storage" example:"910"`
+	SelfConsumptionSavings    float64       `json:"self_consumption_savings" example:"310"`
+	TOUArbitrageSavings       float64       `json:"tou_arbitrage_savings" example:"160"`
+	AnnualSavings             float64       `json:"annual_savings" example:"470"`
+	BatteryDischargedKWh      float64       `json:"battery_discharged_kwh" example:"3900"`
+	GridChargedKWh            float64       `json:"grid_charged_kwh" example:"0"`
+	EquivalentCycles          float64       `json:"equivalent_cycles" example:"144"`
+	BackupHoursAvg            float64       `json:"backup_hours_avg" example:"21.5"`
+	BackupHoursMin            float64       `json:"backup_hours_min" example:"11"`
+	MonthlyBackupHours        []float64     `json:"monthly_backup_hours"`
+}
+
+type storageRun struct {
+	grid           []float64
+	soc            []float64 // state of charge at the start of each hour
+	dischargedKWh  float64
+	gridChargedKWh float64
+}
+
+// SimulateStorage dispatches the battery hour by hour. Self-consumption
+// dispatch stores solar surplus and discharges whenever the home draws from
+// the grid. On a TOU tariff it also tries TOU dispatch, which saves the
+// charge for hours dearer than the day's cheapest, optionally topping up
+// from the grid in those cheapest hours, and keeps whichever bills less.
+//
+// Backup duration is simulated for an outage starting at 6 pm every day
+// from the state of charge the dispatch left, with the battery and solar
+// carrying CriticalLoadPct of the load.
+func SimulateStorage(in StorageSimInput) (*StorageSimulation, error) {
+	if err := in.Tariff.Validate(); err != nil {
+		return nil, err
+	}
+	if len(in.LoadKWh) != 8760 || len(in.SolarKWh) != 8760 {
+		return nil, fmt.Errorf("%w: load and solar need 8760 hourly values", models.ErrInvalidStorageInput)
+	}
+	cfg := in.Storage
+	if cfg.CapacityKWh <= 0 || cfg.PowerKW <= 0 {
+		return nil, fmt.Errorf("%w: storage capacity and power must be greater than 0", models.ErrInvalidStorageInput)
+	}
+	if cfg.RoundTripEfficiency <= 0 || cfg.RoundTripEfficiency > 1 || cfg.ReserveFraction < 0 || cfg.ReserveFraction >= 1 {
+		return nil, fmt.Errorf("%w: efficiency must be 0-1 and reserve below 1", models.ErrInvalidStorageInput)
+	}
+	if in.CriticalLoadPct <= 0 || in.CriticalLoadPct > 100 {
+		return nil, fmt.Errorf("%w: critical load must be 1-100%%", models.ErrInvalidStorageInput)
+	}
+
+	solarOnly := make([]float64, len(in.LoadKWh))
+	for h := range solarOnly {
+		solarOnly[h] = in.LoadKWh[h] - in.SolarKWh[h]
+	}
+	solarOnlyBill := billHours(in.Tariff, in.LoadKWh, in.SolarKWh, solarOnly).AnnualPostSolar
+
+	self := dispatchStorage(in, StorageDispatchSelfConsumption)
+	selfBill := billHours(in.Tariff, in.LoadKWh, in.SolarKWh, self.grid).AnnualPostSolar
+	best, bestBill, dispatch := self, selfBill, StorageDispatchSelfConsumption
+	if in.Tariff.Type == TariffTOU {
+		tou := dispatchStorage(in, StorageDispatchTOU)
+		if touBill := billHours(in.Tariff, in.LoadKWh, in.SolarKWh, tou.grid).AnnualPostSolar; touBill < selfBill {
+			best, bestBill, dispatch = tou, touBill, StorageDispatchTOU
+		}
+	}
+
+	sim := &StorageSimulation{
+		Storage:                   cfg,
+		Dispatch:                  dispatch,
+		SelfConsumptionPct:        selfConsumptionPct(in.SolarKWh, best.grid),
+		SelfConsumptionWithoutPct: selfConsumptionPct(in.SolarKWh, solarOnly),
+		AnnualBillSolarOnly:       round2(solarOnlyBill),
+		AnnualBillWithStorage:     round2(bestBill),
+		SelfConsumptionSavings:    round2(solarOnlyBill - selfBill),
+		TOUArbitrageSavings:       round2(selfBill - bestBill),
+		AnnualSavings:             round2(solarOnlyBill - bestBill),
+		BatteryDischargedKWh:      round2(best.dischargedKWh),
+		GridChargedKWh:            round2(best.gridChargedKWh),
+		EquivalentCycles:          round2(best.dischargedKWh / cfg.CapacityKWh),
+	}
+	sim.SelfConsumptionGainPct = round2(sim.SelfConsumptionPct - sim.SelfConsumptionWithoutPct)
+	sim.BackupHoursAvg, sim.BackupHoursMin, sim.MonthlyBackupHours = backupHours(in, best.soc)
+	return sim, nil
+}
+
+func dispatchStorage(in StorageSimInput, dispatch string) storageRun {
+	cfg := in.Storage
+	oneWay := math.Sqrt(cfg.RoundTripEfficiency)
+	reserve := cfg.CapacityKWh * cfg.ReserveFraction
+	run := storageRun{grid: make([]float64, len(in.LoadKWh)), soc: make([]float64, len(in.LoadKWh))}
+	soc := reserve
+	at := time.Date(billingYear, 1, 1, 0, 0, 0, 0, time.UTC)
+	for day := 0; day*24 < len(in.LoadKWh); day++ {
+		start := day * 24
+		var rates [24]float64
+		minRate, maxRate := math.Inf(1), math.Inf(-1)
+		for h := range rates {
+			rates[h] = in.Tariff.retailRate(at.Add(time.Duration(h) * time.Hour))
+			minRate = math.Min(minRate, rates[h])
+			maxRate = math.Max(maxRate, rates[h])
+		}
+		// On a flat day there is nothing to shift, so discharge freely.
+		shift := dispatch == StorageDispatchTOU && maxRate*cfg.RoundTripEfficiency > minRate
+		// surplusAfter[h] is the solar surplus still to come today, used
+		// to leave room for it when charging from the grid.
+		var surplusAfter [25]float64
+		for h := 23; h >= 0; h-- {
+			surplusAfter[h] = surplusAfter[h+1] + math.Max(0, in.SolarKWh[start+h]-in.LoadKWh[start+h])
+		}
+
+		for h := 0; h < 24; h++ {
+			i := start + h
+			run.soc[i] = soc
+			net := in.LoadKWh[i] - in.SolarKWh[i]
+			if net < 0 {
+				charge := math.Min(math.Min(-net, cfg.PowerKW), (cfg.CapacityKWh-soc)/oneWay)
+				soc += charge * oneWay
+				run.grid[i] = net + charge
+				continue
+			}
+			if shift && rates[h] == minRate {
+				if in.AllowGridCharging {
+					target := cfg.CapacityKWh - surplusAfter[h+1]*oneWay
+					if soc < target {
+						charge := math.Min(cfg.PowerKW, (target-soc)/oneWay)
+						soc += charge * oneWay
+						run.gridChargedKWh += charge
+						net += charge
+					}
+				}
+				run.grid[i] = net
+				continue
+			}
+			discharge := math.Min(math.Min(net, cfg.PowerKW), math.Max(0, soc-reserve)*oneWay)
+			soc -= discharge / oneWay
+			run.dischargedKWh += discharge
+			run.grid[i] = net - discharge
+		}
+		at = at.AddDate(0, 0, 1)
+	}
+	return run
+}
+
+// selfConsumptionPct is the share of solar production used on site rather
+// than exported.
+func selfConsumptionPct(solar, grid []float64) float64 {
+	produced, exported := 0.0, 0.0
+	for h := range solar {
+		produced += solar[h]
+		if grid[h] < 0 {
+			exported -= grid[h]
+		}
+	}
+	if produced == 0 {
+		return 0
+	}
+	return round2((produced - exported) / produced * 100)
+}
+
+// backupHours returns the average, the minimum and the monthly averages of
+// the hours the critical load stays powered for outages starting at 6 pm
+// each day. During an outage the whole capacity, reserve included, is
+// available and solar keeps recharging the battery.
+func backupHours(in StorageSimInput, socAt []float64) (avg, shortest float64, monthly []float64) {
+	cfg := in.Storage
+	oneWay := math.Sqrt(cfg.RoundTripEfficiency)
+	critical := in.CriticalLoadPct / 100
+	n := len(in.LoadKWh)
+	monthlyTotals := make([]float64, 12)
+	monthlyDays := make([]float64, 12)
+	shortest = math.Inf(1)
+	total := 0.0
+	days := 0
+	at := time.Date(billingYear, 1, 1, 0, 0, 0, 0, time.UTC)
+	for start := backupStartHour; start < n; start += 24 {
+		soc := socAt[start]
+		hours := 0.0
+		for k := 0; k < maxBackupHours; k++ {
+			i := (start + k) % n
+			net := in.LoadKWh[i]*critical - in.SolarKWh[i]
+			if net <= 0 {
+				soc += math.Min(math.Min(-net, cfg.PowerKW), (cfg.CapacityKWh-soc)/oneWay) * oneWay
+				hours++
+				continue
+			}
+			available := math.Min(cfg.PowerKW, soc*oneWay)
+			if available < net {
+				hours += available / net
+				break
+			}
+			soc -= net / oneWay
+			hours++
+		}
+		month := at.AddDate(0, 0, start/24).Month() - 1
+		monthlyTotals[month] += hours
+		monthlyDays[month]++
+		total += hours
+		days++
+		shortest = math.Min(shortest, hours)
+	}
+	monthly = make([]float64, 12)
+	for m := range monthly {
+		if monthlyDays[m] > 0 {
+			monthly[m] = round2(monthlyTotals[m] / monthlyDays[m])
+		}
+	}
+	return round2(total / float64(days)), round2(shortest), monthly
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"github.com/Bilal-Cplusoft/sunready/internal/repo"
+)
+
+// The lead services reach the database through these interfaces, which the
+// repo types implement, so that lead creation and the job queue can be run
+// against in-memory stores in tests.
+
+// Transactor runs work in one transaction; see repo.Transactor.
+type Transactor interface {
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// LeadStore is the part of repo.LeadRepo the lead services use.
+type LeadStore interface {
+	Create(ctx context.Context, lead *models.Lead) error
+	GetByID(ctx context.Context, id int) (*models.Lead, error)
+	GetLeadWithUserByLeadID(ctx context.Context, leadID int) (*models.Lead, error)
+	UpdateFields(ctx context.Context, id int, fields map[string]any) error
+	UpdateLocked(ctx context.Context, id int, update func(lead *models.Lead) (map[string]any, error)) (*models.Lead, error)
+	TransitionState(ctx context.Context, entry *models.LeadStateHistory) error
+	ListStateHistory(ctx context.Context, leadID int) ([]*models.LeadStateHistory, error)
+}
+
+// HouseStore is the part of repo.HouseRepo the lead services use.
+type HouseStore interface {
+	Create(ctx context.Context, house *models.House) error
+}
+
+// UserStore is the part of repo.UserRepo the lead services use.
+type UserStore interface {
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	ExistsByID(ctx context.Context, id int) (bool, error)
+}
+
+// HardwareStore is the part of repo.HardwareRepo the lead and design
+// services use.
+type HardwareStore interface {
+	GetPanelByID(ctx context.Context, id int) (*models.Panel, error)
+	GetInverterByID(ctx context.Context, id int) (*models.Inverter, error)
+	GetStorageByID(ctx context.Context, id int) (*models.Storage, error)
+}
+
+// JobStore is the part of repo.JobRepo the job queue uses.
+type JobStore interface {
+	Enqueue(ctx context.Context, job *models.Job) error
+	ClaimNext(ctx context.Context, staleAfter time.Duration) (*models.Job, error)
+	Complete(ctx context.Context, id int) error
+	Retry(ctx context.Context, job *models.Job, runAt time.Time, lastErr string) error
+	Bury(ctx context.Context, job *models.Job, lastErr string) error
+}
+
+var (
+	_ Transactor    = (*repo.Transactor)(nil)
+	_ LeadStore     = (*repo.LeadRepo)(nil)
+	_ HouseStore    = (*repo.HouseRepo)(nil)
+	_ UserStore     = (*repo.UserRepo)(nil)
+	_ HardwareStore = (*repo.HardwareRepo)(nil)
+	_ JobStore      = (*repo.JobRepo)(nil)
+)
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bilal-Cplusoft/sunready/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// In-memory stores for running the lead services without a database. They
+// return the same errors as the repo types but do not roll anything back
+// when a transaction fails.
+
+type memoryTransactor struct{}
+
+func (memoryTransactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type memoryUsers struct {
+	mu    sync.Mutex
+	users map[int]*models.User
+}
+
+func newMemoryUsers() *memoryUsers {
+	return &memoryUsers{users: make(map[int]*models.User)}
+}
+
+func (s *memoryUsers) Create(user *models.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user.ID = len(s.users) + 1
+	stored := *user
+	s.users[user.ID] = &stored
+}
+
+func (s *memoryUsers) GetByID(ctx context.Context, id int) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *user
+	return &found, nil
+}
+
+func (s *memoryUsers) ExistsByID(ctx context.Context, id int) (bool, error) {
+	_, err := s.GetByID(ctx, id)
+	return err == nil, nil
+}
+
+type memoryHouses struct {
+	mu     sync.Mutex
+	houses []models.House
+}
+
+func (s *memoryHouses) Create(ctx context.Context, house *models.House) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	house.ID = uint(len(s.houses) + 1)
+	s.houses = append(s.houses, *house)
+	return nil
+}
+
+type memoryHardware struct {
+	mu        sync.Mutex
+	panels    map[int]*models.Panel
+	inverters map[int]*models.Inverter
+	storage   map[int]*models.Storage
+}
+
+func newMemoryHardware() *memoryHardware {
+	return &memoryHardware{
+		panels:    make(map[int]*models.Panel),
+		inverters: make(map[int]*models.Inverter),
+		storage:   make(map[int]*models.Storage),
+	}
+}
+
+func (s *memoryHardware) CreatePanel(panel *models.Panel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	panel.ID = len(s.panels) + 1
+	stored := *panel
+	s.panels[panel.ID] = &stored
+}
+
+func (s *memoryHardware) CreateInverter(inverter *models.Inverter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inverter.ID = len(s.inverters) + 1
+	stored := *inverter
+	s.inverters[inverter.ID] = &stored
+}
+
+func (s *memoryHardware) GetPanelByID(ctx context.Context, id int) (*models.Panel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	panel, ok := s.panels[id]
+	if !ok {
+		return nil, models.ErrPanelNotFound
+	}
+	found := *panel
+	return &found, nil
+}
+
+func (s *memoryHardware) GetInverterByID(ctx context.Context, id int) (*models.Inverter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inverter, ok := s.inverters[id]
+	if !ok {
+		return nil, models.ErrInverterNotFound
+	}
+	found := *inverter
+	return &found, nil
+}
+
+func (s *memoryHardware) GetStorageByID(ctx context.Context, id int) (*models.Storage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	storage, ok := s.storage[id]
+	if !ok {
+		return nil, models.ErrStorageNotFound
+	}
+	found := *storage
+	return &found, nil
+}
+
+type memoryLeads struct {
+	mu      sync.Mutex
+	users   *memoryUsers
+	leads   map[int]*models.Lead
+	history []*models.LeadStateHistory
+}
+
+func newMemoryLeads(users *memoryUsers) *memoryLeads {
+	return &memoryLeads{users: users, leads: make(map[int]*models.Lead)}
+}
+
+func (s *memoryLeads) Create(ctx context.Context, lead *models.Lead) error {
+	if err := lead.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lead.ID = len(s.leads) + 1
+	stored := *lead
+	s.leads[lead.ID] = &stored
+	return nil
+}
+
+func (s *memoryLeads) GetByID(ctx context.Context, id int) (*models.Lead, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lead, ok := s.leads[id]
+	if !ok {
+		return nil, models.ErrLeadNotFound
+	}
+	found := *lead
+	return &found, nil
+}
+
+func (s *memoryLeads) GetLeadWithUserByLeadID(ctx context.Context, leadID int) (*models.Lead, error) {
+	lead, err := s.GetByID(ctx, leadID)
+	if err != nil {
+		return nil, err
+	}
+	if lead.UserID != nil {
+		user, err := s.users.GetByID(ctx, *lead.UserID)
+		if err != nil {
+			return nil, err
+		}
+		lead.User = *user
+	}
+	return lead, nil
+}
+
+func (s *memoryLeads) UpdateFields(ctx context.Context, id int, fields map[string]any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lead, ok := s.leads[id]
+	if !ok {
+		return models.ErrLeadNotFound
+	}
+	return setLeadColumns(lead, fields)
+}
+
+func (s *memoryLeads) UpdateLocked(ctx context.Context, id int, update func(lead *models.Lead) (map[string]any, error)) (*models.Lead, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.leads[id]
+	if !ok {
+		return nil, models.ErrLeadNotFound
+	}
+	lead := *stored
+	fields, err := update(&lead)
+	if err != nil {
+		return nil, err
+	}
+	if err := setLeadColumns(stored, fields); err != nil {
+		return nil, err
+	}
+	return &lead, nil
+}
+
+func (s *memoryLeads) TransitionState(ctx context.Context, entry *models.LeadStateHistory) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lead, ok := s.leads[entry.LeadID]
+	if !ok || (entry.FromState != nil && lead.State != *entry.FromState) {
+		return models.ErrLeadStateConflict
+	}
+	lead.State = entry.ToState
+	entry.ID = len(s.history) + 1
+	entry.CreatedAt = time.Now()
+	stored := *entry
+	s.history = append(s.history, &stored)
+	return nil
+}
+
+func (s *memoryLeads) ListStateHistory(ctx context.Context, leadID int) ([]*models.LeadStateHistory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var history []*models.LeadStateHistory
+	for _, entry := range s.history {
+		if entry.LeadID == leadID {
+			found := *entry
+			history = append(history, &found)
+		}
+	}
+	return history, nil
+}
+
+// setLeadColumns applies an update keyed by column name, as GORM's
+// Updates does.
+func setLeadColumns(lead *models.Lead, fields map[string]any) error {
+	leadSchema, err := schema.Parse(&models.Lead{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+	for column, value := range fields {
+		field := leadSchema.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("leads has no column %q", column)
+		}
+		if err := field.Set(context.Background(), reflect.ValueOf(lead).Elem(), value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", column, err)
+		}
+	}
+	return nil
+}
+
+type memoryJobs struct {
+	mu   sync.Mutex
+	last int
+	jobs map[int]*models.Job
+	dead []models.Job
+}
+
+func newMemoryJobs() *memoryJobs {
+	return &memoryJobs{jobs: make(map[int]*models.Job)}
+}
+
+func (s *memoryJobs) Enqueue(ctx context.Context, job *models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job.Status == "" {
+		job.Status = models.JobStatusPending
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	s.last++
+	job.ID = s.last
+	stored := *job
+	s.jobs[job.ID] = &stored
+	return nil
+}
+
+func (s *memoryJobs) ClaimNext(ctx context.Context, staleAfter time.Duration) (*models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var runnable []*models.Job
+	for _, job := range s.jobs {
+		if (job.Status == models.JobStatusPending && !job.RunAt.After(now)) ||
+			(job.Status == models.JobStatusRunning && job.LockedAt.Before(now.Add(-staleAfter))) {
+			runnable = append(runnable, job)
+		}
+	}
+	if len(runnable) == 0 {
+		return nil, nil
+	}
+	sort.Slice(runnable, func(i, j int) bool { return runnable[i].RunAt.Before(runnable[j].RunAt) })
+	job := runnable[0]
+	job.Status = models.JobStatusRunning
+	job.LockedAt = &now
+	job.Attempts++
+	claimed := *job
+	return &claimed, nil
+}
+
+func (s *memoryJobs) Complete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
+
+func (s *memoryJobs) Retry(ctx context.Context, job *models.Job, runAt time.Time, lastErr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.jobs[job.ID]; ok {
+		stored.Status = models.JobStatusPending
+		stored.RunAt = runAt
+		stored.LockedAt = nil
+		stored.LastError = lastErr
+	}
+	return nil
+}
+
+func (s *memoryJobs) Bury(ctx context.Context, job *models.Job, lastErr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dead := *job
+	dead.LastError = lastErr
+	s.dead = append(s.dead, dead)
+	delete(s.jobs, job.ID)
+	return nil
+}